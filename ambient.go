@@ -0,0 +1,189 @@
+package main
+
+import (
+	"fmt"
+	"github.com/hajimehoshi/ebiten/v2"
+	"image/color"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var ambientColor = color.RGBA{R: 0x40, G: 0xc0, B: 0xc0, A: 0xff}
+
+// A looping sound placed in the world, e.g a waterfall or machinery, which gets louder as the camera approaches it.
+type Ambient struct {
+	// Center in world units
+	X, Y float64
+	// The sound is silent from this far away
+	Radius float64
+	// The sound to loop
+	Audio Audio
+}
+
+// Keeps the sound looping, mixed for a listener at the given point. Occluded is the thickness of terrain in between
+// in world units.
+func (a *Ambient) play(lx, ly, occluded float64) {
+	if !a.Audio.player.IsPlaying() {
+		_ = a.Audio.player.Seek(0)
+		a.Audio.player.Play()
+	}
+	a.Audio.PlaceWithin(a.Radius, lx, ly, a.X, a.Y, occluded)
+}
+
+// Draws the area the sound can be heard in
+func (a *Ambient) draw(screen *ebiten.Image, toScreen Mx) {
+	drawcircle(screen, a.X, a.Y, a.Radius, 1, toScreen, ambientColor)
+	drawpoint(screen, a.X, a.Y, 10, toScreen, ambientColor)
+	sx, sy := toScreen.Apply(a.X, a.Y)
+	printAt(screen, a.Audio.Path, int(sx)+8, int(sy)+8)
+}
+
+// Plays the level's ambient sounds, heard from the camera
+func (g *Game) playAmbience() {
+	for _, a := range g.ambients {
+		a.play(g.c.x, g.c.y, occlusion(&g.world, g.c.x, g.c.y, a.X, a.Y))
+	}
+}
+
+// Makes ambient sounds selectable, scaling changes their radius
+type AmbientSelector struct {
+	l *Level
+	a *Ambient
+}
+
+func (a *AmbientSelector) Delete() {
+	for i, o := range a.l.Ambients {
+		if o == a.a {
+			a.l.Ambients = append(a.l.Ambients[:i], a.l.Ambients[i+1:]...)
+			return
+		}
+	}
+}
+
+func (a *AmbientSelector) Transform() Mx {
+	var m Mx
+	m.Scale(2*a.a.Radius, 2*a.a.Radius)
+	m.Translate(a.a.X, a.a.Y)
+	return m
+}
+
+func (a *AmbientSelector) SetTransform(m Mx) {
+	a.a.X, a.a.Y = m.Apply(0, 0)
+	// use the larger side as the diameter, the area is always a circle
+	rx, ry := m.Apply(0.5, 0)
+	ux, uy := m.Apply(0, 0.5)
+	a.a.Radius = math.Max(math.Hypot(rx-a.a.X, ry-a.a.Y), math.Hypot(ux-a.a.X, uy-a.a.Y))
+}
+
+// Editor for placing ambient sounds. Click to place one using the current settings, which can be changed by typing
+// commands like "sound resources/wind.wav", "radius 10" or "volume 0.5".
+type AmbientEditor struct {
+	// Settings for the next sound to place
+	path   string
+	radius float64
+	volume float64
+	t      *Typer
+
+	e *Editor
+}
+
+func ActivateAmbientEditor(r *Root, e *Editor) {
+	a := &AmbientEditor{
+		radius: 10,
+		volume: 1,
+		t:      &Typer{C: &e.c},
+		e:      e,
+	}
+	a.t.Placeholder = a.describe()
+	r.a = a
+}
+
+func (a *AmbientEditor) Layout(outsideWidth, outsideHeight int) (screenWidth, screenHeight int) {
+	return a.e.Layout(outsideWidth, outsideHeight)
+}
+
+func (a *AmbientEditor) String() string {
+	return "Ambience"
+}
+
+// Summarizes the settings for the next sound
+func (a *AmbientEditor) describe() string {
+	path := a.path
+	if path == "" {
+		path = "none"
+	}
+	return fmt.Sprintf("Ambience Editor: sound %v, radius %v, volume %v. Enter to change.", path, a.radius, a.volume)
+}
+
+// Applies a settings command of the form "<setting> <value>"
+func (a *AmbientEditor) apply(cmd string) error {
+	parts := strings.Fields(cmd)
+	if len(parts) != 2 {
+		return fmt.Errorf("expected '<setting> <value>'")
+	}
+	switch parts[0] {
+	case "sound":
+		a.path = parts[1]
+	case "radius", "volume":
+		v, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil {
+			return fmt.Errorf("parse %v: %w", parts[0], err)
+		}
+		if parts[0] == "radius" {
+			a.radius = v
+		} else {
+			a.volume = v
+		}
+	default:
+		return fmt.Errorf("unknown setting %v", parts[0])
+	}
+	return nil
+}
+
+// Adds a sound with the current settings at the given point
+func (a *AmbientEditor) place(x, y float64) error {
+	if a.path == "" {
+		return fmt.Errorf("no sound chosen")
+	}
+	placed := &Ambient{X: x, Y: y, Radius: a.radius, Audio: Audio{Path: a.path}}
+	if a.volume != 1 {
+		volume := a.volume
+		placed.Audio.Volume = &volume
+	}
+	err := placed.Audio.Load()
+	if err != nil {
+		return err
+	}
+	a.e.l.Ambients = append(a.e.l.Ambients, placed)
+	return nil
+}
+
+func (a *AmbientEditor) Update(r *Root) error {
+	cmd, typ := a.t.Update()
+	if typ {
+		return nil
+	}
+	if cmd != "" {
+		err := a.apply(cmd)
+		if err != nil {
+			a.t.Placeholder = fmt.Sprintf("Failed to apply '%v': %v", cmd, err)
+		} else {
+			a.t.Placeholder = a.describe()
+		}
+	}
+	if MouseClicked(ebiten.MouseButtonLeft) {
+		x, y := a.e.c.Cursor()
+		err := a.place(x, y)
+		if err != nil {
+			a.t.Placeholder = fmt.Sprintf("Failed to place sound: %v", describe(err))
+		}
+	}
+	return a.e.Update(r)
+}
+
+func (a *AmbientEditor) Draw(screen *ebiten.Image) {
+	a.e.Draw(screen)
+	a.t.Draw(screen)
+	printAt(screen, "Click to place a looping sound", 10, a.e.c.sh-35)
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"fmt"
+	"github.com/ByteArena/box2d"
+	"github.com/hajimehoshi/ebiten/v2"
+	"image/color"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Ways an attractor's pull can fall off with distance
+const (
+	// Full strength anywhere within the radius
+	falloffConstant = "constant"
+	// Full strength at the center fading to nothing at the radius
+	falloffLinear = "linear"
+	// Strength divided by one plus the squared distance, like gravity
+	falloffInverseSquare = "inverse-square"
+)
+
+// A zone which pulls nearby dynamic bodies towards its center, e.g a magnet.
+type Attractor struct {
+	// Center in world units
+	X, Y float64
+	// Bodies further than this from the center are unaffected
+	Radius float64
+	// Acceleration towards the center in world units per second squared at full strength. Negative values repel.
+	Strength float64
+	// How the pull falls off with distance from the center, one of the falloff constants. Default is constant.
+	Falloff string `json:",omitempty"`
+	// If true only the player is pulled
+	PlayerOnly bool `json:",omitempty"`
+	// If true this is a gravity well, and in planetary levels the player's down points at the nearest one
+	Well bool `json:",omitempty"`
+}
+
+// The fraction of the attractor's strength applied at the given distance from its center.
+func (a *Attractor) falloff(d float64) float64 {
+	if d > a.Radius {
+		return 0
+	}
+	switch a.Falloff {
+	case falloffLinear:
+		return 1 - d/a.Radius
+	case falloffInverseSquare:
+		return 1 / (1 + d*d)
+	}
+	return 1
+}
+
+// Pulls the dynamic bodies of the world towards the attractor.
+func (a *Attractor) apply(g *Game) {
+	for b := g.world.GetBodyList(); b != nil; b = b.GetNext() {
+		if b.GetType() != box2d.B2BodyType.B2_dynamicBody {
+			continue
+		}
+		if _, ok := b.GetUserData().(*Player); a.PlayerOnly && !ok {
+			continue
+		}
+		c := b.GetWorldCenter()
+		if g.deterministic {
+			b.ApplyForceToCenter(a.fixedForce(c, b.GetMass()), true)
+			continue
+		}
+		dir := box2d.B2Vec2{X: a.X - c.X, Y: a.Y - c.Y}
+		d := dir.Normalize()
+		f := a.falloff(d)
+		if f == 0 || d == 0 {
+			continue
+		}
+		dir.OperatorScalarMulInplace(a.Strength * f * b.GetMass())
+		b.ApplyForceToCenter(dir, true)
+	}
+}
+
+// The force on a body with the given center and mass, calculated in fixed point for deterministic games.
+func (a *Attractor) fixedForce(c box2d.B2Vec2, mass float64) box2d.B2Vec2 {
+	dx, dy := toFixed(a.X-c.X), toFixed(a.Y-c.Y)
+	d := (dx.Mul(dx) + dy.Mul(dy)).Sqrt()
+	radius := toFixed(a.Radius)
+	if d == 0 || d > radius {
+		return box2d.B2Vec2{}
+	}
+	f := fixedOne
+	switch a.Falloff {
+	case falloffLinear:
+		f = fixedOne - d.Div(radius)
+	case falloffInverseSquare:
+		f = fixedOne.Div(fixedOne + d.Mul(d))
+	}
+	scale := toFixed(a.Strength).Mul(f).Mul(toFixed(mass)).Div(d)
+	return box2d.B2Vec2{X: dx.Mul(scale).Float(), Y: dy.Mul(scale).Float()}
+}
+
+// Draws the attractor's area of effect
+func (a *Attractor) draw(screen *ebiten.Image, toScreen Mx) {
+	clr := color.RGBA{B: 0xff, A: 0xff}
+	if a.Strength < 0 {
+		clr = color.RGBA{R: 0xff, B: 0xff, A: 0xff}
+	}
+	drawcircle(screen, a.X, a.Y, a.Radius, 2, toScreen, clr)
+	if a.Well {
+		drawcircle(screen, a.X, a.Y, a.Radius/2, 1, toScreen, clr)
+	}
+	drawpoint(screen, a.X, a.Y, 10, toScreen, clr)
+}
+
+// Editor for placing attractors. Click to place one using the current settings, which can be changed by typing
+// commands like "strength 20", "radius 3", "falloff linear", "player on" or "well on". "planetary on" makes the level
+// planetary.
+type AttractorEditor struct {
+	// Settings for the next attractor to place
+	next Attractor
+	t    *Typer
+
+	e *Editor
+}
+
+func ActivateAttractorEditor(r *Root, e *Editor) {
+	a := &AttractorEditor{
+		next: Attractor{
+			Radius:   3,
+			Strength: 20,
+			Falloff:  falloffLinear,
+		},
+		t: &Typer{C: &e.c},
+		e: e,
+	}
+	a.t.Placeholder = a.describe()
+	r.a = a
+}
+
+func (a *AttractorEditor) Layout(outsideWidth, outsideHeight int) (screenWidth, screenHeight int) {
+	return a.e.Layout(outsideWidth, outsideHeight)
+}
+
+func (a *AttractorEditor) String() string {
+	return "Magnets"
+}
+
+// Summarizes the settings for the next attractor
+func (a *AttractorEditor) describe() string {
+	return fmt.Sprintf("Magnet Editor: radius %v, strength %v, falloff %v, player only %v, well %v, planetary %v. "+
+		"Enter to change.", a.next.Radius, a.next.Strength, a.next.Falloff, a.next.PlayerOnly, a.next.Well,
+		a.e.l.Planetary)
+}
+
+// Applies a settings command of the form "<setting> <value>"
+func (a *AttractorEditor) apply(cmd string) error {
+	parts := strings.Fields(cmd)
+	if len(parts) != 2 {
+		return fmt.Errorf("expected '<setting> <value>'")
+	}
+	switch parts[0] {
+	case "radius", "strength":
+		v, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil {
+			return fmt.Errorf("parse %v: %w", parts[0], err)
+		}
+		if parts[0] == "radius" {
+			a.next.Radius = v
+		} else {
+			a.next.Strength = v
+		}
+	case "falloff":
+		switch parts[1] {
+		case falloffConstant, falloffLinear, falloffInverseSquare:
+			a.next.Falloff = parts[1]
+		default:
+			return fmt.Errorf("unknown falloff %v", parts[1])
+		}
+	case "player":
+		a.next.PlayerOnly = parts[1] == "on"
+	case "well":
+		a.next.Well = parts[1] == "on"
+	case "planetary":
+		// a level setting, but only useful with wells
+		a.e.l.Planetary = parts[1] == "on"
+	default:
+		return fmt.Errorf("unknown setting %v", parts[0])
+	}
+	return nil
+}
+
+func (a *AttractorEditor) Update(r *Root) error {
+	cmd, typ := a.t.Update()
+	if typ {
+		return nil
+	}
+	if cmd != "" {
+		err := a.apply(cmd)
+		if err != nil {
+			a.t.Placeholder = fmt.Sprintf("Failed to apply '%v': %v", cmd, err)
+		} else {
+			a.t.Placeholder = a.describe()
+		}
+	}
+	if MouseClicked(ebiten.MouseButtonLeft) {
+		placed := a.next
+		placed.X, placed.Y = a.e.c.Cursor()
+		a.e.l.Attractors = append(a.e.l.Attractors, &placed)
+	}
+	return a.e.Update(r)
+}
+
+func (a *AttractorEditor) Draw(screen *ebiten.Image) {
+	a.e.Draw(screen)
+	a.t.Draw(screen)
+	printAt(screen, "Click to place a magnet", 10, a.e.c.sh-35)
+}
+
+// Makes attractors selectable, scaling changes their radius
+type AttractorSelector struct {
+	l *Level
+	a *Attractor
+}
+
+func (a *AttractorSelector) Paste() Selectable {
+	kopy := *a.a
+	a.l.Attractors = append(a.l.Attractors, &kopy)
+	return &AttractorSelector{l: a.l, a: &kopy}
+}
+
+func (a *AttractorSelector) Delete() {
+	for i, o := range a.l.Attractors {
+		if o == a.a {
+			a.l.Attractors = append(a.l.Attractors[:i], a.l.Attractors[i+1:]...)
+			return
+		}
+	}
+}
+
+func (a *AttractorSelector) Transform() Mx {
+	var m Mx
+	m.Scale(2*a.a.Radius, 2*a.a.Radius)
+	m.Translate(a.a.X, a.a.Y)
+	return m
+}
+
+func (a *AttractorSelector) SetTransform(m Mx) {
+	a.a.X, a.a.Y = m.Apply(0, 0)
+	// use the larger side as the diameter, attractors are always circles
+	rx, ry := m.Apply(0.5, 0)
+	ux, uy := m.Apply(0, 0.5)
+	a.a.Radius = math.Max(math.Hypot(rx-a.a.X, ry-a.a.Y), math.Hypot(ux-a.a.X, uy-a.a.Y))
+}
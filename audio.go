@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/ByteArena/box2d"
+	"github.com/hajimehoshi/ebiten/v2/audio"
+	"github.com/hherman1/gobananas/resources"
+	"io"
+	"math"
+	"sync"
+)
+
+const (
+	// Distance in world units from the listener past which positional sounds are silent.
+	audioFalloff = 30.0
+	// Horizontal distance in world units from the listener at which a sound is panned entirely to one side.
+	audioPanWidth = 15.0
+	// Fraction of a sound's volume lost per world unit of terrain between it and the listener.
+	audioOcclusionLoss = 0.15
+	// The most volume terrain can take away from a sound.
+	audioMaxOcclusion = 0.8
+	// Strength of the low pass filter on fully occluded sounds, between 0 for none and 1 for silence.
+	audioOcclusionMuffle = 0.9
+)
+
+// Serializable audio file reference for use in level files
+type Audio struct {
+	// The file path for loading the audio
+	Path string
+	// A number between 0 and 1 indicating the volume to use for this audio. Default is 1
+	Volume *float64
+	// The decoded file for use as a player
+	decoded []byte
+	// The player for this audio, once loaded.
+	player *audio.Player
+	// Stream feeding the player, used to pan the audio between speakers.
+	pan *panner
+	// If true this audio is music and is scaled by the music volume setting, otherwise by the SFX volume setting.
+	music bool
+}
+
+// Loads the audio player into the audio struct. Must be called before sending the audio to the game
+func (a *Audio) Load() error {
+	p, err := resources.Audio(a.Path)
+	if err != nil {
+		return fmt.Errorf("load %v: %w", a.Path, err)
+	}
+	a.decoded = p
+	a.pan = &panner{Reader: bytes.NewReader(a.decoded), l: 1, r: 1}
+	a.player, err = audio.NewPlayer(Actx, a.pan)
+	if err != nil {
+		return fmt.Errorf("create player for %v: %w", a.Path, err)
+	}
+	a.player.SetVolume(a.volume())
+	return nil
+}
+
+// The volume of the audio, ignoring any positioning. Accounts for the user's volume settings.
+func (a *Audio) volume() float64 {
+	v := 1.0
+	if a.Volume != nil {
+		v = *a.Volume
+	}
+	if a.music {
+		return v * settings.MusicVolume
+	}
+	return v * settings.SFXVolume
+}
+
+// Attenuates and pans the audio as if it were emitted at sx, sy and heard from lx, ly, in world units. Occluded is
+// the thickness of terrain in between in world units, which quiets and muffles the sound.
+func (a *Audio) Place(lx, ly, sx, sy, occluded float64) {
+	a.PlaceWithin(audioFalloff, lx, ly, sx, sy, occluded)
+}
+
+// Like Place, but the sound fades out by the given distance in world units instead of the default falloff.
+func (a *Audio) PlaceWithin(radius, lx, ly, sx, sy, occluded float64) {
+	dist := math.Hypot(sx-lx, sy-ly)
+	attenuation := math.Max(0, 1-dist/radius)
+	occlusion := math.Min(audioMaxOcclusion, occluded*audioOcclusionLoss)
+	a.player.SetVolume(a.volume() * attenuation * (1 - occlusion))
+
+	pan := math.Max(-1, math.Min(1, (sx-lx)/audioPanWidth))
+	a.pan.Set(math.Min(1, 1-pan), math.Min(1, 1+pan))
+	a.pan.Muffle(occlusion / audioMaxOcclusion * audioOcclusionMuffle)
+}
+
+// Removes any positioning from the audio, playing it centered at its configured volume.
+func (a *Audio) Center() {
+	a.Fade(1)
+}
+
+// Plays the audio centered at a fraction of its configured volume, e.g while crossfading between tracks.
+func (a *Audio) Fade(level float64) {
+	a.player.SetVolume(a.volume() * level)
+	a.pan.Set(1, 1)
+	a.pan.Muffle(0)
+}
+
+// Measures the thickness in world units of static terrain on the line between the listener at lx, ly and a sound at
+// sx, sy. Terrain containing either point isn't counted, nor are sensors like switch zones, which don't block anything.
+func occlusion(w *box2d.B2World, lx, ly, sx, sy float64) float64 {
+	l, s := box2d.B2Vec2{X: lx, Y: ly}, box2d.B2Vec2{X: sx, Y: sy}
+	length := math.Hypot(sx-lx, sy-ly)
+	if length == 0 {
+		return 0
+	}
+	// where the line enters each fixture from either end, as fractions of the line from the listener
+	type crossing struct {
+		at  [2]float64
+		hit [2]bool
+	}
+	crossings := make(map[*box2d.B2Fixture]crossing)
+	cast := func(from, to box2d.B2Vec2, end int) {
+		w.RayCast(func(f *box2d.B2Fixture, _ box2d.B2Vec2, _ box2d.B2Vec2, fraction float64) float64 {
+			if f.IsSensor() || f.GetBody().GetType() != box2d.B2BodyType.B2_staticBody {
+				return -1
+			}
+			c := crossings[f]
+			if end == 1 {
+				fraction = 1 - fraction
+			}
+			c.at[end] = fraction
+			c.hit[end] = true
+			crossings[f] = c
+			return 1
+		}, from, to)
+	}
+	cast(l, s, 0)
+	cast(s, l, 1)
+	thickness := 0.0
+	for _, c := range crossings {
+		// rays don't report fixtures containing their start, so terrain around either point is only hit by one ray
+		if c.hit[0] && c.hit[1] && c.at[1] > c.at[0] {
+			thickness += (c.at[1] - c.at[0]) * length
+		}
+	}
+	return thickness
+}
+
+// A stream of decoded 16 bit little endian stereo audio that scales the left and right channels independently, and
+// can low pass filter them. Safe to adjust from the game loop while the audio goroutine reads from it.
+type panner struct {
+	*bytes.Reader
+
+	mu sync.Mutex
+	// Gains for the left and right channels, between 0 and 1.
+	l, r float64
+	// Strength of the low pass filter, between 0 for none and 1 for silence.
+	muffle float64
+
+	// Last filtered sample of each channel, only touched by the reader.
+	prev [2]float64
+}
+
+// Sets the strength of the low pass filter.
+func (p *panner) Muffle(m float64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.muffle = m
+}
+
+// Sets the gains for the left and right channels.
+func (p *panner) Set(l, r float64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.l, p.r = l, r
+}
+
+func (p *panner) Read(b []byte) (int, error) {
+	// Only read whole frames so that the channels of each sample can be told apart.
+	if len(b) >= 4 {
+		b = b[:len(b)/4*4]
+	}
+	pos, err := p.Reader.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+	n, err := p.Reader.Read(b)
+
+	p.mu.Lock()
+	l, r, muffle := p.l, p.r, p.muffle
+	p.mu.Unlock()
+	if l == 1 && r == 1 && muffle == 0 {
+		return n, err
+	}
+	for i := 0; i+1 < n; i += 2 {
+		channel := (pos + int64(i)) / 2 % 2
+		gain := l
+		if channel == 1 {
+			gain = r
+		}
+		s := int16(uint16(b[i]) | uint16(b[i+1])<<8)
+		// one pole low pass filter
+		filtered := p.prev[channel] + (1-muffle)*(float64(s)-p.prev[channel])
+		p.prev[channel] = filtered
+		s = int16(filtered * gain)
+		b[i] = byte(s)
+		b[i+1] = byte(uint16(s) >> 8)
+	}
+	return n, err
+}
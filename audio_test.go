@@ -0,0 +1,28 @@
+package main
+
+import (
+	"github.com/ByteArena/box2d"
+	"math"
+	"testing"
+)
+
+func TestOcclusionCountsTerrainBetween(t *testing.T) {
+	w := box2d.MakeB2World(box2d.MakeB2Vec2(0, 0))
+	add := func(x, y, hw, hh float64, sensor bool) {
+		def := box2d.NewB2BodyDef()
+		def.Position = box2d.MakeB2Vec2(x, y)
+		shape := box2d.MakeB2PolygonShape()
+		shape.SetAsBox(hw, hh)
+		fd := box2d.MakeB2FixtureDef()
+		fd.Shape = &shape
+		fd.IsSensor = sensor
+		w.CreateBody(def).CreateFixtureFromDef(&fd)
+	}
+	// a wall between, terrain around the listener and a switch zone the line crosses
+	add(5, 0, 1, 5, false)
+	add(0, 0, 2, 2, false)
+	add(8, 0, 0.5, 5, true)
+	if got := occlusion(&w, 0, 0, 10, 0); math.Abs(got-2) > 1e-6 {
+		t.Errorf("occlusion is %v, want the wall's thickness 2", got)
+	}
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"fmt"
+	"github.com/hajimehoshi/ebiten/v2"
+	"sort"
+	"strings"
+)
+
+// Shortcuts which can be rebound in the settings. All but fullscreen are editor shortcuts.
+const (
+	actionSave       = "save"
+	actionLoad       = "load"
+	actionCopy       = "copy"
+	actionPaste      = "paste"
+	actionDuplicate  = "duplicate"
+	actionGrid       = "grid"
+	actionRulers     = "rulers"
+	actionFullscreen = "fullscreen"
+)
+
+// Modifier keys which can be held for a shortcut, in the order combos are written
+var modifiers = []ebiten.Key{ebiten.KeyControl, ebiten.KeyAlt, ebiten.KeyShift, ebiten.KeyMeta}
+
+// The key combos which trigger an action, written like "Meta+S". The secondary is a fallback for keyboards and
+// platforms which swallow the primary, e.g Meta+S opens search on Windows. Either can be empty.
+type Binding struct {
+	Primary   string
+	Secondary string `json:",omitempty"`
+}
+
+func defaultBindings() map[string]Binding {
+	return map[string]Binding{
+		actionSave:       {Primary: "Meta+S", Secondary: "Control+S"},
+		actionLoad:       {Primary: "Meta+L", Secondary: "Control+L"},
+		actionCopy:       {Primary: "Meta+C", Secondary: "Control+C"},
+		actionPaste:      {Primary: "Meta+V", Secondary: "Control+V"},
+		actionDuplicate:  {Primary: "Meta+D", Secondary: "Control+D"},
+		actionGrid:       {Primary: "Meta+G", Secondary: "Control+G"},
+		actionRulers:     {Primary: "Meta+Shift+G", Secondary: "Control+Shift+G"},
+		actionFullscreen: {Primary: "F11", Secondary: "Control+Shift+F"},
+	}
+}
+
+// Combos the operating system or desktop usually takes before the game sees them, by GOOS, with what they do there
+var reservedCombos = map[string]map[string]string{
+	"darwin": {
+		"Meta+Q":     "quits the game",
+		"Meta+W":     "closes the window",
+		"Meta+H":     "hides the window",
+		"Meta+M":     "minimizes the window",
+		"Meta+Tab":   "switches apps",
+		"Meta+Space": "opens Spotlight",
+	},
+	"windows": {
+		"Meta+S":  "opens search",
+		"Meta+L":  "locks the computer",
+		"Meta+D":  "shows the desktop",
+		"Meta+E":  "opens Explorer",
+		"Meta+R":  "opens Run",
+		"Meta+V":  "opens the clipboard history",
+		"Alt+Tab": "switches windows",
+		"Alt+F4":  "closes the window",
+	},
+	"linux": {
+		"Meta+L":  "locks the screen on many desktops",
+		"Meta+S":  "opens the overview on GNOME",
+		"Meta+V":  "opens notifications on GNOME",
+		"Alt+Tab": "switches windows",
+		"Alt+F4":  "closes the window",
+	},
+}
+
+// A key pressed while holding some modifiers
+type combo struct {
+	// Whether each of the modifiers is held, in the same order
+	mods [4]bool
+	key  ebiten.Key
+}
+
+// Parses a combo written like "Meta+S" or "Control+Shift+Z", with the key last
+func parseCombo(s string) (combo, error) {
+	var c combo
+	parts := strings.Split(s, "+")
+	for _, p := range parts[:len(parts)-1] {
+		found := false
+		for i, m := range modifiers {
+			if p == m.String() {
+				c.mods[i] = true
+				found = true
+			}
+		}
+		if !found {
+			return combo{}, fmt.Errorf("%q: unknown modifier %q, expected one of Control, Alt, Shift or Meta", s, p)
+		}
+	}
+	k, err := parseKey(parts[len(parts)-1])
+	if err != nil {
+		return combo{}, fmt.Errorf("%q: %w", s, err)
+	}
+	c.key = k
+	return c, nil
+}
+
+// The combo written the standard way, with modifiers in a fixed order
+func (c combo) String() string {
+	var parts []string
+	for i, m := range modifiers {
+		if c.mods[i] {
+			parts = append(parts, m.String())
+		}
+	}
+	return strings.Join(append(parts, c.key.String()), "+")
+}
+
+// Whether the combo's key was just pressed while its modifiers are held. Other modifiers may be held too.
+func (c combo) clicked() bool {
+	for i, m := range modifiers {
+		if c.mods[i] && !driver.IsKeyPressed(m) {
+			return false
+		}
+	}
+	return Clicked(c.key)
+}
+
+// Whether either of the action's combos was just pressed. Combos which don't parse never trigger, they're reported by
+// bindingWarnings.
+func shortcut(action string) bool {
+	b := settings.Bindings[action]
+	for _, s := range []string{b.Primary, b.Secondary} {
+		if s == "" {
+			continue
+		}
+		if c, err := parseCombo(s); err == nil && c.clicked() {
+			return true
+		}
+	}
+	return false
+}
+
+// Whether any action's combos were just pressed, e.g so the key of Meta+D isn't also taken as a plain D
+func anyShortcut() bool {
+	for action := range settings.Bindings {
+		if shortcut(action) {
+			return true
+		}
+	}
+	return false
+}
+
+// The action's primary combo, for help text
+func shortcutName(action string) string {
+	b := settings.Bindings[action]
+	if b.Primary == "" {
+		return b.Secondary
+	}
+	return b.Primary
+}
+
+// Finds problems with the bindings on the given operating system: combos which don't parse, combos bound to more
+// than one action, and actions whose every combo is likely to be taken by the operating system.
+func bindingWarnings(bindings map[string]Binding, goos string) []string {
+	var warnings []string
+	actions := make([]string, 0, len(bindings))
+	for a := range bindings {
+		actions = append(actions, a)
+	}
+	sort.Strings(actions)
+	// the first action bound to each combo
+	bound := make(map[string]string)
+	for _, a := range actions {
+		b := bindings[a]
+		var usable []string
+		var taken []string
+		for _, s := range []string{b.Primary, b.Secondary} {
+			if s == "" {
+				continue
+			}
+			c, err := parseCombo(s)
+			if err != nil {
+				warnings = append(warnings, fmt.Sprintf("%v: %v", a, err))
+				continue
+			}
+			name := c.String()
+			if other, ok := bound[name]; ok && other != a {
+				warnings = append(warnings, fmt.Sprintf("%v and %v are both bound to %v", other, a, name))
+			}
+			bound[name] = a
+			if does, ok := reservedCombos[goos][name]; ok {
+				taken = append(taken, fmt.Sprintf("%v %v", name, does))
+				continue
+			}
+			usable = append(usable, name)
+		}
+		if len(usable) == 0 && len(taken) > 0 {
+			warnings = append(warnings, fmt.Sprintf("%v may not work on %v, %v. Bind a secondary combo in %v",
+				a, goos, strings.Join(taken, " and "), settingsPath))
+		}
+	}
+	return warnings
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+	"reflect"
+	"testing"
+)
+
+func TestBindingWarnings(t *testing.T) {
+	if w := bindingWarnings(defaultBindings(), "windows"); len(w) != 0 {
+		t.Errorf("default bindings have warnings on windows: %v", w)
+	}
+	bindings := map[string]Binding{
+		actionSave:  {Primary: "Meta+S"},
+		actionCopy:  {Primary: "Shift+Control+C"},
+		actionPaste: {Primary: "Control+Shift+C", Secondary: "Hyper+V"},
+	}
+	want := []string{
+		"copy and paste are both bound to Control+Shift+C",
+		`paste: "Hyper+V": unknown modifier "Hyper", expected one of Control, Alt, Shift or Meta`,
+		"save may not work on windows, Meta+S opens search. Bind a secondary combo in " + settingsPath,
+	}
+	if got := bindingWarnings(bindings, "windows"); !reflect.DeepEqual(got, want) {
+		t.Errorf("got warnings %q, want %q", got, want)
+	}
+}
+
+func TestSecondaryBindingTriggersShortcut(t *testing.T) {
+	r, _, f := testEditor(t)
+	f.keys[ebiten.KeyControl] = true
+	press(t, r, f, ebiten.KeyS)
+	if _, ok := r.a.(*SaveAndLoadEditor); !ok {
+		t.Fatalf("Control+S opened %v, want the save editor", r.a)
+	}
+}
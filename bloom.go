@@ -0,0 +1,67 @@
+package main
+
+import "github.com/hajimehoshi/ebiten/v2"
+
+// Brightness between 0 and 1 above which parts of the screen glow
+const bloomThreshold = 0.75
+
+// Times the bright parts of the screen are blurred, each pass spreading the glow further
+const bloomPasses = 2
+
+// A post processing pass which makes bright parts of the screen glow, e.g bullets and goals. The images it draws
+// through are kept between frames.
+type Bloom struct {
+	// The frame drawn without bloom
+	frame *ebiten.Image
+	// The frame's bright parts at half size, blurred back and forth between the two
+	bright, blurred *ebiten.Image
+}
+
+// The image to draw the frame to before it's bloomed, cleared and sized to match the screen
+func (b *Bloom) target(screen *ebiten.Image) *ebiten.Image {
+	w, h := screen.Size()
+	if b.frame == nil || b.frame.Bounds().Dx() != w || b.frame.Bounds().Dy() != h {
+		for _, img := range []*ebiten.Image{b.frame, b.bright, b.blurred} {
+			if img != nil {
+				img.Dispose()
+			}
+		}
+		b.frame = ebiten.NewImage(w, h)
+		b.bright = ebiten.NewImage((w+1)/2, (h+1)/2)
+		b.blurred = ebiten.NewImage((w+1)/2, (h+1)/2)
+	}
+	b.frame.Clear()
+	return b.frame
+}
+
+// Draws the frame from target to the screen with its bright parts glowing. Strength scales the glow, 1 adding it at
+// full brightness.
+func (b *Bloom) apply(screen *ebiten.Image, strength float64) {
+	w, h := b.frame.Size()
+	var half ebiten.GeoM
+	half.Scale(0.5, 0.5)
+	b.bright.Clear()
+	b.bright.DrawRectShader(w, h, brightShader, &ebiten.DrawRectShaderOptions{
+		GeoM:     half,
+		Uniforms: map[string]interface{}{"Threshold": float32(bloomThreshold)},
+		Images:   [4]*ebiten.Image{b.frame},
+	})
+	hw, hh := b.bright.Size()
+	for i := 0; i < bloomPasses; i++ {
+		for _, pass := range []struct {
+			src, dst  *ebiten.Image
+			direction []float32
+		}{{b.bright, b.blurred, []float32{1, 0}}, {b.blurred, b.bright, []float32{0, 1}}} {
+			pass.dst.Clear()
+			pass.dst.DrawRectShader(hw, hh, blurShader, &ebiten.DrawRectShaderOptions{
+				Uniforms: map[string]interface{}{"Direction": pass.direction},
+				Images:   [4]*ebiten.Image{pass.src},
+			})
+		}
+	}
+	screen.DrawImage(b.frame, nil)
+	op := &ebiten.DrawImageOptions{CompositeMode: ebiten.CompositeModeLighter}
+	op.GeoM.Scale(2, 2)
+	op.ColorM.Scale(strength, strength, strength, strength)
+	screen.DrawImage(b.bright, op)
+}
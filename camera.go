@@ -0,0 +1,282 @@
+package main
+
+import (
+	"fmt"
+	"github.com/hajimehoshi/ebiten/v2"
+	"image/color"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var (
+	cameraBoundsColor = color.RGBA{R: 0x80, G: 0x80, B: 0xff, A: 0xff}
+	cameraZoneColor   = color.RGBA{R: 0x80, G: 0xc0, B: 0xff, A: 0xff}
+)
+
+// Fraction of the way the camera's zoom moves to a camera zone's each tick
+const cameraZoneEase = 0.05
+
+// A region of the level which frames the camera differently while the player is inside it, e.g zooming out to show
+// a whole arena, or holding the camera still over a room
+type CameraZone struct {
+	// Transform of a unit square centered at the origin to the zone
+	T Mx
+	// Multiplies the size of the camera's view while the player is in the zone, e.g 2 to see twice as far. 0 leaves
+	// it unchanged.
+	Zoom float64 `json:",omitempty"`
+	// If set the camera holds still over the center of the zone instead of following the player
+	Lock bool `json:",omitempty"`
+	// Trigger fired when the player enters the zone, if any
+	Trigger string `json:",omitempty"`
+}
+
+// Draws the outline of the zone with how it frames the camera
+func (z *CameraZone) draw(screen *ebiten.Image, toScreen Mx) {
+	drawoutline(screen, z.T, 2, toScreen, cameraZoneColor)
+	x, y := z.T.Apply(-0.5, 0.5)
+	sx, sy := toScreen.Apply(x, y)
+	printAt(screen, z.describe(), int(sx)+4, int(sy)+4)
+}
+
+// Summarizes how the zone frames the camera, e.g "zoom 2x, locked, fires arena"
+func (z *CameraZone) describe() string {
+	zoom := z.Zoom
+	if zoom == 0 {
+		zoom = 1
+	}
+	parts := []string{fmt.Sprintf("zoom %vx", zoom)}
+	if z.Lock {
+		parts = append(parts, "locked")
+	}
+	if z.Trigger != "" {
+		parts = append(parts, "fires "+z.Trigger)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Finds the camera zone the player is in, firing its trigger if they just entered it, and eases the camera's zoom
+// towards the zone's
+func (g *Game) frame() {
+	pos := g.p.b.GetPosition()
+	var in *CameraZone
+	for _, z := range g.cameraZones {
+		if contains(z.T, pos.X, pos.Y) {
+			in = z
+			break
+		}
+	}
+	if in != g.cameraZone && in != nil && in.Trigger != "" {
+		g.fire(in.Trigger, pos.X, pos.Y)
+	}
+	g.cameraZone = in
+	target := 1.0
+	if in != nil && in.Zoom > 0 {
+		target = in.Zoom
+	}
+	if g.framing == 0 {
+		g.framing = 1
+	}
+	next := g.framing + cameraZoneEase*(target-g.framing)
+	g.c.hw *= next / g.framing
+	g.c.hh *= next / g.framing
+	g.framing = next
+}
+
+// The half width and height of the camera's view without any zoom from camera zones, for keeping the player's own
+// zoom in a fresh game
+func (g *Game) unframed() (hw, hh float64) {
+	hw, hh = g.c.hw, g.c.hh
+	if g.cutscene != nil {
+		hw, hh = hw/g.cutscene.zoom, hh/g.cutscene.zoom
+	}
+	if g.framing == 0 {
+		return hw, hh
+	}
+	return hw / g.framing, hh / g.framing
+}
+
+// Makes camera zones selectable
+type CameraZoneSelector struct {
+	l *Level
+	z *CameraZone
+}
+
+func (s *CameraZoneSelector) Paste() Selectable {
+	kopy := *s.z
+	s.l.CameraZones = append(s.l.CameraZones, &kopy)
+	return &CameraZoneSelector{l: s.l, z: &kopy}
+}
+
+func (s *CameraZoneSelector) Delete() {
+	for i, o := range s.l.CameraZones {
+		if o == s.z {
+			s.l.CameraZones = append(s.l.CameraZones[:i], s.l.CameraZones[i+1:]...)
+			return
+		}
+	}
+}
+
+func (s *CameraZoneSelector) Transform() Mx {
+	return s.z.T
+}
+
+func (s *CameraZoneSelector) SetTransform(m Mx) {
+	s.z.T = m
+}
+
+// The corners of the axis aligned box around a transformed unit square centered at the origin
+func boxAround(m Mx) (minx, miny, maxx, maxy float64) {
+	minx, miny = math.Inf(1), math.Inf(1)
+	maxx, maxy = math.Inf(-1), math.Inf(-1)
+	for _, corner := range [][2]float64{{-0.5, -0.5}, {0.5, -0.5}, {0.5, 0.5}, {-0.5, 0.5}} {
+		x, y := m.Apply(corner[0], corner[1])
+		minx, miny = math.Min(minx, x), math.Min(miny, y)
+		maxx, maxy = math.Max(maxx, x), math.Max(maxy, y)
+	}
+	return
+}
+
+// Moves a camera's center along one axis so the half of its view either side stays between min and max. Views
+// wider than the range are centered on it.
+func clampView(center, half, min, max float64) float64 {
+	if max-min <= 2*half {
+		return (min + max) / 2
+	}
+	return math.Max(min+half, math.Min(max-half, center))
+}
+
+// Keeps the camera's view inside the level's camera bounds, if it has any, so it doesn't show the void past the
+// level's edges
+func (g *Game) clampCamera() {
+	if g.cameraBounds == nil {
+		return
+	}
+	minx, miny, maxx, maxy := boxAround(*g.cameraBounds)
+	g.c.x = clampView(g.c.x, g.c.hw, minx, maxx)
+	g.c.y = clampView(g.c.y, g.c.hh, miny, maxy)
+}
+
+func drawCameraBounds(screen *ebiten.Image, bounds Mx, toScreen Mx) {
+	drawoutline(screen, bounds, 2, toScreen, cameraBoundsColor)
+}
+
+// Editor for how the camera frames the level. Dragging draws the bounds the camera is kept inside, or after typing
+// "zone <zoom> [lock] [trigger]", draws camera zones set up that way. "bounds" goes back to drawing the bounds.
+type CameraEditor struct {
+	e *Editor
+	t *Typer
+	// The zone new zones copy, nil while drawing the bounds
+	zone *CameraZone
+	// The box being dragged out, and the point the drag started at
+	creating *Mx
+	startx   float64
+	starty   float64
+}
+
+func ActivateCameraEditor(r *Root, e *Editor) {
+	c := &CameraEditor{e: e, t: &Typer{C: &e.c}}
+	c.t.Placeholder = c.describe()
+	r.a = c
+}
+
+func (c *CameraEditor) Layout(outsideWidth, outsideHeight int) (screenWidth, screenHeight int) {
+	return c.e.Layout(outsideWidth, outsideHeight)
+}
+
+func (c *CameraEditor) String() string {
+	return "Camera"
+}
+
+// Summarizes what dragging draws
+func (c *CameraEditor) describe() string {
+	if c.zone == nil {
+		return "Camera Editor: drawing bounds. Enter 'zone <zoom> [lock] [trigger]' to draw zones."
+	}
+	return fmt.Sprintf("Camera Editor: drawing zones, %v. Enter 'bounds' to draw bounds.", c.zone.describe())
+}
+
+// Applies a command of the form "bounds" or "zone <zoom> [lock] [trigger]"
+func (c *CameraEditor) apply(cmd string) error {
+	parts := strings.Fields(cmd)
+	if len(parts) == 1 && parts[0] == "bounds" {
+		c.zone = nil
+		return nil
+	}
+	if len(parts) < 2 || len(parts) > 4 || parts[0] != "zone" {
+		return fmt.Errorf("expected 'bounds' or 'zone <zoom> [lock] [trigger]'")
+	}
+	zoom, err := strconv.ParseFloat(parts[1], 64)
+	if err != nil || zoom <= 0 {
+		return fmt.Errorf("zoom %q: expected a positive number", parts[1])
+	}
+	z := &CameraZone{Zoom: zoom}
+	rest := parts[2:]
+	if len(rest) > 0 && rest[0] == "lock" {
+		z.Lock = true
+		rest = rest[1:]
+	}
+	if len(rest) > 1 {
+		return fmt.Errorf("expected at most one trigger, got %v", strings.Join(rest, " "))
+	}
+	if len(rest) == 1 {
+		z.Trigger = rest[0]
+	}
+	c.zone = z
+	return nil
+}
+
+func (c *CameraEditor) Update(r *Root) error {
+	cmd, typ := c.t.Update()
+	if typ {
+		return nil
+	}
+	if cmd != "" {
+		err := c.apply(cmd)
+		if err != nil {
+			c.t.Placeholder = fmt.Sprintf("Failed to apply '%v': %v", cmd, err)
+		} else {
+			c.t.Placeholder = c.describe()
+		}
+	}
+	if c.zone == nil && Clicked(ebiten.KeyBackspace) {
+		c.e.l.CameraBounds = nil
+	}
+	if driver.IsMouseButtonPressed(ebiten.MouseButtonLeft) {
+		wx, wy := c.e.c.Cursor()
+		if c.creating == nil {
+			c.creating = &Mx{}
+			c.startx, c.starty = wx, wy
+		}
+		*c.creating = boxBetween(c.startx, c.starty, wx, wy)
+	} else if c.creating != nil {
+		if _, _, hw, hh := decompose(*c.creating); hw*2 >= minBlockSize && hh*2 >= minBlockSize {
+			if c.zone == nil {
+				c.e.l.CameraBounds = c.creating
+			} else {
+				z := *c.zone
+				z.T = *c.creating
+				c.e.l.CameraZones = append(c.e.l.CameraZones, &z)
+			}
+		}
+		c.creating = nil
+	}
+	return c.e.Update(r)
+}
+
+func (c *CameraEditor) Draw(screen *ebiten.Image) {
+	c.e.Draw(screen)
+	c.t.Draw(screen)
+	if c.creating != nil {
+		if c.zone == nil {
+			drawCameraBounds(screen, *c.creating, c.e.c.ToScreen())
+		} else {
+			drawoutline(screen, *c.creating, 2, c.e.c.ToScreen(), cameraZoneColor)
+		}
+	}
+	msg := "Drag to bound the camera, (Backspace) Remove"
+	if c.zone != nil {
+		msg = "Drag to draw a camera zone"
+	}
+	printAt(screen, msg, 10, c.e.c.sh-35)
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"fmt"
+	"github.com/ByteArena/box2d"
+	"github.com/hajimehoshi/ebiten/v2"
+	"image/color"
+	"math"
+	"sort"
+	"strings"
+)
+
+var stationColor = color.RGBA{R: 0xff, G: 0x60, B: 0x60, A: 0xff}
+
+// The character the player is unless the level says otherwise
+const defaultCharacter = "banana"
+
+// A kind of player character, with its own size and abilities
+type Character struct {
+	// Size of the character's box in world units
+	Width, Height float64
+	// Fastest the character walks on their own, in world units per second
+	Speed float64
+	// How fast the character leaves the ground when jumping, in world units per second
+	Jump float64
+	// How fast the character is kicked away from walls they jump off, in world units per second. 0 means they can't
+	// wall jump.
+	WallKick float64 `json:",omitempty"`
+	// If true the character can shoot
+	Shoots bool `json:",omitempty"`
+	// Art to draw over the character. The level's player art is used if unset.
+	Art *Art `json:",omitempty"`
+}
+
+// Characters every level can use. Levels can add their own, or replace these with the same name.
+var builtinCharacters = map[string]Character{
+	defaultCharacter: {Width: 1, Height: 1, Speed: walkSpeed, Jump: 5, WallKick: 4, Shoots: true},
+	// small and quick, but can't shoot
+	"scout": {Width: 0.6, Height: 0.6, Speed: 8, Jump: 6, WallKick: 6},
+	// big and slow, too heavy to wall jump
+	"heavy": {Width: 1.5, Height: 1.5, Speed: 3, Jump: 4, Shoots: true},
+}
+
+// Finds the character with the given name, from the level or the built in ones. "" is the default character.
+func (l *Level) character(name string) (Character, bool) {
+	if name == "" {
+		name = defaultCharacter
+	}
+	if c, ok := l.Characters[name]; ok {
+		return *c, true
+	}
+	c, ok := builtinCharacters[name]
+	return c, ok
+}
+
+// How much bigger characters are in the level than they are defined
+func (l *Level) playerScale() float64 {
+	if l.PlayerScale == 0 {
+		return 1
+	}
+	return l.PlayerScale
+}
+
+// Scales a character's size by the given factor, with their speed and jumps scaled to move them as far relative to
+// their size in the same gravity. Box2D is tuned for moving bodies between minBodySize and maxBodySize, so scaling
+// characters beyond those makes their physics behave strangely, zoom the camera with the level's PixelsPerUnit to
+// draw them bigger or smaller instead.
+func scaleCharacter(c Character, scale float64) Character {
+	c.Width *= scale
+	c.Height *= scale
+	c.Speed *= scale
+	// jump height goes with the square of the jump speed
+	c.Jump *= math.Sqrt(scale)
+	c.WallKick *= math.Sqrt(scale)
+	return c
+}
+
+// Names of every character the level can use, in order
+func (l *Level) characterNames() []string {
+	seen := make(map[string]bool)
+	var names []string
+	for n := range builtinCharacters {
+		seen[n] = true
+		names = append(names, n)
+	}
+	for n := range l.Characters {
+		if !seen[n] {
+			names = append(names, n)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// A zone which turns the player into another character when they enter it
+type Station struct {
+	// Transform of a unit square centered at the origin to the station's zone
+	T Mx
+	// Name of the character the player becomes
+	Character string
+}
+
+// Draws the station's zone with the name of its character
+func (s *Station) draw(screen *ebiten.Image, toScreen Mx) {
+	drawoutline(screen, s.T, 2, toScreen, stationColor)
+	x, y := s.T.Apply(-0.5, 0.5)
+	sx, sy := toScreen.Apply(x, y)
+	printAt(screen, s.Character, int(sx)+4, int(sy)+4)
+}
+
+// Turns the player into the named character, rebuilding their body's fixture for the character's size. Unknown
+// names are ignored. Returns whether the player changed.
+func (g *Game) become(name string) bool {
+	c, ok := g.characters[name]
+	if !ok {
+		return false
+	}
+	for f := g.p.b.GetFixtureList(); f != nil; {
+		next := f.GetNext()
+		g.p.b.DestroyFixture(f)
+		f = next
+	}
+	shape := box2d.MakeB2PolygonShape()
+	shape.SetAsBox(c.Width/2, c.Height/2)
+	def := box2d.MakeB2FixtureDef()
+	def.Shape = &shape
+	def.Density = 1
+	def.Friction = 3
+	def.Filter = collisionFilter(categoryPlayer)
+	g.p.b.CreateFixtureFromDef(&def)
+	// keep the player's feet where they were, so growing doesn't push them into the ground
+	lift := box2d.B2Vec2MulScalar(-(c.Height-g.p.h)/2, g.down())
+	g.p.b.SetTransform(box2d.B2Vec2Add(g.p.b.GetPosition(), lift), g.p.b.GetAngle())
+	g.p.w, g.p.h = c.Width, c.Height
+	g.p.s = &shape
+	g.p.character = name
+	g.p.c = c
+	g.pArt = g.playerArt
+	if c.Art != nil {
+		g.pArt = c.Art
+	}
+	return true
+}
+
+// Swaps the player's character when they enter a station for another one. Only the first station containing the
+// player counts, so overlapping stations can't swap back and forth.
+func (g *Game) swapCharacters() {
+	pos := g.p.b.GetPosition()
+	for _, s := range g.stations {
+		if !contains(s.T, pos.X, pos.Y) {
+			continue
+		}
+		if s.Character != g.p.character {
+			g.become(s.Character)
+		}
+		return
+	}
+}
+
+// Makes stations selectable
+type StationSelector struct {
+	l *Level
+	s *Station
+}
+
+func (s *StationSelector) Paste() Selectable {
+	kopy := *s.s
+	s.l.Stations = append(s.l.Stations, &kopy)
+	return &StationSelector{l: s.l, s: &kopy}
+}
+
+func (s *StationSelector) Delete() {
+	for i, o := range s.l.Stations {
+		if o == s.s {
+			s.l.Stations = append(s.l.Stations[:i], s.l.Stations[i+1:]...)
+			return
+		}
+	}
+}
+
+func (s *StationSelector) Transform() Mx {
+	return s.s.T
+}
+
+func (s *StationSelector) SetTransform(m Mx) {
+	s.s.T = m
+}
+
+// Editor for the level's starting character and its swap stations. Type "character <name>" to choose a character,
+// "start" to make it the one the player starts as, and drag to draw a station which swaps to it.
+type StationEditor struct {
+	e *Editor
+	t *Typer
+	// The character for new stations
+	character string
+	// The station being dragged out, and the point the drag started at
+	creating *Mx
+	startx   float64
+	starty   float64
+}
+
+func ActivateStationEditor(r *Root, e *Editor) {
+	s := &StationEditor{e: e, t: &Typer{C: &e.c}, character: defaultCharacter}
+	s.t.Placeholder = s.describe()
+	r.a = s
+}
+
+func (s *StationEditor) Layout(outsideWidth, outsideHeight int) (screenWidth, screenHeight int) {
+	return s.e.Layout(outsideWidth, outsideHeight)
+}
+
+func (s *StationEditor) String() string {
+	return "Characters"
+}
+
+// Summarizes the characters
+func (s *StationEditor) describe() string {
+	start := s.e.l.Character
+	if start == "" {
+		start = defaultCharacter
+	}
+	return fmt.Sprintf("Character Editor: station character %v, start as %v. Enter 'character <%v>' or 'start'.",
+		s.character, start, strings.Join(s.e.l.characterNames(), "|"))
+}
+
+// Applies a command of the form "character <name>" or "start"
+func (s *StationEditor) apply(cmd string) error {
+	parts := strings.Fields(cmd)
+	switch {
+	case len(parts) == 2 && parts[0] == "character":
+		if _, ok := s.e.l.character(parts[1]); !ok {
+			return fmt.Errorf("unknown character %v", parts[1])
+		}
+		s.character = parts[1]
+	case len(parts) == 1 && parts[0] == "start":
+		s.e.l.Character = s.character
+		if s.character == defaultCharacter {
+			s.e.l.Character = ""
+		}
+	default:
+		return fmt.Errorf("expected 'character <name>' or 'start'")
+	}
+	return nil
+}
+
+func (s *StationEditor) Update(r *Root) error {
+	cmd, typ := s.t.Update()
+	if typ {
+		return nil
+	}
+	if cmd != "" {
+		err := s.apply(cmd)
+		if err != nil {
+			s.t.Placeholder = fmt.Sprintf("Failed to apply '%v': %v", cmd, err)
+		} else {
+			s.t.Placeholder = s.describe()
+		}
+	}
+	if driver.IsMouseButtonPressed(ebiten.MouseButtonLeft) {
+		wx, wy := s.e.c.Cursor()
+		if s.creating == nil {
+			s.creating = &Mx{}
+			s.startx, s.starty = wx, wy
+		}
+		*s.creating = boxBetween(s.startx, s.starty, wx, wy)
+	} else if s.creating != nil {
+		if _, _, hw, hh := decompose(*s.creating); hw*2 >= minBlockSize && hh*2 >= minBlockSize {
+			s.e.l.Stations = append(s.e.l.Stations, &Station{T: *s.creating, Character: s.character})
+		}
+		s.creating = nil
+	}
+	return s.e.Update(r)
+}
+
+func (s *StationEditor) Draw(screen *ebiten.Image) {
+	s.e.Draw(screen)
+	s.t.Draw(screen)
+	if s.creating != nil {
+		drawoutline(screen, *s.creating, 2, s.e.c.ToScreen(), stationColor)
+	}
+	printAt(screen, "Drag to draw a station which swaps the player's character", 10, s.e.c.sh-35)
+}
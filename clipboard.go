@@ -0,0 +1,174 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+)
+
+// Copied blocks and art are stored here, so they can be pasted into another level or after restarting the editor
+const clipboardPath = "clipboard.json"
+
+// Blocks and art copied in the select editor, or saved as a prefab
+type Clip struct {
+	Blocks []*Block `json:",omitempty"`
+	// Art pinned to a copied block has its Parent set to the block's index in Blocks, other art is placed in the world
+	Art []*Art `json:",omitempty"`
+}
+
+// The blocks and art in the selection, copied from the level
+func clipOf(s Selectable, l *Level) Clip {
+	members := []Selectable{s}
+	if g, ok := s.(*GroupSelector); ok {
+		members = g.members
+	}
+	var c Clip
+	// where each copied block is in the clip, for pinning art to them
+	blocks := make(map[int]int)
+	for _, m := range members {
+		if bs, ok := m.(*BlockSelector); ok {
+			for i, b := range l.Blocks {
+				if b == bs.b {
+					blocks[i] = len(c.Blocks)
+				}
+			}
+			kopy := *bs.b
+			c.Blocks = append(c.Blocks, &kopy)
+		}
+	}
+	for _, m := range members {
+		as, ok := m.(*ArtSelector)
+		if !ok {
+			continue
+		}
+		kopy := *as.a
+		kopy.Parent = nil
+		if as.a.Parent != nil {
+			if i, ok := blocks[*as.a.Parent]; ok {
+				kopy.Parent = &i
+			} else {
+				kopy.T = l.artTransform(as.a)
+			}
+		}
+		c.Art = append(c.Art, &kopy)
+	}
+	return c
+}
+
+// Whether there's nothing in the clip
+func (c Clip) empty() bool {
+	return len(c.Blocks) == 0 && len(c.Art) == 0
+}
+
+// Adds copies of the clip's blocks and art to the level, loading their images and shaders. Returns them selected.
+func (c Clip) paste(l *Level) ([]Selectable, error) {
+	if err := c.loadAssets(); err != nil {
+		return nil, err
+	}
+	var pasted []Selectable
+	first := len(l.Blocks)
+	for _, b := range c.Blocks {
+		kopy := *b
+		l.Blocks = append(l.Blocks, &kopy)
+		pasted = append(pasted, &BlockSelector{l: l, b: &kopy})
+	}
+	for _, a := range c.Art {
+		kopy := *a
+		if a.Parent != nil {
+			parent := first + *a.Parent
+			kopy.Parent = &parent
+		}
+		l.Art = append(l.Art, &kopy)
+		pasted = append(pasted, &ArtSelector{l: l, a: &kopy})
+	}
+	return pasted, nil
+}
+
+// Replaces the clip with the one stored at the given path. A missing file leaves it empty, as does a broken one, which
+// is reported.
+func (c *Clip) load(path string) error {
+	err := c.decode(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		*c = Clip{}
+		return nil
+	}
+	return err
+}
+
+// Replaces the clip with the one stored at the given path, without loading its images or shaders
+func (c *Clip) decode(path string) error {
+	*c = Clip{}
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open clip: %w", err)
+	}
+	defer f.Close()
+	return c.read(f)
+}
+
+// Replaces the clip with one decoded from JSON, without loading its images or shaders. Clips which fail check are
+// rejected, leaving it empty.
+func (c *Clip) read(r io.Reader) error {
+	*c = Clip{}
+	err := json.NewDecoder(r).Decode(c)
+	if err != nil {
+		return fmt.Errorf("decode clip: %w", err)
+	}
+	if err := c.check(); err != nil {
+		*c = Clip{}
+		return fmt.Errorf("check clip: %w", err)
+	}
+	return nil
+}
+
+// Checks a decoded clip for structural problems which would crash pasting or placing it, like Level.check
+func (c Clip) check() error {
+	for i, b := range c.Blocks {
+		if b == nil {
+			return fmt.Errorf("block %v is null", i)
+		}
+	}
+	for i, a := range c.Art {
+		if a == nil {
+			return fmt.Errorf("art %v is null", i)
+		}
+		if a.Parent != nil && (*a.Parent < 0 || *a.Parent >= len(c.Blocks)) {
+			return fmt.Errorf("art %v: pinned to block %v, which isn't in the clip", i, *a.Parent)
+		}
+	}
+	return nil
+}
+
+// Loads the images and shaders of the clip's blocks and art
+func (c Clip) loadAssets() error {
+	for _, b := range c.Blocks {
+		if b.Shader != nil {
+			if err := b.Shader.Load(); err != nil {
+				return fmt.Errorf("load block shader: %w", err)
+			}
+		}
+	}
+	for _, a := range c.Art {
+		if err := a.Load(); err != nil {
+			return fmt.Errorf("load art %v: %w", a.Path, err)
+		}
+	}
+	return nil
+}
+
+// Saves the clip to the given path
+func (c Clip) save(path string) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0777)
+	if err != nil {
+		return fmt.Errorf("open file to save clip: %w", err)
+	}
+	defer f.Close()
+	err = json.NewEncoder(f).Encode(c)
+	if err != nil {
+		return fmt.Errorf("save clip: %w", err)
+	}
+	return nil
+}
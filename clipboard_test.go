@@ -0,0 +1,66 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestClipKeepsArtPinnedToCopiedBlocks(t *testing.T) {
+	l := NewLevel()
+	var left, right, offset Mx
+	left.Translate(-3, 0)
+	right.Translate(3, 0)
+	offset.Translate(0, 1)
+	other, copied := &Block{T: left}, &Block{T: right, Name: "door"}
+	l.Blocks = []*Block{other, copied}
+	zero, one := 0, 1
+	onOther, onCopied := &Art{T: offset, Parent: &zero}, &Art{T: offset, Parent: &one}
+	l.Art = []*Art{onOther, onCopied}
+	sel := group([]Selectable{&ArtSelector{l: &l, a: onOther}, &ArtSelector{l: &l, a: onCopied},
+		&BlockSelector{l: &l, b: copied}})
+
+	c := clipOf(sel, &l)
+	path := filepath.Join(t.TempDir(), "clipboard.json")
+	if err := c.save(path); err != nil {
+		t.Fatal(err)
+	}
+	var loaded Clip
+	if err := loaded.load(path); err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.Blocks) != 1 || loaded.Blocks[0].Name != "door" || len(loaded.Art) != 2 {
+		t.Fatalf("clipped %+v, want the door and both art", loaded)
+	}
+	// the art on the block left behind is placed in the world, the other stays pinned
+	if a := loaded.Art[0]; a.Parent != nil {
+		t.Errorf("art on a block which wasn't copied is pinned to %v, want it unpinned", *a.Parent)
+	} else {
+		assertCorner(t, &Camera{sw: 1000, hw: 1}, a.T, 0, 0, -3, 1)
+	}
+	if a := loaded.Art[1]; a.Parent == nil || *a.Parent != 0 {
+		t.Errorf("art on the copied block is pinned to %v, want the first clipped block", a.Parent)
+	}
+}
+
+func TestMissingClipIsEmpty(t *testing.T) {
+	c := Clip{Blocks: []*Block{{}}}
+	if err := c.load(filepath.Join(t.TempDir(), "missing.json")); err != nil || !c.empty() {
+		t.Errorf("loaded %+v, %v from a missing file, want an empty clip", c, err)
+	}
+}
+
+func TestBrokenClipIsRejected(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "clipboard.json")
+	if err := os.WriteFile(path, []byte(`{"Blocks": [{}], "Art": [null, {"Parent": 1}]}`), 0666); err != nil {
+		t.Fatal(err)
+	}
+	var c Clip
+	if err := c.load(path); err == nil || !c.empty() {
+		t.Errorf("loaded %+v, %v from a broken clipboard, want an error and an empty clip", c, err)
+	}
+	e := &SelectEditor{e: &Editor{l: NewLevel()}, clip: path}
+	if err := e.pasteClip(); err == nil || len(e.e.l.Blocks) != 0 {
+		t.Errorf("pasted %v blocks, %v from a broken clipboard, want an error and nothing", len(e.e.l.Blocks), err)
+	}
+}
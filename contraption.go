@@ -0,0 +1,202 @@
+package main
+
+import (
+	"fmt"
+	"github.com/ByteArena/box2d"
+	"github.com/hajimehoshi/ebiten/v2"
+	"image/color"
+	"math"
+)
+
+// Kinds of contraption
+const (
+	// A plank balanced on a pivot at its center
+	contraptionSeesaw = "seesaw"
+	// A plank hanging from a pivot above it
+	contraptionSwing = "swing"
+)
+
+var contraptionKinds = []string{contraptionSeesaw, contraptionSwing}
+
+// A built in physics object made of a dynamic plank pinned to the world by a revolute joint.
+type Contraption struct {
+	// One of the contraption kinds
+	Kind string
+	// A transformation that maps a unit square to the plank in world coordinates.
+	T Mx
+	// How far the plank may rotate either way in radians. 0 means unlimited, e.g for a spinning platform.
+	Limit float64 `json:",omitempty"`
+	// For swings, the distance in world units from the pivot down to the center of the plank.
+	Reach float64 `json:",omitempty"`
+}
+
+// The pivot point of the contraption in world units
+func (c *Contraption) pivot() box2d.B2Vec2 {
+	center, angle, _, _ := decompose(c.T)
+	if c.Kind == contraptionSwing {
+		// straight up from the plank's perspective
+		center.X -= math.Sin(angle) * c.Reach
+		center.Y += math.Cos(angle) * c.Reach
+	}
+	return center
+}
+
+// A pivot in a running game, kept around for drawing
+type pivot struct {
+	at box2d.B2Vec2
+	b  *box2d.B2Body
+}
+
+// Adds the contraption's bodies and joint to the game. Planks too thin to have mass are left out, box2d can't simulate
+// them.
+func (c *Contraption) build(g *Game) {
+	center, angle, hw, hh := decompose(c.T)
+	if hw*2 < minBlockSize || hh*2 < minBlockSize {
+		return
+	}
+	body := box2d.NewB2BodyDef()
+	body.Type = box2d.B2BodyType.B2_dynamicBody
+	body.Position = center
+	body.Angle = angle
+	plank := &Entity{
+		w:            hw * 2,
+		h:            hh * 2,
+		b:            g.world.CreateBody(body),
+		restoresJump: true,
+	}
+	plank.b.SetUserData(plank)
+	plank.b.CreateFixtureFromDef((&Block{T: c.T}).fixture(hw, hh))
+	g.entities = append(g.entities, plank)
+
+	// pin the plank to a static anchor
+	at := c.pivot()
+	anchorDef := box2d.NewB2BodyDef()
+	anchorDef.Position = at
+	anchor := g.world.CreateBody(anchorDef)
+
+	joint := box2d.MakeB2RevoluteJointDef()
+	joint.Initialize(anchor, plank.b, at)
+	if c.Limit != 0 {
+		joint.EnableLimit = true
+		joint.LowerAngle = -c.Limit
+		joint.UpperAngle = c.Limit
+	}
+	g.world.CreateJoint(&joint)
+	g.pivots = append(g.pivots, pivot{at: at, b: plank.b})
+}
+
+// Draws the pivot of a contraption and the rod connecting it to the plank, for the given plank center.
+func drawPivot(screen *ebiten.Image, at, plank box2d.B2Vec2, toScreen Mx) {
+	drawline(screen, at.X, at.Y, plank.X, plank.Y, 3, toScreen, color.RGBA{R: 0x80, G: 0x80, B: 0x80, A: 0xff})
+	drawpoint(screen, at.X, at.Y, 10, toScreen, color.White)
+}
+
+// Draws a contraption in the editor
+func (c *Contraption) draw(e *Editor, screen *ebiten.Image) {
+	e.drawBlock(screen, &Block{T: c.T})
+	center, _, _, _ := decompose(c.T)
+	drawPivot(screen, c.pivot(), center, e.c.ToScreen())
+}
+
+// Editor for placing contraptions. Drag out the plank like a block, and use the number keys to pick the kind.
+type ContraptionEditor struct {
+	// The contraption being dragged out, if any, and the point that was initially clicked
+	creating *Contraption
+	cpinx    float64
+	cpiny    float64
+
+	// Index of the kind of contraption to create
+	kind int
+
+	e *Editor
+}
+
+func (p *ContraptionEditor) Layout(outsideWidth, outsideHeight int) (screenWidth, screenHeight int) {
+	return p.e.Layout(outsideWidth, outsideHeight)
+}
+
+func (p *ContraptionEditor) String() string {
+	return "Contraptions"
+}
+
+func (p *ContraptionEditor) Update(r *Root) error {
+	for i := range contraptionKinds {
+		if Clicked(ebiten.KeyDigit1 + ebiten.Key(i)) {
+			p.kind = i
+		}
+	}
+	if driver.IsMouseButtonPressed(ebiten.MouseButtonLeft) {
+		wx, wy := p.e.c.Cursor()
+		if p.creating == nil {
+			p.creating = &Contraption{
+				Kind:  contraptionKinds[p.kind],
+				Limit: 0.4,
+				Reach: 3,
+			}
+			p.cpinx = wx
+			p.cpiny = wy
+		}
+		minx := math.Min(wx, p.cpinx)
+		maxx := math.Max(wx, p.cpinx)
+		miny := math.Min(wy, p.cpiny)
+		maxy := math.Max(wy, p.cpiny)
+		geo := Mx{}
+		geo.Translate(0.5, 0.5)
+		geo.Scale(maxx-minx, maxy-miny)
+		geo.Translate(minx, miny)
+		p.creating.T = geo
+	}
+	if !driver.IsMouseButtonPressed(ebiten.MouseButtonLeft) && p.creating != nil {
+		// a click without a drag makes a plank too thin to have mass
+		if _, _, hw, hh := decompose(p.creating.T); hw*2 >= minBlockSize && hh*2 >= minBlockSize {
+			p.e.l.Contraptions = append(p.e.l.Contraptions, p.creating)
+		}
+		p.creating = nil
+	}
+	return p.e.Update(r)
+}
+
+func (p *ContraptionEditor) Draw(screen *ebiten.Image) {
+	if p.creating != nil {
+		p.creating.draw(p.e, screen)
+	}
+	msg := "Contraption Editor:"
+	for i, k := range contraptionKinds {
+		marker := " "
+		if i == p.kind {
+			marker = "*"
+		}
+		msg += fmt.Sprintf(" (%v)%v%v", i+1, marker, k)
+	}
+	printAt(screen, msg, 10, p.e.c.sh-20)
+	p.e.Draw(screen)
+}
+
+// Makes contraptions selectable
+type ContraptionSelector struct {
+	l *Level
+	c *Contraption
+}
+
+func (c *ContraptionSelector) Paste() Selectable {
+	kopy := *c.c
+	c.l.Contraptions = append(c.l.Contraptions, &kopy)
+	return &ContraptionSelector{l: c.l, c: &kopy}
+}
+
+func (c *ContraptionSelector) Delete() {
+	for i, o := range c.l.Contraptions {
+		if o == c.c {
+			c.l.Contraptions = append(c.l.Contraptions[:i], c.l.Contraptions[i+1:]...)
+			return
+		}
+	}
+}
+
+func (c *ContraptionSelector) Transform() Mx {
+	return c.c.T
+}
+
+func (c *ContraptionSelector) SetTransform(m Mx) {
+	c.c.T = m
+}
@@ -0,0 +1,32 @@
+package main
+
+import "github.com/hajimehoshi/ebiten/v2"
+
+// A post processing pass which makes the game look like it's shown on an old CRT television, with curved glass,
+// scanlines and colors split slightly apart. Turned on by the CRT setting. The frame it draws through is kept between
+// frames.
+type CRT struct {
+	frame *ebiten.Image
+}
+
+// The image to draw the frame to before it's shown on the CRT, cleared and sized to match the screen
+func (c *CRT) target(screen *ebiten.Image) *ebiten.Image {
+	w, h := screen.Size()
+	if c.frame == nil || c.frame.Bounds().Dx() != w || c.frame.Bounds().Dy() != h {
+		if c.frame != nil {
+			c.frame.Dispose()
+		}
+		c.frame = ebiten.NewImage(w, h)
+	}
+	c.frame.Clear()
+	return c.frame
+}
+
+// Draws the frame from target to the screen as the CRT shows it
+func (c *CRT) apply(screen *ebiten.Image) {
+	w, h := c.frame.Size()
+	screen.DrawRectShader(w, h, crtShader, &ebiten.DrawRectShaderOptions{
+		Uniforms: map[string]interface{}{"ScreenPixels": []float32{float32(w), float32(h)}},
+		Images:   [4]*ebiten.Image{c.frame},
+	})
+}
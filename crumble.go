@@ -0,0 +1,70 @@
+package main
+
+import (
+	"github.com/ByteArena/box2d"
+	"math"
+)
+
+// How far in world units a crumbling platform shakes
+const crumbleShake = 0.05
+
+// Makes a block crumble away after the player stands on it
+type Crumble struct {
+	// Seconds after the player first stands on the block until it falls away
+	Delay float64
+	// Seconds after falling away until the block returns. 0 means never.
+	Respawn float64 `json:",omitempty"`
+}
+
+// The state of a crumbling block in a running game
+type crumbling struct {
+	c Crumble
+	// Set from when the player first stands on the block until it falls away
+	shaking bool
+	// Set while the block has fallen away
+	broken bool
+}
+
+// Starts the entity's block crumbling if it isn't already, scheduling it to fall away and then return.
+func (c *crumbling) touch(g *Game, e *Entity) {
+	if c.shaking || c.broken {
+		return
+	}
+	c.shaking = true
+	steps := []Step{{Wait: secondsToTicks(c.c.Delay), Do: func() {
+		c.shaking = false
+		c.broken = true
+		e.b.SetActive(false)
+	}}}
+	if c.c.Respawn != 0 {
+		steps = append(steps, Step{Wait: secondsToTicks(c.c.Respawn), Do: func() {
+			c.broken = false
+			e.b.SetActive(true)
+		}})
+	}
+	g.schedule.Sequence(steps...)
+}
+
+// Offset to draw the block at, shaking while it crumbles.
+func (c *crumbling) shake(time int) box2d.B2Vec2 {
+	if !c.shaking {
+		return box2d.B2Vec2{}
+	}
+	t := float64(time)
+	return box2d.B2Vec2{X: crumbleShake * math.Sin(t*1.7), Y: crumbleShake * math.Sin(t*2.3)}
+}
+
+// Starts any crumbling blocks the player is standing on.
+func (g *Game) crumble() {
+	pos := g.p.b.GetPosition()
+	for next := g.p.b.GetContactList(); next != nil; next = next.Next {
+		e, ok := next.Other.GetUserData().(*Entity)
+		if !ok || e.crumble == nil || !next.Contact.IsTouching() {
+			continue
+		}
+		// only when standing on top, not bumping the side or bottom
+		if pos.Y-g.p.h/2 >= e.b.GetPosition().Y {
+			e.crumble.touch(g, e)
+		}
+	}
+}
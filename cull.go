@@ -0,0 +1,23 @@
+package main
+
+import "math"
+
+// Whether any of the rectangle from x0, y0 to x1, y1 lands on a screen of the given size once transformed by m, which
+// maps it to screen pixels. Things which don't are skipped when drawing, so large levels only pay for what's in view.
+func onScreen(m Mx, x0, y0, x1, y1 float64, sw, sh int) bool {
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, corner := range [4][2]float64{{x0, y0}, {x1, y0}, {x0, y1}, {x1, y1}} {
+		x, y := m.Apply(corner[0], corner[1])
+		minX, maxX = math.Min(minX, x), math.Max(maxX, x)
+		minY, maxY = math.Min(minY, y), math.Max(maxY, y)
+	}
+	return maxX >= 0 && minX <= float64(sw) && maxY >= 0 && minY <= float64(sh)
+}
+
+// Whether any of a unit square centered at the origin, transformed by t in the world, is in the view through
+// toScreen, e.g a block or art
+func squareOnScreen(t, toScreen Mx, sw, sh int) bool {
+	t.Concat(toScreen.GeoM)
+	return onScreen(t, -0.5, -0.5, 0.5, 0.5, sw, sh)
+}
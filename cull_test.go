@@ -0,0 +1,27 @@
+package main
+
+import "testing"
+
+func TestSquareOnScreen(t *testing.T) {
+	var c Camera
+	c.Layout(240, 160)
+	toScreen := c.ToScreen()
+	for _, s := range []struct {
+		name    string
+		x, y, w float64
+		want    bool
+	}{
+		{"centered", 0, 0, 1, true},
+		{"off the right", 20, 0, 2, false},
+		{"overlapping the right edge", 12.5, 0, 2, true},
+		{"below", 0, -10, 2, false},
+		{"covering the view", 0, 0, 100, true},
+	} {
+		var m Mx
+		m.Scale(s.w, s.w)
+		m.Translate(s.x, s.y)
+		if got := squareOnScreen(m, toScreen, c.sw, c.sh); got != s.want {
+			t.Errorf("%v: on screen %v, want %v", s.name, got, s.want)
+		}
+	}
+}
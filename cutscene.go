@@ -0,0 +1,180 @@
+package main
+
+import "math"
+
+// A scripted sequence played by a trigger, e.g a level intro panning over the level, or an ending opening the exit.
+// The player can't move until it's over.
+type Cutscene struct {
+	// Points the camera moves between in order, starting from wherever it was. The camera returns to the player after
+	// the last.
+	Camera []CameraKey `json:",omitempty"`
+	// Art moved during the cutscene
+	Moves []ArtMove `json:",omitempty"`
+	// Audio played during the cutscene
+	Sounds []CutsceneSound `json:",omitempty"`
+}
+
+// Where the camera is at a point in a cutscene
+type CameraKey struct {
+	// Seconds from the start of the cutscene
+	Time float64
+	X, Y float64
+	// Multiplies the size of the camera's view, like a camera zone's zoom. 0 leaves it unchanged.
+	Zoom float64 `json:",omitempty"`
+}
+
+// Art a cutscene moves, which stays where it's moved to after the cutscene
+type ArtMove struct {
+	// Index of the art in the level's art
+	Art int
+	// Where the art is at points in the cutscene, moving from where it was placed to each in order
+	Keys []ArtKey
+}
+
+// How far art is moved from where it was placed at a point in a cutscene
+type ArtKey struct {
+	// Seconds from the start of the cutscene
+	Time float64
+	// World units the art is moved by
+	X, Y float64
+	// Radians the art is turned about its center
+	Angle float64 `json:",omitempty"`
+}
+
+// Audio a cutscene plays, without attenuation
+type CutsceneSound struct {
+	// Seconds from the start of the cutscene
+	Time  float64
+	Audio Audio
+}
+
+// Moves the transform of a unit square by the key's offset
+func (k ArtKey) apply(t Mx) Mx {
+	cx, cy := t.Apply(0, 0)
+	t.Translate(-cx, -cy)
+	t.Rotate(k.Angle)
+	t.Translate(cx+k.X, cy+k.Y)
+	return t
+}
+
+// Seconds from the start of the cutscene to its last key or sound
+func (c *Cutscene) duration() float64 {
+	d := 0.0
+	for _, k := range c.Camera {
+		d = math.Max(d, k.Time)
+	}
+	for _, m := range c.Moves {
+		for _, k := range m.Keys {
+			d = math.Max(d, k.Time)
+		}
+	}
+	for _, s := range c.Sounds {
+		d = math.Max(d, s.Time)
+	}
+	return d
+}
+
+// Finds where t seconds falls between keys at the given times, which are in order. Returns the keys either side and
+// the fraction of the way from the first to the second, eased to slow into and out of each key. Past the last key both
+// are the last.
+func between(times []float64, t float64) (a, b int, f float64) {
+	for i := 1; i < len(times); i++ {
+		if t < times[i] {
+			f = math.Max(0, (t-times[i-1])/(times[i]-times[i-1]))
+			return i - 1, i, f * f * (3 - 2*f)
+		}
+	}
+	last := len(times) - 1
+	return last, last, 0
+}
+
+// A cutscene being played in a game
+type playingCutscene struct {
+	c     *Cutscene
+	ticks int
+	// Where the camera was when the cutscene started
+	fromX, fromY float64
+	// How much the cutscene has zoomed the camera's view
+	zoom float64
+}
+
+// Starts playing the cutscene, replacing any already playing
+func (g *Game) play(c *Cutscene) {
+	g.endCutscene()
+	g.cutscene = &playingCutscene{c: c, fromX: g.c.x, fromY: g.c.y, zoom: 1}
+}
+
+// Stops the playing cutscene, if any, leaving its art where it is and undoing its zoom
+func (g *Game) endCutscene() {
+	if g.cutscene == nil {
+		return
+	}
+	g.c.hw /= g.cutscene.zoom
+	g.c.hh /= g.cutscene.zoom
+	g.cutscene = nil
+}
+
+// Advances the playing cutscene by a tick, moving its art, zooming the camera and playing its sounds. Reports whether
+// a cutscene is still playing, in which case the player's input should be ignored.
+func (g *Game) direct() bool {
+	p := g.cutscene
+	if p == nil {
+		return false
+	}
+	prev := float64(p.ticks) / ticksPerSecond
+	p.ticks++
+	now := float64(p.ticks) / ticksPerSecond
+	for _, m := range p.c.Moves {
+		if m.Art < 0 || m.Art >= len(g.levelArt) || len(m.Keys) == 0 {
+			continue
+		}
+		keys := append([]ArtKey{{}}, m.Keys...)
+		times := make([]float64, len(keys))
+		for i, k := range keys {
+			times[i] = k.Time
+		}
+		a, b, f := between(times, now)
+		g.moved[g.levelArt[m.Art]] = ArtKey{
+			X:     keys[a].X + f*(keys[b].X-keys[a].X),
+			Y:     keys[a].Y + f*(keys[b].Y-keys[a].Y),
+			Angle: keys[a].Angle + f*(keys[b].Angle-keys[a].Angle),
+		}
+	}
+	if _, _, zoom, ok := p.camera(now); ok {
+		g.c.hw *= zoom / p.zoom
+		g.c.hh *= zoom / p.zoom
+		p.zoom = zoom
+	}
+	done := now >= p.c.duration()
+	for _, s := range p.c.Sounds {
+		if s.Time >= prev && (s.Time < now || done) && s.Audio.player != nil {
+			s.Audio.Center()
+			_ = s.Audio.player.Seek(0)
+			s.Audio.player.Play()
+		}
+	}
+	if done {
+		// input is still ignored on the last tick, so keys held through the cutscene don't jolt the player
+		g.endCutscene()
+	}
+	return true
+}
+
+// Where the cutscene has the camera at t seconds in, and the zoom on its view. Not ok if the cutscene doesn't move the
+// camera.
+func (p *playingCutscene) camera(t float64) (x, y, zoom float64, ok bool) {
+	if len(p.c.Camera) == 0 {
+		return 0, 0, 0, false
+	}
+	keys := append([]CameraKey{{X: p.fromX, Y: p.fromY, Zoom: 1}}, p.c.Camera...)
+	times := make([]float64, len(keys))
+	for i, k := range keys {
+		times[i] = k.Time
+		if k.Zoom == 0 {
+			keys[i].Zoom = 1
+		}
+	}
+	a, b, f := between(times, t)
+	from, to := keys[a], keys[b]
+	return from.X + f*(to.X-from.X), from.Y + f*(to.Y-from.Y), from.Zoom + f*(to.Zoom-from.Zoom), true
+}
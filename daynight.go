@@ -0,0 +1,94 @@
+package main
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+	"math"
+)
+
+// A level's time of day, which cycles through night, dawn, day and dusk. The level's background is tinted with the
+// color of the light at each time of day, as is its ambient light if it has lights.
+type DayNight struct {
+	// Seconds in a full day
+	Length float64
+	// Time of day the level starts at, from 0 at midnight through 0.5 at noon to 1 at the next midnight
+	Start float64 `json:",omitempty"`
+}
+
+// Changes the time of day when a trigger fires, in levels with a day night cycle
+type TimeChange struct {
+	// Time of day to jump to, see DayNight.Start. Unchanged if unset.
+	At *float64 `json:",omitempty"`
+	// If set, true stops the clock and false starts it again
+	Freeze *bool `json:",omitempty"`
+}
+
+// Colors of the light through the day, from midnight to midnight, see DayNight.Start
+var daylight = []struct {
+	at    float64
+	color [3]float64
+}{
+	{0, [3]float64{0.15, 0.2, 0.4}},
+	{0.25, [3]float64{1, 0.6, 0.45}},
+	{0.35, [3]float64{1, 1, 1}},
+	{0.65, [3]float64{1, 1, 1}},
+	{0.75, [3]float64{1, 0.5, 0.35}},
+	{1, [3]float64{0.15, 0.2, 0.4}},
+}
+
+// The color of the light at the given time of day
+func daylightAt(at float64) [3]float64 {
+	at -= math.Floor(at)
+	times := make([]float64, len(daylight))
+	for i, d := range daylight {
+		times[i] = d.at
+	}
+	a, b, f := between(times, at)
+	var c [3]float64
+	for i := range c {
+		c[i] = daylight[a].color[i] + f*(daylight[b].color[i]-daylight[a].color[i])
+	}
+	return c
+}
+
+// A game's time of day
+type clock struct {
+	// Seconds in a full day
+	length float64
+	// Time of day, see DayNight.Start
+	at     float64
+	frozen bool
+}
+
+// Moves the clock on by a tick, unless it's frozen
+func (c *clock) advance() {
+	if c == nil || c.frozen || c.length <= 0 {
+		return
+	}
+	c.at = math.Mod(c.at+1/(c.length*ticksPerSecond), 1)
+}
+
+// Changes the time of day, if the level has a day night cycle
+func (g *Game) changeTime(t *TimeChange) {
+	if g.day == nil {
+		return
+	}
+	if t.At != nil {
+		g.day.at = *t.At - math.Floor(*t.At)
+	}
+	if t.Freeze != nil {
+		g.day.frozen = *t.Freeze
+	}
+}
+
+// Tints everything drawn to the screen so far with the color of the light at the time of day, if the level has a day
+// night cycle
+func (g *Game) tintBackground(screen *ebiten.Image) {
+	if g.day == nil {
+		return
+	}
+	c := daylightAt(g.day.at)
+	op := &ebiten.DrawImageOptions{CompositeMode: ebiten.CompositeModeMultiply}
+	op.GeoM.Scale(float64(g.c.sw), float64(g.c.sh))
+	op.ColorM.Scale(c[0], c[1], c[2], 1)
+	screen.DrawImage(emptySubImage, op)
+}
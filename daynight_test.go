@@ -0,0 +1,49 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDayNightCycle(t *testing.T) {
+	l := floorLevel()
+	l.DayNight = &DayNight{Length: 4, Start: 0.5}
+	midnight, freeze, resume := 0.0, true, false
+	l.Triggers["dark"] = Trigger{Time: &TimeChange{At: &midnight, Freeze: &freeze}}
+	l.Triggers["resume"] = Trigger{Time: &TimeChange{Freeze: &resume}}
+	g := NewHeadlessGame(l, &Script{})
+	if err := Simulate(g, ticksPerSecond); err != nil {
+		t.Fatal(err)
+	}
+	// a quarter of the way through the day from noon
+	if math.Abs(g.day.at-0.75) > 1e-9 {
+		t.Errorf("time of day %v after a second, want 0.75", g.day.at)
+	}
+	g.fire("dark", 0, 0)
+	if err := Simulate(g, ticksPerSecond); err != nil {
+		t.Fatal(err)
+	}
+	if g.day.at != 0 {
+		t.Errorf("time of day %v with the clock frozen at midnight, want 0", g.day.at)
+	}
+	g.fire("resume", 0, 0)
+	if err := Simulate(g, 4*ticksPerSecond); err != nil {
+		t.Fatal(err)
+	}
+	// a full day later, wrapped back around to midnight
+	if at := g.day.at; at > 1e-9 && at < 1-1e-9 {
+		t.Errorf("time of day %v a day after midnight, want midnight", at)
+	}
+}
+
+func TestDaylight(t *testing.T) {
+	if noon := daylightAt(0.5); noon != [3]float64{1, 1, 1} {
+		t.Errorf("light at noon is %v, want white", noon)
+	}
+	if a, b := daylightAt(0), daylightAt(1); a != b {
+		t.Errorf("light at midnight is %v, but a day later it's %v", a, b)
+	}
+	if night, day := daylightAt(0.1), daylightAt(0.5); night[0] >= day[0] {
+		t.Errorf("light at night %v is as bright as during the day %v", night, day)
+	}
+}
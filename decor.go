@@ -0,0 +1,48 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"time"
+)
+
+// Picks a new decoration seed, for new levels and reshuffling
+func newSeed() int64 {
+	return time.Now().UnixNano()
+}
+
+// A random source for decorating one thing in the level, e.g the art with the given index. The same seed and index
+// always give the same numbers, so adding to the level doesn't change how the rest of it is decorated.
+func decorRand(seed int64, index int) *rand.Rand {
+	h := fnv.New64a()
+	var b [16]byte
+	binary.LittleEndian.PutUint64(b[:8], uint64(seed))
+	binary.LittleEndian.PutUint64(b[8:], uint64(index))
+	_, _ = h.Write(b[:])
+	return rand.New(rand.NewSource(int64(h.Sum64())))
+}
+
+// Chooses which image each piece of art with variants shows, from the level's decoration seed. Art must be loaded.
+func (l *Level) decorate() {
+	for i, a := range l.Art {
+		if len(a.imgs) < 2 {
+			continue
+		}
+		a.img = a.imgs[decorRand(l.Seed, i).Intn(len(a.imgs))]
+	}
+}
+
+// Adds the image at the given path as a variant of the art, and redecorates the level
+func (l *Level) addVariant(a *Art, path string) error {
+	kopy := *a
+	kopy.Variants = append(append([]string(nil), a.Variants...), path)
+	err := kopy.Load()
+	if err != nil {
+		return fmt.Errorf("load variant: %w", err)
+	}
+	*a = kopy
+	l.decorate()
+	return nil
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/hajimehoshi/ebiten/v2"
+	"os"
+	"path/filepath"
+)
+
+// Where bundled demos are kept
+const demoPattern = "demos/*.json"
+
+// How long a demo keeps playing after its last input
+const demoTail = 3 * ticksPerSecond
+
+// A recorded run of a level, played back in attract mode
+type Demo struct {
+	// Path to the level the run was recorded on
+	Level string
+	// The player's input during the run
+	Script Script
+}
+
+// Loads a demo from the given path
+func loadDemo(path string) (Demo, error) {
+	var d Demo
+	f, err := os.Open(path)
+	if err != nil {
+		return d, fmt.Errorf("open demo: %w", err)
+	}
+	defer f.Close()
+	err = json.NewDecoder(f).Decode(&d)
+	if err != nil {
+		return d, fmt.Errorf("decode demo: %w", err)
+	}
+	return d, nil
+}
+
+// How many ticks the demo plays for
+func (d Demo) length() int {
+	last := 0
+	for _, s := range d.Script.Steps {
+		if s.Tick > last {
+			last = s.Tick
+		}
+	}
+	return last + demoTail
+}
+
+// Starts a game playing back the demo on its loaded level
+func (d Demo) start(l Level) *Game {
+	g := NewGame()
+	l.apply(g)
+	g.respawn("")
+	// a fresh copy, scripts keep track of what's held
+	s := Script{Steps: d.Script.Steps}
+	g.in = &s
+	return g
+}
+
+// Plays demos one after another in a loop until there is any input, giving the game some life while nobody is
+// playing.
+type AttractMode struct {
+	// Paths of the demos to play, and the index of the next one
+	demos []string
+	next  int
+	// The demo playing
+	g      *Game
+	length int
+	// The app to return to on input, a new editor if nil
+	back App
+	// Demos which have failed to play since the last which played, and why the last of them failed
+	failed  int
+	lastErr error
+}
+
+// Activates attract mode, returning to the current app on input. If no demo paths are given the bundled demos are
+// played.
+func ActivateAttractMode(r *Root, demos []string) error {
+	if len(demos) == 0 {
+		var err error
+		demos, err = filepath.Glob(demoPattern)
+		if err != nil {
+			return fmt.Errorf("find demos: %w", err)
+		}
+	}
+	if len(demos) == 0 {
+		return fmt.Errorf("no demos to play")
+	}
+	r.a = &AttractMode{demos: demos, back: r.a}
+	return nil
+}
+
+func (a *AttractMode) Layout(outsideWidth, outsideHeight int) (screenWidth, screenHeight int) {
+	if a.g == nil {
+		return outsideWidth, outsideHeight
+	}
+	return a.g.Layout(outsideWidth, outsideHeight)
+}
+
+// Loads the next demo behind a loading screen and plays it. Broken demos are skipped, returning an error once every
+// demo has failed in a row.
+func (a *AttractMode) advance(r *Root) error {
+	if a.g != nil {
+		a.g.Stop()
+		a.g = nil
+	}
+	if a.failed >= len(a.demos) {
+		return fmt.Errorf("no demos could be played, last error: %w", a.lastErr)
+	}
+	path := a.demos[a.next]
+	a.next = (a.next + 1) % len(a.demos)
+	// the next update moves on to the demo after
+	skip := func(err error) error {
+		fmt.Printf("Skipping demo %v: %v\n", path, err)
+		a.failed++
+		a.lastErr = err
+		r.a = a
+		return nil
+	}
+	d, err := loadDemo(path)
+	if err != nil {
+		return skip(err)
+	}
+	ActivateLevelLoading(r, d.Level, func(r *Root, l Level, err error) error {
+		if err != nil {
+			return skip(fmt.Errorf("load %v: %w", d.Level, err))
+		}
+		a.failed = 0
+		a.g = d.start(l)
+		a.length = d.length()
+		r.a = a
+		return nil
+	})
+	return nil
+}
+
+func (a *AttractMode) Update(r *Root) error {
+	if anyInput() {
+		if a.g != nil {
+			a.g.Stop()
+		}
+		if a.back == nil {
+			ActivateNewEditor(r, func(r *Root, e *Editor) error {
+				r.transition(e, transitionFade)
+				return nil
+			})
+			return nil
+		}
+		r.transition(a.back, transitionFade)
+		return nil
+	}
+	if a.g == nil || a.g.time >= a.length {
+		return a.advance(r)
+	}
+	return a.g.Update()
+}
+
+func (a *AttractMode) Draw(screen *ebiten.Image) {
+	if a.g == nil {
+		return
+	}
+	a.g.Draw(screen)
+	printAt(screen, "Demo - press any key", 10, 10)
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestBundledDemosPlay(t *testing.T) {
+	paths, err := filepath.Glob(demoPattern)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) == 0 {
+		t.Fatal("no bundled demos")
+	}
+	for _, p := range paths {
+		d, err := loadDemo(p)
+		if err != nil {
+			t.Errorf("%v: %v", p, err)
+			continue
+		}
+		var l Level
+		err = l.decode(d.Level)
+		if err != nil {
+			t.Errorf("%v: %v", p, err)
+			continue
+		}
+		s := Script{Steps: d.Script.Steps}
+		g := NewHeadlessGame(l, &s)
+		err = Simulate(g, d.length())
+		if err != nil {
+			t.Errorf("%v: %v", p, err)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"image/color"
+	"strings"
+)
+
+// Characters of dialogue revealed per second
+const dialogueSpeed = 40
+
+// Key which reveals the rest of a page of dialogue, or moves on to the next once it's all shown
+const dialogueKey = ebiten.KeyEnter
+
+// Unscaled size of the dialogue box's text and the space around it, see hudScale
+const (
+	dialogueTextSize = 16
+	dialoguePadding  = 12
+)
+
+var (
+	dialogueBackground = color.RGBA{R: 0x10, G: 0x10, B: 0x20, A: 0xe0}
+	dialogueSpeaker    = color.RGBA{R: 0xff, G: 0xd0, B: 0x60, A: 0xff}
+)
+
+// Text shown in a box over the game, e.g a character greeting the player. The player can't move until they've read
+// every page.
+type Dialogue struct {
+	// Name shown above the text, if any
+	Speaker string `json:",omitempty"`
+	// Shown one after another, each typed out a character at a time
+	Pages []string
+}
+
+// Dialogue being shown in a game
+type openDialogue struct {
+	d *Dialogue
+	// The page being shown, and ticks since it was opened
+	page  int
+	ticks int
+	// Set once the page is shown in full, whether it was all typed out or skipped to
+	revealed bool
+	// Whether the dialogue key was held last tick, so holding it doesn't skip every page
+	held bool
+}
+
+// Opens the dialogue, replacing any already open
+func (g *Game) say(d *Dialogue) {
+	if len(d.Pages) == 0 {
+		return
+	}
+	g.dialogue = &openDialogue{d: d, held: g.in.IsKeyPressed(dialogueKey)}
+}
+
+// Types out the open dialogue and moves through its pages as the dialogue key is pressed. Reports whether dialogue is
+// still open, in which case the player's input should be ignored.
+func (g *Game) talk() bool {
+	o := g.dialogue
+	if o == nil {
+		return false
+	}
+	o.ticks++
+	if o.ticks*dialogueSpeed/ticksPerSecond >= len([]rune(o.d.Pages[o.page])) {
+		o.revealed = true
+	}
+	held := g.in.IsKeyPressed(dialogueKey)
+	pressed := held && !o.held
+	o.held = held
+	if !pressed {
+		return true
+	}
+	if !o.revealed {
+		o.revealed = true
+		return true
+	}
+	o.page++
+	o.ticks, o.revealed = 0, false
+	if o.page == len(o.d.Pages) {
+		// input is still ignored on the tick the dialogue closes, so the key closing it doesn't count as the player's
+		g.dialogue = nil
+	}
+	return true
+}
+
+// The part of the page typed out so far, wrapped to the given width in pixels so words don't jump between lines as
+// they're typed
+func (o *openDialogue) shown(style TextStyle, width int) string {
+	lines := wrapText(face(style.Size), o.d.Pages[o.page], width)
+	page := []rune(strings.Join(lines, "\n"))
+	n := o.ticks * dialogueSpeed / ticksPerSecond
+	if o.revealed || n > len(page) {
+		n = len(page)
+	}
+	return string(page[:n])
+}
+
+// Draws the open dialogue in a box along the bottom of the screen
+func (g *Game) drawDialogue(screen *ebiten.Image) {
+	o := g.dialogue
+	if o == nil {
+		return
+	}
+	scale := hudScale(g.c.sh)
+	margin, padding := int(hudMargin*scale), int(dialoguePadding*scale)
+	style := TextStyle{Size: dialogueTextSize * scale}
+	line := lineHeight(style)
+	// room for the speaker, three lines of text and the prompt to continue
+	w, h := g.c.sw-2*margin, 5*line+2*padding
+	x, y := margin, g.c.sh-margin-h
+	ebitenutil.DrawRect(screen, float64(x), float64(y), float64(w), float64(h), dialogueBackground)
+	x, y = x+padding, y+padding
+	if o.d.Speaker != "" {
+		speaker := style
+		speaker.Color = dialogueSpeaker
+		drawText(screen, o.d.Speaker, x, y, speaker)
+	}
+	drawText(screen, o.shown(style, w-2*padding), x, y+line, style)
+	if o.revealed {
+		prompt := style
+		prompt.Align = alignRight
+		drawText(screen, "(Enter)", x+w-2*padding, y+4*line, prompt)
+	}
+}
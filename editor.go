@@ -1,16 +1,20 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/ByteArena/box2d"
 	"github.com/hajimehoshi/ebiten/v2"
-	"github.com/hajimehoshi/ebiten/v2/audio"
-	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
 	"github.com/hherman1/gobananas/resources"
 	"image/color"
+	"io"
 	"math"
 	"os"
+	"sort"
 	"strings"
 	"time"
 )
@@ -18,6 +22,13 @@ import (
 // We automatically write updates to the current level to the autosave file periodically
 const autosave = "autosave.lvl"
 
+// Levels saved to paths with this suffix, e.g "cave.lvl.gz", are gzipped. Compressed levels are recognized by their
+// contents when loading, whatever their path.
+const compressedSuffix = ".gz"
+
+// The first bytes of a gzip stream
+var gzipMagic = []byte{0x1f, 0x8b}
+
 // Level editing mode with routines for saving and loading levels
 type Editor struct {
 	// Where in the level are we looking
@@ -27,22 +38,73 @@ type Editor struct {
 	l Level
 
 	autotimer *time.Ticker
+	// Periodically checks for art which changed on disk
+	reloadtimer *time.Ticker
+
+	// Results of the last validation of the level, or other news about it like the session summary on save, shown
+	// until the next
+	validation string
+	// Developer overlay for frame rate and memory
+	perf PerfOverlay
+
+	// Where the level was loaded from or last saved to, used to find its best times
+	path string
+	// Name of the spawn point playtests start from, "" for the default spawn
+	start string
+	// Name of the character playtests start as, "" for the level's
+	character string
+	// If true the level is drawn simplified so huge levels stay smooth to edit, see drawBlocksFast
+	fast bool
+
+	// Name of the subeditor last activated, and what's been done this session
+	mode    string
+	session EditorSession
+
+	// Layer new blocks and art are put in, and which layers are hidden or locked, see LayerEditor
+	layer  string
+	layers map[string]layerState
 }
 var unitVertices, unitIs = rect(0, 0, 1, 1, color.RGBA{})
 
 
-// Creates a new editor
-func NewEditor() *Editor {
+// Loads the autosaved level behind a loading screen, then calls then with a new editor for it
+func ActivateNewEditor(r *Root, then func(r *Root, e *Editor) error) {
+	ActivateLevelLoading(r, autosave, func(r *Root, l Level, err error) error {
+		e := newEditor()
+		e.path = autosave
+		e.l = l
+		if err != nil {
+			// autosave is broken, reset level
+			geo := Mx{}
+			geo.Scale(100, 0.5)
+			e.l = NewLevel()
+			e.l.Blocks = []*Block{{T: geo}}
+		}
+		return then(r, e)
+	})
+}
+
+// Loads the level at the given path behind a loading screen, then calls then with a new editor for it
+func ActivateEditLevel(r *Root, path string, then func(r *Root, e *Editor) error) {
+	ActivateLevelLoading(r, path, func(r *Root, l Level, err error) error {
+		if err != nil {
+			return fmt.Errorf("load level %v: %w", path, err)
+		}
+		e := newEditor()
+		e.path = path
+		e.l = l
+		if e.l.modified {
+			e.validation = modifiedWarning
+		}
+		e.c.zoom = e.l.PixelsPerUnit
+		return then(r, e)
+	})
+}
+
+func newEditor() *Editor {
 	var e Editor
 	e.autotimer = time.NewTicker(10 * time.Second)
-	err := e.l.load(autosave)
-	if err != nil {
-		// autosave is broken, reset level
-		geo := Mx{}
-		geo.Scale(100, 0.5)
-		e.l = NewLevel()
-		e.l.Blocks = []*Block{{T: geo}}
-	}
+	e.reloadtimer = time.NewTicker(time.Second)
 	return &e
 }
 
@@ -59,7 +121,9 @@ var subeditors = []struct {
 		name: "Blocks",
 		key:  ebiten.KeyL,
 		activate: func(r *Root, e *Editor) {
-			r.a = &PlatformEditor{e: e}
+			p := &PlatformEditor{e: e, t: &Typer{C: &e.c}}
+			p.t.Placeholder = p.describe()
+			r.a = p
 		},
 	},
 	{
@@ -67,11 +131,99 @@ var subeditors = []struct {
 		key:  ebiten.KeyA,
 		activate: func(r *Root, e *Editor) {
 			r.a = &ArtEditor{e: e, t: &Typer{
-				Placeholder: "Art Editor: Press enter to load art resources into the level",
+				Placeholder: "Art Editor: Enter a path to add art, 'variant <path>' to vary the last art, " +
+					"'slice <left> <top> <right> <bottom>' to nine slice it, 'normal <path>' to light it with a " +
+					"normal map, or 'reseed'",
 				C:           &e.c,
 			}}
 		},
 	},
+	{
+		name: "Ropes",
+		key:  ebiten.KeyK,
+		activate: func(r *Root, e *Editor) {
+			r.a = &RopeEditor{e: e}
+		},
+	},
+	{
+		name:     "Magnets",
+		key:      ebiten.KeyM,
+		activate: ActivateAttractorEditor,
+	},
+	{
+		name: "Portals",
+		key:  ebiten.KeyO,
+		activate: func(r *Root, e *Editor) {
+			r.a = &PortalEditor{e: e}
+		},
+	},
+	{
+		name: "Contraptions",
+		key:  ebiten.KeyN,
+		activate: func(r *Root, e *Editor) {
+			r.a = &ContraptionEditor{e: e}
+		},
+	},
+	{
+		name:     "Tiles",
+		key:      ebiten.KeyT,
+		activate: ActivateTileEditor,
+	},
+	{
+		name:     "Spawns",
+		key:      ebiten.KeyH,
+		activate: ActivateSpawnEditor,
+	},
+	{
+		name:     "Goal",
+		key:      ebiten.KeyG,
+		activate: ActivateGoalEditor,
+	},
+	{
+		name:     "Switches",
+		key:      ebiten.KeyW,
+		activate: ActivateSwitchEditor,
+	},
+	{
+		name:     "Ambience",
+		key:      ebiten.KeyB,
+		activate: ActivateAmbientEditor,
+	},
+	{
+		name:     "Music",
+		key:      ebiten.KeyZ,
+		activate: ActivateMusicEditor,
+	},
+	{
+		name:     "Characters",
+		key:      ebiten.KeyX,
+		activate: ActivateStationEditor,
+	},
+	{
+		name:     "Enemies",
+		key:      ebiten.KeyE,
+		activate: ActivateEnemyEditor,
+	},
+	{
+		name:     "Camera",
+		key:      ebiten.KeyD,
+		activate: ActivateCameraEditor,
+	},
+	{
+		name:     "Labels",
+		key:      ebiten.KeyY,
+		activate: ActivateLabelEditor,
+	},
+	{
+		name:     "Lights",
+		key:      ebiten.KeyDigit0,
+		activate: ActivateLightEditor,
+	},
+	{
+		name:     "Layers",
+		key:      ebiten.KeyDigit9,
+		activate: ActivateLayerEditor,
+	},
 	{
 		name:     "Select",
 		key:      ebiten.KeyS,
@@ -83,6 +235,121 @@ var subeditors = []struct {
 type Block struct {
 	// A transformation that maps a unit square to a rectangle representing this block in world coordinates.
 	T Mx
+	// If set, the block crumbles away after the player stands on it
+	Crumble *Crumble `json:",omitempty"`
+	// Name triggers can refer to the block by, e.g a trigger named "touched door" fires when the player touches a
+	// block named "door". Several blocks can share a name.
+	Name string `json:",omitempty"`
+	// What the block's surface is made of, e.g "ice", see materials. Normal if empty.
+	Material string `json:",omitempty"`
+	// How bouncy the block is, from 0 for not at all to 1 for a spring which bounces the player back as high as they
+	// fell from
+	Restitution float64 `json:",omitempty"`
+	// Draws the block in place of the main shader, if set
+	Shader *CustomShader `json:",omitempty"`
+	// Editor layer the block is in, e.g "collision", which can be hidden or locked while editing. Has no effect on
+	// the game.
+	Layer string `json:",omitempty"`
+}
+
+// Bounciness presets for blocks, in the order the block editor cycles through them
+var bouncinessPresets = []struct {
+	name        string
+	restitution float64
+}{
+	{"none", 0},
+	{"bouncy", 0.5},
+	{"spring", 1},
+}
+
+var springColor = color.RGBA{R: 0x60, G: 0xff, B: 0xff, A: 0xff}
+
+// Removes the block with the given index from the level. Art pinned to it is unpinned in place, switches stop
+// targeting it, and references to later blocks are shifted down.
+func (l *Level) removeBlock(i int) {
+	for _, a := range l.Art {
+		if a.Parent == nil {
+			continue
+		}
+		switch {
+		case *a.Parent == i:
+			l.pin(a, -1)
+		case *a.Parent > i:
+			parent := *a.Parent - 1
+			a.Parent = &parent
+		}
+	}
+	for _, s := range l.Switches {
+		var targets []int
+		for _, t := range s.Targets {
+			switch {
+			case t < i:
+				targets = append(targets, t)
+			case t > i:
+				targets = append(targets, t-1)
+			}
+		}
+		s.Targets = targets
+	}
+	l.Blocks = append(l.Blocks[:i], l.Blocks[i+1:]...)
+}
+
+// Removes the art at the given index, rewiring cutscenes to the new indices. Cutscene moves of the removed art are
+// dropped.
+func (l *Level) removeArt(i int) {
+	for _, t := range l.Triggers {
+		if t.Cutscene == nil {
+			continue
+		}
+		var moves []ArtMove
+		for _, m := range t.Cutscene.Moves {
+			switch {
+			case m.Art < i:
+				moves = append(moves, m)
+			case m.Art > i:
+				m.Art--
+				moves = append(moves, m)
+			}
+		}
+		t.Cutscene.Moves = moves
+	}
+	l.Art = append(l.Art[:i], l.Art[i+1:]...)
+}
+
+// Decomposes the block's transform into the center, rotation, and half width and height of a box in world units.
+func (b *Block) box() (center box2d.B2Vec2, angle, hw, hh float64) {
+	return decompose(b.T)
+}
+
+// Decomposes a transform of a unit square centered at the origin into the center, rotation, and half width and height
+// of the resulting box.
+func decompose(t Mx) (center box2d.B2Vec2, angle, hw, hh float64) {
+	cx, cy := t.Apply(0, 0)
+	center = box2d.B2Vec2{X: cx, Y: cy}
+
+	// Compute half width, distance from center to right edge
+	wx, wy := t.Apply(0.5, 0)
+	hw = math.Sqrt((wx-cx)*(wx-cx) + (wy-cy)*(wy-cy))
+	// Half height
+	hx, hy := t.Apply(0, 0.5)
+	hh = math.Sqrt((hx-cx)*(hx-cx) + (hy-cy)*(hy-cy))
+
+	// Angle, rotation between transformed right edge and original right edge
+	ax, ay := wx - cx, wy - cy
+	angle = math.Atan2(ay, ax)
+	return
+}
+
+// Creates the physics fixture for the block given its half width and height.
+func (b *Block) fixture(hw, hh float64) *box2d.B2FixtureDef {
+	shape := box2d.MakeB2PolygonShape()
+	shape.SetAsBox(hw, hh)
+	def := box2d.MakeB2FixtureDef()
+	def.Shape = &shape
+	def.Density = 1
+	def.Friction = 0.3
+	def.Restitution = b.Restitution
+	return &def
 }
 
 // Art to display on top of the level for covering up platforms and beautifying the world.
@@ -91,43 +358,69 @@ type Art struct {
 	T Mx
 	// The path to load the art from from resources. e.g "resources/grass.png"
 	Path string
+	// Paths to other images the art may show instead, one of which is picked by the level's decoration seed
+	Variants []string `json:",omitempty"`
+	// Index in the level's blocks of the block this art is pinned to, if any. T is then relative to the block's center
+	// and rotation, so the art follows the block when it moves.
+	Parent *int `json:",omitempty"`
+	// Layer the art is drawn in, higher layers over lower ones. Art in the same layer is drawn in the order it was
+	// added.
+	Z int `json:",omitempty"`
+	// If set only the middle of the image stretches with the art, see NineSlice
+	Slice *NineSlice `json:",omitempty"`
+	// Path to a normal map the same size as the image, which shapes how the level's lights fall on the art
+	Normal string `json:",omitempty"`
+	// Draws the art in place of the art shader, if set
+	Shader *CustomShader `json:",omitempty"`
+	// Editor layer the art is in, e.g "decoration", see Block.Layer
+	Layer string `json:",omitempty"`
 	// The loaded image. Always set once the level is loaded.
 	img *ebiten.Image
+	// The loaded image at Path followed by each variant's
+	imgs []*ebiten.Image
+	// The loaded normal map, if any
+	normal *ebiten.Image
 }
 
-// Load the art from resources
+// The art in the order it's drawn, lowest layer first
+func layered(art []*Art) []*Art {
+	sorted := append([]*Art(nil), art...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Z < sorted[j].Z
+	})
+	return sorted
+}
+
+// Load the art from resources, showing the image at Path until the level is decorated
 func (a *Art) Load() error {
 	img, err := resources.Image(a.Path)
 	if err != nil {
 		return fmt.Errorf("load image: %w", err)
 	}
-	a.img = img
-	return nil
-}
-
-// Serializable audio file reference for use in level files
-type Audio struct {
-	// The file path for loading the audio
-	Path string
-	// A number between 0 and 1 indicating the volume to use for this audio. Default is 1
-	Volume *float64
-	// The decoded file for use as a player
-	decoded []byte
-	// The player for this audio, once loaded.
-	player *audio.Player
-}
-
-// Loads the audio player into the audio struct. Must be called before sending the audio to the game
-func (a *Audio) Load() error {
-	p, err := resources.Audio(a.Path)
-	if err != nil {
-		return fmt.Errorf("load %v: %w", a.Path, err)
+	imgs := []*ebiten.Image{img}
+	for _, v := range a.Variants {
+		vimg, err := resources.Image(v)
+		if err != nil {
+			return fmt.Errorf("load variant %v: %w", v, err)
+		}
+		imgs = append(imgs, vimg)
 	}
-	a.decoded = p
-	a.player = audio.NewPlayerFromBytes(Actx, a.decoded)
-	if a.Volume != nil {
-		a.player.SetVolume(*a.Volume)
+	var normal *ebiten.Image
+	if a.Normal != "" {
+		normal, err = resources.Image(a.Normal)
+		if err != nil {
+			return fmt.Errorf("load normal map: %w", err)
+		}
 	}
+	if a.Shader != nil {
+		err = a.Shader.Load()
+		if err != nil {
+			return err
+		}
+	}
+	a.img = img
+	a.imgs = imgs
+	a.normal = normal
 	return nil
 }
 
@@ -135,6 +428,14 @@ func (a *Audio) Load() error {
 type Level struct {
 	// Where does the player spawn in the level
 	Spawn box2d.B2Vec2
+	// Other places the player can spawn by name, which triggers can send the player to and playtests can start from
+	Spawns map[string]box2d.B2Vec2 `json:",omitempty"`
+	// Zone the player races to from the spawn, a transform of a unit square centered at the origin. Runs are timed
+	// if set.
+	Goal *Mx `json:",omitempty"`
+	// Area the camera is kept inside while playing, a transform of a unit square centered at the origin. The camera
+	// can go anywhere if unset.
+	CameraBounds *Mx `json:",omitempty"`
 	// All the platforms in the physics world
 	Blocks []*Block
 	// Images for display
@@ -145,13 +446,77 @@ type Level struct {
 	BGArt *Art
 	// Art to render over the character
 	PlayerArt *Art
+	// Characters the player can be besides the built in ones, by name
+	Characters map[string]*Character `json:",omitempty"`
+	// Name of the character the player starts as, "" for the default
+	Character string `json:",omitempty"`
 	// Functions to call on certain game events
 	Triggers map[string]Trigger
+	// Decorative ropes and chains hanging between points
+	Ropes []*Rope `json:",omitempty"`
+	// Zones which pull bodies towards them
+	Attractors []*Attractor `json:",omitempty"`
+	// Linked pairs of portals
+	Portals []*Portal `json:",omitempty"`
+	// Jointed physics objects like seesaws
+	Contraptions []*Contraption `json:",omitempty"`
+	// Zones which fire triggers and turn blocks on or off when touched or shot
+	Switches []*Switch `json:",omitempty"`
+	// Looping sounds placed in the world
+	Ambients []*Ambient `json:",omitempty"`
+	// Regions with their own music, which is crossfaded to from the background audio when the camera enters them
+	MusicZones []*MusicZone `json:",omitempty"`
+	// Regions which zoom or hold the camera while the player is in them
+	CameraZones []*CameraZone `json:",omitempty"`
+	// Point lights. The level is dark away from them if it has any.
+	Lights []*Light `json:",omitempty"`
+	// If set blocks cast shadows from the lights, e.g for caves and night levels
+	Shadows bool `json:",omitempty"`
+	// Strength of the glow around bright parts of the level like bullets and goals, 0 for none and 1 for full
+	Bloom float64 `json:",omitempty"`
+	// Color grade of everything but the HUD, if any
+	Grade *Grade `json:",omitempty"`
+	// Cycles the level's light through the day, if set
+	DayNight *DayNight `json:",omitempty"`
+	// Zones which turn the player into another character
+	Stations []*Station `json:",omitempty"`
+	// Turrets which shoot at the player
+	Enemies []*Enemy `json:",omitempty"`
+	// Grids of painted tiles
+	Tilemaps []*Tilemap `json:",omitempty"`
+	// Prefabs placed in the level, whose blocks and art are added to the level's own when it's played
+	Prefabs []*Prefab `json:",omitempty"`
+	// Text placed in the level, e.g tutorial hints
+	Labels []*Label `json:",omitempty"`
+	// Who made the level, credited in level indexes
+	Author string `json:",omitempty"`
+	// If true the level is simulated deterministically, so runs with the same input play out identically on every
+	// platform, e.g for replays and leaderboards
+	Deterministic bool `json:",omitempty"`
+	// If true there is no world gravity, and the player stands and walks relative to the nearest gravity well
+	// attractor, for small planet levels
+	Planetary bool `json:",omitempty"`
+	// Pixels per world unit the camera starts zoomed to, 0 for the default
+	PixelsPerUnit float64 `json:",omitempty"`
+	// Multiplies the size of every character, 0 for 1, see scaleCharacter
+	PlayerScale float64 `json:",omitempty"`
+	// Seeds random decoration, like which variant art shows, so the level looks the same every time it loads
+	Seed int64 `json:",omitempty"`
+	// Hash of the rest of the level when it was saved, see levelHash. Set on save, so a level edited by hand or some
+	// other tool since can be told apart.
+	Hash string `json:",omitempty"`
+
+	// Set on load if the level doesn't match its hash
+	modified bool
 }
 
+// Warning shown for levels which were changed outside the editor
+const modifiedWarning = "Warning: this level was modified since it was last saved by the editor"
+
 func NewLevel() Level {
 	var l Level
 	l.Triggers = make(map[string]Trigger)
+	l.Seed = newSeed()
 	return l
 }
 
@@ -159,11 +524,34 @@ func NewLevel() Level {
 type Trigger struct {
 	// If set, when this trigger is called it will play the given audio once.
 	Audio *Audio
+	// If set, when this trigger is called the camera pans to the given point for a while
+	LookAt *LookAt `json:",omitempty"`
+	// If set, when this trigger is called the camera shakes, e.g for an explosion
+	Shake *Shake `json:",omitempty"`
+	// If set, when this trigger is called the dialogue is shown, and the player can't move until they've read it
+	Dialogue *Dialogue `json:",omitempty"`
+	// If set, when this trigger is called the cutscene plays, and the player can't move until it's over
+	Cutscene *Cutscene `json:",omitempty"`
+	// If set, when this trigger is called the time of day changes
+	Time *TimeChange `json:",omitempty"`
+	// If set, when this trigger is called the player is moved to the spawn point with this name
+	Spawn string `json:",omitempty"`
 }
 
 // Runs the actual trigger. Should only be called when the event its associated with happens.
 func (t Trigger) Activate() {
 	if t.Audio != nil  {
+		t.Audio.Center()
+		_ = t.Audio.player.Seek(0)
+		t.Audio.player.Play()
+	}
+}
+
+// Runs the trigger as if its event happened at the given world position, so that its audio is attenuated and panned
+// relative to the camera. Occluded is the thickness of terrain between the camera and the event in world units.
+func (t Trigger) ActivateAt(c *Camera, x, y, occluded float64) {
+	if t.Audio != nil {
+		t.Audio.Place(c.x, c.y, x, y, occluded)
 		_ = t.Audio.player.Seek(0)
 		t.Audio.player.Play()
 	}
@@ -176,61 +564,188 @@ func (t *Trigger) Load() error {
 			return fmt.Errorf("load audio: %w", err)
 		}
 	}
+	if t.Cutscene != nil {
+		for i := range t.Cutscene.Sounds {
+			err := t.Cutscene.Sounds[i].Audio.Load()
+			if err != nil {
+				return fmt.Errorf("load cutscene sound %v: %w", i, err)
+			}
+		}
+	}
 	return nil
 }
 
-// Saves the level design to the given path
+// Saves the level design to the given path, along with its hash. The level is gzipped if the path ends in
+// compressedSuffix.
 func (l Level) save(path string) error {
+	hash, err := levelHash(&l)
+	if err != nil {
+		return fmt.Errorf("hash level: %w", err)
+	}
+	l.Hash = hash
 	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0777)
 	if err != nil {
 		return fmt.Errorf("open file to save level: %w", err)
 	}
 	defer f.Close()
-	encoder := json.NewEncoder(f)
+	var w io.Writer = f
+	var zw *gzip.Writer
+	if strings.HasSuffix(path, compressedSuffix) {
+		zw = gzip.NewWriter(f)
+		w = zw
+	}
+	encoder := json.NewEncoder(w)
 	encoder.SetIndent("", "    ")
 	err = encoder.Encode(l)
 	if err != nil {
 		return fmt.Errorf("save level: %w", err)
 	}
+	if zw != nil {
+		err = zw.Close()
+		if err != nil {
+			return fmt.Errorf("compress level: %w", err)
+		}
+	}
 	return nil
 }
 
-// Replaces a level with the one stored at the given path
-func (l *Level) load(path string) error {
-	*l = NewLevel()
+// Replaces a level with the one stored at the given path, without loading any of its assets. Maps from the Tiled
+// editor (.tmj) are imported, and gzipped levels are decompressed.
+func (l *Level) decode(path string) error {
+	if strings.HasSuffix(path, ".tmj") {
+		return l.importTiled(path)
+	}
 	f, err := os.Open(path)
 	if err != nil {
 		return fmt.Errorf("open file to load level: %w", err)
 	}
 	defer f.Close()
-	decoder := json.NewDecoder(f)
-	err = decoder.Decode(l)
+	r := bufio.NewReader(f)
+	magic, _ := r.Peek(len(gzipMagic))
+	if !bytes.Equal(magic, gzipMagic) {
+		return l.read(r)
+	}
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("decompress level: %w", err)
+	}
+	defer zr.Close()
+	return l.read(zr)
+}
+
+// Replaces a level with one decoded from JSON, without loading any of its assets. Levels which are well formed JSON
+// but would crash the game are rejected.
+func (l *Level) read(r io.Reader) error {
+	*l = NewLevel()
+	// levels saved without a seed always decorate with 0, so their art and hash are the same every time they load
+	l.Seed = 0
+	decoder := json.NewDecoder(r)
+	err := decoder.Decode(l)
 	if err != nil {
 		return fmt.Errorf("decoding level from file: %w", err)
 	}
+	if l.Triggers == nil {
+		// "Triggers": null
+		l.Triggers = make(map[string]Trigger)
+	}
+	if l.Hash != "" {
+		hash, err := levelHash(l)
+		if err != nil {
+			return err
+		}
+		l.modified = hash != l.Hash
+	}
+	return l.check()
+}
+
+// Loads the art and audio of a decoded level. If progress is set it is called after each asset is loaded with the
+// number of assets loaded so far and the total.
+func (l *Level) loadAssets(progress func(done, total int)) error {
+	total := len(l.Art) + len(l.Tilemaps) + len(l.Prefabs) + len(l.Triggers) + len(l.Ambients) + len(l.MusicZones)
+	for _, b := range l.Blocks {
+		if b.Shader != nil {
+			total++
+		}
+	}
+	for _, a := range []interface{}{l.PlayerArt, l.BGArt, l.BGAudio} {
+		if a != nil {
+			total++
+		}
+	}
+	for _, c := range l.Characters {
+		if c.Art != nil {
+			total++
+		}
+	}
+	done := 0
+	step := func() {
+		done++
+		if progress != nil {
+			progress(done, total)
+		}
+	}
+	for i, b := range l.Blocks {
+		if b.Shader == nil {
+			continue
+		}
+		err := b.Shader.Load()
+		if err != nil {
+			return fmt.Errorf("load block %v %v: %w", i, b.Shader.Path, err)
+		}
+		step()
+	}
 	for _, a := range l.Art {
 		err := a.Load()
 		if err != nil {
 			return fmt.Errorf("load %v: %w", a.Path, err)
 		}
+		step()
+	}
+	for _, t := range l.Tilemaps {
+		err := t.Load()
+		if err != nil {
+			return fmt.Errorf("load tilemap %v: %w", t.Tileset, err)
+		}
+		step()
+	}
+	for _, p := range l.Prefabs {
+		err := p.Load()
+		if err != nil {
+			return fmt.Errorf("load prefab %v: %w", p.Path, err)
+		}
+		step()
 	}
 	if l.PlayerArt != nil {
 		err := l.PlayerArt.Load()
 		if err != nil {
 			return fmt.Errorf("load player art %v: %w", l.PlayerArt.Path, err)
 		}
+		step()
+	}
+	for n, c := range l.Characters {
+		if c.Art == nil {
+			continue
+		}
+		err := c.Art.Load()
+		if err != nil {
+			return fmt.Errorf("load art for character %v: %w", n, err)
+		}
+		step()
 	}
 	if l.BGArt != nil {
 		err := l.BGArt.Load()
 		if err != nil {
 			return fmt.Errorf("load BG art %v: %w", l.BGArt.Path, err)
 		}
+		step()
 	}
 	if l.BGAudio != nil {
-		err = l.BGAudio.Load()
+		l.BGAudio.music = true
+		err := l.BGAudio.Load()
 		if err != nil {
 			return fmt.Errorf("load bg audio: %w", err)
 		}
+		step()
 	}
 	for n, t := range l.Triggers {
 		err := t.Load()
@@ -238,57 +753,189 @@ func (l *Level) load(path string) error {
 			return fmt.Errorf("load trigger '%v': %w", n, err)
 		}
 		l.Triggers[n] = t
+		step()
+	}
+	for _, a := range l.Ambients {
+		err := a.Audio.Load()
+		if err != nil {
+			return fmt.Errorf("load ambient sound: %w", err)
+		}
+		step()
+	}
+	for _, z := range l.MusicZones {
+		z.Audio.music = true
+		err := z.Audio.Load()
+		if err != nil {
+			return fmt.Errorf("load music zone: %w", err)
+		}
+		step()
+	}
+	l.decorate()
+	return nil
+}
+
+// Reloads any art in the level using one of the given resource paths
+func (l *Level) reloadArt(paths []string) error {
+	if len(paths) == 0 {
+		return nil
+	}
+	changed := make(map[string]bool)
+	for _, p := range paths {
+		changed[p] = true
+	}
+	art := append([]*Art{l.BGArt, l.PlayerArt}, l.Art...)
+	for _, c := range l.Characters {
+		art = append(art, c.Art)
+	}
+	for _, a := range art {
+		if a == nil {
+			continue
+		}
+		stale := changed[a.Path]
+		for _, v := range a.Variants {
+			stale = stale || changed[v]
+		}
+		if !stale {
+			continue
+		}
+		err := a.Load()
+		if err != nil {
+			return fmt.Errorf("reload %v: %w", a.Path, err)
+		}
+	}
+	l.decorate()
+	for _, t := range l.Tilemaps {
+		if !changed[t.Tileset] {
+			continue
+		}
+		err := t.Load()
+		if err != nil {
+			return fmt.Errorf("reload %v: %w", t.Tileset, err)
+		}
 	}
 	return nil
 }
 
 // Adds the contents of this level to a given game world
 func (l Level) apply(g *Game) {
+	// prefabs are played as if their blocks and art were the level's own
+	l.Blocks, l.Art = l.expanded()
 	g.p.b.SetTransform(l.Spawn, 0)
+	g.spawns = map[string]box2d.B2Vec2{"": l.Spawn}
+	for n, p := range l.Spawns {
+		g.spawns[n] = p
+	}
+	g.goal = l.Goal
+	g.cameraBounds = l.CameraBounds
+	g.deterministic = l.Deterministic
+	if l.Planetary {
+		g.planetary = true
+		g.world.SetGravity(box2d.B2Vec2{})
+		// the player is turned to face down instead
+		g.p.b.SetFixedRotation(true)
+	}
 	for _, p := range l.Blocks {
 		// make a body
 		body := box2d.NewB2BodyDef()
-		cx, cy := p.T.Apply(0, 0)
-		body.Position = box2d.B2Vec2{X: cx, Y: cy}
-
-		// Compute half width, distance from center to right edge
-		wx, wy := p.T.Apply(0.5, 0)
-		hw := math.Sqrt((wx-cx)*(wx-cx) + (wy-cy)*(wy-cy))
-		// Half height
-		hx, hy := p.T.Apply(0, 0.5)
-		hh := math.Sqrt((hx-cx)*(hx-cx) + (hy-cy)*(hy-cy))
-		shape := box2d.MakeB2PolygonShape()
-		shape.SetAsBox(hw, hh)
-
-		// Angle, rotation between transformed right edge and original right edge
-		ax, ay := wx - cx, wy - cy
-		body.Angle = math.Atan2(ay, ax)
-
-		def := box2d.MakeB2FixtureDef()
-		def.Shape = &shape
-		def.Density = 1
-		def.Friction = 0.3
+		center, angle, hw, hh := p.box()
+		body.Position = center
+		body.Angle = angle
+
 		entity := Entity{
 			w:            hw * 2,
 			h:            hh * 2,
 			b:            g.world.CreateBody(body),
 			restoresJump: true,
+			block:        p,
+		}
+		if p.Crumble != nil {
+			entity.crumble = &crumbling{c: *p.Crumble}
 		}
 		entity.b.SetUserData(&entity)
 		g.entities = append(g.entities, &entity)
-		entity.b.CreateFixtureFromDef(&def)
+		entity.b.CreateFixtureFromDef(p.fixture(hw, hh))
 	}
+	for _, s := range l.Switches {
+		s.build(g, l.Blocks)
+	}
+	g.art = append(g.art, layered(l.Art)...)
+	g.levelArt = l.Art
+	g.moved = make(map[*Art]ArtKey)
+	g.pinned = make(map[*Art]*Entity)
 	for _, a := range l.Art {
-		g.art = append(g.art, a)
+		if b := l.parent(a); b != nil {
+			for _, e := range g.entities {
+				if e.block == b {
+					g.pinned[a] = e
+				}
+			}
+		}
+	}
+	for _, a := range l.Attractors {
+		g.attractors = append(g.attractors, *a)
+	}
+	g.portals = l.Portals
+	for _, c := range l.Contraptions {
+		c.build(g)
+	}
+	for _, t := range l.Tilemaps {
+		t.build(g)
+		g.tilemaps = append(g.tilemaps, t)
+	}
+	g.labels = l.Labels
+	for _, r := range l.Ropes {
+		kopy := *r
+		kopy.attach(&g.world)
+		g.ropes = append(g.ropes, &kopy)
 	}
 	g.bgArt = l.BGArt
 	g.bgAudio = l.BGAudio
+	g.ambients = l.Ambients
+	g.musicZones = l.MusicZones
+	g.cameraZones = l.CameraZones
+	g.lights = l.Lights
+	g.shadows = l.Shadows
+	g.glow = l.Bloom
+	g.day = nil
+	if l.DayNight != nil {
+		g.day = &clock{length: l.DayNight.Length, at: l.DayNight.Start}
+	}
+	g.grade = nil
+	if l.Grade != nil {
+		m := l.Grade.colorM()
+		g.grade = &m
+	}
 	g.pArt = l.PlayerArt
+	g.playerArt = l.PlayerArt
+	g.c.zoom = l.PixelsPerUnit
+	g.characters = make(map[string]Character)
+	for _, n := range l.characterNames() {
+		c, _ := l.character(n)
+		g.characters[n] = scaleCharacter(c, l.playerScale())
+	}
+	g.stations = l.Stations
+	for _, en := range l.Enemies {
+		en.build(g)
+	}
+	start := l.Character
+	if start == "" {
+		start = defaultCharacter
+	}
+	g.become(start)
 	g.Triggers = l.Triggers
+	g.setBottom()
 }
 
 // Run a single tick of editing updates
 func (e *Editor) Update(r *Root) error {
+	{
+		// session statistics, subeditors update the editor after themselves
+		mode := e.mode
+		if r.a == e {
+			mode = ""
+		}
+		e.session.update(mode, &e.l)
+	}
 	{
 		// autosave
 		select {
@@ -300,9 +947,20 @@ func (e *Editor) Update(r *Root) error {
 		default:
 		}
 	}
+	{
+		// hot reload art
+		select {
+		case <-e.reloadtimer.C:
+			err := e.l.reloadArt(resources.StaleImages())
+			if err != nil {
+				fmt.Println("Failed to reload art:", err)
+			}
+		default:
+		}
+	}
 	{
 		// camera controls
-		_, yoff := ebiten.Wheel()
+		_, yoff := driver.Wheel()
 		if yoff != 0 {
 			e.c.hh *= math.Pow(0.98, yoff)
 			e.c.hw *= math.Pow(0.98, yoff)
@@ -321,11 +979,11 @@ func (e *Editor) Update(r *Root) error {
 	}
 	// save/load level
 	{
-		if Clicked(ebiten.KeyS) && ebiten.IsKeyPressed(ebiten.KeyMeta) {
+		if shortcut(actionSave) {
 			ActivateSave(r, e)
 			return r.Update()
 		}
-		if Clicked(ebiten.KeyL) && ebiten.IsKeyPressed(ebiten.KeyMeta) {
+		if shortcut(actionLoad) {
 			ActivateLoad(r, e)
 			return r.Update()
 		}
@@ -336,24 +994,56 @@ func (e *Editor) Update(r *Root) error {
 			// play mode
 			g := NewGame()
 			e.l.apply(g)
+			g.become(e.character)
+			g.respawn(e.start)
 			err := e.l.save(autosave)
 			if err != nil {
 				// still usable, just buggy
 				fmt.Println("Failed to autosave:", err)
 			}
-			r.a = &Admin{g}
+			r.transition(&Admin{g: g, e: e}, transitionFade)
 			return r.a.Update(r)
 		}
 		for _, sub := range subeditors {
-			if Clicked(sub.key) {
+			if Clicked(sub.key) && !anyShortcut() {
+				e.mode = sub.name
 				sub.activate(r, e)
 				return r.Update()
 			}
 		}
 	}
+	e.perf.Update()
+	// rulers first, their default combos also hold the grid's
+	toggled := true
+	switch {
+	case shortcut(actionRulers):
+		settings.Rulers = !settings.Rulers
+	case shortcut(actionGrid):
+		settings.Grid = !settings.Grid
+	default:
+		toggled = false
+	}
+	if toggled {
+		err := settings.save(settingsPath)
+		if err != nil {
+			fmt.Println("Failed to save settings:", err)
+		}
+	}
+	if Clicked(ebiten.KeyQ) {
+		e.fast = !e.fast
+	}
+	// validate
+	if Clicked(ebiten.KeyV) && !shortcut(actionPaste) {
+		problems := e.l.validate()
+		e.validation = "Level is valid"
+		if len(problems) > 0 {
+			e.validation = fmt.Sprintf("%v problems:\n%v", len(problems), strings.Join(problems, "\n"))
+		}
+	}
 	// reset
 	if Clicked(ebiten.KeyR) {
 		e.l = NewLevel()
+		e.session.recount(&e.l)
 		return nil
 	}
 	return nil
@@ -370,12 +1060,35 @@ func (e *Editor) drawBlock(screen *ebiten.Image, block *Block) {
 		v.DstY = float32(sy)
 		vertices[i] = v
 	}
-	screen.DrawTrianglesShader(vertices, is, mainShader, &ebiten.DrawTrianglesShaderOptions{
-		Uniforms: map[string]interface{}{
-			"ScreenPixels": []float32{float32(e.c.sw), float32(e.c.sh)},
-		},
-		Images: [4]*ebiten.Image{},
+	shader, uniforms := block.Shader.use(mainShader, map[string]interface{}{
+		"ScreenPixels": []float32{float32(e.c.sw), float32(e.c.sh)},
+	})
+	screen.DrawTrianglesShader(vertices, is, shader, &ebiten.DrawTrianglesShaderOptions{
+		Uniforms: uniforms,
+		Images:   [4]*ebiten.Image{},
 	})
+	if block.Crumble != nil {
+		// cross out crumbling blocks
+		x0, y0 := block.T.Apply(-0.5, -0.5)
+		x1, y1 := block.T.Apply(0.5, 0.5)
+		drawline(screen, x0, y0, x1, y1, 2, screenTransform, color.White)
+		x0, y0 = block.T.Apply(-0.5, 0.5)
+		x1, y1 = block.T.Apply(0.5, -0.5)
+		drawline(screen, x0, y0, x1, y1, 2, screenTransform, color.White)
+	}
+	if c, ok := materialColors[block.Material]; ok {
+		drawoutline(screen, block.T, 3, screenTransform, c)
+	}
+	if block.Name != "" {
+		sx, sy := screenTransform.Apply(block.T.Apply(-0.5, 0.5))
+		printAt(screen, block.Name, int(sx)+4, int(sy)+4)
+	}
+	if block.Restitution > 0 {
+		// underline the top of bouncy blocks, thicker the bouncier they are
+		x0, y0 := block.T.Apply(-0.5, 0.5)
+		x1, y1 := block.T.Apply(0.5, 0.5)
+		drawline(screen, x0, y0, x1, y1, 2+4*block.Restitution, screenTransform, springColor)
+	}
 }
 
 func (e *Editor) Draw(screen *ebiten.Image) {
@@ -389,36 +1102,105 @@ func (e *Editor) Draw(screen *ebiten.Image) {
 		geo.Translate(float64(e.c.sw)/2, float64(e.c.sh)/2)
 		screen.DrawImage(e.l.BGArt.img, &ebiten.DrawImageOptions{GeoM: geo.GeoM})
 	}
+	e.drawGrid(screen)
 
-	for _, entity := range e.l.Blocks {
-		e.drawBlock(screen, entity)
+	for _, t := range e.l.Tilemaps {
+		t.draw(screen, e.c.ToScreen())
 	}
+	if e.fast {
+		e.drawBlocksFast(screen)
+	} else {
+		toScreen := e.c.ToScreen()
+		for _, b := range e.l.Blocks {
+			if !e.hidden(b.Layer) && squareOnScreen(b.T, toScreen, e.c.sw, e.c.sh) {
+				e.drawBlock(screen, b)
+			}
+		}
+	}
+	for _, p := range e.l.Prefabs {
+		p.draw(e, screen)
+	}
+	for _, c := range e.l.Contraptions {
+		c.draw(e, screen)
+	}
+	e.drawRulers(screen)
 	var s strings.Builder
-	s.WriteString(`(P) Play
+	_, _ = fmt.Fprintf(&s, `(P) Play
+(V) Validate
+(F) Performance
+(Q) Fast drawing
+(%v) Save
+(%v) Load
+(%v) Fullscreen
+(%v) Grid
+(%v) Rulers
 
 Editors:
-`)
+`, shortcutName(actionSave), shortcutName(actionLoad), shortcutName(actionFullscreen), shortcutName(actionGrid),
+		shortcutName(actionRulers))
 	for _, sub := range subeditors {
 		_, _ = fmt.Fprintf(&s, "(%v) %v\n", sub.key, sub.name)
 	}
-	ebitenutil.DebugPrintAt(screen, s.String(), 10, 5)
+	hx, hy := e.helpOrigin()
+	printAt(screen, s.String(), hx, hy)
+	if e.validation != "" {
+		// problems can be long, keep them on screen
+		drawText(screen, e.validation, e.c.sw/3, hy, TextStyle{Width: e.c.sw*2/3 - 10})
+	}
+	e.perf.Draw(screen, &e.l, nil)
 
 
 
 	// player spawn
 	screenTransform := e.c.ToScreen()
-	drawpoint(screen, e.l.Spawn.X, e.l.Spawn.Y, 20, screenTransform, color.White)
+	drawSpawns(screen, &e.l, e.start, screenTransform)
+	if e.l.Goal != nil {
+		drawGoal(screen, *e.l.Goal, screenTransform)
+	}
+	if e.l.CameraBounds != nil {
+		drawCameraBounds(screen, *e.l.CameraBounds, screenTransform)
+	}
 
-	for _, a := range e.l.Art {
-		// unflip the images
-		var geo Mx
-		w, h := a.img.Size()
-		geo.Scale(1/float64(w), 1/float64(h))
-		geo.Translate(-0.5, -0.5)
-		geo.Scale(1, -1)
-		geo.Concat(a.T.GeoM)
-		geo.Concat(screenTransform.GeoM)
-		screen.DrawImage(a.img, &ebiten.DrawImageOptions{GeoM: geo.GeoM})
+	drawRopes(screen, e.l.Ropes, screenTransform)
+	for _, a := range e.l.Attractors {
+		a.draw(screen, screenTransform)
+	}
+	for _, p := range e.l.Portals {
+		p.draw(screen, screenTransform)
+	}
+	for _, s := range e.l.Switches {
+		s.draw(screen, e.l.Blocks, screenTransform)
+	}
+	for _, a := range e.l.Ambients {
+		a.draw(screen, screenTransform)
+	}
+	for _, z := range e.l.MusicZones {
+		z.draw(screen, screenTransform)
+	}
+	for _, z := range e.l.CameraZones {
+		z.draw(screen, screenTransform)
+	}
+	for _, lt := range e.l.Lights {
+		lt.draw(screen, screenTransform)
+	}
+	for _, en := range e.l.Enemies {
+		en.draw(screen, screenTransform)
+	}
+	for _, s := range e.l.Stations {
+		s.draw(screen, screenTransform)
+	}
+
+	if e.fast && len(e.l.Art) > fastArtLimit {
+		e.drawArtBoxes(screen)
+	} else {
+		for _, a := range layered(e.l.Art) {
+			if t := e.l.artTransform(a); !e.hidden(a.Layer) && squareOnScreen(t, screenTransform, e.c.sw, e.c.sh) {
+				drawArt(screen, a, t, screenTransform, nil, 0)
+			}
+		}
+	}
+	for _, lb := range e.l.Labels {
+		lb.draw(screen, screenTransform)
 	}
 }
 
@@ -429,9 +1211,59 @@ type PlatformEditor struct {
 	cpinx float64
 	cpiny float64
 
+	// If set, new blocks crumble like this
+	crumble *Crumble
+	// What new blocks are made of
+	material string
+	// Index in bouncinessPresets of how bouncy new blocks are
+	bounciness int
+	// Name for new blocks, "" for none
+	name string
+	// Custom shader new blocks are drawn with, if any
+	shader *CustomShader
+	t      *Typer
+
 	e *Editor
 }
 
+// Summarizes the name and shader for new blocks
+func (p *PlatformEditor) describe() string {
+	name, shader := p.name, "none"
+	if name == "" {
+		name = "none"
+	}
+	if p.shader != nil {
+		shader = p.shader.Path
+	}
+	return fmt.Sprintf("Name: %v, shader: %v. Enter 'name <name>' to fire '%v' triggers, 'shader <path>' to draw "+
+		"with a custom shader, or either alone to clear", name, touchEvent("<name>"), shader)
+}
+
+// Applies a command of the form "name [name]" or "shader [path]"
+func (p *PlatformEditor) apply(cmd string) error {
+	parts := strings.Fields(cmd)
+	if len(parts) == 0 || len(parts) > 2 || (parts[0] != "name" && parts[0] != "shader") {
+		return fmt.Errorf("expected 'name [name]' or 'shader [path]'")
+	}
+	if parts[0] == "shader" {
+		p.shader = nil
+		if len(parts) == 2 {
+			s := &CustomShader{Path: parts[1]}
+			err := s.Load()
+			if err != nil {
+				return fmt.Errorf("shader %v: %v", parts[1], describe(err))
+			}
+			p.shader = s
+		}
+		return nil
+	}
+	p.name = ""
+	if len(parts) == 2 {
+		p.name = parts[1]
+	}
+	return nil
+}
+
 func (p *PlatformEditor) Layout(outsideWidth, outsideHeight int) (screenWidth, screenHeight int) {
 	return p.e.Layout(outsideWidth, outsideHeight)
 }
@@ -442,14 +1274,69 @@ func (p *PlatformEditor) String() string {
 }
 
 func (p *PlatformEditor) Update(r *Root) error {
-	if ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft) {
+	cmd, typ := p.t.Update()
+	if typ {
+		return nil
+	}
+	if cmd != "" {
+		err := p.apply(cmd)
+		if err != nil {
+			p.t.Placeholder = fmt.Sprintf("Failed to apply '%v': %v", cmd, err)
+		} else {
+			p.t.Placeholder = p.describe()
+		}
+	}
+	if Clicked(ebiten.KeyC) {
+		if p.crumble == nil {
+			p.crumble = &Crumble{Delay: 0.5, Respawn: 3}
+		} else {
+			p.crumble = nil
+		}
+	}
+	if p.crumble != nil {
+		// tune the delay, or the respawn time with shift
+		setting := &p.crumble.Delay
+		if driver.IsKeyPressed(ebiten.KeyShift) {
+			setting = &p.crumble.Respawn
+		}
+		if Clicked(ebiten.KeyRightBracket) {
+			*setting += 0.25
+		}
+		if Clicked(ebiten.KeyLeftBracket) {
+			*setting = math.Max(0, *setting-0.25)
+		}
+	}
+	if Clicked(ebiten.KeyU) {
+		for i, m := range materials {
+			if m == p.material {
+				p.material = materials[(i+1)%len(materials)]
+				break
+			}
+		}
+	}
+	if Clicked(ebiten.KeyI) {
+		p.bounciness = (p.bounciness + 1) % len(bouncinessPresets)
+	}
+	if driver.IsMouseButtonPressed(ebiten.MouseButtonLeft) {
 		wx, wy := p.e.c.Cursor()
 		if p.creating == nil {
 			geo := Mx{}
 			geo.Scale(0, 0)
 			geo.Translate(wx, wy)
 			p.creating = &Block{
-				T: geo,
+				T:           geo,
+				Name:        p.name,
+				Material:    p.material,
+				Restitution: bouncinessPresets[p.bounciness].restitution,
+				Layer:       p.e.layer,
+			}
+			if p.shader != nil {
+				kopy := *p.shader
+				p.creating.Shader = &kopy
+			}
+			if p.crumble != nil {
+				kopy := *p.crumble
+				p.creating.Crumble = &kopy
 			}
 			p.cpinx = wx
 			p.cpiny = wy
@@ -464,7 +1351,7 @@ func (p *PlatformEditor) Update(r *Root) error {
 		geo.Translate(minx, miny)
 		p.creating.T = geo
 	}
-	if !ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft) && p.creating != nil {
+	if !driver.IsMouseButtonPressed(ebiten.MouseButtonLeft) && p.creating != nil {
 		p.e.l.Blocks = append(p.e.l.Blocks, p.creating)
 		p.creating = nil
 	}
@@ -475,8 +1362,34 @@ func (p *PlatformEditor) Draw(screen *ebiten.Image) {
 	if p.creating != nil {
 		p.e.drawBlock(screen, p.creating)
 	}
-	ebitenutil.DebugPrintAt(screen, "Platform Editor", 10, p.e.c.sh-20)
+	msg := "Platform Editor: (C) Crumbling off"
+	if p.crumble != nil {
+		msg = fmt.Sprintf("Platform Editor: (C) Crumbling after ([/]) %.2fs, respawning after (Shift+[/]) %.2fs",
+			p.crumble.Delay, p.crumble.Respawn)
+	}
+	material := p.material
+	if material == "" {
+		material = "normal"
+	}
+	printAt(screen, fmt.Sprintf("(U) Surface: %v (I) Bounciness: %v", material,
+		bouncinessPresets[p.bounciness].name), 10, p.e.c.sh-50)
+	printAt(screen, msg, 10, p.e.c.sh-35)
+	p.t.Draw(screen)
 	p.e.Draw(screen)
+	// beside the cursor, so it's read where the block is being placed
+	cx, cy := driver.CursorPosition()
+	printAt(screen, p.readout(), cx-p.e.c.left+12, cy+12)
+}
+
+// The cursor's position in world units, and the size of the block being dragged out if any
+func (p *PlatformEditor) readout() string {
+	wx, wy := p.e.c.Cursor()
+	s := fmt.Sprintf("%.2f, %.2f", wx, wy)
+	if p.creating != nil {
+		pl := place(p.creating.T)
+		s += fmt.Sprintf("\n%.2f x %.2f", pl.w, math.Abs(pl.h))
+	}
+	return s
 }
 
 // Editor for manipulating art in the level
@@ -485,6 +1398,11 @@ type ArtEditor struct {
 
 	// The editor we came from
 	e *Editor
+
+	// Available images to browse, loaded on first update
+	images []string
+	// Index of the highlighted image
+	cursor int
 }
 
 func (a *ArtEditor) Layout(outsideWidth, outsideHeight int) (screenWidth, screenHeight int) {
@@ -498,8 +1416,10 @@ func (a *ArtEditor) AddImage(e *Editor, path string) error {
 		return fmt.Errorf("load image: %w", err)
 	}
 	e.l.Art = append(e.l.Art, &Art{
-		Path: path,
-		img:  img,
+		Path:  path,
+		Layer: e.layer,
+		img:   img,
+		imgs:  []*ebiten.Image{img},
 	})
 	return nil
 }
@@ -509,15 +1429,99 @@ func (a *ArtEditor) String() string {
 }
 
 func (a *ArtEditor) Update(r *Root) error {
+	if a.images == nil {
+		paths, err := resources.Glob("resources/*.png")
+		if err != nil {
+			a.t.Placeholder = fmt.Sprintf("Failed to list images: %v", err)
+		}
+		a.images = append([]string{}, paths...)
+	}
 	// command processing
 	cmd, typ := a.t.Update()
 	if typ {
 		return nil
 	}
-	if cmd != "" {
+	// browsing
+	if len(a.images) > 0 {
+		if Clicked(ebiten.KeyDown) {
+			a.cursor = (a.cursor + 1) % len(a.images)
+		}
+		if Clicked(ebiten.KeyUp) {
+			a.cursor = (a.cursor + len(a.images) - 1) % len(a.images)
+		}
+		if Clicked(ebiten.KeySpace) {
+			cmd = a.images[a.cursor]
+			if driver.IsKeyPressed(ebiten.KeyShift) {
+				cmd = "variant " + cmd
+			}
+		}
+	}
+	switch {
+	case cmd == "reseed":
+		a.e.l.Seed = newSeed()
+		a.e.l.decorate()
+		a.t.Placeholder = "Reshuffled decoration"
+	case strings.HasPrefix(cmd, "slice "):
+		if len(a.e.l.Art) == 0 {
+			a.t.Placeholder = "Add art before slicing it"
+			break
+		}
+		s, err := parseNineSlice(strings.Fields(cmd)[1:])
+		if err != nil {
+			a.t.Placeholder = fmt.Sprintf("Failed to slice the last art: %v", err)
+			break
+		}
+		a.e.l.Art[len(a.e.l.Art)-1].Slice = &s
+		a.t.Placeholder = fmt.Sprintf("Nine sliced the last art %v, %v, %v and %v pixels in", s.Left, s.Top, s.Right,
+			s.Bottom)
+	case strings.HasPrefix(cmd, "normal "):
+		path := strings.TrimPrefix(cmd, "normal ")
+		if len(a.e.l.Art) == 0 {
+			a.t.Placeholder = "Add art before adding a normal map to it"
+			break
+		}
+		last := a.e.l.Art[len(a.e.l.Art)-1]
+		kopy := *last
+		kopy.Normal = path
+		err := kopy.Load()
+		if err != nil {
+			a.t.Placeholder = fmt.Sprintf("Failed to add normal map %v: %v", path, describe(err))
+			break
+		}
+		// keep the variant the level's decoration picked
+		kopy.img = last.img
+		*last = kopy
+		a.t.Placeholder = fmt.Sprintf("Added normal map %v to the last art", path)
+	case strings.HasPrefix(cmd, "shader "):
+		path := strings.TrimPrefix(cmd, "shader ")
+		if len(a.e.l.Art) == 0 {
+			a.t.Placeholder = "Add art before giving it a shader"
+			break
+		}
+		s := &CustomShader{Path: path}
+		err := s.Load()
+		if err != nil {
+			a.t.Placeholder = fmt.Sprintf("Failed to add shader %v: %v", path, describe(err))
+			break
+		}
+		a.e.l.Art[len(a.e.l.Art)-1].Shader = s
+		a.t.Placeholder = fmt.Sprintf("Drawing the last art with shader %v", path)
+	case strings.HasPrefix(cmd, "variant "):
+		path := strings.TrimPrefix(cmd, "variant ")
+		if len(a.e.l.Art) == 0 {
+			a.t.Placeholder = "Add art before adding variants of it"
+			break
+		}
+		err := a.e.l.addVariant(a.e.l.Art[len(a.e.l.Art)-1], path)
+		if err != nil {
+			a.t.Placeholder = fmt.Sprintf("Failed to add variant %v: %v", path, describe(err))
+		} else {
+			a.t.Placeholder = fmt.Sprintf("Added variant %v to the last art", path)
+		}
+	case cmd != "":
 		err := a.AddImage(a.e, cmd)
 		if err != nil {
-			a.t.Placeholder = fmt.Sprintf("Failed to load %v: %v", string(cmd), err)
+			a.t.Placeholder = fmt.Sprintf("Failed to load %v: %v", string(cmd), describe(err))
 		} else {
 			a.t.Placeholder = fmt.Sprintf("Successfully loaded %v", string(cmd))
 		}
@@ -528,6 +1532,32 @@ func (a *ArtEditor) Update(r *Root) error {
 func (a *ArtEditor) Draw(screen *ebiten.Image) {
 	a.e.Draw(screen)
 	a.t.Draw(screen)
+	var s strings.Builder
+	s.WriteString("(Up/Down) Browse (Space) Add (Shift+Space) Add as variant of the last art\n")
+	for i, p := range a.images {
+		if i == a.cursor {
+			s.WriteString("> ")
+		} else {
+			s.WriteString("  ")
+		}
+		s.WriteString(p)
+		s.WriteString("\n")
+	}
+	printAt(screen, s.String(), a.e.c.sw-250, 5)
+}
+
+// Describes an error for display in an editor. Missing or unsupported resources are described by themselves, with
+// any suggestions, rather than at the end of the chain of operations which led to them.
+func describe(err error) string {
+	var nf *resources.NotFoundError
+	if errors.As(err, &nf) {
+		return nf.Error()
+	}
+	var uf *resources.UnsupportedFormatError
+	if errors.As(err, &uf) {
+		return uf.Error()
+	}
+	return err.Error()
 }
 
 // A widget for creating interactive text inputs
@@ -561,7 +1591,7 @@ func (t *Typer) Update() (string, bool) {
 			if len(t.cmd) > 0 && Clicked(ebiten.KeyBackspace) {
 				t.cmd = t.cmd[:len(t.cmd) - 1]
 			}
-			t.cmd = append(t.cmd, ebiten.InputChars()...)
+			t.cmd = append(t.cmd, driver.InputChars()...)
 			return "", true
 		}
 	}
@@ -570,9 +1600,9 @@ func (t *Typer) Update() (string, bool) {
 
 func (t *Typer) Draw(screen *ebiten.Image) {
 	if !t.typ {
-		ebitenutil.DebugPrintAt(screen, t.Placeholder, 10, t.C.sh-20)
+		printAt(screen, t.Placeholder, 10, t.C.sh-20)
 	} else {
-		ebitenutil.DebugPrintAt(screen, string(t.cmd), 10, t.C.sh-20)
+		printAt(screen, string(t.cmd)+"_", 10, t.C.sh-20)
 	}
 }
 
@@ -587,6 +1617,7 @@ type SaveAndLoadEditor struct {
 
 // Activates a save editor
 func ActivateSave(r *Root, e *Editor) {
+	e.mode = "Save"
 	r.a = &SaveAndLoadEditor{
 		e:    e,
 		t:    &Typer{
@@ -600,6 +1631,7 @@ func ActivateSave(r *Root, e *Editor) {
 
 // Activates a load editor
 func ActivateLoad(r *Root, e *Editor) {
+	e.mode = "Load"
 	r.a = &SaveAndLoadEditor{
 		e:    e,
 		t:    &Typer{
@@ -622,15 +1654,43 @@ func (s *SaveAndLoadEditor) Update(r *Root) error {
 	}
 	if path != "" {
 		if s.load {
-			err := s.e.l.load(path)
+			var l Level
+			err := l.decode(path)
 			if err != nil {
-				return fmt.Errorf("failed to load %v: %w", path, err)
+				s.t.Placeholder = fmt.Sprintf("Failed to load %v: %v", path, describe(err))
+				return nil
 			}
+			ActivateLoading(r, fmt.Sprintf("Loading %v", path), l.loadAssets, func(r *Root, err error) error {
+				if err != nil {
+					// stay here to try another path
+					s.t.Placeholder = fmt.Sprintf("Failed to load %v: %v", path, describe(err))
+					r.a = s
+					return nil
+				}
+				s.e.l = l
+				s.e.session.recount(&l)
+				s.e.c.zoom = l.PixelsPerUnit
+				s.e.path = path
+				s.e.validation = ""
+				if l.modified {
+					s.e.validation = modifiedWarning
+				}
+				r.transition(s.e, transitionWipe)
+				return nil
+			})
+			return nil
+		}
+		if s.e.l.Author == "" {
+			s.e.l.Author = settings.Author
+		}
+		err := s.e.l.save(path)
+		if err != nil {
+			fmt.Println("Failed to save:", err)
 		} else {
-			err := s.e.l.save(path)
-			if err != nil {
-				fmt.Println("Failed to save:", err)
-			}
+			s.e.path = path
+			// the editor's changes are now the saved level
+			s.e.l.modified = false
+			s.e.validation = fmt.Sprintf("Saved %v\n%v", path, s.e.session.summary())
 		}
 		r.a = s.e
 		return r.Update()
@@ -0,0 +1,623 @@
+package main
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+	"image/color"
+	"math"
+	"path/filepath"
+	"testing"
+)
+
+// Scripted input for tests. Set the fields between frames.
+type fakeDriver struct {
+	keys   map[ebiten.Key]bool
+	mouse  map[ebiten.MouseButton]bool
+	cx, cy int
+	chars  []rune
+}
+
+func (f *fakeDriver) IsKeyPressed(k ebiten.Key) bool {
+	return f.keys[k]
+}
+
+func (f *fakeDriver) IsMouseButtonPressed(m ebiten.MouseButton) bool {
+	return f.mouse[m]
+}
+
+func (f *fakeDriver) CursorPosition() (int, int) {
+	return f.cx, f.cy
+}
+
+func (f *fakeDriver) Wheel() (float64, float64) {
+	return 0, 0
+}
+
+func (f *fakeDriver) InputChars() []rune {
+	chars := f.chars
+	f.chars = nil
+	return chars
+}
+
+// An editor on an empty level, with a 720x480 screen showing the area around the origin, and a fake driver feeding
+// it input. The real driver is restored when the test ends.
+func testEditor(t *testing.T) (*Root, *Editor, *fakeDriver) {
+	f := &fakeDriver{keys: make(map[ebiten.Key]bool), mouse: make(map[ebiten.MouseButton]bool)}
+	driver = f
+	t.Cleanup(func() {
+		driver = ebitenDriver{}
+	})
+	e := newEditor()
+	e.l = NewLevel()
+	// keep the spawn point from being selected instead of things near the origin
+	e.l.Spawn.X, e.l.Spawn.Y = 10, 10
+	e.c.Layout(720, 480)
+	return &Root{a: e}, e, f
+}
+
+// Runs a frame of the app
+func frame(t *testing.T, r *Root) {
+	if err := r.Update(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Moves the cursor over the given world position
+func (f *fakeDriver) moveTo(c *Camera, wx, wy float64) {
+	toScreen := c.ToScreen()
+	sx, sy := toScreen.Apply(wx, wy)
+	f.cx, f.cy = int(math.Round(sx))+c.left, int(math.Round(sy))
+}
+
+// Presses the given key for a frame
+func press(t *testing.T, r *Root, f *fakeDriver, k ebiten.Key) {
+	f.keys[k] = true
+	frame(t, r)
+	f.keys[k] = false
+	frame(t, r)
+}
+
+// Drags with the left mouse button between two world positions
+func dragMouse(t *testing.T, r *Root, f *fakeDriver, c *Camera, x0, y0, x1, y1 float64) {
+	f.moveTo(c, x0, y0)
+	f.mouse[ebiten.MouseButtonLeft] = true
+	frame(t, r)
+	f.moveTo(c, x1, y1)
+	frame(t, r)
+	f.mouse[ebiten.MouseButtonLeft] = false
+	frame(t, r)
+}
+
+// Checks that the unit square corner at lx, ly maps to wx, wy under the transform, to within a pixel.
+func assertCorner(t *testing.T, c *Camera, m Mx, lx, ly, wx, wy float64) {
+	t.Helper()
+	x, y := m.Apply(lx, ly)
+	pixel := 2 * c.hw / float64(c.sw)
+	if math.Abs(x-wx) > pixel || math.Abs(y-wy) > pixel {
+		t.Errorf("corner %v, %v is at %.3f, %.3f, want %.3f, %.3f", lx, ly, x, y, wx, wy)
+	}
+}
+
+func TestPlatformEditorDragsOutBlock(t *testing.T) {
+	r, e, f := testEditor(t)
+	press(t, r, f, ebiten.KeyL)
+	if _, ok := r.a.(*PlatformEditor); !ok {
+		t.Fatalf("L opened %v, want the platform editor", r.a)
+	}
+	// drag from the top right to the bottom left, the block should still cover the area
+	dragMouse(t, r, f, &e.c, 3, 2, 1, -1)
+	if len(e.l.Blocks) != 1 {
+		t.Fatalf("got %v blocks, want 1", len(e.l.Blocks))
+	}
+	b := e.l.Blocks[0].T
+	assertCorner(t, &e.c, b, -0.5, -0.5, 1, -1)
+	assertCorner(t, &e.c, b, 0.5, 0.5, 3, 2)
+}
+
+func TestSelectorScalesAroundOppositeCorner(t *testing.T) {
+	r, e, f := testEditor(t)
+	var m Mx
+	m.Scale(2, 2)
+	e.l.Blocks = []*Block{{T: m}}
+	press(t, r, f, ebiten.KeyS)
+
+	// select the block, then drag its top right handle out
+	f.moveTo(&e.c, 0.5, 0.5)
+	f.mouse[ebiten.MouseButtonLeft] = true
+	frame(t, r)
+	f.mouse[ebiten.MouseButtonLeft] = false
+	frame(t, r)
+	dragMouse(t, r, f, &e.c, 1, 1, 3, 2)
+
+	b := e.l.Blocks[0].T
+	// the bottom left corner stays put
+	assertCorner(t, &e.c, b, -0.5, -0.5, -1, -1)
+	assertCorner(t, &e.c, b, 0.5, 0.5, 3, 2)
+}
+
+func TestSelectorScalingModifiers(t *testing.T) {
+	r, e, f := testEditor(t)
+	var m Mx
+	m.Scale(2, 2)
+	e.l.Blocks = []*Block{{T: m}}
+	press(t, r, f, ebiten.KeyS)
+	f.moveTo(&e.c, 0.5, 0.5)
+	f.mouse[ebiten.MouseButtonLeft] = true
+	frame(t, r)
+	f.mouse[ebiten.MouseButtonLeft] = false
+	frame(t, r)
+
+	// keeping the shape, the block grows square to the further side
+	f.keys[ebiten.KeyShift] = true
+	dragMouse(t, r, f, &e.c, 1, 1, 3, 2)
+	f.keys[ebiten.KeyShift] = false
+	b := e.l.Blocks[0].T
+	assertCorner(t, &e.c, b, -0.5, -0.5, -1, -1)
+	assertCorner(t, &e.c, b, 0.5, 0.5, 3, 3)
+
+	// about the center, the opposite corner moves out too
+	f.keys[ebiten.KeyAlt] = true
+	dragMouse(t, r, f, &e.c, 3, 3, 4, 3)
+	f.keys[ebiten.KeyAlt] = false
+	b = e.l.Blocks[0].T
+	assertCorner(t, &e.c, b, -0.5, -0.5, -2, -1)
+	assertCorner(t, &e.c, b, 0.5, 0.5, 4, 3)
+}
+
+func TestSelectorMovesSelection(t *testing.T) {
+	r, e, f := testEditor(t)
+	e.l.Blocks = []*Block{{T: Mx{}}}
+	press(t, r, f, ebiten.KeyS)
+	dragMouse(t, r, f, &e.c, 0, 0, 2, 1)
+	assertCorner(t, &e.c, e.l.Blocks[0].T, 0, 0, 2, 1)
+}
+
+func TestSelectorDeletesSelection(t *testing.T) {
+	r, e, f := testEditor(t)
+	e.l.Blocks = []*Block{{T: Mx{}}}
+	press(t, r, f, ebiten.KeyS)
+	f.moveTo(&e.c, 0, 0)
+	f.mouse[ebiten.MouseButtonLeft] = true
+	frame(t, r)
+	f.mouse[ebiten.MouseButtonLeft] = false
+	press(t, r, f, ebiten.KeyBackspace)
+	if len(e.l.Blocks) != 0 {
+		t.Errorf("got %v blocks after deleting, want 0", len(e.l.Blocks))
+	}
+}
+
+func TestSelectorMarqueeSelectsGroup(t *testing.T) {
+	r, e, f := testEditor(t)
+	var left, right, far Mx
+	left.Translate(-2, 0)
+	right.Translate(2, 1)
+	far.Translate(-2, -6)
+	e.l.Blocks = []*Block{{T: left}, {T: right}, {T: far}}
+	press(t, r, f, ebiten.KeyS)
+	// the marquee only needs to touch the blocks
+	dragMouse(t, r, f, &e.c, -3, 3, 2, 0.3)
+	g, ok := r.a.(*SelectEditor).s.s.(*GroupSelector)
+	if !ok || len(g.members) != 2 {
+		t.Fatalf("selected %+v, want the two blocks in the marquee", r.a.(*SelectEditor).s.s)
+	}
+
+	// dragging the group moves both blocks
+	dragMouse(t, r, f, &e.c, 0, 0.5, 1, -0.5)
+	assertCorner(t, &e.c, e.l.Blocks[0].T, 0, 0, -1, -1)
+	assertCorner(t, &e.c, e.l.Blocks[1].T, 0, 0, 3, 0)
+	assertCorner(t, &e.c, e.l.Blocks[2].T, 0, 0, -2, -6)
+
+	press(t, r, f, ebiten.KeyBackspace)
+	if len(e.l.Blocks) != 1 || e.l.Blocks[0].T != far {
+		t.Errorf("got blocks %+v after deleting the group, want only the far block", e.l.Blocks)
+	}
+}
+
+func TestSelectEditorPastesIntoAnotherLevel(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "clipboard.json")
+	r, e, f := testEditor(t)
+	var m Mx
+	m.Translate(1, 2)
+	e.l.Blocks = []*Block{{T: m, Name: "door", Material: materialIce}}
+	press(t, r, f, ebiten.KeyS)
+	r.a.(*SelectEditor).clip = path
+	f.moveTo(&e.c, 1, 2)
+	f.mouse[ebiten.MouseButtonLeft] = true
+	frame(t, r)
+	f.mouse[ebiten.MouseButtonLeft] = false
+	f.keys[ebiten.KeyControl] = true
+	press(t, r, f, ebiten.KeyC)
+	f.keys[ebiten.KeyControl] = false
+
+	// a fresh select editor on another level
+	e.l = NewLevel()
+	press(t, r, f, ebiten.KeyS)
+	r.a.(*SelectEditor).clip = path
+	f.keys[ebiten.KeyControl] = true
+	press(t, r, f, ebiten.KeyV)
+	f.keys[ebiten.KeyControl] = false
+	if len(e.l.Blocks) != 1 || e.l.Blocks[0].Name != "door" || e.l.Blocks[0].Material != materialIce ||
+		e.l.Blocks[0].T != m {
+		t.Fatalf("pasted blocks %+v, want the door", e.l.Blocks)
+	}
+	if b, ok := r.a.(*SelectEditor).s.s.(*BlockSelector); !ok || b.b != e.l.Blocks[0] {
+		t.Errorf("selected %+v after pasting, want the door", r.a.(*SelectEditor).s.s)
+	}
+}
+
+func TestSelectorDuplicatesSelection(t *testing.T) {
+	r, e, f := testEditor(t)
+	e.l.Blocks = []*Block{{T: Mx{}, Name: "step"}}
+	press(t, r, f, ebiten.KeyS)
+	f.moveTo(&e.c, 0, 0)
+	f.mouse[ebiten.MouseButtonLeft] = true
+	frame(t, r)
+	f.mouse[ebiten.MouseButtonLeft] = false
+	f.keys[ebiten.KeyControl] = true
+	press(t, r, f, ebiten.KeyD)
+	press(t, r, f, ebiten.KeyD)
+	f.keys[ebiten.KeyControl] = false
+	s, ok := r.a.(*SelectEditor)
+	if !ok {
+		t.Fatalf("duplicating opened %v, want the select editor to stay open", r.a)
+	}
+	if len(e.l.Blocks) != 3 {
+		t.Fatalf("got %v blocks after duplicating twice, want 3", len(e.l.Blocks))
+	}
+	// each copy is offset from the last, and selected
+	assertCorner(t, &e.c, e.l.Blocks[1].T, 0, 0, duplicateOffset, -duplicateOffset)
+	assertCorner(t, &e.c, e.l.Blocks[2].T, 0, 0, 2*duplicateOffset, -2*duplicateOffset)
+	if b, ok := s.s.s.(*BlockSelector); !ok || b.b != e.l.Blocks[2] || b.b.Name != "step" {
+		t.Errorf("selected %+v, want the last copy", s.s.s)
+	}
+}
+
+func TestDuplicatePinsArtToCopiedBlock(t *testing.T) {
+	r, e, f := testEditor(t)
+	var small Mx
+	small.Scale(0.5, 0.5)
+	parent := 0
+	e.l.Blocks = []*Block{{T: Mx{}}}
+	e.l.Art = []*Art{{T: small, Parent: &parent}}
+	press(t, r, f, ebiten.KeyS)
+	dragMouse(t, r, f, &e.c, -3, 3, 3, -3)
+	f.keys[ebiten.KeyControl] = true
+	press(t, r, f, ebiten.KeyD)
+	f.keys[ebiten.KeyControl] = false
+	if len(e.l.Blocks) != 2 || len(e.l.Art) != 2 {
+		t.Fatalf("got %v blocks and %v art, want both copied", len(e.l.Blocks), len(e.l.Art))
+	}
+	if p := e.l.Art[1].Parent; p == nil || *p != 1 {
+		t.Fatalf("copied art is pinned to %v, want the copied block", p)
+	}
+	assertCorner(t, &e.c, e.l.Blocks[1].T, 0, 0, duplicateOffset, -duplicateOffset)
+	assertCorner(t, &e.c, e.l.artTransform(e.l.Art[1]), 0.5, 0.5, duplicateOffset+0.25, 0.25-duplicateOffset)
+	assertCorner(t, &e.c, e.l.artTransform(e.l.Art[0]), 0.5, 0.5, 0.25, 0.25)
+}
+
+func TestSelectEditorMakesAndUpdatesPrefabs(t *testing.T) {
+	r, e, f := testEditor(t)
+	var left, right Mx
+	left.Translate(-1, 0)
+	right.Translate(1, 0)
+	e.l.Blocks = []*Block{{T: left}, {T: right}}
+	press(t, r, f, ebiten.KeyS)
+	r.a.(*SelectEditor).prefabs = t.TempDir()
+	dragMouse(t, r, f, &e.c, -3, 3, 3, -3)
+	typeCommand(t, r, f, "prefab bridge")
+	if len(e.l.Blocks) != 0 || len(e.l.Prefabs) != 1 {
+		t.Fatalf("level has %v blocks and %v prefabs, want the blocks replaced by a prefab", len(e.l.Blocks),
+			len(e.l.Prefabs))
+	}
+	blocks, _ := e.l.expanded()
+	assertCorner(t, &e.c, blocks[0].T, 0, 0, -1, 0)
+	assertCorner(t, &e.c, blocks[1].T, 0, 0, 1, 0)
+
+	e.c.x, e.c.y = 0, 10
+	typeCommand(t, r, f, "place bridge")
+	typeCommand(t, r, f, "unpack")
+	if len(e.l.Blocks) != 2 || len(e.l.Prefabs) != 1 {
+		t.Fatalf("level has %v blocks and %v prefabs after unpacking, want 2 blocks and the first prefab",
+			len(e.l.Blocks), len(e.l.Prefabs))
+	}
+	assertCorner(t, &e.c, e.l.Blocks[0].T, 0, 0, -1, 10)
+
+	// saving the edited copy over the prefab updates the first instance too
+	e.l.Blocks[0].Material = materialIce
+	typeCommand(t, r, f, "prefab bridge")
+	blocks, _ = e.l.expanded()
+	if len(blocks) != 4 || blocks[0].Material != materialIce || blocks[2].Material != materialIce {
+		t.Errorf("got %v blocks, the first of each instance made of %q and %q, want both ice", len(blocks),
+			blocks[0].Material, blocks[2].Material)
+	}
+}
+
+func TestLayersHideAndLock(t *testing.T) {
+	r, e, f := testEditor(t)
+	e.l.Blocks = []*Block{{T: Mx{}}}
+	press(t, r, f, ebiten.KeyDigit9)
+	typeCommand(t, r, f, "layer decor")
+	press(t, r, f, ebiten.KeyL)
+	dragMouse(t, r, f, &e.c, 3, 2, 1, -1)
+	if len(e.l.Blocks) != 2 || e.l.Blocks[1].Layer != "decor" {
+		t.Fatalf("got blocks %+v, want a new block in the decor layer", e.l.Blocks)
+	}
+
+	// locked blocks can't be selected, so dragging one does nothing
+	press(t, r, f, ebiten.KeyDigit9)
+	typeCommand(t, r, f, "lock decor")
+	press(t, r, f, ebiten.KeyS)
+	before := e.l.Blocks[1].T
+	dragMouse(t, r, f, &e.c, 2, 0.5, 4, 0.5)
+	if e.l.Blocks[1].T != before {
+		t.Errorf("moved a block in a locked layer")
+	}
+
+	// the other block can be moved into the layer from the select editor
+	dragMouse(t, r, f, &e.c, 0, 0, 0, 0)
+	typeCommand(t, r, f, "layer decor")
+	if e.l.Blocks[0].Layer != "decor" {
+		t.Errorf("block is in layer %q after moving it, want decor", e.l.Blocks[0].Layer)
+	}
+	press(t, r, f, ebiten.KeyDigit9)
+	typeCommand(t, r, f, "hide decor")
+	typeCommand(t, r, f, "unlock decor")
+	if !e.hidden("decor") || !e.locked("decor") || e.hidden("") {
+		t.Errorf("decor is hidden %v and locked %v, want it hidden and so still locked", e.hidden("decor"),
+			e.locked("decor"))
+	}
+}
+
+func TestSessionCountsPlacedObjects(t *testing.T) {
+	r, e, f := testEditor(t)
+	press(t, r, f, ebiten.KeyL)
+	dragMouse(t, r, f, &e.c, 3, 2, 1, -1)
+	dragMouse(t, r, f, &e.c, -3, 2, -1, -1)
+	if e.session.placed != 2 || e.session.deleted != 0 {
+		t.Errorf("session placed %v and deleted %v, want 2 placed", e.session.placed, e.session.deleted)
+	}
+	if e.session.spent["Blocks"] == 0 {
+		t.Errorf("no time spent in the platform editor, spent %v", e.session.spent)
+	}
+	// resetting the level isn't deleting
+	press(t, r, f, ebiten.KeyR)
+	if e.session.deleted != 0 || len(e.l.Blocks) != 0 {
+		t.Errorf("session deleted %v after resetting the level to %v blocks, want 0", e.session.deleted,
+			len(e.l.Blocks))
+	}
+}
+
+func TestSelectorReordersArt(t *testing.T) {
+	r, e, f := testEditor(t)
+	var behind Mx
+	behind.Scale(4, 4)
+	front := &Art{T: Mx{}, Path: "resources/front.png"}
+	e.l.Art = []*Art{front, {T: behind, Path: "resources/behind.png"}}
+	press(t, r, f, ebiten.KeyS)
+	f.moveTo(&e.c, 0, 0)
+	f.mouse[ebiten.MouseButtonLeft] = true
+	frame(t, r)
+	f.mouse[ebiten.MouseButtonLeft] = false
+	press(t, r, f, ebiten.KeyPageUp)
+	if front.Z != 1 {
+		t.Fatalf("art in layer %v after bringing it forward, want 1", front.Z)
+	}
+	if drawn := layered(e.l.Art); drawn[1] != front {
+		t.Errorf("art brought forward is drawn at %v, want on top", drawn[1].Path)
+	}
+	press(t, r, f, ebiten.KeyPageDown)
+	press(t, r, f, ebiten.KeyPageDown)
+	if drawn := layered(e.l.Art); drawn[0] != front {
+		t.Errorf("art sent back is drawn over %v, want underneath", drawn[0].Path)
+	}
+}
+
+func TestSelectorFlipsArt(t *testing.T) {
+	r, e, f := testEditor(t)
+	var m Mx
+	m.Scale(2, 1)
+	m.Translate(1, 0)
+	e.l.Art = []*Art{{T: m, Path: "resources/grass.png"}}
+	press(t, r, f, ebiten.KeyS)
+	f.moveTo(&e.c, 1, 0)
+	f.mouse[ebiten.MouseButtonLeft] = true
+	frame(t, r)
+	f.mouse[ebiten.MouseButtonLeft] = false
+	press(t, r, f, ebiten.KeyLeft)
+	// the art covers the same area, mirrored
+	assertCorner(t, &e.c, e.l.Art[0].T, -0.5, -0.5, 2, -0.5)
+	assertCorner(t, &e.c, e.l.Art[0].T, 0.5, 0.5, 0, 0.5)
+	press(t, r, f, ebiten.KeyUp)
+	assertCorner(t, &e.c, e.l.Art[0].T, -0.5, -0.5, 2, 0.5)
+	press(t, r, f, ebiten.KeyRight)
+	press(t, r, f, ebiten.KeyDown)
+	assertCorner(t, &e.c, e.l.Art[0].T, -0.5, -0.5, 0, -0.5)
+}
+
+func TestOutlineSelectsCoveredObject(t *testing.T) {
+	r, e, f := testEditor(t)
+	var big, small Mx
+	big.Scale(4, 4)
+	small.Translate(5, 3)
+	e.l.Blocks = []*Block{{T: big}, {T: small, Name: "door"}}
+	e.l.Triggers[touchEvent("door")] = Trigger{Spawn: "far"}
+	press(t, r, f, ebiten.KeyS)
+	press(t, r, f, ebiten.KeyTab)
+	s := r.a.(*SelectEditor)
+	entries := outlineEntries(&e.l, s.s.Selectables)
+	if last := entries[len(entries)-1]; last.label != "Trigger 'touched door'" || last.s != entries[2].s {
+		t.Fatalf("last entry is %+v, want the trigger linked to the door", last)
+	}
+
+	// click the door's row
+	f.cx, f.cy = e.c.sw-10, 10+2*lineHeight(TextStyle{})+1
+	f.mouse[ebiten.MouseButtonLeft] = true
+	frame(t, r)
+	f.mouse[ebiten.MouseButtonLeft] = false
+	frame(t, r)
+	if b, ok := s.s.s.(*BlockSelector); !ok || b.b != e.l.Blocks[1] {
+		t.Fatalf("selected %+v, want the door", s.s.s)
+	}
+	if e.c.x != 5 || e.c.y != 3 {
+		t.Errorf("camera is centered on %v, %v, want the door at 5, 3", e.c.x, e.c.y)
+	}
+}
+
+func TestOutlineLeavesViewClicksInSplitView(t *testing.T) {
+	r, e, f := testEditor(t)
+	e.c.left = 300
+	e.l.Blocks = []*Block{{T: Mx{}}}
+	press(t, r, f, ebiten.KeyS)
+	press(t, r, f, ebiten.KeyTab)
+	s := r.a.(*SelectEditor)
+	f.moveTo(&e.c, 0, 0)
+	f.mouse[ebiten.MouseButtonLeft] = true
+	frame(t, r)
+	f.mouse[ebiten.MouseButtonLeft] = false
+	frame(t, r)
+	if b, ok := s.s.s.(*BlockSelector); !ok || b.b != e.l.Blocks[0] {
+		t.Fatalf("selected %+v, want the block clicked in the middle of the view", s.s.s)
+	}
+}
+
+func TestSelectEditorSetsProperties(t *testing.T) {
+	r, e, f := testEditor(t)
+	e.l.Blocks = []*Block{{T: Mx{}}}
+	press(t, r, f, ebiten.KeyS)
+	f.moveTo(&e.c, 0, 0)
+	f.mouse[ebiten.MouseButtonLeft] = true
+	frame(t, r)
+	f.mouse[ebiten.MouseButtonLeft] = false
+	frame(t, r)
+	for _, cmd := range []string{"x 3", "y -1", "w 4", "h 2", "rotation 90", "material ice"} {
+		typeCommand(t, r, f, cmd)
+	}
+	b := e.l.Blocks[0]
+	// turned a quarter, so the 4 wide block stands upright about 3, -1
+	assertCorner(t, &e.c, b.T, -0.5, -0.5, 4, -3)
+	assertCorner(t, &e.c, b.T, 0.5, 0.5, 2, 1)
+	if b.Material != materialIce {
+		t.Errorf("block is made of %q, want ice", b.Material)
+	}
+}
+
+// Types a command into the active editor's typer
+func typeCommand(t *testing.T, r *Root, f *fakeDriver, cmd string) {
+	press(t, r, f, ebiten.KeyEnter)
+	f.chars = []rune(cmd)
+	frame(t, r)
+	press(t, r, f, ebiten.KeyEnter)
+}
+
+func TestLabelEditorPlacesLabels(t *testing.T) {
+	r, e, f := testEditor(t)
+	press(t, r, f, ebiten.KeyY)
+	typeCommand(t, r, f, "size 2")
+	typeCommand(t, r, f, "Jump!")
+	f.moveTo(&e.c, 1, 1)
+	f.mouse[ebiten.MouseButtonLeft] = true
+	frame(t, r)
+	f.mouse[ebiten.MouseButtonLeft] = false
+	frame(t, r)
+	if len(e.l.Labels) != 1 {
+		t.Fatalf("got %v labels, want 1", len(e.l.Labels))
+	}
+	lb := e.l.Labels[0]
+	if lb.Text != "Jump!" || lb.Size != 2 {
+		t.Errorf("placed label %q of size %v, want \"Jump!\" of size 2", lb.Text, lb.Size)
+	}
+	// 2 units tall, keeping the text's proportions
+	w, h := measureText("Jump!", labelStyle)
+	width := 2 * float64(w) / float64(h)
+	box := lb.box()
+	assertCorner(t, &e.c, box, -0.5, -0.5, 1-width/2, 0)
+	assertCorner(t, &e.c, box, 0.5, 0.5, 1+width/2, 2)
+
+	// scaling the label changes its size
+	s := &LabelSelector{l: &e.l, lb: lb}
+	box.Scale(2, 2)
+	s.SetTransform(box)
+	if math.Abs(lb.Size-4) > 1e-9 {
+		t.Errorf("label size %v after doubling it, want 4", lb.Size)
+	}
+}
+
+func TestLightEditorPlacesLights(t *testing.T) {
+	r, e, f := testEditor(t)
+	press(t, r, f, ebiten.KeyDigit0)
+	typeCommand(t, r, f, "radius 5")
+	typeCommand(t, r, f, "color 255 128 0")
+	f.moveTo(&e.c, 3, 2)
+	f.mouse[ebiten.MouseButtonLeft] = true
+	frame(t, r)
+	f.mouse[ebiten.MouseButtonLeft] = false
+	frame(t, r)
+	if len(e.l.Lights) != 1 {
+		t.Fatalf("got %v lights, want 1", len(e.l.Lights))
+	}
+	lt := e.l.Lights[0]
+	want := color.RGBA{R: 255, G: 128, A: 0xff}
+	if math.Abs(lt.X-3) > 1e-9 || math.Abs(lt.Y-2) > 1e-9 || lt.Radius != 5 || lt.Color == nil || *lt.Color != want {
+		t.Errorf("placed light %+v, want one at (3, 2) of radius 5 colored %v", lt, want)
+	}
+}
+
+func TestLightEditorSetsAtmosphere(t *testing.T) {
+	r, e, f := testEditor(t)
+	press(t, r, f, ebiten.KeyDigit0)
+	typeCommand(t, r, f, "shadows")
+	typeCommand(t, r, f, "bloom 0.5")
+	if !e.l.Shadows || e.l.Bloom != 0.5 {
+		t.Errorf("level has shadows %v and bloom %v, want shadows and 0.5", e.l.Shadows, e.l.Bloom)
+	}
+	typeCommand(t, r, f, "bloom -1")
+	if e.l.Bloom != 0.5 {
+		t.Errorf("level has bloom %v after setting a negative bloom, want it unchanged", e.l.Bloom)
+	}
+	typeCommand(t, r, f, "grade 90 -1 0.5")
+	typeCommand(t, r, f, "tint 0 0 255")
+	want := Grade{Tint: &color.RGBA{B: 255, A: 0xff}, Hue: math.Pi / 2, Saturation: -1, Brightness: 0.5}
+	if gr := e.l.Grade; gr == nil || gr.Tint == nil || *gr.Tint != *want.Tint || math.Abs(gr.Hue-want.Hue) > 1e-9 ||
+		gr.Saturation != want.Saturation || gr.Brightness != want.Brightness {
+		t.Errorf("level has grade %+v, want %+v", gr, want)
+	}
+	typeCommand(t, r, f, "grade off")
+	if e.l.Grade != nil {
+		t.Errorf("level has grade %+v after turning it off, want none", e.l.Grade)
+	}
+	typeCommand(t, r, f, "day 90 0.25")
+	if d := e.l.DayNight; d == nil || *d != (DayNight{Length: 90, Start: 0.25}) {
+		t.Errorf("level has day night cycle %+v, want 90s from dawn", d)
+	}
+}
+
+func TestPlatformEditorReadout(t *testing.T) {
+	r, e, f := testEditor(t)
+	press(t, r, f, ebiten.KeyL)
+	p := r.a.(*PlatformEditor)
+	f.moveTo(&e.c, 1, 2)
+	if got, want := p.readout(), "1.00, 2.00"; got != want {
+		t.Errorf("got readout %q, want %q", got, want)
+	}
+	f.mouse[ebiten.MouseButtonLeft] = true
+	frame(t, r)
+	f.moveTo(&e.c, 3, -1)
+	frame(t, r)
+	if got, want := p.readout(), "3.00, -1.00\n2.00 x 3.00"; got != want {
+		t.Errorf("got readout %q while dragging, want %q", got, want)
+	}
+	f.mouse[ebiten.MouseButtonLeft] = false
+	frame(t, r)
+}
+
+func TestContraptionEditorIgnoresClicks(t *testing.T) {
+	r, e, f := testEditor(t)
+	press(t, r, f, ebiten.KeyN)
+	f.moveTo(&e.c, 2, 2)
+	f.mouse[ebiten.MouseButtonLeft] = true
+	frame(t, r)
+	f.mouse[ebiten.MouseButtonLeft] = false
+	frame(t, r)
+	if len(e.l.Contraptions) != 0 {
+		t.Errorf("a click without a drag made %v contraptions, want none", len(e.l.Contraptions))
+	}
+}
@@ -0,0 +1,553 @@
+package main
+
+import (
+	"fmt"
+	"github.com/ByteArena/box2d"
+	"github.com/hajimehoshi/ebiten/v2"
+	"image/color"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var (
+	enemyColor      = color.RGBA{R: 0xe0, G: 0x20, B: 0x40, A: 0xff}
+	bossHealthColor = color.RGBA{R: 0xff, G: 0x60, B: 0x60, A: 0xff}
+)
+
+// Defaults for enemies which don't set their own
+const (
+	enemyHealth   = 3
+	enemyInterval = 1.5
+	enemyRange    = 15
+)
+
+const (
+	// Speed in world units per second of enemies' bullets, which fly straight
+	enemyBulletSpeed = 10
+	// Seconds before an enemy's bullet which hasn't hit anything disappears
+	enemyBulletLife = 5
+)
+
+// Collision categories, so bullets pass through whoever fired them and their allies. Bodies without a category, like
+// blocks, are in categoryWorld, Box2D's default.
+const (
+	categoryWorld uint16 = 1 << iota
+	categoryPlayer
+	categoryEnemy
+	categoryPlayerBullet
+	categoryEnemyBullet
+)
+
+// The collision filter for fixtures of the given category: the player's bullets don't hit the player, and enemies'
+// bullets don't hit enemies or each other.
+func collisionFilter(category uint16) box2d.B2Filter {
+	f := box2d.MakeB2Filter()
+	f.CategoryBits = category
+	switch category {
+	case categoryPlayer:
+		f.MaskBits &^= categoryPlayerBullet
+	case categoryEnemy:
+		f.MaskBits &^= categoryEnemyBullet
+	case categoryPlayerBullet:
+		f.MaskBits &^= categoryPlayer
+	case categoryEnemyBullet:
+		f.MaskBits &^= categoryEnemy | categoryEnemyBullet
+	}
+	return f
+}
+
+// A turret which shoots at the player when they're in range, and is destroyed by the player's bullets. Touching it or
+// its bullets hurts the player, see Game.hurt. Bosses are enemies with phases, which change how they attack as they
+// take damage.
+type Enemy struct {
+	// Transform of a unit square centered at the origin to the enemy's body
+	T Mx
+	// Hits from the player's bullets it takes to destroy, 0 for enemyHealth
+	Health int `json:",omitempty"`
+	// Seconds between shots, 0 for enemyInterval
+	Interval float64 `json:",omitempty"`
+	// Furthest away in world units the enemy shoots at the player from, 0 for enemyRange
+	Range float64 `json:",omitempty"`
+	// Name of the trigger to fire when destroyed, if any
+	Trigger string `json:",omitempty"`
+	// Stages of the fight if the enemy is a boss, each with its own attack. Replaces Health and Interval.
+	Phases []Phase `json:",omitempty"`
+}
+
+// How an enemy shoots
+const (
+	// A single bullet at the player
+	patternAimed = "aimed"
+	// A fan of bullets centered on the player
+	patternSpread = "spread"
+	// Bullets in every direction
+	patternRing = "ring"
+)
+
+var patterns = map[string]bool{"": true, patternAimed: true, patternSpread: true, patternRing: true}
+
+const (
+	// Bullets in a spread or ring, for phases which don't set their own
+	patternCount = 5
+	// Radians between the outermost bullets of a spread
+	spreadAngle = math.Pi / 3
+)
+
+// A stage of a boss fight, lasting until the boss has taken the phase's hits
+type Phase struct {
+	// Hits from the player's bullets it takes to end the phase
+	Health int
+	// One of the patterns, "" for aimed
+	Pattern string `json:",omitempty"`
+	// Seconds between shots, 0 for the enemy's interval
+	Interval float64 `json:",omitempty"`
+	// Bullets per shot in a spread or ring, 0 for patternCount
+	Count int `json:",omitempty"`
+	// Name of the trigger to fire when the boss moves on to this phase, if any. Not fired for the first phase.
+	Trigger string `json:",omitempty"`
+}
+
+// The stages the enemy goes through, a single one from its own settings unless it's a boss
+func (en *Enemy) phases() []Phase {
+	if len(en.Phases) > 0 {
+		return en.Phases
+	}
+	return []Phase{{Health: en.health()}}
+}
+
+// Total hits the enemy takes to destroy
+func (en *Enemy) totalHealth() int {
+	total := 0
+	for _, p := range en.phases() {
+		total += p.Health
+	}
+	return total
+}
+
+// The enemy's settings, with defaults filled in
+func (en *Enemy) health() int {
+	if en.Health == 0 {
+		return enemyHealth
+	}
+	return en.Health
+}
+
+func (en *Enemy) interval() float64 {
+	if en.Interval == 0 {
+		return enemyInterval
+	}
+	return en.Interval
+}
+
+func (en *Enemy) reach() float64 {
+	if en.Range == 0 {
+		return enemyRange
+	}
+	return en.Range
+}
+
+func (en *Enemy) draw(screen *ebiten.Image, toScreen Mx) {
+	drawoutline(screen, en.T, 3, toScreen, enemyColor)
+}
+
+// An enemy in a running game
+type foe struct {
+	en *Enemy
+	e  *Entity
+	// Index of the phase the enemy is in, and the hits left before it ends
+	phase  int
+	health int
+	// Fires at the player, until the enemy is destroyed
+	shooting *Task
+}
+
+// Adds the enemy to the game as a static body which starts shooting
+func (en *Enemy) build(g *Game) {
+	center, angle, hw, hh := decompose(en.T)
+	body := box2d.NewB2BodyDef()
+	body.Position = center
+	body.Angle = angle
+	// not in the game's entities, enemies are drawn separately
+	e := &Entity{
+		w: hw * 2,
+		h: hh * 2,
+		b: g.world.CreateBody(body),
+	}
+	e.b.SetUserData(e)
+	shape := box2d.MakeB2PolygonShape()
+	shape.SetAsBox(hw, hh)
+	def := box2d.MakeB2FixtureDef()
+	def.Shape = &shape
+	def.Filter = collisionFilter(categoryEnemy)
+	e.b.CreateFixtureFromDef(&def)
+
+	f := &foe{en: en, e: e}
+	e.foe = f
+	g.startPhase(f, 0)
+	g.foes = append(g.foes, f)
+}
+
+// Moves the enemy on to the phase with the given index, shooting in its pattern
+func (g *Game) startPhase(f *foe, phase int) {
+	p := f.en.phases()[phase]
+	f.phase = phase
+	// at least a hit, so a phase can't leave the enemy standing with no health
+	f.health = p.Health
+	if f.health < 1 {
+		f.health = 1
+	}
+	interval := p.Interval
+	if interval == 0 {
+		interval = f.en.interval()
+	}
+	if f.shooting != nil {
+		f.shooting.Cancel()
+	}
+	f.shooting = g.schedule.Every(secondsToTicks(interval), func() {
+		g.fireAt(f)
+	})
+}
+
+// Rotates the vector by the given angle in radians
+func rotate(v box2d.B2Vec2, angle float64) box2d.B2Vec2 {
+	sin, cos := math.Sincos(angle)
+	return box2d.B2Vec2{X: v.X*cos - v.Y*sin, Y: v.X*sin + v.Y*cos}
+}
+
+// Adds a bullet to the world at the given position, which doesn't hit anything in the given category's allies
+func (g *Game) spawnBullet(pos box2d.B2Vec2, category uint16) *Entity {
+	body := box2d.NewB2BodyDef()
+	body.Position = pos
+	body.Type = box2d.B2BodyType.B2_dynamicBody
+	e := &Entity{
+		w:       0.25,
+		h:       0.25,
+		b:       g.world.CreateBody(body),
+		bullet:  category == categoryPlayerBullet,
+		hostile: category == categoryEnemyBullet,
+	}
+	e.b.SetUserData(e)
+	g.entities = append(g.entities, e)
+	shape := box2d.MakeB2PolygonShape()
+	shape.SetAsBox(0.125, 0.125)
+	def := box2d.MakeB2FixtureDef()
+	def.Shape = &shape
+	def.Density = 1
+	def.Friction = 0.3
+	def.Restitution = 0.7
+	def.Filter = collisionFilter(category)
+	e.b.CreateFixtureFromDef(&def)
+	return e
+}
+
+// Shoots at the player in the pattern of the enemy's phase, if they're in range
+func (g *Game) fireAt(f *foe) {
+	from := f.e.b.GetPosition()
+	aim := box2d.B2Vec2Sub(g.p.b.GetPosition(), from)
+	if aim.Length() > f.en.reach() || aim.Length() == 0 {
+		return
+	}
+	aim.Normalize()
+	p := f.en.phases()[f.phase]
+	n := p.Count
+	if n == 0 {
+		n = patternCount
+	}
+	switch p.Pattern {
+	case patternSpread:
+		for i := 0; i < n; i++ {
+			offset := 0.0
+			if n > 1 {
+				offset = spreadAngle * (float64(i)/float64(n-1) - 0.5)
+			}
+			g.fireBullet(from, rotate(aim, offset))
+		}
+	case patternRing:
+		for i := 0; i < n; i++ {
+			g.fireBullet(from, rotate(aim, 2*math.Pi*float64(i)/float64(n)))
+		}
+	default:
+		g.fireBullet(from, aim)
+	}
+}
+
+// Shoots an enemy bullet from the given point, flying straight in the given direction
+func (g *Game) fireBullet(from, direction box2d.B2Vec2) {
+	e := g.spawnBullet(from, categoryEnemyBullet)
+	e.b.SetGravityScale(0)
+	e.b.SetLinearVelocity(box2d.B2Vec2MulScalar(enemyBulletSpeed, direction))
+	g.schedule.After(secondsToTicks(enemyBulletLife), func() {
+		g.removeEntity(e)
+	})
+}
+
+// Takes the entity out of the game, if it's still in it
+func (g *Game) removeEntity(e *Entity) {
+	for i, o := range g.entities {
+		if o == e {
+			g.entities = append(g.entities[:i], g.entities[i+1:]...)
+			delete(g.prev, e.b)
+			g.world.DestroyBody(e.b)
+			return
+		}
+	}
+}
+
+// Removes enemies' bullets which hit something, after they've had the chance to hurt the player
+func (g *Game) clearHostileBullets() {
+	var hit []*Entity
+	for _, e := range g.entities {
+		if !e.hostile {
+			continue
+		}
+		for next := e.b.GetContactList(); next != nil; next = next.Next {
+			if next.Contact.IsTouching() {
+				hit = append(hit, e)
+				break
+			}
+		}
+	}
+	for _, e := range hit {
+		g.removeEntity(e)
+	}
+}
+
+// Queues a hit on an enemy if the contact is between it and one of the player's bullets
+func (g *Game) shot(contact box2d.B2ContactInterface) {
+	a, _ := contact.GetFixtureA().GetBody().GetUserData().(*Entity)
+	b, _ := contact.GetFixtureB().GetBody().GetUserData().(*Entity)
+	if a == nil || b == nil {
+		return
+	}
+	if b.foe != nil {
+		a, b = b, a
+	}
+	if a.foe != nil && b.bullet {
+		g.hits = append(g.hits, a.foe)
+	}
+}
+
+// Damages the enemies shot during the last physics step, moving bosses on to their next phase and destroying any out
+// of health
+func (g *Game) hitFoes() {
+	for _, f := range g.hits {
+		if f.health <= 0 {
+			// already destroyed
+			continue
+		}
+		f.health--
+		if f.health > 0 {
+			continue
+		}
+		if phases := f.en.phases(); f.phase+1 < len(phases) {
+			g.startPhase(f, f.phase+1)
+			if t := phases[f.phase].Trigger; t != "" {
+				pos := f.e.b.GetPosition()
+				g.fire(t, pos.X, pos.Y)
+			}
+			continue
+		}
+		f.shooting.Cancel()
+		f.e.b.SetActive(false)
+		if f.en.Trigger != "" {
+			pos := f.e.b.GetPosition()
+			g.fire(f.en.Trigger, pos.X, pos.Y)
+		}
+	}
+	g.hits = g.hits[:0]
+}
+
+// Draws the enemies still standing, with a health bar over bosses
+func (g *Game) drawFoes(screen *ebiten.Image, toScreen Mx) {
+	for _, f := range g.foes {
+		if f.health <= 0 {
+			continue
+		}
+		f.en.draw(screen, toScreen)
+		if len(f.en.Phases) == 0 {
+			continue
+		}
+		left := f.health
+		for _, p := range f.en.Phases[f.phase+1:] {
+			left += p.Health
+		}
+		x0, y0 := f.en.T.Apply(-0.5, 0.5)
+		x1, y1 := f.en.T.Apply(0.5, 0.5)
+		// just above the boss
+		above := g.c.pixels(10)
+		fraction := float64(left) / float64(f.en.totalHealth())
+		drawline(screen, x0, y0+above, x0+(x1-x0)*fraction, y0+above+(y1-y0)*fraction, 6, toScreen, bossHealthColor)
+	}
+}
+
+// Makes enemies selectable
+type EnemySelector struct {
+	l  *Level
+	en *Enemy
+}
+
+func (s *EnemySelector) Paste() Selectable {
+	kopy := *s.en
+	s.l.Enemies = append(s.l.Enemies, &kopy)
+	return &EnemySelector{l: s.l, en: &kopy}
+}
+
+func (s *EnemySelector) Delete() {
+	for i, o := range s.l.Enemies {
+		if o == s.en {
+			s.l.Enemies = append(s.l.Enemies[:i], s.l.Enemies[i+1:]...)
+			return
+		}
+	}
+}
+
+func (s *EnemySelector) Transform() Mx {
+	return s.en.T
+}
+
+func (s *EnemySelector) SetTransform(m Mx) {
+	s.en.T = m
+}
+
+// Editor for enemies. Drag to draw one, and type "health <hits>", "interval <seconds>", "range <units>" or
+// "trigger <name>" to set up the enemies drawn after. Adding phases with "phase <hits> <pattern> [count]
+// [trigger]" makes them bosses, and "clear" removes the phases again.
+type EnemyEditor struct {
+	e *Editor
+	t *Typer
+	// Settings for new enemies
+	template Enemy
+	// The enemy being dragged out, and the point the drag started at
+	creating *Mx
+	startx   float64
+	starty   float64
+}
+
+func ActivateEnemyEditor(r *Root, e *Editor) {
+	s := &EnemyEditor{e: e, t: &Typer{C: &e.c}}
+	s.t.Placeholder = s.describe()
+	r.a = s
+}
+
+func (s *EnemyEditor) Layout(outsideWidth, outsideHeight int) (screenWidth, screenHeight int) {
+	return s.e.Layout(outsideWidth, outsideHeight)
+}
+
+// Summarizes the settings for new enemies
+func (s *EnemyEditor) describe() string {
+	t := s.template
+	trigger := t.Trigger
+	if trigger == "" {
+		trigger = "none"
+	}
+	health := fmt.Sprint(t.health())
+	if len(t.Phases) > 0 {
+		health = fmt.Sprintf("%v in %v phases", t.totalHealth(), len(t.Phases))
+	}
+	return fmt.Sprintf("Enemy Editor: health %v, interval %vs, range %v, trigger %v. Enter 'health|interval|range "+
+		"<n>', 'trigger <name>', 'phase <hits> <%v> [count] [trigger]' or 'clear'", health, t.interval(), t.reach(),
+		trigger, strings.Join([]string{patternAimed, patternSpread, patternRing}, "|"))
+}
+
+// Applies a command of the form "phase <hits> <pattern> [count] [trigger]", adding a phase to new enemies
+func (s *EnemyEditor) addPhase(parts []string) error {
+	if len(parts) < 3 || len(parts) > 5 {
+		return fmt.Errorf("expected 'phase <hits> <pattern> [count] [trigger]'")
+	}
+	var p Phase
+	var err error
+	p.Health, err = strconv.Atoi(parts[1])
+	if err != nil || p.Health <= 0 {
+		return fmt.Errorf("hits must be a positive whole number")
+	}
+	if !patterns[parts[2]] {
+		return fmt.Errorf("unknown pattern %v", parts[2])
+	}
+	p.Pattern = parts[2]
+	if len(parts) > 3 {
+		p.Count, err = strconv.Atoi(parts[3])
+		if err != nil || p.Count <= 0 {
+			return fmt.Errorf("count must be a positive whole number")
+		}
+	}
+	if len(parts) > 4 {
+		p.Trigger = parts[4]
+	}
+	s.template.Phases = append(s.template.Phases, p)
+	return nil
+}
+
+// Applies a command setting one of the new enemies' settings
+func (s *EnemyEditor) apply(cmd string) error {
+	parts := strings.Fields(cmd)
+	switch {
+	case len(parts) > 0 && parts[0] == "phase":
+		return s.addPhase(parts)
+	case len(parts) == 1 && parts[0] == "clear":
+		s.template.Phases = nil
+		return nil
+	case len(parts) != 2:
+		return fmt.Errorf("expected 'health|interval|range <n>', 'trigger <name>', 'phase ...' or 'clear'")
+	}
+	if parts[0] == "trigger" {
+		s.template.Trigger = parts[1]
+		return nil
+	}
+	n, err := strconv.ParseFloat(parts[1], 64)
+	if err != nil || n <= 0 {
+		return fmt.Errorf("%v must be a positive number", parts[0])
+	}
+	switch parts[0] {
+	case "health":
+		s.template.Health = int(n)
+	case "interval":
+		s.template.Interval = n
+	case "range":
+		s.template.Range = n
+	default:
+		return fmt.Errorf("unknown setting %v", parts[0])
+	}
+	return nil
+}
+
+func (s *EnemyEditor) Update(r *Root) error {
+	cmd, typ := s.t.Update()
+	if typ {
+		return nil
+	}
+	if cmd != "" {
+		err := s.apply(cmd)
+		if err != nil {
+			s.t.Placeholder = fmt.Sprintf("Failed to apply '%v': %v", cmd, err)
+		} else {
+			s.t.Placeholder = s.describe()
+		}
+	}
+	if driver.IsMouseButtonPressed(ebiten.MouseButtonLeft) {
+		wx, wy := s.e.c.Cursor()
+		if s.creating == nil {
+			s.creating = &Mx{}
+			s.startx, s.starty = wx, wy
+		}
+		*s.creating = boxBetween(s.startx, s.starty, wx, wy)
+	} else if s.creating != nil {
+		if _, _, hw, hh := decompose(*s.creating); hw*2 >= minBlockSize && hh*2 >= minBlockSize {
+			en := s.template
+			en.T = *s.creating
+			en.Phases = append([]Phase(nil), s.template.Phases...)
+			s.e.l.Enemies = append(s.e.l.Enemies, &en)
+		}
+		s.creating = nil
+	}
+	return s.e.Update(r)
+}
+
+func (s *EnemyEditor) Draw(screen *ebiten.Image) {
+	s.e.Draw(screen)
+	s.t.Draw(screen)
+	if s.creating != nil {
+		drawoutline(screen, *s.creating, 2, s.e.c.ToScreen(), enemyColor)
+	}
+	printAt(screen, "Drag to draw an enemy", 10, s.e.c.sh-35)
+}
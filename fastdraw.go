@@ -0,0 +1,86 @@
+package main
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+	"image/color"
+	"math"
+)
+
+// In fast drawing, levels with more art than this draw it as boxes instead of images
+const fastArtLimit = 500
+
+var (
+	fastBlockColor = color.RGBA{R: 0x90, G: 0x90, B: 0x90, A: 0xff}
+	fastArtColor   = color.RGBA{R: 0x40, G: 0x80, B: 0x40, A: 0x60}
+)
+
+// Batches flat colored quads into as few draw calls as possible, skipping any which are off screen.
+type quadBatch struct {
+	screen   *ebiten.Image
+	toScreen Mx
+	vertices []ebiten.Vertex
+	is       []uint16
+}
+
+// Adds the transform of a unit square centered at the origin to the batch
+func (q *quadBatch) add(m Mx, c color.Color) {
+	w, h := q.screen.Size()
+	if !squareOnScreen(m, q.toScreen, w, h) {
+		return
+	}
+	geo := m
+	geo.Concat(q.toScreen.GeoM)
+	vertices, is := rect(-0.5, -0.5, 1, 1, color.RGBAModel.Convert(c).(color.RGBA))
+	for i, v := range vertices {
+		sx, sy := geo.Apply(float64(v.DstX), float64(v.DstY))
+		v.DstX = float32(sx)
+		v.DstY = float32(sy)
+		vertices[i] = v
+	}
+	if len(q.vertices)+len(vertices) > math.MaxUint16 {
+		// indices must fit in 16 bits
+		q.flush()
+	}
+	base := uint16(len(q.vertices))
+	for _, i := range is {
+		q.is = append(q.is, base+i)
+	}
+	q.vertices = append(q.vertices, vertices...)
+}
+
+// Draws the quads added so far
+func (q *quadBatch) flush() {
+	if len(q.vertices) == 0 {
+		return
+	}
+	q.screen.DrawTriangles(q.vertices, q.is, emptyImage, nil)
+	q.vertices, q.is = q.vertices[:0], q.is[:0]
+}
+
+// Draws blocks as flat rectangles in their material's color, without shaders or any of the usual markings.
+func (e *Editor) drawBlocksFast(screen *ebiten.Image) {
+	q := quadBatch{screen: screen, toScreen: e.c.ToScreen()}
+	for _, b := range e.l.Blocks {
+		if e.hidden(b.Layer) {
+			continue
+		}
+		c, ok := materialColors[b.Material]
+		if !ok {
+			c = fastBlockColor
+		}
+		q.add(b.T, c)
+	}
+	q.flush()
+}
+
+// Draws the level's art as translucent boxes instead of images
+func (e *Editor) drawArtBoxes(screen *ebiten.Image) {
+	q := quadBatch{screen: screen, toScreen: e.c.ToScreen()}
+	for _, a := range e.l.Art {
+		if e.hidden(a.Layer) {
+			continue
+		}
+		q.add(e.l.artTransform(a), fastArtColor)
+	}
+	q.flush()
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"github.com/ByteArena/box2d"
+	"math"
+)
+
+// Fractional bits of a Fixed
+const fixedShift = 16
+
+// A fixed point number with 16 fractional bits. Arithmetic on fixed point numbers is integer arithmetic, so gives the
+// same results on every platform, unlike floating point where e.g fused multiply-adds vary between architectures.
+type Fixed int64
+
+const fixedOne = Fixed(1) << fixedShift
+
+// Converts a float to the nearest fixed point number
+func toFixed(f float64) Fixed {
+	return Fixed(math.Round(f * float64(fixedOne)))
+}
+
+func (a Fixed) Float() float64 {
+	return float64(a) / float64(fixedOne)
+}
+
+func (a Fixed) Mul(b Fixed) Fixed {
+	return (a * b) >> fixedShift
+}
+
+func (a Fixed) Div(b Fixed) Fixed {
+	return (a << fixedShift) / b
+}
+
+// The square root of a, which must not be negative, by Newton's method on integers.
+func (a Fixed) Sqrt() Fixed {
+	if a <= 0 {
+		return 0
+	}
+	// the square root of a / 2^16 in fixed point is sqrt(a / 2^16) * 2^16 == sqrt(a * 2^16)
+	n := int64(a) << fixedShift
+	x := n
+	y := (x + 1) / 2
+	for y < x {
+		x = y
+		y = (x + n/x) / 2
+	}
+	return Fixed(x)
+}
+
+// Rounds a float to the precision of a Fixed
+func quantize(f float64) float64 {
+	return toFixed(f).Float()
+}
+
+// Rounds the position, angle and velocity of every dynamic body to fixed point precision. Run after each tick in
+// deterministic games so that floating point differences between platforms, which box2d can't avoid, are discarded
+// before they can compound.
+func (g *Game) quantize() {
+	for b := g.world.GetBodyList(); b != nil; b = b.GetNext() {
+		if b.GetType() != box2d.B2BodyType.B2_dynamicBody {
+			continue
+		}
+		pos := b.GetPosition()
+		b.SetTransform(box2d.B2Vec2{X: quantize(pos.X), Y: quantize(pos.Y)}, quantize(b.GetAngle()))
+		v := b.GetLinearVelocity()
+		b.SetLinearVelocity(box2d.B2Vec2{X: quantize(v.X), Y: quantize(v.Y)})
+		b.SetAngularVelocity(quantize(b.GetAngularVelocity()))
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"github.com/ByteArena/box2d"
+	"testing"
+)
+
+func TestFixedArithmetic(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		got  Fixed
+		want float64
+	}{
+		{"mul", toFixed(1.5).Mul(toFixed(-2.25)), -3.375},
+		{"div", toFixed(7).Div(toFixed(2)), 3.5},
+		{"sqrt", toFixed(6.25).Sqrt(), 2.5},
+		{"sqrt small", toFixed(0.0625).Sqrt(), 0.25},
+		{"sqrt zero", Fixed(0).Sqrt(), 0},
+	} {
+		if c.got.Float() != c.want {
+			t.Errorf("%v: got %v, want %v", c.name, c.got.Float(), c.want)
+		}
+	}
+}
+
+func TestAttractorFixedForceMatchesFloat(t *testing.T) {
+	for _, falloff := range []string{falloffConstant, falloffLinear, falloffInverseSquare} {
+		a := Attractor{X: 1, Y: 2, Radius: 5, Strength: 10, Falloff: falloff}
+		c := toFixed(3).Float()
+		got := a.fixedForce(box2d.B2Vec2{X: c, Y: -0.5}, 2)
+		// the float calculation from apply
+		dir := box2d.B2Vec2{X: a.X - c, Y: a.Y + 0.5}
+		d := dir.Normalize()
+		dir.OperatorScalarMulInplace(a.Strength * a.falloff(d) * 2)
+		if diff := got.X - dir.X; diff > 1e-2 || diff < -1e-2 {
+			t.Errorf("%v: x force %v, want about %v", falloff, got.X, dir.X)
+		}
+		if diff := got.Y - dir.Y; diff > 1e-2 || diff < -1e-2 {
+			t.Errorf("%v: y force %v, want about %v", falloff, got.Y, dir.Y)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+//go:build go1.18
+// +build go1.18
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Decoding a level must never panic, and any level it accepts must survive being saved and decoded again. Run with
+// go test -fuzz FuzzLevelRead.
+func FuzzLevelRead(f *testing.F) {
+	seeds, err := filepath.Glob("levels/*.json")
+	if err != nil {
+		f.Fatal(err)
+	}
+	seeds = append(seeds, goldenLevel)
+	for _, p := range seeds {
+		bs, err := os.ReadFile(p)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(bs)
+	}
+	f.Add([]byte(`{"Blocks": [null]}`))
+	f.Add([]byte(`{"Tilemaps": [{"TileSize": 16, "Width": 2, "Height": 2, "Tiles": [1]}]}`))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var l Level
+		if l.read(bytes.NewReader(data)) != nil {
+			return
+		}
+		path := filepath.Join(t.TempDir(), "level.json")
+		err := l.save(path)
+		if err != nil {
+			t.Fatalf("saving an accepted level: %v", err)
+		}
+		var again Level
+		err = again.decode(path)
+		if err != nil {
+			t.Fatalf("decoding a saved level: %v", err)
+		}
+	})
+}
+
+// Decoding a clip, as prefabs and the clipboard are, must never panic, and any clip it accepts must be placeable in a
+// level. Run with go test -fuzz FuzzClipRead.
+func FuzzClipRead(f *testing.F) {
+	seeds, err := filepath.Glob(filepath.Join(prefabDir, "*.json"))
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, p := range seeds {
+		bs, err := os.ReadFile(p)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(bs)
+	}
+	f.Add([]byte(`{"Blocks": [null]}`))
+	f.Add([]byte(`{"Art": [null]}`))
+	f.Add([]byte(`{"Blocks": [{}], "Art": [{"Parent": 1}]}`))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var c Clip
+		if c.read(bytes.NewReader(data)) != nil {
+			return
+		}
+		l := NewLevel()
+		l.Prefabs = []*Prefab{{clip: c}}
+		blocks, art := l.expanded()
+		for _, a := range art {
+			if a.Parent != nil && (*a.Parent < 0 || *a.Parent >= len(blocks)) {
+				t.Fatalf("placed art pinned to block %v of %v", *a.Parent, len(blocks))
+			}
+		}
+	})
+}
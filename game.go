@@ -1,12 +1,14 @@
 package main
 
 import (
+	"fmt"
 	"github.com/ByteArena/box2d"
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/audio"
 	"github.com/hherman1/gobananas/resources"
 	"image/color"
 	"math"
+	"time"
 )
 
 // A renderable object in the physics sim
@@ -17,11 +19,31 @@ type Entity struct {
 
 	// If true, the players jump will be restored on contact with this entity
 	restoresJump bool
+
+	// The level block this entity was created from, if any
+	block *Block
+
+	// Set if the entity crumbles away when stood on
+	crumble *crumbling
+
+	// If true the entity was shot by the player, and presses switches it hits
+	bullet bool
+	// If true the entity was shot by an enemy, and hurts the player. It disappears when it hits anything.
+	hostile bool
+	// Set if the entity is an enemy
+	foe *foe
 }
 
 // The audio context. Can only be one per process.
 var Actx = audio.NewContext(resources.SampleRate)
 
+// Ticks after jumping or shooting before the player can do it again
+const playerCooldown = ticksPerSecond / 2
+
+// How closely a contact's normal has to point up, or sideways, for the player to be standing on the ground, or
+// against a wall they can jump off. The cosine of the steepest angle allowed.
+const wallNormal = 0.7
+
 // A game actually simulates a level and allows player control.
 type Game struct {
 	world box2d.B2World
@@ -29,19 +51,126 @@ type Game struct {
 	c Camera
 	p Player
 
+	// Where player input comes from
+	in Input
+
 	// Entities to draw on each frame
 	entities []*Entity
 
 	// Fully loaded art for rendering
 	art []*Art
+	// Entities of the blocks art is pinned to
+	pinned map[*Art]*Entity
+
+	// Simulated ropes for rendering
+	ropes []*Rope
+
+	// Zones pulling bodies towards them
+	attractors []Attractor
+
+	// Linked portals teleporting bodies between them
+	portals []*Portal
+
+	// Joints pinning contraptions to the world
+	pivots []pivot
+
+	// Switches the player can press
+	switches []*pressable
+	// Named blocks the player started touching during the last physics step, see fireTouches
+	touched []*Entity
+
+	// Enemies, and those the player's bullets hit during the last physics step, see hitFoes
+	foes []*foe
+	hits []*foe
+
+	// Set while a trigger has the camera looking away from the player
+	look *LookAt
+	// Set while a trigger's dialogue is shown
+	dialogue *openDialogue
+	// Set while a trigger's cutscene plays
+	cutscene *playingCutscene
+	// The level's art in the order it was added, which cutscenes refer to it by, and how far cutscenes have moved it
+	levelArt []*Art
+	moved    map[*Art]ArtKey
+
+	// Gameplay code waiting to run at a later tick
+	schedule Scheduler
+
+	// If true the player walks around gravity wells, see down
+	planetary bool
+
+	// If true gameplay math is done in fixed point, and bodies are rounded to fixed point precision after each tick
+	deterministic bool
+
+	// Where the camera and bodies were before the last tick, for interpolating when rendering is uncapped
+	prev       map[*box2d.B2Body]snapshot
+	prevCamera Camera
+
+	// Time not yet simulated, less than a tick except when catching up, and when it was last added to
+	accumulator time.Duration
+	updated     time.Time
+
+	// Painted tiles
+	tilemaps []*Tilemap
+	// Text placed in the level
+	labels []*Label
+
+	// Spawn points by name, "" for the default
+	spawns map[string]box2d.B2Vec2
+
+	// Zone the player races to, if any, and the time of the run to it
+	goal  *Mx
+	timer speedrun
+
+	// Area the camera is kept inside, if any
+	cameraBounds *Mx
+	// Regions framing the camera, the one the player is in if any, and how much it has zoomed the camera's view
+	cameraZones []*CameraZone
+	cameraZone  *CameraZone
+	framing     float64
+	// Lights lighting the level, which is drawn unlit without any, and whether bodies cast shadows from them
+	lights  []*Light
+	shadows bool
+	// Strength of the level's bloom, and the pass drawing it
+	glow  float64
+	bloom Bloom
+	// The time of day, if the level has a day night cycle
+	day *clock
+	// The level's color grade if it has one, and the pass drawing it
+	grade   *ebiten.ColorM
+	grading Grading
+	// Draws the frame through the CRT setting's effect
+	crt CRT
+	// Bodies, art and tilemaps drawn in the last frame, roughly its draw calls, for the performance overlay
+	draws int
+
+	// The player's path so far, and a previous run to race against if any
+	run   Ghost
+	ghost *Ghost
+
+	// What the player has done so far, and how low they can fall before they've fallen out of the level
+	stats  runStats
+	bottom float64
 
 	// Number of evaluated ticks for timekeeping.
 	time int
 
 	bgAudio *Audio
+	// Regions with their own music, and how loud each track is as they crossfade
+	musicZones  []*MusicZone
+	musicLevels map[*Audio]float64
+	// Looping sounds placed in the world
+	ambients []*Ambient
 	bgArt *Art
-	// Player's art
-	pArt *Art
+	// Player's art, from their character or the level's player art
+	pArt      *Art
+	playerArt *Art
+	// Characters the player can become by name, and zones which swap between them
+	characters map[string]Character
+	stations   []*Station
+
+	// Widgets drawn over the game, like the player's health
+	hud HUD
 
 	// Functions to call on certain game events
 	Triggers map[string]Trigger
@@ -50,6 +179,8 @@ type Game struct {
 // Creates a new game with a default player and empty world
 func NewGame() *Game {
 	var g Game
+	g.in = liveInput{}
+	g.hud = defaultHUD()
 	g.c = Camera{
 		hw: 12,
 		hh: 8,
@@ -57,6 +188,9 @@ func NewGame() *Game {
 		y:  0,
 	}
 	g.world = box2d.MakeB2World(box2d.MakeB2Vec2(0.0, -10.0))
+	g.world.SetAutoClearForces(false)
+	// for surface materials
+	g.world.SetContactListener(&g)
 
 	// set up the player
 	player := box2d.NewB2BodyDef()
@@ -70,11 +204,15 @@ func NewGame() *Game {
 	def.Shape = &shape
 	def.Density = 1
 	def.Friction = 3
+	def.Filter = collisionFilter(categoryPlayer)
 	g.p = Player{
-		w: 1,
-		h: 1,
-		s: &shape,
-		b: g.world.CreateBody(player),
+		w:         1,
+		h:         1,
+		s:         &shape,
+		b:         g.world.CreateBody(player),
+		character: defaultCharacter,
+		c:         builtinCharacters[defaultCharacter],
+		health:    playerHealth,
 	}
 	g.p.b.SetLinearDamping(0)
 	g.p.b.CreateFixtureFromDef(&def)
@@ -83,7 +221,19 @@ func NewGame() *Game {
 	return &g
 }
 
+// Called by the world when two fixtures start touching
 func (g *Game) BeginContact(contact box2d.B2ContactInterface) {
+	e := g.touch(contact)
+	if e != nil && e.block != nil && e.block.Name != "" {
+		// the world can't be changed during a step, so the triggers fire after it
+		g.touched = append(g.touched, e)
+	}
+	g.shot(contact)
+}
+
+// Updates the player for a contact between them and an entity, which is returned. Returns nil if the contact isn't
+// the player's.
+func (g *Game) touch(contact box2d.B2ContactInterface) *Entity {
 	var p *Player
 	var e *Entity
 	if np, ok := contact.GetFixtureA().GetBody().GetUserData().(*Player); ok {
@@ -93,52 +243,132 @@ func (g *Game) BeginContact(contact box2d.B2ContactInterface) {
 		p = np
 		e = contact.GetFixtureA().GetBody().GetUserData().(*Entity)
 	} else {
-		return
+		return nil
 	}
 	if e.restoresJump {
 		p.hasJump = true
 	}
+	if !contact.IsTouching() {
+		return e
+	}
+	// note which way the player is touching things, for wall jumps
+	normal := contactNormal(contact, p.b)
+	down := g.down()
+	right := box2d.B2Vec2{X: -down.Y, Y: down.X}
+	if -box2d.B2Vec2Dot(normal, down) > wallNormal {
+		p.grounded = true
+	} else if side := box2d.B2Vec2Dot(normal, right); math.Abs(side) > wallNormal {
+		p.wall = math.Copysign(1, side)
+	}
+	return e
 }
 
-func (g *Game) EndContact(contact box2d.B2ContactInterface) {
+// The event fired when the player starts touching blocks with the given name
+func touchEvent(name string) string {
+	return "touched " + name
 }
 
-func (g *Game) PreSolve(contact box2d.B2ContactInterface, oldManifold box2d.B2Manifold) {
+// Fires the touch triggers of the named blocks the player started touching during the last physics step
+func (g *Game) fireTouches() {
+	for _, e := range g.touched {
+		pos := e.b.GetPosition()
+		g.fire(touchEvent(e.block.Name), pos.X, pos.Y)
+	}
+	g.touched = g.touched[:0]
+}
+
+// The normal of a contact with the given body, pointing out of whatever the body touches
+func contactNormal(contact box2d.B2ContactInterface, b *box2d.B2Body) box2d.B2Vec2 {
+	var manifold box2d.B2WorldManifold
+	contact.GetWorldManifold(&manifold)
+	// the manifold's normal points from fixture A to B
+	normal := manifold.Normal
+	if contact.GetFixtureA().GetBody() == b {
+		normal = normal.OperatorNegate()
+	}
+	return normal
+}
+
+func (g *Game) EndContact(contact box2d.B2ContactInterface) {
 }
 
 func (g *Game) PostSolve(contact box2d.B2ContactInterface, impulse *box2d.B2ContactImpulse) {
 }
 
 
+// Advances the game by however many ticks fit in the time since the last update, so the simulation runs at the same
+// speed however often it is updated.
 func (g *Game) Update() error {
+	now := time.Now()
+	if g.updated.IsZero() {
+		g.updated = now.Add(-tickDuration)
+	}
+	g.accumulator += now.Sub(g.updated)
+	g.updated = now
+	if g.accumulator > maxCatchUp*tickDuration {
+		// too far behind to catch up, e.g after being paused, so slow down instead
+		g.accumulator = maxCatchUp * tickDuration
+	}
+	for g.accumulator >= tickDuration {
+		g.accumulator -= tickDuration
+		err := g.tick()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Advances the game by one tick
+func (g *Game) tick() error {
+	if s, ok := g.in.(*Script); ok {
+		err := s.advance()
+		if err != nil {
+			return fmt.Errorf("script: %w", err)
+		}
+	}
 	g.time++
+	g.schedule.advance(g.time)
+	g.snapshot()
+	wasGrounded := g.p.grounded
+	g.p.grounded, g.p.wall = false, 0
 	for next := g.p.b.GetContactList(); next != nil; next = next.Next {
-		g.BeginContact(next.Contact)
+		g.touch(next.Contact)
+	}
+	g.land(wasGrounded)
+	g.c.advanceShake()
+	g.day.advance()
+	g.frame()
+	// player input is suspended while the camera looks elsewhere, dialogue is open or a cutscene plays
+	directed := g.direct()
+	tx, ty, following := g.cameraTarget()
+	if g.talk() || directed {
+		following = false
 	}
 	{
 		// camera pan
-		if ebiten.IsKeyPressed(ebiten.KeyRight) {
+		if g.in.IsKeyPressed(ebiten.KeyRight) {
 			g.c.x++
 		}
-		if ebiten.IsKeyPressed(ebiten.KeyLeft) {
+		if g.in.IsKeyPressed(ebiten.KeyLeft) {
 			g.c.x--
 		}
-		if ebiten.IsKeyPressed(ebiten.KeyUp) {
+		if g.in.IsKeyPressed(ebiten.KeyUp) {
 			g.c.y++
 		}
-		if ebiten.IsKeyPressed(ebiten.KeyDown) {
+		if g.in.IsKeyPressed(ebiten.KeyDown) {
 			g.c.y--
 		}
 	}
 	{
 		// camera zoom
-		_, yoff := ebiten.Wheel()
+		_, yoff := g.in.Wheel()
 		if yoff != 0 {
 			g.c.hh *= math.Pow(0.98, yoff)
 			g.c.hw *= math.Pow(0.98, yoff)
 		}
-		if ebiten.IsKeyPressed(ebiten.KeySpace) {
-			if ebiten.IsKeyPressed(ebiten.KeyShift) {
+		if g.in.IsKeyPressed(ebiten.KeySpace) {
+			if g.in.IsKeyPressed(ebiten.KeyShift) {
 				g.c.hw *= 0.99
 				g.c.hh *= 0.99
 			} else {
@@ -147,45 +377,73 @@ func (g *Game) Update() error {
 			}
 		}
 	}
-	{
+	// whether the player did anything this tick, to start the run timer
+	input := following && (g.in.IsKeyPressed(ebiten.KeyD) || g.in.IsKeyPressed(ebiten.KeyA) ||
+		g.in.IsKeyPressed(ebiten.KeyW) || g.in.IsMouseButtonPressed(ebiten.MouseButtonRight))
+	// movement is relative to the player's down, which only changes in planetary levels
+	down := g.down()
+	g.orient(down)
+	if normal, ok := g.touching(materialSticky); ok {
+		// walking along the wall or ceiling
+		down = normal.OperatorNegate()
+		g.stick(normal)
+	}
+	right := box2d.B2Vec2{X: -down.Y, Y: down.X}
+	maxSpeed := g.p.c.Speed
+	if _, ok := g.touching(materialMud); ok {
+		maxSpeed *= float64(mudSpeed) / walkSpeed
+	}
+	if following {
 		// movement
-		velocity := g.p.b.GetLinearVelocity()
-		if ebiten.IsKeyPressed(ebiten.KeyD) && velocity.X < 5 {
-			g.p.b.ApplyForceToCenter(box2d.B2Vec2{60, 0}, true)
+		speed := box2d.B2Vec2Dot(g.p.b.GetLinearVelocity(), right)
+		walk := 60 * g.p.b.GetMass() * g.grip()
+		if g.in.IsKeyPressed(ebiten.KeyD) && speed < maxSpeed {
+			g.p.b.ApplyForceToCenter(box2d.B2Vec2MulScalar(walk, right), true)
 		}
-		if ebiten.IsKeyPressed(ebiten.KeyA) && velocity.X > -5 {
-			g.p.b.ApplyForceToCenter(box2d.B2Vec2{-60, 0}, true)
+		if g.in.IsKeyPressed(ebiten.KeyA) && speed > -maxSpeed {
+			g.p.b.ApplyForceToCenter(box2d.B2Vec2MulScalar(-walk, right), true)
 		}
-		if ebiten.IsKeyPressed(ebiten.KeyW) {
-			if g.p.hasJump && g.time - g.p.lastJump > 30 {
-				g.p.b.ApplyForceToCenter(box2d.B2Vec2{0, 60*5}, true)
-				g.p.lastJump = g.time
-				if t, ok := g.Triggers["jump"]; ok {
-					t.Activate()
+		if g.in.IsKeyPressed(ebiten.KeyW) {
+			if g.p.hasJump && !g.p.jumpCooling {
+				jump := g.p.c.Jump * g.p.b.GetMass() * ticksPerSecond
+				g.p.b.ApplyForceToCenter(box2d.B2Vec2MulScalar(-jump, down), true)
+				if g.p.wall != 0 && !g.p.grounded && g.p.c.WallKick > 0 {
+					// kick off the wall, without the speed of sliding down it eating into the jump
+					v := g.p.b.GetLinearVelocity()
+					if fall := box2d.B2Vec2Dot(v, down); fall > 0 {
+						g.p.b.SetLinearVelocity(box2d.B2Vec2Sub(v, box2d.B2Vec2MulScalar(fall, down)))
+					}
+					kick := g.p.wall * g.p.c.WallKick * g.p.b.GetMass() * ticksPerSecond
+					g.p.b.ApplyForceToCenter(box2d.B2Vec2MulScalar(kick, right), true)
 				}
+				g.p.jumpCooling = true
+				g.schedule.After(playerCooldown, func() { g.p.jumpCooling = false })
+				g.stats.jumps++
+				pos := g.p.b.GetPosition()
+				g.fire("jump", pos.X, pos.Y)
 			}
 			g.p.hasJump = false
 		}
 	}
-	// have camera approach player
+	// have camera approach its target, usually the player
 	{
-		position := g.p.b.GetPosition()
-		g.c.x += 0.1 * (position.X - g.c.x)
-		g.c.y += 0.1 * (position.Y - g.c.y)
+		g.c.x += 0.1 * (tx - g.c.x)
+		g.c.y += 0.1 * (ty - g.c.y)
+		g.clampCamera()
 	}
 	{
 		// Audio
-		if g.bgAudio != nil && !g.bgAudio.player.IsPlaying() {
-			_ = g.bgAudio.player.Seek(0)
-			g.bgAudio.player.Play()
-		}
+		g.mixMusic()
+		g.playAmbience()
 	}
 	{
 		// shooting
-		if ebiten.IsMouseButtonPressed(ebiten.MouseButtonRight) && g.time - g.p.lastShot > 30 {
+		if following && g.p.c.Shoots && g.in.IsMouseButtonPressed(ebiten.MouseButtonRight) && !g.p.shotCooling {
 			// fire away
-			g.p.lastShot = g.time
-			wx, wy := g.c.Cursor()
+			g.p.shotCooling = true
+			g.schedule.After(playerCooldown, func() { g.p.shotCooling = false })
+			g.stats.shots++
+			wx, wy := g.in.Cursor(&g.c)
 			pos := g.p.b.GetPosition()
 
 			force := box2d.B2Vec2{wx - pos.X, wy - pos.Y}
@@ -193,25 +451,7 @@ func (g *Game) Update() error {
 			force.OperatorScalarMulInplace(0.5)
 
 			// Spawn bullet
-			body := box2d.NewB2BodyDef()
-			body.Position = box2d.B2Vec2{pos.X + force.X, pos.Y + force.Y}
-			body.Type = box2d.B2BodyType.B2_dynamicBody
-			e := &Entity{
-				w:            0.25,
-				h:            0.25,
-				b:            g.world.CreateBody(body),
-				restoresJump: false,
-			}
-			e.b.SetUserData(e)
-			g.entities = append(g.entities, e)
-			shape := box2d.MakeB2PolygonShape()
-			shape.SetAsBox(0.125, 0.125)
-			def := box2d.MakeB2FixtureDef()
-			def.Shape = &shape
-			def.Density = 1
-			def.Friction = 0.3
-			def.Restitution = 0.7
-			e.b.CreateFixtureFromDef(&def)
+			e := g.spawnBullet(box2d.B2Vec2{pos.X + force.X, pos.Y + force.Y}, categoryPlayerBullet)
 
 			force.OperatorScalarMulInplace(100)
 			e.b.ApplyForceToCenter(force, true)
@@ -219,34 +459,94 @@ func (g *Game) Update() error {
 			g.p.b.ApplyForceToCenter(force, true)
 
 			// Apply any triggers
-			if t, ok := g.Triggers["shoot"]; ok {
-				t.Activate()
-			}
+			g.fire("shoot", pos.X, pos.Y)
 		}
 	}
-	g.world.Step(1.0/60., 16, 3)
+	for i := range g.attractors {
+		g.attractors[i].apply(g)
+	}
+	for _, p := range g.portals {
+		p.teleport(g)
+	}
+	g.crumble()
+	g.pressSwitches()
+	g.swapCharacters()
+	g.hurt()
+	g.clearHostileBullets()
+	g.p.fall = box2d.B2Vec2Dot(g.p.b.GetLinearVelocity(), down)
+	// forces applied this tick act on every physics step
+	steps := physicsSteps()
+	end := phase("physics")
+	for i := 0; i < steps; i++ {
+		g.world.Step(1/float64(ticksPerSecond*steps), 16, 3)
+	}
+	end()
+	g.world.ClearForces()
+	g.fireTouches()
+	g.hitFoes()
+	if g.deterministic {
+		g.quantize()
+	}
+	for _, r := range g.ropes {
+		r.step(1.0/ticksPerSecond, g.world.GetGravity())
+	}
+	g.timeRun(input)
+	g.record()
 	return nil
 }
 
 func (g *Game) Draw(screen *ebiten.Image) {
+	out := screen
+	if settings.CRT {
+		out = g.crt.target(screen)
+	}
+	world := out
+	if g.grade != nil {
+		world = g.grading.target(out)
+	}
+	end := phase("draw world")
+	if g.glow > 0 && !lowQuality {
+		g.drawWorld(g.bloom.target(world))
+		end()
+		end = phase("bloom")
+		g.bloom.apply(world, g.glow)
+	} else {
+		g.drawWorld(world)
+	}
+	end()
+	if g.grade != nil {
+		g.grading.apply(out, *g.grade)
+	}
+	g.hud.Draw(out, g)
+	g.drawDialogue(out)
+	if settings.CRT {
+		g.crt.apply(screen)
+	}
+}
+
+// Draws everything in the world, without the HUD over it
+func (g *Game) drawWorld(screen *ebiten.Image) {
 	//geo.Scale(1, -1)
 	geo := Mx{}
-	position := g.p.b.GetPosition()
+	position, angle := g.drawTransform(g.p.b)
 	geo.Translate(-g.p.w/2, -g.p.h/2)
-	geo.Rotate(g.p.b.GetAngle())
+	geo.Rotate(angle)
 	geo.Translate(position.X, position.Y)
-	screenTransform := g.c.ToScreen()
+	camera := g.drawCamera()
+	screenTransform := camera.ToScreen()
 	geo.Concat(screenTransform.GeoM)
 
 	velocity := g.p.b.GetLinearVelocity()
+	lit := g.lighting(screenTransform)
+	g.draws = 0
 
-	screen.DrawRectShader(g.c.sw, g.c.sh, mainShader, &ebiten.DrawRectShaderOptions{
-		Uniforms: map[string]interface{}{
-			"Vx": float32(velocity.X),
-			"Vy": float32(velocity.Y),
-			"ScreenPixels": []float32{float32(g.c.sw)*2, float32(g.c.sh)*2},
-		},
-	})
+	if lowQuality {
+		screen.Fill(color.RGBA{R: 0x20, G: 0x20, B: 0x30, A: 0xff})
+	} else {
+		screen.DrawRectShader(g.c.sw, g.c.sh, mainShader, &ebiten.DrawRectShaderOptions{
+			Uniforms: g.uniforms(lit, velocity, float64(g.c.sw)*2, float64(g.c.sh)*2),
+		})
+	}
 
 	// bg art
 	if g.bgArt != nil {
@@ -266,34 +566,35 @@ func (g *Game) Draw(screen *ebiten.Image) {
 		geo.Scale(scale, scale)
 		screen.DrawImage(g.bgArt.img, &ebiten.DrawImageOptions{GeoM: geo.GeoM})
 	}
+	g.tintBackground(screen)
 
-
+	// Previous run, behind the player
+	g.drawGhost(screen, screenTransform)
 	// Player art
-	if g.pArt != nil {
-		var ageo Mx
-		w, h := g.bgArt.img.Size()
-		ageo.Scale(1/float64(w), -1/float64(h))
-		ageo.Translate(0, 1)
-		ageo.Scale(g.p.w, g.p.h)
-		ageo.Concat(geo.GeoM)
-		screen.DrawImage(g.bgArt.img, &ebiten.DrawImageOptions{GeoM: ageo.GeoM})
-	} else {
-		screen.DrawRectShader(int(g.p.w), int(g.p.h), mainShader, &ebiten.DrawRectShaderOptions{GeoM: geo.GeoM,
-			Uniforms: map[string]interface{}{
-				"Vx": float32(velocity.X),
-				"Vy": float32(velocity.Y),
-				"ScreenPixels": []float32{float32(g.c.sw), float32(g.c.sh)},
-			},
-		})
+	g.drawPlayer(screen, geo, velocity)
+	// Portions of the player passing through portals
+	for _, p := range g.portals {
+		g.drawPlayerThrough(screen, p, screenTransform)
 	}
 
 	for _, e := range g.entities {
+		if !e.b.IsActive() {
+			// crumbled away
+			continue
+		}
 		geo := Mx{}
-		position := e.b.GetPosition()
+		position, angle := g.drawTransform(e.b)
+		if e.crumble != nil {
+			position.OperatorPlusInplace(e.crumble.shake(g.time))
+		}
 		geo.Translate(-e.w/2, -e.h/2)
-		geo.Rotate(e.b.GetAngle())
+		geo.Rotate(angle)
 		geo.Translate(position.X, position.Y)
 		geo.Concat(screenTransform.GeoM)
+		if !onScreen(geo, 0, 0, e.w, e.h, g.c.sw, g.c.sh) {
+			continue
+		}
+		g.draws++
 		velocity = e.b.GetLinearVelocity()
 		vertices, is := rect(0, 0, float32(e.w), float32(e.h), color.RGBA{})
 		for i, v := range vertices {
@@ -302,15 +603,25 @@ func (g *Game) Draw(screen *ebiten.Image) {
 			v.DstY = float32(sy)
 			vertices[i] = v
 		}
-		screen.DrawTrianglesShader(vertices, is, mainShader, &ebiten.DrawTrianglesShaderOptions{
+		shader, uniforms := mainShader, g.uniforms(lit, velocity, float64(g.c.sw), float64(g.c.sh))
+		if e.block != nil {
+			shader, uniforms = e.block.Shader.use(shader, uniforms)
+		}
+		screen.DrawTrianglesShader(vertices, is, shader, &ebiten.DrawTrianglesShaderOptions{
 			CompositeMode: 0,
-			Uniforms: map[string]interface{}{
-				"Vx": float32(velocity.X),
-				"Vy": float32(velocity.Y),
-				"ScreenPixels": []float32{float32(g.c.sw), float32(g.c.sh)},
-			},
+			Uniforms:      uniforms,
 			Images:        [4]*ebiten.Image{},
 		})
+		if e.block != nil {
+			if c, ok := materialColors[e.block.Material]; ok {
+				// geo maps the entity's corner based box, so shift the unit square into it
+				var outline Mx
+				outline.Translate(0.5, 0.5)
+				outline.Scale(e.w, e.h)
+				outline.Concat(geo.GeoM)
+				drawoutline(screen, outline, 3, Mx{}, c)
+			}
+		}
 		//screen.DrawRectShader(int(e.w), int(e.h), mainShader, &ebiten.DrawRectShaderOptions{GeoM: geo,
 		//	Uniforms: map[string]interface{}{
 		//		"Vx": float32(velocity.X),
@@ -319,16 +630,93 @@ func (g *Game) Draw(screen *ebiten.Image) {
 		//})
 	}
 
+	for _, t := range g.tilemaps {
+		t.draw(screen, screenTransform)
+	}
+	g.draws += len(g.tilemaps)
+	if g.goal != nil {
+		drawGoal(screen, *g.goal, screenTransform)
+	}
+	g.drawSwitches(screen, screenTransform)
+	for _, s := range g.stations {
+		s.draw(screen, screenTransform)
+	}
+	g.drawFoes(screen, screenTransform)
+	drawRopes(screen, g.ropes, screenTransform)
+	for _, p := range g.pivots {
+		drawPivot(screen, p.at, p.b.GetPosition(), screenTransform)
+	}
+
 	for _, a := range g.art {
-		// unflip the images
-		var geo Mx
-		w, h := a.img.Size()
-		geo.Scale(1/float64(w), 1/float64(h))
-		geo.Translate(-0.5, -0.5)
-		geo.Scale(1, -1)
-		geo.Concat(a.T.GeoM)
-		geo.Concat(screenTransform.GeoM)
-		screen.DrawImage(a.img, &ebiten.DrawImageOptions{GeoM: geo.GeoM})
+		t, visible := g.artTransform(a)
+		if !visible || !squareOnScreen(t, screenTransform, g.c.sw, g.c.sh) {
+			continue
+		}
+		g.draws++
+		drawArt(screen, a, t, screenTransform, lit, float64(g.time)/ticksPerSecond)
+	}
+	for _, lb := range g.labels {
+		lb.draw(screen, screenTransform)
+	}
+}
+
+// The uniforms to draw with the main shader, lit by the lighting if set. The gradient the shader draws spans the given
+// width and height in pixels.
+func (g *Game) uniforms(lit *lighting, velocity box2d.B2Vec2, width, height float64) map[string]interface{} {
+	u := lit.uniforms()
+	cx, cy := driver.CursorPosition()
+	u["Time"] = float32(g.time) / ticksPerSecond
+	u["Cursor"] = []float32{float32(cx), float32(cy)}
+	u["Vx"] = float32(velocity.X)
+	u["Vy"] = float32(velocity.Y)
+	u["ScreenPixels"] = []float32{float32(width), float32(height)}
+	return u
+}
+
+// Draws the player using a transform from the player's local coordinates, with 0,0 at the bottom left corner, to the
+// screen.
+func (g *Game) drawPlayer(screen *ebiten.Image, geo Mx, velocity box2d.B2Vec2) {
+	if g.flashing() {
+		return
+	}
+	if g.pArt != nil {
+		var ageo Mx
+		w, h := g.pArt.img.Size()
+		ageo.Scale(1/float64(w), -1/float64(h))
+		ageo.Translate(0, 1)
+		ageo.Scale(g.p.w, g.p.h)
+		ageo.Concat(geo.GeoM)
+		screen.DrawImage(g.pArt.img, &ebiten.DrawImageOptions{GeoM: ageo.GeoM})
+	} else {
+		// drawn as a unit square scaled to the player, characters can be smaller than a pixel in world units
+		var sgeo Mx
+		sgeo.Scale(g.p.w, g.p.h)
+		sgeo.Concat(geo.GeoM)
+		screen.DrawRectShader(1, 1, mainShader, &ebiten.DrawRectShaderOptions{GeoM: sgeo.GeoM,
+			Uniforms: map[string]interface{}{
+				"Vx": float32(velocity.X),
+				"Vy": float32(velocity.Y),
+				"ScreenPixels": []float32{float32(g.c.sw), float32(g.c.sh)},
+			},
+		})
+	}
+}
+
+// Stops any audio the game is playing, for when the game is being discarded.
+func (g *Game) Stop() {
+	if g.bgAudio != nil {
+		g.bgAudio.player.Pause()
+	}
+	for _, a := range g.ambients {
+		a.Audio.player.Pause()
+	}
+	for _, z := range g.musicZones {
+		z.Audio.player.Pause()
+	}
+	for _, t := range g.Triggers {
+		if t.Audio != nil {
+			t.Audio.player.Pause()
+		}
 	}
 }
 
@@ -345,9 +733,24 @@ type Player struct {
 
 	// when the player contacts a jump restoring surface it refreshes its ability to jump
 	hasJump bool
-	// used for jump cooldowns
-	lastJump int
-
-	// shooting cooldowns
-	lastShot int
+	// Whether the player is standing on something this tick, and which side of them a wall is on if they're
+	// touching one: 1 if the wall is to their left, so they kick off it to the right, -1 if to their right, 0 if none
+	grounded bool
+	wall     float64
+	// Speed the player was falling at before the last physics step, for how hard they landed
+	fall float64
+
+	// Set after jumping or shooting until the player can again, see playerCooldown
+	jumpCooling bool
+	shotCooling bool
+
+	// The character the player is, by name
+	character string
+	c         Character
+
+	// Hits the player can take before dying, and whether they were hurt too recently to be hurt again
+	health     int
+	invincible bool
+	// The spawn point the player last started from, which they go back to when they die
+	spawn string
 }
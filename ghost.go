@@ -0,0 +1,51 @@
+package main
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+	"image/color"
+)
+
+// Colour of the ghost player, translucent so it never hides the live player
+var ghostColor = color.RGBA{R: 0xcc, G: 0xcc, B: 0xff, A: 0x60}
+
+// Where the player was at the end of a tick
+type ghostFrame struct {
+	X, Y  float64
+	Angle float64
+}
+
+// A recording of the player's path through a level, one frame per tick, which can be raced in a later run.
+type Ghost struct {
+	Frames []ghostFrame
+}
+
+// Records where the player ended the tick. Called at the end of every tick.
+func (g *Game) record() {
+	pos := g.p.b.GetPosition()
+	g.run.Frames = append(g.run.Frames, ghostFrame{X: pos.X, Y: pos.Y, Angle: g.p.b.GetAngle()})
+}
+
+// Draws the ghost where its run was at the same tick as the game. Nothing is drawn once the ghost's run is over.
+func (g *Game) drawGhost(screen *ebiten.Image, toScreen Mx) {
+	if g.ghost == nil {
+		return
+	}
+	i := g.time - 1
+	if i < 0 || i >= len(g.ghost.Frames) {
+		return
+	}
+	f := g.ghost.Frames[i]
+	geo := Mx{}
+	geo.Translate(-g.p.w/2, -g.p.h/2)
+	geo.Rotate(f.Angle)
+	geo.Translate(f.X, f.Y)
+	geo.Concat(toScreen.GeoM)
+	vertices, is := rect(0, 0, float32(g.p.w), float32(g.p.h), ghostColor)
+	for i, v := range vertices {
+		sx, sy := geo.Apply(float64(v.DstX), float64(v.DstY))
+		v.DstX = float32(sx)
+		v.DstY = float32(sy)
+		vertices[i] = v
+	}
+	screen.DrawTriangles(vertices, is, emptyImage, nil)
+}
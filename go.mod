@@ -5,6 +5,7 @@ go 1.17
 require (
 	github.com/ByteArena/box2d v1.0.2
 	github.com/hajimehoshi/ebiten/v2 v2.1.5
+	golang.org/x/image v0.0.0-20210220032944-ac19c3e999fb
 )
 
 require (
@@ -12,8 +13,8 @@ require (
 	github.com/hajimehoshi/oto v0.7.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	golang.org/x/exp v0.0.0-20190731235908-ec7cb31e5a56 // indirect
-	golang.org/x/image v0.0.0-20210220032944-ac19c3e999fb // indirect
 	golang.org/x/mobile v0.0.0-20210220033013-bdb1ca9a1e08 // indirect
 	golang.org/x/sync v0.0.0-20210220032951-036812b2e83c // indirect
 	golang.org/x/sys v0.0.0-20210415045647-66c3f260301c // indirect
+	golang.org/x/text v0.3.3 // indirect
 )
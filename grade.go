@@ -0,0 +1,52 @@
+package main
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+	"image/color"
+)
+
+// A color grade applied to everything in a level, giving it a palette of its own without new art, e.g blue for ice or
+// dark and desaturated for night. The zero grade leaves colors unchanged.
+type Grade struct {
+	// Multiplies each color channel after the other changes. White if unset.
+	Tint *color.RGBA `json:",omitempty"`
+	// Radians hues are turned by
+	Hue float64 `json:",omitempty"`
+	// Added to saturation as a fraction of it, -1 for greyscale and 1 for twice as saturated
+	Saturation float64 `json:",omitempty"`
+	// Added to brightness as a fraction of it, -0.5 halving it
+	Brightness float64 `json:",omitempty"`
+}
+
+// The color matrix applying the grade
+func (gr *Grade) colorM() ebiten.ColorM {
+	var m ebiten.ColorM
+	m.ChangeHSV(gr.Hue, 1+gr.Saturation, 1+gr.Brightness)
+	if gr.Tint != nil {
+		m.Scale(float64(gr.Tint.R)/0xff, float64(gr.Tint.G)/0xff, float64(gr.Tint.B)/0xff, 1)
+	}
+	return m
+}
+
+// A post processing pass grading the world. The image it draws through is kept between frames.
+type Grading struct {
+	frame *ebiten.Image
+}
+
+// The image to draw the frame to before it's graded, cleared and sized to match the screen
+func (gr *Grading) target(screen *ebiten.Image) *ebiten.Image {
+	w, h := screen.Size()
+	if gr.frame == nil || gr.frame.Bounds().Dx() != w || gr.frame.Bounds().Dy() != h {
+		if gr.frame != nil {
+			gr.frame.Dispose()
+		}
+		gr.frame = ebiten.NewImage(w, h)
+	}
+	gr.frame.Clear()
+	return gr.frame
+}
+
+// Draws the frame from target to the screen graded by the given color matrix
+func (gr *Grading) apply(screen *ebiten.Image, m ebiten.ColorM) {
+	screen.DrawImage(gr.frame, &ebiten.DrawImageOptions{ColorM: m})
+}
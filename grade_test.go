@@ -0,0 +1,39 @@
+package main
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestGradeColorM(t *testing.T) {
+	red := color.RGBA{R: 0xff, A: 0xff}
+	for _, c := range []struct {
+		name  string
+		grade Grade
+		want  color.RGBA
+	}{
+		{"unchanged", Grade{}, red},
+		{"greyscale", Grade{Saturation: -1}, color.RGBA{R: 0x4c, G: 0x4c, B: 0x4c, A: 0xff}},
+		{"tinted", Grade{Tint: &color.RGBA{R: 0x80, G: 0xff, B: 0xff, A: 0xff}}, color.RGBA{R: 0x80, A: 0xff}},
+	} {
+		m := c.grade.colorM()
+		r, g, b, a := m.Apply(red).RGBA()
+		got := color.RGBA{R: uint8(r >> 8), G: uint8(g >> 8), B: uint8(b >> 8), A: uint8(a >> 8)}
+		if channelDiff(got, c.want) > 2 {
+			t.Errorf("%v: graded red to %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+// The largest difference between the channels of two colors
+func channelDiff(a, b color.RGBA) int {
+	d := 0
+	for _, p := range [][2]uint8{{a.R, b.R}, {a.G, b.G}, {a.B, b.B}, {a.A, b.A}} {
+		if x := int(p[0]) - int(p[1]); x > d {
+			d = x
+		} else if -x > d {
+			d = -x
+		}
+	}
+	return d
+}
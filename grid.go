@@ -0,0 +1,65 @@
+package main
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+	"image/color"
+	"math"
+)
+
+// Units between the grid's heavier lines when the settings don't say
+const defaultGridMajor = 10
+
+// Grid lines closer together than this many pixels are left out, so a zoomed out grid doesn't fill the screen
+const minGridSpacing = 6
+
+var (
+	gridColor      = color.RGBA{R: 0x30, G: 0x30, B: 0x30, A: 0x30}
+	gridMajorColor = color.RGBA{R: 0x60, G: 0x60, B: 0x60, A: 0x60}
+	// The x axis is drawn red and the y axis green, as in most editors
+	gridXAxisColor = color.RGBA{R: 0xc0, G: 0x40, B: 0x40, A: 0xc0}
+	gridYAxisColor = color.RGBA{R: 0x40, G: 0xc0, B: 0x40, A: 0xc0}
+)
+
+// The world units between grid lines and between its heavier lines, at the given zoom. Lines are a unit apart unless
+// that would put them closer than minGridSpacing, in which case the heavier lines take their place.
+func gridSteps(pixelsPerUnit float64, major int) (minor, heavy float64) {
+	if major < 2 {
+		major = defaultGridMajor
+	}
+	minor = 1
+	for minor*pixelsPerUnit < minGridSpacing {
+		minor *= float64(major)
+	}
+	return minor, minor * float64(major)
+}
+
+// The color and pixel thickness of the grid line at the given coordinate, which is an axis if it's 0
+func gridLine(v, heavy float64, axis color.Color) (color.Color, float64) {
+	switch {
+	case v == 0:
+		return axis, 2
+	case math.Mod(v, heavy) == 0:
+		return gridMajorColor, 1
+	}
+	return gridColor, 1
+}
+
+// Draws a grid of world units behind the level if it's turned on in the settings, so distances and jump heights can be
+// judged while building
+func (e *Editor) drawGrid(screen *ebiten.Image) {
+	if !settings.Grid || e.c.sw == 0 || e.c.hw == 0 {
+		return
+	}
+	minor, heavy := gridSteps(float64(e.c.sw)/(2*e.c.hw), settings.GridMajor)
+	toScreen := e.c.ToScreen()
+	x0, x1 := e.c.x-e.c.hw, e.c.x+e.c.hw
+	y0, y1 := e.c.y-e.c.hh, e.c.y+e.c.hh
+	for x := math.Ceil(x0/minor) * minor; x <= x1; x += minor {
+		c, thickness := gridLine(x, heavy, gridYAxisColor)
+		drawline(screen, x, y0, x, y1, thickness, toScreen, c)
+	}
+	for y := math.Ceil(y0/minor) * minor; y <= y1; y += minor {
+		c, thickness := gridLine(y, heavy, gridXAxisColor)
+		drawline(screen, x0, y, x1, y, thickness, toScreen, c)
+	}
+}
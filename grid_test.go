@@ -0,0 +1,34 @@
+package main
+
+import "testing"
+
+func TestGridSteps(t *testing.T) {
+	for _, c := range []struct {
+		pixelsPerUnit float64
+		major         int
+		minor, heavy  float64
+	}{
+		{pixelsPerUnit: 30, major: 10, minor: 1, heavy: 10},
+		{pixelsPerUnit: 30, major: 4, minor: 1, heavy: 4},
+		{pixelsPerUnit: 2, major: 10, minor: 10, heavy: 100},
+		{pixelsPerUnit: 0.05, major: 10, minor: 1000, heavy: 10000},
+		{pixelsPerUnit: 2, major: 0, minor: 10, heavy: 100},
+	} {
+		minor, heavy := gridSteps(c.pixelsPerUnit, c.major)
+		if minor != c.minor || heavy != c.heavy {
+			t.Errorf("gridSteps(%v, %v) = %v, %v, want %v, %v", c.pixelsPerUnit, c.major, minor, heavy, c.minor, c.heavy)
+		}
+	}
+}
+
+func TestGridLine(t *testing.T) {
+	if c, thickness := gridLine(0, 10, gridXAxisColor); c != gridXAxisColor || thickness != 2 {
+		t.Errorf("line at 0 is %v, %v thick, want the axis", c, thickness)
+	}
+	if c, _ := gridLine(-20, 10, gridXAxisColor); c != gridMajorColor {
+		t.Errorf("line at -20 is %v, want a heavier line", c)
+	}
+	if c, _ := gridLine(3, 10, gridXAxisColor); c != gridColor {
+		t.Errorf("line at 3 is %v, want a light line", c)
+	}
+}
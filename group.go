@@ -0,0 +1,132 @@
+package main
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+	"image/color"
+	"math"
+)
+
+// Several selectables moved, scaled, rotated, copied and deleted together, e.g everything in a marquee. Its transform
+// starts as the box around its members.
+type GroupSelector struct {
+	members []Selectable
+	t       Mx
+}
+
+// Groups the selectables, or returns nil if there are none
+func group(members []Selectable) Selectable {
+	switch len(members) {
+	case 0:
+		return nil
+	case 1:
+		return members[0]
+	}
+	ts := make([]Mx, len(members))
+	for i, m := range members {
+		ts[i] = m.Transform()
+	}
+	return &GroupSelector{members: members, t: enclosing(ts)}
+}
+
+// The transform of the smallest unit square around the unit squares moved by the transforms, which can't be empty
+func enclosing(ts []Mx) Mx {
+	minx, miny, maxx, maxy := boxAround(ts[0])
+	for _, m := range ts[1:] {
+		x0, y0, x1, y1 := boxAround(m)
+		minx, miny = math.Min(minx, x0), math.Min(miny, y0)
+		maxx, maxy = math.Max(maxx, x1), math.Max(maxy, y1)
+	}
+	var t Mx
+	t.Scale(maxx-minx, maxy-miny)
+	t.Translate((minx+maxx)/2, (miny+maxy)/2)
+	return t
+}
+
+func (g *GroupSelector) Transform() Mx {
+	return g.t
+}
+
+// Moves every member the way the group moved. Art is placed last, so art pinned to a block in the group lands where
+// it should rather than moving twice.
+func (g *GroupSelector) SetTransform(m Mx) {
+	delta := g.t
+	delta.Invert()
+	delta.Concat(m.GeoM)
+	ts := make([]Mx, len(g.members))
+	for i, s := range g.members {
+		ts[i] = s.Transform()
+		ts[i].Concat(delta.GeoM)
+	}
+	for _, art := range []bool{false, true} {
+		for i, s := range g.members {
+			if _, ok := s.(*ArtSelector); ok == art {
+				s.SetTransform(ts[i])
+			}
+		}
+	}
+	g.t = m
+}
+
+func (g *GroupSelector) Delete() {
+	for _, s := range g.members {
+		if del, ok := s.(Deletable); ok {
+			del.Delete()
+		}
+	}
+}
+
+// Pastes the members which can be copied, grouped together. Copied art pinned to a copied block is pinned to the
+// block's copy, as in clipOf.
+func (g *GroupSelector) Paste() Selectable {
+	var pasted []Selectable
+	// where each copied block's copy is, by the original's index
+	blocks := make(map[int]int)
+	for _, s := range g.members {
+		kopy, ok := s.(Copyable)
+		if !ok {
+			continue
+		}
+		pasted = append(pasted, kopy.Paste())
+		if b, ok := s.(*BlockSelector); ok {
+			for i, o := range b.l.Blocks {
+				if o == b.b {
+					blocks[i] = len(b.l.Blocks) - 1
+				}
+			}
+		}
+	}
+	for _, p := range pasted {
+		if a, ok := p.(*ArtSelector); ok && a.a.Parent != nil {
+			if i, ok := blocks[*a.a.Parent]; ok {
+				a.a.Parent = &i
+			}
+		}
+	}
+	return group(pasted)
+}
+
+// The selectables at least partly inside the box between the two corners, in world units
+func (s *Selector) inside(x0, y0, x1, y1 float64) []Selectable {
+	minx, maxx := math.Min(x0, x1), math.Max(x0, x1)
+	miny, maxy := math.Min(y0, y1), math.Max(y0, y1)
+	var in []Selectable
+	for _, se := range s.Selectables {
+		bx0, by0, bx1, by1 := boxAround(se.Transform())
+		if bx1 >= minx && bx0 <= maxx && by1 >= miny && by0 <= maxy {
+			in = append(in, se)
+		}
+	}
+	return in
+}
+
+// Draws the marquee being dragged out from where it started to the cursor
+func (s *Selector) drawMarquee(screen *ebiten.Image) {
+	geom := s.C.ToScreen()
+	x0, y0 := s.from.X, s.from.Y
+	x1, y1 := s.C.Cursor()
+	c := color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	drawline(screen, x0, y0, x1, y0, 1, geom, c)
+	drawline(screen, x1, y0, x1, y1, 1, geom, c)
+	drawline(screen, x1, y1, x0, y1, 1, geom, c)
+	drawline(screen, x0, y1, x0, y0, 1, geom, c)
+}
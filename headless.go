@@ -0,0 +1,201 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/ByteArena/box2d"
+	"github.com/hajimehoshi/ebiten/v2"
+	"os"
+)
+
+// Source of the player's input to a game. Games read the keyboard and mouse by default, but can be driven by a Script
+// instead to simulate them without a window.
+type Input interface {
+	IsKeyPressed(k ebiten.Key) bool
+	IsMouseButtonPressed(m ebiten.MouseButton) bool
+	Wheel() (float64, float64)
+	// The cursor's position in world units, as seen by the given camera
+	Cursor(c *Camera) (float64, float64)
+}
+
+// Reads input from the global input driver
+type liveInput struct{}
+
+func (liveInput) IsKeyPressed(k ebiten.Key) bool {
+	return driver.IsKeyPressed(k)
+}
+
+func (liveInput) IsMouseButtonPressed(m ebiten.MouseButton) bool {
+	return driver.IsMouseButtonPressed(m)
+}
+
+func (liveInput) Wheel() (float64, float64) {
+	return driver.Wheel()
+}
+
+func (liveInput) Cursor(c *Camera) (float64, float64) {
+	return c.Cursor()
+}
+
+// A change to scripted input
+type ScriptStep struct {
+	// Tick at which to apply the step, counting from 0 at the start of the simulation
+	Tick int
+	// Keys to start and stop holding, named like ebiten's keys e.g "D" or "Space"
+	Press, Release []string `json:",omitempty"`
+	// Mouse buttons to start and stop holding, "Left", "Right" or "Middle"
+	PressMouse, ReleaseMouse []string `json:",omitempty"`
+	// If set, moves the cursor to the given world position
+	Cursor *box2d.B2Vec2 `json:",omitempty"`
+}
+
+// Input which follows a list of steps, for simulating a game without a window.
+type Script struct {
+	Steps []ScriptStep
+
+	// Ticks run so far
+	tick  int
+	keys  map[ebiten.Key]bool
+	mouse map[ebiten.MouseButton]bool
+	// In world units
+	cursor box2d.B2Vec2
+}
+
+var mouseButtons = map[string]ebiten.MouseButton{
+	"Left":   ebiten.MouseButtonLeft,
+	"Right":  ebiten.MouseButtonRight,
+	"Middle": ebiten.MouseButtonMiddle,
+}
+
+// Finds the key with the given name
+func parseKey(name string) (ebiten.Key, error) {
+	for k := ebiten.Key(0); k <= ebiten.KeyMax; k++ {
+		if k.String() == name {
+			return k, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown key %q", name)
+}
+
+// Applies any steps for the current tick and advances to the next.
+func (s *Script) advance() error {
+	if s.keys == nil {
+		s.keys = make(map[ebiten.Key]bool)
+		s.mouse = make(map[ebiten.MouseButton]bool)
+	}
+	for _, step := range s.Steps {
+		if step.Tick != s.tick {
+			continue
+		}
+		for _, names := range []struct {
+			names []string
+			held  bool
+		}{{step.Press, true}, {step.Release, false}} {
+			for _, n := range names.names {
+				k, err := parseKey(n)
+				if err != nil {
+					return fmt.Errorf("tick %v: %w", step.Tick, err)
+				}
+				s.keys[k] = names.held
+			}
+		}
+		for _, names := range []struct {
+			names []string
+			held  bool
+		}{{step.PressMouse, true}, {step.ReleaseMouse, false}} {
+			for _, n := range names.names {
+				m, ok := mouseButtons[n]
+				if !ok {
+					return fmt.Errorf("tick %v: unknown mouse button %q", step.Tick, n)
+				}
+				s.mouse[m] = names.held
+			}
+		}
+		if step.Cursor != nil {
+			s.cursor = *step.Cursor
+		}
+	}
+	s.tick++
+	return nil
+}
+
+func (s *Script) IsKeyPressed(k ebiten.Key) bool {
+	return s.keys[k]
+}
+
+func (s *Script) IsMouseButtonPressed(m ebiten.MouseButton) bool {
+	return s.mouse[m]
+}
+
+func (s *Script) Wheel() (float64, float64) {
+	return 0, 0
+}
+
+func (s *Script) Cursor(c *Camera) (float64, float64) {
+	return s.cursor.X, s.cursor.Y
+}
+
+// Creates a game for the level that can run without a window. The level's audio is dropped and its art is left
+// unloaded, since neither is needed to simulate it.
+func NewHeadlessGame(l Level, s *Script) *Game {
+	l.BGAudio = nil
+	l.Ambients = nil
+	l.MusicZones = nil
+	triggers := make(map[string]Trigger)
+	for n, t := range l.Triggers {
+		t.Audio = nil
+		if t.Cutscene != nil {
+			c := *t.Cutscene
+			c.Sounds = nil
+			t.Cutscene = &c
+		}
+		triggers[n] = t
+	}
+	l.Triggers = triggers
+	g := NewGame()
+	g.in = s
+	l.apply(g)
+	return g
+}
+
+// Runs the given number of ticks of a headless game. Ticks run back to back rather than in real time, and a script
+// driving the game is advanced before each one.
+func Simulate(g *Game, ticks int) error {
+	for i := 0; i < ticks; i++ {
+		err := g.tick()
+		if err != nil {
+			return fmt.Errorf("tick %v: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// Simulates the level at the given path for a number of ticks without a window, and prints where the player ends up.
+// If a script path is given, it is decoded from JSON to drive the player.
+func simulateLevel(path string, ticks int, script string) error {
+	var l Level
+	err := l.decode(path)
+	if err != nil {
+		return err
+	}
+	var s Script
+	if script != "" {
+		f, err := os.Open(script)
+		if err != nil {
+			return fmt.Errorf("open script: %w", err)
+		}
+		defer f.Close()
+		err = json.NewDecoder(f).Decode(&s)
+		if err != nil {
+			return fmt.Errorf("decode script: %w", err)
+		}
+	}
+	g := NewHeadlessGame(l, &s)
+	err = Simulate(g, ticks)
+	if err != nil {
+		return err
+	}
+	pos, v := g.p.b.GetPosition(), g.p.b.GetLinearVelocity()
+	fmt.Printf("After %v ticks the player is at %.3f, %.3f moving at %.3f, %.3f\n", ticks, pos.X, pos.Y, v.X, v.Y)
+	return nil
+}
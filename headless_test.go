@@ -0,0 +1,497 @@
+package main
+
+import (
+	"github.com/ByteArena/box2d"
+	"math"
+	"testing"
+)
+
+// A level with a wide floor whose top is at y = 0.25, and the player spawning above it.
+func floorLevel() Level {
+	l := NewLevel()
+	var floor Mx
+	floor.Scale(100, 0.5)
+	l.Blocks = []*Block{{T: floor}}
+	l.Spawn.Y = 3
+	return l
+}
+
+func TestPlayerLandsOnFloor(t *testing.T) {
+	s := &Script{}
+	g := NewHeadlessGame(floorLevel(), s)
+	err := Simulate(g, 3*ticksPerSecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pos := g.p.b.GetPosition()
+	// resting with its bottom on the floor
+	if pos.Y < 0.7 || pos.Y > 0.8 {
+		t.Errorf("player at y %v, want about 0.75", pos.Y)
+	}
+	if !g.p.hasJump {
+		t.Errorf("player landed without regaining their jump")
+	}
+}
+
+func TestPlayerWalksRight(t *testing.T) {
+	s := &Script{Steps: []ScriptStep{
+		{Tick: ticksPerSecond, Press: []string{"D"}},
+		{Tick: 2 * ticksPerSecond, Release: []string{"D"}},
+	}}
+	g := NewHeadlessGame(floorLevel(), s)
+	err := Simulate(g, 3*ticksPerSecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x := g.p.b.GetPosition().X; x < 1 {
+		t.Errorf("player at x %v after walking right, want at least 1", x)
+	}
+}
+
+func TestScriptRejectsUnknownKeys(t *testing.T) {
+	s := &Script{Steps: []ScriptStep{{Press: []string{"NotAKey"}}}}
+	g := NewHeadlessGame(floorLevel(), s)
+	if err := Simulate(g, 1); err == nil {
+		t.Errorf("expected an error for an unknown key")
+	}
+}
+
+func TestTriggerSendsPlayerToSpawn(t *testing.T) {
+	l := floorLevel()
+	l.Spawns = map[string]box2d.B2Vec2{"far": {X: 20, Y: 3}}
+	l.Triggers["jump"] = Trigger{Spawn: "far"}
+	s := &Script{Steps: []ScriptStep{
+		{Tick: ticksPerSecond, Press: []string{"W"}},
+	}}
+	g := NewHeadlessGame(l, s)
+	err := Simulate(g, 2*ticksPerSecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x := g.p.b.GetPosition().X; x < 19 || x > 21 {
+		t.Errorf("player at x %v after jumping, want about 20", x)
+	}
+}
+
+func TestSwitchTogglesTargets(t *testing.T) {
+	l := floorLevel()
+	var wall, button Mx
+	wall.Scale(1, 4)
+	wall.Translate(10, 2)
+	// around the player as they land
+	button.Scale(2, 1)
+	button.Translate(0, 1)
+	l.Blocks = append(l.Blocks, &Block{T: wall})
+	l.Switches = []*Switch{{T: button, Trigger: "press", Targets: []int{1}}}
+	l.Spawns = map[string]box2d.B2Vec2{"far": {X: 20, Y: 3}}
+	l.Triggers["press"] = Trigger{Spawn: "far"}
+	s := &Script{}
+	g := NewHeadlessGame(l, s)
+	err := Simulate(g, 2*ticksPerSecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.entities[1].b.IsActive() {
+		t.Errorf("wall still there after the player touched the switch")
+	}
+	if x := g.p.b.GetPosition().X; x < 19 || x > 21 {
+		t.Errorf("player at x %v after touching the switch, want about 20", x)
+	}
+}
+
+// How far the player gets along a floor of the given material, walking right for a second and then letting go.
+func walkDistance(t *testing.T, material string) float64 {
+	l := floorLevel()
+	l.Blocks[0].Material = material
+	s := &Script{Steps: []ScriptStep{
+		{Tick: ticksPerSecond, Press: []string{"D"}},
+		{Tick: 2 * ticksPerSecond, Release: []string{"D"}},
+	}}
+	g := NewHeadlessGame(l, s)
+	err := Simulate(g, 3*ticksPerSecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g.p.b.GetPosition().X
+}
+
+func TestMaterialsChangeWalking(t *testing.T) {
+	normal := walkDistance(t, "")
+	if mud := walkDistance(t, materialMud); mud >= normal {
+		t.Errorf("walked %v through mud, want less than %v on a normal floor", mud, normal)
+	}
+	if ice := walkDistance(t, materialIce); ice <= normal {
+		t.Errorf("slid %v on ice, want more than %v on a normal floor", ice, normal)
+	}
+}
+
+func TestSpringBouncesPlayer(t *testing.T) {
+	l := floorLevel()
+	l.Blocks[0].Restitution = 1
+	s := &Script{}
+	g := NewHeadlessGame(l, s)
+	// lands after about 0.7s
+	err := Simulate(g, ticksPerSecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	highest := 0.0
+	for i := 0; i < ticksPerSecond; i++ {
+		err := Simulate(g, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		highest = math.Max(highest, g.p.b.GetPosition().Y)
+	}
+	if highest < 2 {
+		t.Errorf("player bounced to y %v, want above 2", highest)
+	}
+}
+
+func TestStationSwapsCharacter(t *testing.T) {
+	l := floorLevel()
+	l.Character = "scout"
+	var station Mx
+	// around the player as they land
+	station.Scale(2, 2)
+	station.Translate(0, 1)
+	l.Stations = []*Station{{T: station, Character: "heavy"}}
+	s := &Script{}
+	g := NewHeadlessGame(l, s)
+	if g.p.w != 0.6 {
+		t.Fatalf("player starts %v wide, want the scout's 0.6", g.p.w)
+	}
+	err := Simulate(g, 2*ticksPerSecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.p.character != "heavy" || g.p.w != 1.5 || g.p.h != 1.5 {
+		t.Fatalf("player is %q sized %vx%v after entering the station, want heavy", g.p.character, g.p.w, g.p.h)
+	}
+	// resting on the floor with the bigger box
+	if y := g.p.b.GetPosition().Y; math.Abs(y-1) > 0.05 {
+		t.Errorf("player at y %v, want about 1", y)
+	}
+}
+
+func TestPlayerJumpsOffWalls(t *testing.T) {
+	l := floorLevel()
+	var wall Mx
+	wall.Scale(1, 10)
+	wall.Translate(2.5, 5)
+	l.Blocks = append(l.Blocks, &Block{T: wall})
+	// falling alongside the wall, pressed against it
+	l.Spawn = box2d.B2Vec2{X: 1.45, Y: 8}
+	s := &Script{Steps: []ScriptStep{
+		{Tick: 0, Press: []string{"D"}},
+		{Tick: 20, Press: []string{"W"}, Release: []string{"D"}},
+	}}
+	g := NewHeadlessGame(l, s)
+	err := Simulate(g, 25)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := g.p.b.GetLinearVelocity()
+	if v.X > -1 {
+		t.Errorf("player moving at %.3f, %.3f after jumping off the wall, want kicked away to the left", v.X, v.Y)
+	}
+	if v.Y <= 0 {
+		t.Errorf("player moving at %.3f, %.3f after jumping off the wall, want moving up", v.X, v.Y)
+	}
+}
+
+func TestPlayerScaleResizesCharacters(t *testing.T) {
+	l := floorLevel()
+	l.PlayerScale = 2
+	g := NewHeadlessGame(l, &Script{})
+	err := Simulate(g, 2*ticksPerSecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.p.w != 2 || g.p.h != 2 {
+		t.Fatalf("player is %vx%v at player scale 2, want 2x2", g.p.w, g.p.h)
+	}
+	if y := g.p.b.GetPosition().Y; math.Abs(y-1.25) > 0.05 {
+		t.Errorf("player at y %v, want resting on the floor at about 1.25", y)
+	}
+}
+
+func TestTouchingNamedBlockFiresTrigger(t *testing.T) {
+	l := floorLevel()
+	l.Blocks[0].Name = "floor"
+	l.Spawns = map[string]box2d.B2Vec2{"far": {X: 20, Y: 3}}
+	l.Triggers[touchEvent("floor")] = Trigger{Spawn: "far"}
+	g := NewHeadlessGame(l, &Script{})
+	err := Simulate(g, 2*ticksPerSecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x := g.p.b.GetPosition().X; x < 19 || x > 21 {
+		t.Errorf("player at x %v after landing on the floor, want about 20", x)
+	}
+}
+
+func TestHazardsKnockBackAndHurtPlayer(t *testing.T) {
+	l := floorLevel()
+	l.Blocks[0].Material = materialHazard
+	s := &Script{}
+	g := NewHeadlessGame(l, s)
+	// lands after about 0.7s
+	err := Simulate(g, ticksPerSecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.p.health != playerHealth-1 || !g.p.invincible {
+		t.Fatalf("player has %v health, invincible %v after landing on a hazard, want %v and invincible",
+			g.p.health, g.p.invincible, playerHealth-1)
+	}
+	if v := g.p.b.GetLinearVelocity(); v.Y <= 0 {
+		t.Errorf("player moving at %.3f, %.3f after landing on a hazard, want knocked up", v.X, v.Y)
+	}
+	// bounces on the hazard until it runs out of health
+	for i := 0; i < 10*ticksPerSecond && g.stats.deaths == 0; i++ {
+		err := Simulate(g, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if g.stats.deaths != 1 || g.p.health != playerHealth {
+		t.Errorf("player died %v times and has %v health, want 1 death and health restored", g.stats.deaths,
+			g.p.health)
+	}
+}
+
+func TestEnemyShootsPlayer(t *testing.T) {
+	l := floorLevel()
+	l.Enemies = []*Enemy{{T: boxBetween(4, 0.25, 5, 2.25), Interval: 0.5}}
+	s := &Script{}
+	g := NewHeadlessGame(l, s)
+	err := Simulate(g, 2*ticksPerSecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.p.health >= playerHealth {
+		t.Errorf("player has %v health after standing by an enemy, want hurt", g.p.health)
+	}
+	for _, e := range g.entities {
+		if e.hostile && e.b.GetPosition().X < 1 {
+			t.Errorf("enemy bullet at %v still there after hitting the player", e.b.GetPosition())
+		}
+	}
+}
+
+func TestPlayerShootsEnemy(t *testing.T) {
+	l := floorLevel()
+	// out of range of the player, so it doesn't shoot back
+	l.Enemies = []*Enemy{{T: boxBetween(4, 0.25, 5, 2.25), Health: 1, Range: 0.1, Trigger: "destroyed"}}
+	l.Spawns = map[string]box2d.B2Vec2{"far": {X: 20, Y: 3}}
+	l.Triggers["destroyed"] = Trigger{Spawn: "far"}
+	s := &Script{Steps: []ScriptStep{
+		{Tick: ticksPerSecond, PressMouse: []string{"Right"}, Cursor: &box2d.B2Vec2{X: 4.5, Y: 1.25}},
+		{Tick: ticksPerSecond + 1, ReleaseMouse: []string{"Right"}},
+	}}
+	g := NewHeadlessGame(l, s)
+	err := Simulate(g, 2*ticksPerSecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.foes[0].health != 0 || g.foes[0].e.b.IsActive() {
+		t.Errorf("enemy has %v health after being shot, want destroyed", g.foes[0].health)
+	}
+	if x := g.p.b.GetPosition().X; x < 19 || x > 21 {
+		t.Errorf("player at x %v after destroying the enemy, want about 20", x)
+	}
+	if g.p.health != playerHealth {
+		t.Errorf("player has %v health after shooting, want %v", g.p.health, playerHealth)
+	}
+}
+
+func TestBossMovesThroughPhases(t *testing.T) {
+	l := floorLevel()
+	l.Enemies = []*Enemy{{T: boxBetween(4, 0.25, 7, 3.25), Phases: []Phase{
+		{Health: 1},
+		{Health: 2, Pattern: patternRing, Count: 8, Trigger: "enraged"},
+	}}}
+	l.Spawns = map[string]box2d.B2Vec2{"far": {X: 20, Y: 3}}
+	l.Triggers["enraged"] = Trigger{Spawn: "far"}
+	g := NewHeadlessGame(l, &Script{})
+	f := g.foes[0]
+	g.hits = append(g.hits, f)
+	g.hitFoes()
+	if f.phase != 1 || f.health != 2 {
+		t.Fatalf("boss in phase %v with %v health after its first phase, want phase 1 with 2", f.phase, f.health)
+	}
+	if x := g.p.b.GetPosition().X; x != 20 {
+		t.Errorf("player at x %v after the boss's phase changed, want 20 from its trigger", x)
+	}
+	g.fireAt(f)
+	shot := 0
+	for _, e := range g.entities {
+		if e.hostile {
+			shot++
+		}
+	}
+	if shot != 8 {
+		t.Errorf("boss shot %v bullets in a ring, want 8", shot)
+	}
+	g.hits = append(g.hits, f, f)
+	g.hitFoes()
+	if f.health != 0 || f.e.b.IsActive() {
+		t.Errorf("boss has %v health after its last phase, want destroyed", f.health)
+	}
+}
+
+func TestHardLandingShakesCamera(t *testing.T) {
+	for _, c := range []struct {
+		height float64
+		shakes bool
+	}{{3, false}, {30, true}} {
+		l := floorLevel()
+		l.Spawn.Y = c.height
+		g := NewHeadlessGame(l, &Script{})
+		shook := false
+		for i := 0; i < 4*ticksPerSecond; i++ {
+			if err := Simulate(g, 1); err != nil {
+				t.Fatal(err)
+			}
+			if x, y := g.c.shakeOffset(); x != 0 || y != 0 {
+				shook = true
+			}
+		}
+		if shook != c.shakes {
+			t.Errorf("camera shook %v after dropping from y %v, want %v", shook, c.height, c.shakes)
+		}
+		if g.c.shake != (Shake{}) {
+			t.Errorf("camera still shaking %v seconds after dropping from y %v", g.c.shaken, c.height)
+		}
+	}
+}
+
+func TestCameraStaysInBounds(t *testing.T) {
+	l := floorLevel()
+	bounds := boxBetween(-5, 0, 30, 20)
+	l.CameraBounds = &bounds
+	g := NewHeadlessGame(l, &Script{})
+	if err := Simulate(g, 3*ticksPerSecond); err != nil {
+		t.Fatal(err)
+	}
+	// the view is 24x16, so it's pushed up off the floor and right of the bounds' left edge
+	if g.c.x-g.c.hw < -5-1e-9 || g.c.y-g.c.hh < -1e-9 {
+		t.Errorf("camera view's bottom left at (%v, %v), want inside the bounds", g.c.x-g.c.hw, g.c.y-g.c.hh)
+	}
+	if math.Abs(g.c.y-8) > 1e-9 {
+		t.Errorf("camera at y %v with the player on the floor, want 8 at the bottom of the bounds", g.c.y)
+	}
+}
+
+func TestCameraZoneZoomsAndLocks(t *testing.T) {
+	l := floorLevel()
+	l.CameraZones = []*CameraZone{{T: boxBetween(-5, 0, 5, 10), Zoom: 2, Lock: true}}
+	g := NewHeadlessGame(l, &Script{})
+	if err := Simulate(g, 3*ticksPerSecond); err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(g.c.hh-16) > 0.01 {
+		t.Errorf("camera view half height %v in a zone zooming 2x, want about 16", g.c.hh)
+	}
+	if math.Abs(g.c.y-5) > 0.01 {
+		t.Errorf("camera at y %v in a zone locked at y 5, want about 5", g.c.y)
+	}
+	if hw, hh := g.unframed(); math.Abs(hw-12) > 1e-9 || math.Abs(hh-8) > 1e-9 {
+		t.Errorf("camera view unframed to %vx%v, want the default 12x8", hw, hh)
+	}
+}
+
+func TestDialoguePausesPlayer(t *testing.T) {
+	l := floorLevel()
+	l.Triggers["jump"] = Trigger{Dialogue: &Dialogue{Speaker: "Guide", Pages: []string{"Hi.", "Off you go."}}}
+	s := &Script{Steps: []ScriptStep{
+		{Tick: ticksPerSecond, Press: []string{"W"}},
+		{Tick: ticksPerSecond + 1, Release: []string{"W"}, Press: []string{"D"}},
+		// the first press skips to the end of the page, the second and third turn the pages
+		{Tick: 2 * ticksPerSecond, Press: []string{"Enter"}},
+		{Tick: 2*ticksPerSecond + 1, Release: []string{"Enter"}},
+		{Tick: 2*ticksPerSecond + 2, Press: []string{"Enter"}},
+		{Tick: 2*ticksPerSecond + 3, Release: []string{"Enter"}},
+		{Tick: 3 * ticksPerSecond, Press: []string{"Enter"}},
+		{Tick: 3*ticksPerSecond + 1, Release: []string{"Enter"}},
+	}}
+	g := NewHeadlessGame(l, s)
+	if err := Simulate(g, 2*ticksPerSecond+4); err != nil {
+		t.Fatal(err)
+	}
+	if g.dialogue == nil || g.dialogue.page != 1 {
+		t.Fatalf("dialogue %+v, want it on its second page", g.dialogue)
+	}
+	if g.dialogue.shown(TextStyle{}, 0) != "" {
+		t.Errorf("second page shows %q as soon as it's opened, want it typed out", g.dialogue.shown(TextStyle{}, 0))
+	}
+	if x := g.p.b.GetPosition().X; math.Abs(x) > 0.1 {
+		t.Errorf("player walked to x %v during dialogue, want them held still", x)
+	}
+	if err := Simulate(g, 2*ticksPerSecond); err != nil {
+		t.Fatal(err)
+	}
+	if g.dialogue != nil {
+		t.Errorf("dialogue still open after reading every page")
+	}
+	if x := g.p.b.GetPosition().X; x < 1 {
+		t.Errorf("player at x %v after dialogue closed, want them walking right", x)
+	}
+}
+
+func TestCutsceneMovesCameraAndArt(t *testing.T) {
+	l := floorLevel()
+	var door Mx
+	door.Translate(5, 1)
+	l.Art = []*Art{{T: door}}
+	l.Triggers["jump"] = Trigger{Cutscene: &Cutscene{
+		Camera: []CameraKey{{Time: 2, X: 20, Y: 5, Zoom: 2}},
+		Moves:  []ArtMove{{Art: 0, Keys: []ArtKey{{Time: 2, Y: 3}}}},
+	}}
+	s := &Script{Steps: []ScriptStep{
+		{Tick: ticksPerSecond, Press: []string{"W"}},
+		{Tick: ticksPerSecond + 1, Release: []string{"W"}, Press: []string{"D"}},
+	}}
+	g := NewHeadlessGame(l, s)
+	hw := g.c.hw
+	if err := Simulate(g, 2*ticksPerSecond); err != nil {
+		t.Fatal(err)
+	}
+	if g.cutscene == nil {
+		t.Fatalf("cutscene over halfway through, want it still playing")
+	}
+	if x := g.p.b.GetPosition().X; math.Abs(x) > 0.1 {
+		t.Errorf("player walked to x %v during the cutscene, want them held still", x)
+	}
+	if g.c.x < 5 || g.c.hw < 1.25*hw {
+		t.Errorf("camera at x %v with half width %v, want it panning and zooming out towards x 20", g.c.x, g.c.hw)
+	}
+	if err := Simulate(g, 3*ticksPerSecond); err != nil {
+		t.Fatal(err)
+	}
+	if g.cutscene != nil {
+		t.Fatalf("cutscene still playing after it ended")
+	}
+	if math.Abs(g.c.hw-hw) > 1e-9 {
+		t.Errorf("camera's half width %v after the cutscene, want its zoom undone back to %v", g.c.hw, hw)
+	}
+	at, _ := g.artTransform(l.Art[0])
+	if x, y := at.Apply(0, 0); math.Abs(x-5) > 1e-9 || math.Abs(y-4) > 1e-9 {
+		t.Errorf("art at (%v, %v) after the cutscene, want it moved up to (5, 4)", x, y)
+	}
+	if x := g.p.b.GetPosition().X; x < 1 {
+		t.Errorf("player at x %v after the cutscene, want them walking right", x)
+	}
+}
+
+func TestCheckpointsArentDeaths(t *testing.T) {
+	l := floorLevel()
+	l.Spawns = map[string]box2d.B2Vec2{"checkpoint": {X: 5, Y: 3}}
+	l.Triggers["reached"] = Trigger{Spawn: "checkpoint"}
+	g := NewHeadlessGame(l, &Script{})
+	g.fire("reached", 0, 0)
+	if p := g.p.b.GetPosition(); p.X != 5 || g.stats.deaths != 0 {
+		t.Errorf("player at %v with %v deaths after a checkpoint, want at 5 with none", p, g.stats.deaths)
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"image/color"
+	"math"
+)
+
+// Corners of the screen HUD widgets are anchored to
+type Anchor int
+
+const (
+	anchorTopLeft Anchor = iota
+	anchorTopRight
+	anchorBottomLeft
+	anchorBottomRight
+	anchors
+)
+
+const (
+	// Screen height in pixels the HUD is drawn at its own size for. Taller screens scale it up and shorter ones down.
+	hudReferenceHeight = 480
+	// Unscaled pixels between the screen's edges and widgets, and between widgets sharing a corner
+	hudMargin = 10
+	hudGap    = 4
+)
+
+var (
+	healthBarColor      = color.RGBA{R: 0xe0, G: 0x40, B: 0x40, A: 0xff}
+	healthBarBackground = color.RGBA{R: 0x40, G: 0x40, B: 0x40, A: 0xc0}
+)
+
+// Something drawn over the game in a corner of the screen, like the player's health
+type Widget interface {
+	// Size in unscaled pixels of the widget this frame, with 0 hiding it
+	Size(g *Game) (w, h int)
+	// Draws the widget with its top left corner at x, y, scaled by scale
+	Draw(screen *ebiten.Image, g *Game, x, y int, scale float64)
+}
+
+// The widgets drawn over the game, stacked away from the corners they're anchored to in the order they were added
+type HUD struct {
+	widgets [anchors][]Widget
+}
+
+// Adds a widget to the HUD in the given corner, after any already there
+func (h *HUD) Add(a Anchor, w Widget) {
+	h.widgets[a] = append(h.widgets[a], w)
+}
+
+// How much the HUD is scaled on a screen of the given height, in quarter steps so text doesn't need a new font size
+// every time the window is resized
+func hudScale(height int) float64 {
+	return math.Max(0.5, math.Round(4*float64(height)/hudReferenceHeight)/4)
+}
+
+func (h *HUD) Draw(screen *ebiten.Image, g *Game) {
+	scale := hudScale(g.c.sh)
+	margin := int(hudMargin * scale)
+	for a, widgets := range h.widgets {
+		offset := margin
+		for _, w := range widgets {
+			ww, wh := w.Size(g)
+			if ww == 0 || wh == 0 {
+				continue
+			}
+			sw, sh := int(float64(ww)*scale), int(float64(wh)*scale)
+			x, y := margin, offset
+			if a := Anchor(a); a == anchorTopRight || a == anchorBottomRight {
+				x = g.c.sw - margin - sw
+			}
+			if a := Anchor(a); a == anchorBottomLeft || a == anchorBottomRight {
+				y = g.c.sh - offset - sh
+			}
+			w.Draw(screen, g, x, y, scale)
+			offset += sh + int(hudGap*scale)
+		}
+	}
+}
+
+// A widget showing some text, hidden while the text is empty
+type TextWidget func(g *Game) string
+
+func (t TextWidget) Size(g *Game) (int, int) {
+	s := t(g)
+	if s == "" {
+		return 0, 0
+	}
+	return measureText(s, TextStyle{})
+}
+
+func (t TextWidget) Draw(screen *ebiten.Image, g *Game, x, y int, scale float64) {
+	drawText(screen, t(g), x, y, TextStyle{Size: defaultTextSize * scale})
+}
+
+// Shows the player's health once they've been hurt
+type healthBar struct{}
+
+func (healthBar) Size(g *Game) (int, int) {
+	if g.p.health >= playerHealth {
+		return 0, 0
+	}
+	return 120, 12
+}
+
+func (healthBar) Draw(screen *ebiten.Image, g *Game, x, y int, scale float64) {
+	w, h := 120*scale, 12*scale
+	ebitenutil.DrawRect(screen, float64(x), float64(y), w, h, healthBarBackground)
+	left := math.Max(0, float64(g.p.health)) / playerHealth
+	ebitenutil.DrawRect(screen, float64(x), float64(y), w*left, h, healthBarColor)
+}
+
+// The widgets every game starts with
+func defaultHUD() HUD {
+	var h HUD
+	h.Add(anchorTopRight, TextWidget(func(g *Game) string {
+		// the run's time, once the level has a goal to race to
+		if g.goal == nil {
+			return ""
+		}
+		msg := formatRunTime(g.timer.elapsed(g.time))
+		if g.timer.end != 0 {
+			msg += " Finished!"
+		}
+		return msg
+	}))
+	h.Add(anchorBottomLeft, healthBar{})
+	h.Add(anchorBottomRight, TextWidget(func(g *Game) string {
+		left := 0
+		for _, f := range g.foes {
+			if f.health > 0 {
+				left++
+			}
+		}
+		if left == 0 {
+			return ""
+		}
+		return fmt.Sprintf("Enemies %v/%v", left, len(g.foes))
+	}))
+	return h
+}
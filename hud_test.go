@@ -0,0 +1,63 @@
+package main
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+	"testing"
+)
+
+// A widget of a fixed size which records where it was drawn
+type boxWidget struct {
+	w, h   int
+	drawn  bool
+	x, y   int
+	scaled float64
+}
+
+func (b *boxWidget) Size(g *Game) (int, int) {
+	return b.w, b.h
+}
+
+func (b *boxWidget) Draw(screen *ebiten.Image, g *Game, x, y int, scale float64) {
+	b.drawn, b.x, b.y, b.scaled = true, x, y, scale
+}
+
+func TestHUDStacksWidgets(t *testing.T) {
+	g := &Game{}
+	g.c.sw, g.c.sh = 1280, 960
+	first, second, hidden := &boxWidget{w: 50, h: 20}, &boxWidget{w: 30, h: 10}, &boxWidget{}
+	corner := &boxWidget{w: 40, h: 10}
+	var h HUD
+	h.Add(anchorBottomRight, first)
+	h.Add(anchorBottomRight, hidden)
+	h.Add(anchorBottomRight, second)
+	h.Add(anchorTopLeft, corner)
+	h.Draw(nil, g)
+
+	if hidden.drawn {
+		t.Errorf("drew a widget with no size")
+	}
+	// twice the reference height, so everything is doubled
+	if first.scaled != 2 {
+		t.Errorf("scaled widgets by %v, want 2", first.scaled)
+	}
+	if first.x != 1280-20-100 || first.y != 960-20-40 {
+		t.Errorf("drew the first widget at %v,%v, want it in the bottom right corner", first.x, first.y)
+	}
+	if second.x != 1280-20-60 || second.y != first.y-8-20 {
+		t.Errorf("drew the second widget at %v,%v, want it stacked above the first", second.x, second.y)
+	}
+	if corner.x != 20 || corner.y != 20 {
+		t.Errorf("drew the top left widget at %v,%v, want 20,20", corner.x, corner.y)
+	}
+}
+
+func TestHUDScale(t *testing.T) {
+	for _, c := range []struct {
+		height int
+		want   float64
+	}{{480, 1}, {720, 1.5}, {1080, 2.25}, {100, 0.5}} {
+		if got := hudScale(c.height); got != c.want {
+			t.Errorf("hudScale(%v) = %v, want %v", c.height, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"github.com/ByteArena/box2d"
+)
+
+// Hits from hazards the player can take before they're sent back to the spawn point they last started from
+const playerHealth = 3
+
+const (
+	// Speed in world units per second the player is knocked away from a hazard at
+	knockbackSpeed = 8
+	// Seconds after being hurt before the player can be hurt again
+	invincibleSeconds = 1
+	// Ticks the player's sprite spends shown and hidden in turn while they can't be hurt
+	flashTicks = 4
+)
+
+// Hurts the player if they're touching a hazard, an enemy or an enemy's bullet, knocking them away from it. The player can't be hurt again for
+// invincibleSeconds after, so staying in contact doesn't drain their health all at once.
+func (g *Game) hurt() {
+	if g.p.invincible {
+		return
+	}
+	normal, ok := g.harmed()
+	if !ok {
+		return
+	}
+	pos := g.p.b.GetPosition()
+	g.p.health--
+	if g.p.health <= 0 {
+		g.respawn(g.p.spawn)
+		g.stats.deaths++
+		g.fire("died", pos.X, pos.Y)
+		return
+	}
+	// replace any speed towards the hazard with the knockback
+	toward := box2d.B2Vec2Dot(g.p.b.GetLinearVelocity(), normal)
+	if toward < knockbackSpeed {
+		impulse := box2d.B2Vec2MulScalar(g.p.b.GetMass()*(knockbackSpeed-toward), normal)
+		g.p.b.ApplyLinearImpulseToCenter(impulse, true)
+	}
+	g.c.Shake(hurtShake)
+	g.p.invincible = true
+	g.schedule.After(secondsToTicks(invincibleSeconds), func() {
+		g.p.invincible = false
+	})
+	g.fire("hurt", pos.X, pos.Y)
+}
+
+// Whether the player is touching something which hurts them, and if so the contact's normal pointing away from it
+// towards the player
+func (g *Game) harmed() (box2d.B2Vec2, bool) {
+	for next := g.p.b.GetContactList(); next != nil; next = next.Next {
+		if !next.Contact.IsTouching() {
+			continue
+		}
+		e, _ := next.Other.GetUserData().(*Entity)
+		if material(next.Other) == materialHazard || (e != nil && (e.hostile || e.foe != nil)) {
+			return contactNormal(next.Contact, g.p.b), true
+		}
+	}
+	return box2d.B2Vec2{}, false
+}
+
+// Whether the player's sprite is hidden this tick, flashing while they can't be hurt
+func (g *Game) flashing() bool {
+	return g.p.invincible && g.time/flashTicks%2 == 0
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Name of the index written to a directory of levels when no other path is given
+const indexName = "index.json"
+
+// Size in pixels of the thumbnails drawn for indexed levels
+const (
+	thumbnailWidth  = 240
+	thumbnailHeight = 160
+)
+
+var (
+	thumbnailBackground = color.RGBA{R: 0x20, G: 0x20, B: 0x30, A: 0xff}
+	thumbnailSpawn      = color.RGBA{R: 0xff, G: 0xd0, B: 0x40, A: 0xff}
+	thumbnailGoal       = color.RGBA{R: 0x40, G: 0xd0, B: 0x60, A: 0xff}
+)
+
+// A summary of each level in a directory, for browsing levels without loading them
+type LevelIndex struct {
+	Levels []IndexedLevel
+}
+
+// The index entry of one level. Paths are relative to the indexed directory.
+type IndexedLevel struct {
+	// The level's file name without its extensions
+	Name   string
+	Path   string
+	Author string `json:",omitempty"`
+	// See levelHash. Matches the level's saved hash unless it was modified outside the editor.
+	Hash      string
+	Thumbnail string
+	Stats     LevelStats
+}
+
+// Counts of what a level contains, and its size
+type LevelStats struct {
+	Blocks, Art, Triggers, Spawns, Switches, Characters int
+	// Whether the level has a goal to race to
+	Goal bool
+	// Size in world units of the box around the level's blocks
+	Width, Height float64
+}
+
+// Where the thumbnail of the level at the given path is saved
+func thumbnailPath(level string) string {
+	return level + ".png"
+}
+
+// Whether the file at the given path looks like a level, going by its name
+func isLevelFile(path string) bool {
+	path = strings.TrimSuffix(path, compressedSuffix)
+	switch filepath.Ext(path) {
+	case ".json", ".lvl", ".tmj":
+		return true
+	}
+	return false
+}
+
+// The corners of the axis aligned box around the level's blocks, spawn and goal
+func (l *Level) bounds() (minx, miny, maxx, maxy float64) {
+	minx, miny = l.Spawn.X, l.Spawn.Y
+	maxx, maxy = minx, miny
+	add := func(m Mx) {
+		for _, corner := range [][2]float64{{-0.5, -0.5}, {0.5, -0.5}, {0.5, 0.5}, {-0.5, 0.5}} {
+			x, y := m.Apply(corner[0], corner[1])
+			minx, miny = math.Min(minx, x), math.Min(miny, y)
+			maxx, maxy = math.Max(maxx, x), math.Max(maxy, y)
+		}
+	}
+	for _, b := range l.Blocks {
+		add(b.T)
+	}
+	if l.Goal != nil {
+		add(*l.Goal)
+	}
+	return
+}
+
+func (l *Level) stats() LevelStats {
+	minx, miny, maxx, maxy := l.bounds()
+	return LevelStats{
+		Blocks:     len(l.Blocks),
+		Art:        len(l.Art),
+		Triggers:   len(l.Triggers),
+		Spawns:     1 + len(l.Spawns),
+		Switches:   len(l.Switches),
+		Characters: len(l.Characters),
+		Goal:       l.Goal != nil,
+		Width:      maxx - minx,
+		Height:     maxy - miny,
+	}
+}
+
+// Draws an overview of the level's blocks, spawn point and goal, without loading any of its assets, so it can be done
+// without a window.
+func (l *Level) thumbnail() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, thumbnailWidth, thumbnailHeight))
+	draw.Draw(img, img.Rect, image.NewUniform(thumbnailBackground), image.Point{}, draw.Src)
+	// fit the level in the image with a margin, keeping its proportions
+	minx, miny, maxx, maxy := l.bounds()
+	scale := math.Min(0.9*thumbnailWidth/math.Max(maxx-minx, 1), 0.9*thumbnailHeight/math.Max(maxy-miny, 1))
+	var toImage Mx
+	toImage.Translate(-(minx+maxx)/2, -(miny+maxy)/2)
+	toImage.Scale(scale, -scale)
+	toImage.Translate(thumbnailWidth/2, thumbnailHeight/2)
+	fill := func(m Mx, c color.Color) {
+		m.Concat(toImage.GeoM)
+		inv := m
+		inv.Invert()
+		x0, y0, x1, y1 := math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+		for _, corner := range [][2]float64{{-0.5, -0.5}, {0.5, -0.5}, {0.5, 0.5}, {-0.5, 0.5}} {
+			x, y := m.Apply(corner[0], corner[1])
+			x0, y0 = math.Min(x0, x), math.Min(y0, y)
+			x1, y1 = math.Max(x1, x), math.Max(y1, y)
+		}
+		r := image.Rect(int(x0), int(y0), int(math.Ceil(x1)), int(math.Ceil(y1))).Intersect(img.Rect)
+		for py := r.Min.Y; py < r.Max.Y; py++ {
+			for px := r.Min.X; px < r.Max.X; px++ {
+				lx, ly := inv.Apply(float64(px)+0.5, float64(py)+0.5)
+				if lx >= -0.5 && lx <= 0.5 && ly >= -0.5 && ly <= 0.5 {
+					img.Set(px, py, c)
+				}
+			}
+		}
+	}
+	for _, b := range l.Blocks {
+		c, ok := materialColors[b.Material]
+		if !ok {
+			c = fastBlockColor
+		}
+		fill(b.T, c)
+	}
+	if l.Goal != nil {
+		fill(*l.Goal, thumbnailGoal)
+	}
+	// a few pixels across whatever the level's size
+	dot := 4 / scale
+	fill(boxBetween(l.Spawn.X-dot/2, l.Spawn.Y-dot/2, l.Spawn.X+dot/2, l.Spawn.Y+dot/2), thumbnailSpawn)
+	return img
+}
+
+// Saves a PNG thumbnail of the level to the given path
+func (l *Level) saveThumbnail(path string) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0777)
+	if err != nil {
+		return fmt.Errorf("open file to save thumbnail: %w", err)
+	}
+	defer f.Close()
+	err = png.Encode(f, l.thumbnail())
+	if err != nil {
+		return fmt.Errorf("encode thumbnail: %w", err)
+	}
+	return nil
+}
+
+// Indexes every level in the directory, saving a thumbnail beside each one and the index to the out path, or
+// indexName in the directory if it's empty. Files which can't be decoded as levels are skipped.
+func indexLevels(dir, out string) error {
+	if out == "" {
+		out = filepath.Join(dir, indexName)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("read level directory: %w", err)
+	}
+	index := LevelIndex{Levels: []IndexedLevel{}}
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		if entry.IsDir() || !isLevelFile(entry.Name()) || entry.Name() == indexName ||
+			filepath.Clean(path) == filepath.Clean(out) {
+			continue
+		}
+		var l Level
+		err := l.decode(path)
+		if err != nil {
+			fmt.Println("Skipping", path+":", err)
+			continue
+		}
+		hash, err := levelHash(&l)
+		if err != nil {
+			return err
+		}
+		err = l.saveThumbnail(thumbnailPath(path))
+		if err != nil {
+			return fmt.Errorf("%v: %w", path, err)
+		}
+		name := strings.TrimSuffix(entry.Name(), compressedSuffix)
+		index.Levels = append(index.Levels, IndexedLevel{
+			Name:      strings.TrimSuffix(name, filepath.Ext(name)),
+			Path:      entry.Name(),
+			Author:    l.Author,
+			Hash:      hash,
+			Thumbnail: thumbnailPath(entry.Name()),
+			Stats:     l.stats(),
+		})
+	}
+	f, err := os.OpenFile(out, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0777)
+	if err != nil {
+		return fmt.Errorf("open file to save index: %w", err)
+	}
+	defer f.Close()
+	encoder := json.NewEncoder(f)
+	encoder.SetIndent("", "    ")
+	err = encoder.Encode(index)
+	if err != nil {
+		return fmt.Errorf("save index: %w", err)
+	}
+	fmt.Println("Indexed", len(index.Levels), "levels to", out)
+	return nil
+}
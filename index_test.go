@@ -0,0 +1,51 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIndexSummarizesLevels(t *testing.T) {
+	dir := t.TempDir()
+	l := NewLevel()
+	l.Author = "someone"
+	l.Blocks = []*Block{{T: boxBetween(-10, -1, 10, 0)}, {T: boxBetween(4, 0, 6, 2)}}
+	err := l.save(filepath.Join(dir, "hills.json.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not a level"), 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = indexLevels(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(filepath.Join(dir, indexName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var index LevelIndex
+	err = json.Unmarshal(b, &index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(index.Levels) != 1 {
+		t.Fatalf("indexed %v levels, want 1", len(index.Levels))
+	}
+	got := index.Levels[0]
+	if got.Name != "hills" || got.Author != "someone" || got.Stats.Blocks != 2 || got.Stats.Width != 20 {
+		t.Errorf("indexed %+v, want hills by someone with 2 blocks 20 units wide", got)
+	}
+	if _, err := os.Stat(filepath.Join(dir, got.Thumbnail)); err != nil {
+		t.Errorf("thumbnail: %v", err)
+	}
+	// indexing again skips the index itself
+	err = indexLevels(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+}
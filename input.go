@@ -5,6 +5,43 @@ import (
 	"github.com/hajimehoshi/ebiten/v2"
 )
 
+// Where keyboard and mouse input comes from. Normally ebiten, but tests can substitute their own to script input.
+type Driver interface {
+	IsKeyPressed(k ebiten.Key) bool
+	IsMouseButtonPressed(m ebiten.MouseButton) bool
+	// In screen pixels
+	CursorPosition() (int, int)
+	Wheel() (float64, float64)
+	// Characters typed since the last frame
+	InputChars() []rune
+}
+
+// Reads input from ebiten
+type ebitenDriver struct{}
+
+func (ebitenDriver) IsKeyPressed(k ebiten.Key) bool {
+	return ebiten.IsKeyPressed(k)
+}
+
+func (ebitenDriver) IsMouseButtonPressed(m ebiten.MouseButton) bool {
+	return ebiten.IsMouseButtonPressed(m)
+}
+
+func (ebitenDriver) CursorPosition() (int, int) {
+	return ebiten.CursorPosition()
+}
+
+func (ebitenDriver) Wheel() (float64, float64) {
+	return ebiten.Wheel()
+}
+
+func (ebitenDriver) InputChars() []rune {
+	return ebiten.InputChars()
+}
+
+// The source of all input
+var driver Driver = ebitenDriver{}
+
 // The current position and change since last frame of a point.
 type drag struct {
 	pos box2d.B2Vec2
@@ -22,20 +59,21 @@ var (
 func InputsUpdate() {
 	iframe++
 	for k := range kdown {
-		if !ebiten.IsKeyPressed(k) {
+		if !driver.IsKeyPressed(k) {
 			delete(kdown, k)
 		}
 	}
 	for m := range mdown {
-		if !ebiten.IsMouseButtonPressed(m) {
+		if !driver.IsMouseButtonPressed(m) {
 			delete(mdown, m)
 		}
 	}
 	for m, d := range mdrag {
-		if !ebiten.IsMouseButtonPressed(m) {
+		if !driver.IsMouseButtonPressed(m) {
 			delete(mdrag, m)
+			continue
 		}
-		x, y := ebiten.CursorPosition()
+		x, y := driver.CursorPosition()
 		mdrag[m] = drag{
 			pos:   box2d.B2Vec2{
 				X: float64(x),
@@ -49,9 +87,24 @@ func InputsUpdate() {
 	}
 }
 
+// Whether any key or mouse button is held
+func anyInput() bool {
+	for k := ebiten.Key(0); k <= ebiten.KeyMax; k++ {
+		if driver.IsKeyPressed(k) {
+			return true
+		}
+	}
+	for _, m := range mouseButtons {
+		if driver.IsMouseButtonPressed(m) {
+			return true
+		}
+	}
+	return false
+}
+
 // Returns true if a given k has just started to be pressed
 func Clicked(k ebiten.Key) bool {
-	if !ebiten.IsKeyPressed(k) {
+	if !driver.IsKeyPressed(k) {
 		return false
 	}
 	f, ok := kdown[k]
@@ -67,7 +120,7 @@ func Clicked(k ebiten.Key) bool {
 
 // Returns true if a given button has just started to be pressed
 func MouseClicked(m ebiten.MouseButton) bool {
-	if !ebiten.IsMouseButtonPressed(m) {
+	if !driver.IsMouseButtonPressed(m) {
 		return false
 	}
 	f, ok := mdown[m]
@@ -83,12 +136,12 @@ func MouseClicked(m ebiten.MouseButton) bool {
 
 // Reports the distance the mouse button was dragged since last frame in pixels.
 func MouseDrag(m ebiten.MouseButton) box2d.B2Vec2 {
-	if !ebiten.IsMouseButtonPressed(m) {
+	if !driver.IsMouseButtonPressed(m) {
 		return box2d.B2Vec2{}
 	}
 	d, ok := mdrag[m]
 	if !ok {
-		x, y := ebiten.CursorPosition()
+		x, y := driver.CursorPosition()
 		mdrag[m] = drag{
 			pos:   box2d.B2Vec2{
 				X: float64(x),
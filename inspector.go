@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"github.com/ByteArena/box2d"
+	"github.com/hajimehoshi/ebiten/v2"
+	"image/color"
+	"strconv"
+	"strings"
+)
+
+// Developer overlay for inspecting and tweaking the bodies of a running game. Click a body to inspect it, then type
+// commands like "vx 3" to change its properties.
+type Inspector struct {
+	// Whether the overlay is showing
+	open bool
+	// The body being inspected, if any
+	b *box2d.B2Body
+	// For entering tweaks
+	t *Typer
+}
+
+// Values that can be tweaked on the inspected body, and how to apply them.
+var inspectorFields = map[string]func(b *box2d.B2Body, v float64){
+	"x": func(b *box2d.B2Body, v float64) {
+		pos := b.GetPosition()
+		b.SetTransform(box2d.B2Vec2{X: v, Y: pos.Y}, b.GetAngle())
+	},
+	"y": func(b *box2d.B2Body, v float64) {
+		pos := b.GetPosition()
+		b.SetTransform(box2d.B2Vec2{X: pos.X, Y: v}, b.GetAngle())
+	},
+	"angle": func(b *box2d.B2Body, v float64) {
+		b.SetTransform(b.GetPosition(), v)
+	},
+	"vx": func(b *box2d.B2Body, v float64) {
+		b.SetLinearVelocity(box2d.B2Vec2{X: v, Y: b.GetLinearVelocity().Y})
+	},
+	"vy": func(b *box2d.B2Body, v float64) {
+		b.SetLinearVelocity(box2d.B2Vec2{X: b.GetLinearVelocity().X, Y: v})
+	},
+	"spin": func(b *box2d.B2Body, v float64) {
+		b.SetAngularVelocity(v)
+	},
+	"gravity": func(b *box2d.B2Body, v float64) {
+		b.SetGravityScale(v)
+	},
+}
+
+// Updates the inspector for the given game. Returns true if the inspector is consuming keyboard input, in which case
+// the game should be paused.
+func (i *Inspector) Update(g *Game) bool {
+	if i.t == nil {
+		i.t = &Typer{
+			Placeholder: "Inspector: click a body, press enter to tweak it, e.g 'vx 3'",
+			C:           &g.c,
+		}
+	}
+	if Clicked(ebiten.KeyI) && !i.t.typ {
+		i.open = !i.open
+		i.b = nil
+	}
+	if !i.open {
+		return false
+	}
+	cmd, typ := i.t.Update()
+	if typ {
+		return true
+	}
+	if cmd != "" {
+		err := i.apply(cmd)
+		if err != nil {
+			i.t.Placeholder = fmt.Sprintf("Failed to apply '%v': %v", cmd, err)
+		} else {
+			i.t.Placeholder = fmt.Sprintf("Applied '%v'", cmd)
+		}
+	}
+	if MouseClicked(ebiten.MouseButtonLeft) {
+		i.b = i.pick(g)
+	}
+	return false
+}
+
+// Finds the body at the cursor, if any
+func (i *Inspector) pick(g *Game) *box2d.B2Body {
+	wx, wy := g.c.Cursor()
+	cursor := box2d.B2Vec2{X: wx, Y: wy}
+	var hit *box2d.B2Body
+	g.world.QueryAABB(func(f *box2d.B2Fixture) bool {
+		if f.TestPoint(cursor) {
+			hit = f.GetBody()
+			return false
+		}
+		return true
+	}, box2d.B2AABB{LowerBound: cursor, UpperBound: cursor})
+	return hit
+}
+
+// Applies a tweak of the form "<field> <value>" to the inspected body
+func (i *Inspector) apply(cmd string) error {
+	if i.b == nil {
+		return fmt.Errorf("no body selected")
+	}
+	parts := strings.Fields(cmd)
+	if len(parts) != 2 {
+		return fmt.Errorf("expected '<field> <value>'")
+	}
+	set, ok := inspectorFields[parts[0]]
+	if !ok {
+		return fmt.Errorf("unknown field %v", parts[0])
+	}
+	v, err := strconv.ParseFloat(parts[1], 64)
+	if err != nil {
+		return fmt.Errorf("parse value: %w", err)
+	}
+	set(i.b, v)
+	i.b.SetAwake(true)
+	return nil
+}
+
+// Describes the inspected body's live properties
+func (i *Inspector) describe() string {
+	b := i.b
+	var s strings.Builder
+	switch u := b.GetUserData().(type) {
+	case *Player:
+		_, _ = fmt.Fprintf(&s, "Player\nhas jump: %v\n", u.hasJump)
+	case *Entity:
+		_, _ = fmt.Fprintf(&s, "Entity %.2fx%.2f\nrestores jump: %v\n", u.w, u.h, u.restoresJump)
+	default:
+		_, _ = fmt.Fprintf(&s, "Unknown %T\n", u)
+	}
+	pos := b.GetPosition()
+	vel := b.GetLinearVelocity()
+	contacts := 0
+	for c := b.GetContactList(); c != nil; c = c.Next {
+		if c.Contact.IsTouching() {
+			contacts++
+		}
+	}
+	_, _ = fmt.Fprintf(&s, "x, y: %.2f, %.2f\nangle: %.2f\nvx, vy: %.2f, %.2f\nspin: %.2f\n",
+		pos.X, pos.Y, b.GetAngle(), vel.X, vel.Y, b.GetAngularVelocity())
+	_, _ = fmt.Fprintf(&s, "gravity: %.2f\nmass: %.2f\nawake: %v\ncontacts: %v\n",
+		b.GetGravityScale(), b.GetMass(), b.IsAwake(), contacts)
+	return s.String()
+}
+
+func (i *Inspector) Draw(screen *ebiten.Image, g *Game) {
+	if !i.open {
+		return
+	}
+	i.t.Draw(screen)
+	if i.b == nil {
+		return
+	}
+	// outline the body's fixtures
+	geom := g.c.ToScreen()
+	xf := i.b.GetTransform()
+	for f := i.b.GetFixtureList(); f != nil; f = f.GetNext() {
+		poly, ok := f.GetShape().(*box2d.B2PolygonShape)
+		if !ok {
+			continue
+		}
+		for j := 0; j < poly.M_count; j++ {
+			a := box2d.B2TransformVec2Mul(xf, poly.M_vertices[j])
+			b := box2d.B2TransformVec2Mul(xf, poly.M_vertices[(j+1)%poly.M_count])
+			drawline(screen, a.X, a.Y, b.X, b.Y, 2, geom, color.RGBA{G: 255, A: 255})
+		}
+	}
+	printAt(screen, i.describe(), g.c.sw-200, 10)
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"fmt"
+	"github.com/hajimehoshi/ebiten/v2"
+	"image/color"
+	"strconv"
+	"strings"
+)
+
+// Labels are drawn at this size then scaled into the world, big enough to stay sharp when zoomed in
+var labelStyle = TextStyle{Size: 48, Align: alignCenter}
+
+// Line height in world units of new labels
+const defaultLabelSize = 0.5
+
+// Text placed in the level, e.g a tutorial hint like "Press W to jump"
+type Label struct {
+	// Transform of the label's center, rotation included. Any scale is ignored, the text is sized by Size.
+	T    Mx
+	Text string
+	// Height in world units of a line of the text
+	Size float64
+	// White if unset
+	Color *color.RGBA `json:",omitempty"`
+}
+
+// Images of texts drawn in labelStyle, by text, so labels aren't redrawn every frame
+var labelImages = make(map[string]*ebiten.Image)
+
+// The transform of a unit square centered at the origin to the box the label's text fills
+func (lb *Label) box() Mx {
+	w, h := measureText(lb.Text, labelStyle)
+	height := float64(h/lineHeight(labelStyle)) * lb.Size
+	width := 0.0
+	if h > 0 {
+		width = height * float64(w) / float64(h)
+	}
+	var m Mx
+	m.Scale(width, height)
+	m.Concat(lb.T.GeoM)
+	return m
+}
+
+func (lb *Label) draw(screen *ebiten.Image, toScreen Mx) {
+	w, h := measureText(lb.Text, labelStyle)
+	if w == 0 || h == 0 {
+		return
+	}
+	img, ok := labelImages[lb.Text]
+	if !ok {
+		img = ebiten.NewImage(w, h)
+		drawText(img, lb.Text, w/2, 0, labelStyle)
+		labelImages[lb.Text] = img
+	}
+	var geo Mx
+	geo.Scale(1/float64(w), -1/float64(h))
+	geo.Translate(-0.5, 0.5)
+	box := lb.box()
+	geo.Concat(box.GeoM)
+	geo.Concat(toScreen.GeoM)
+	op := &ebiten.DrawImageOptions{GeoM: geo.GeoM}
+	if lb.Color != nil {
+		op.ColorM.Scale(float64(lb.Color.R)/0xff, float64(lb.Color.G)/0xff, float64(lb.Color.B)/0xff,
+			float64(lb.Color.A)/0xff)
+	}
+	screen.DrawImage(img, op)
+}
+
+// Makes labels selectable. Scaling a label changes its text size, keeping its proportions.
+type LabelSelector struct {
+	l  *Level
+	lb *Label
+}
+
+func (s *LabelSelector) Paste() Selectable {
+	kopy := *s.lb
+	s.l.Labels = append(s.l.Labels, &kopy)
+	return &LabelSelector{l: s.l, lb: &kopy}
+}
+
+func (s *LabelSelector) Delete() {
+	for i, o := range s.l.Labels {
+		if o == s.lb {
+			s.l.Labels = append(s.l.Labels[:i], s.l.Labels[i+1:]...)
+			return
+		}
+	}
+}
+
+func (s *LabelSelector) Transform() Mx {
+	return s.lb.box()
+}
+
+func (s *LabelSelector) SetTransform(m Mx) {
+	center, angle, _, hh := decompose(m)
+	lines := strings.Count(s.lb.Text, "\n") + 1
+	s.lb.Size = 2 * hh / float64(lines)
+	var t Mx
+	t.Rotate(angle)
+	t.Translate(center.X, center.Y)
+	s.lb.T = t
+}
+
+// Editor for labels. Typing sets the text of new labels, and clicking places one. "size <units>" and
+// "color <r> <g> <b>" set up the labels placed after.
+type LabelEditor struct {
+	e *Editor
+	t *Typer
+	// The label new labels copy
+	next Label
+}
+
+func ActivateLabelEditor(r *Root, e *Editor) {
+	l := &LabelEditor{e: e, t: &Typer{C: &e.c}, next: Label{Size: defaultLabelSize}}
+	l.t.Placeholder = l.describe()
+	r.a = l
+}
+
+func (l *LabelEditor) Layout(outsideWidth, outsideHeight int) (screenWidth, screenHeight int) {
+	return l.e.Layout(outsideWidth, outsideHeight)
+}
+
+func (l *LabelEditor) String() string {
+	return "Labels"
+}
+
+// Summarizes the next label
+func (l *LabelEditor) describe() string {
+	text := l.next.Text
+	if text == "" {
+		text = "none"
+	}
+	c := color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+	if l.next.Color != nil {
+		c = *l.next.Color
+	}
+	return fmt.Sprintf("Label Editor: text %q, size %v, color %v %v %v. Enter text, 'size <units>' or "+
+		"'color <r> <g> <b>'.", text, l.next.Size, c.R, c.G, c.B)
+}
+
+// Applies a command of the form "size <units>" or "color <r> <g> <b>", or sets the text of the next label to any
+// other command
+func (l *LabelEditor) apply(cmd string) error {
+	parts := strings.Fields(cmd)
+	switch {
+	case len(parts) == 2 && parts[0] == "size":
+		size, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil || size <= 0 {
+			return fmt.Errorf("size %q: expected a positive number of world units", parts[1])
+		}
+		l.next.Size = size
+	case len(parts) == 4 && parts[0] == "color":
+		var rgb [3]uint8
+		for i, p := range parts[1:] {
+			n, err := strconv.ParseUint(p, 10, 8)
+			if err != nil {
+				return fmt.Errorf("color %q: expected a number from 0 to 255", p)
+			}
+			rgb[i] = uint8(n)
+		}
+		l.next.Color = &color.RGBA{R: rgb[0], G: rgb[1], B: rgb[2], A: 0xff}
+	default:
+		l.next.Text = cmd
+	}
+	return nil
+}
+
+func (l *LabelEditor) Update(r *Root) error {
+	cmd, typ := l.t.Update()
+	if typ {
+		return nil
+	}
+	if cmd != "" {
+		err := l.apply(cmd)
+		if err != nil {
+			l.t.Placeholder = fmt.Sprintf("Failed to apply '%v': %v", cmd, err)
+		} else {
+			l.t.Placeholder = l.describe()
+		}
+	}
+	if MouseClicked(ebiten.MouseButtonLeft) {
+		if l.next.Text == "" {
+			l.t.Placeholder = "Enter the label's text before placing it"
+		} else {
+			lb := l.next
+			wx, wy := l.e.c.Cursor()
+			lb.T = Mx{}
+			lb.T.Translate(wx, wy)
+			l.e.l.Labels = append(l.e.l.Labels, &lb)
+		}
+	}
+	return l.e.Update(r)
+}
+
+func (l *LabelEditor) Draw(screen *ebiten.Image) {
+	l.e.Draw(screen)
+	l.t.Draw(screen)
+	printAt(screen, "Click to place a label", 10, l.e.c.sh-35)
+}
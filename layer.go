@@ -0,0 +1,189 @@
+package main
+
+import (
+	"fmt"
+	"github.com/hajimehoshi/ebiten/v2"
+	"sort"
+	"strings"
+)
+
+// Name editor commands use for the layer of blocks and art which aren't in a named one
+const defaultLayer = "default"
+
+// How the editor treats a layer's blocks and art
+type layerState struct {
+	// Not drawn in the editor, and can't be selected
+	hidden bool
+	// Drawn, but can't be selected
+	locked bool
+}
+
+// The layer a name given to an editor command refers to
+func layerNamed(name string) string {
+	if name == defaultLayer {
+		return ""
+	}
+	return name
+}
+
+// The name editor commands use for the layer
+func layerName(layer string) string {
+	if layer == "" {
+		return defaultLayer
+	}
+	return layer
+}
+
+// Whether the layer's blocks and art are hidden in the editor
+func (e *Editor) hidden(layer string) bool {
+	return e.layers[layer].hidden
+}
+
+// Whether the layer's blocks and art can't be selected, because it's locked or hidden
+func (e *Editor) locked(layer string) bool {
+	s := e.layers[layer]
+	return s.hidden || s.locked
+}
+
+// The layers blocks and art in the level are in, and the one new ones are put in, the default first then by name
+func (e *Editor) layerNames() []string {
+	seen := map[string]bool{"": true, e.layer: true}
+	for _, b := range e.l.Blocks {
+		seen[b.Layer] = true
+	}
+	for _, a := range e.l.Art {
+		seen[a.Layer] = true
+	}
+	var names []string
+	for n := range seen {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Editor for choosing the layer new blocks and art are put in, and for hiding and locking layers, e.g to keep
+// decoration out of the way while editing collision
+type LayerEditor struct {
+	e *Editor
+	t *Typer
+}
+
+func ActivateLayerEditor(r *Root, e *Editor) {
+	l := &LayerEditor{e: e, t: &Typer{C: &e.c}}
+	l.t.Placeholder = l.describe()
+	r.a = l
+}
+
+func (l *LayerEditor) Layout(outsideWidth, outsideHeight int) (screenWidth, screenHeight int) {
+	return l.e.Layout(outsideWidth, outsideHeight)
+}
+
+func (l *LayerEditor) String() string {
+	return "Layers"
+}
+
+func (l *LayerEditor) describe() string {
+	return fmt.Sprintf("Layer Editor: new blocks and art go in %v. Enter 'layer <name>', 'hide <name>', "+
+		"'show <name>', 'lock <name>' or 'unlock <name>'. Select things and enter 'layer <name>' in the select "+
+		"editor to move them.", layerName(l.e.layer))
+}
+
+// Applies a command of the form "<layer|hide|show|lock|unlock> <name>"
+func (l *LayerEditor) apply(cmd string) error {
+	parts := strings.Fields(cmd)
+	if len(parts) != 2 {
+		return fmt.Errorf("expected 'layer <name>', 'hide <name>', 'show <name>', 'lock <name>' or 'unlock <name>'")
+	}
+	layer := layerNamed(parts[1])
+	if l.e.layers == nil {
+		l.e.layers = make(map[string]layerState)
+	}
+	s := l.e.layers[layer]
+	switch parts[0] {
+	case "layer":
+		l.e.layer = layer
+	case "hide":
+		s.hidden = true
+	case "show":
+		s.hidden = false
+	case "lock":
+		s.locked = true
+	case "unlock":
+		s.locked = false
+	default:
+		return fmt.Errorf("unknown command %v", parts[0])
+	}
+	l.e.layers[layer] = s
+	return nil
+}
+
+func (l *LayerEditor) Update(r *Root) error {
+	cmd, typ := l.t.Update()
+	if typ {
+		return nil
+	}
+	if cmd != "" {
+		err := l.apply(cmd)
+		if err != nil {
+			l.t.Placeholder = fmt.Sprintf("Failed to apply '%v': %v", cmd, err)
+		} else {
+			l.t.Placeholder = l.describe()
+		}
+	}
+	return l.e.Update(r)
+}
+
+func (l *LayerEditor) Draw(screen *ebiten.Image) {
+	l.e.Draw(screen)
+	l.t.Draw(screen)
+	var s strings.Builder
+	s.WriteString("Layers\n")
+	for _, n := range l.e.layerNames() {
+		blocks, art := 0, 0
+		for _, b := range l.e.l.Blocks {
+			if b.Layer == n {
+				blocks++
+			}
+		}
+		for _, a := range l.e.l.Art {
+			if a.Layer == n {
+				art++
+			}
+		}
+		_, _ = fmt.Fprintf(&s, "%v: %v blocks, %v art", layerName(n), blocks, art)
+		if st := l.e.layers[n]; st.hidden {
+			s.WriteString(", hidden")
+		} else if st.locked {
+			s.WriteString(", locked")
+		}
+		if n == l.e.layer {
+			s.WriteString(", new things go here")
+		}
+		s.WriteString("\n")
+	}
+	printAt(screen, s.String(), l.e.c.sw-250, 5)
+}
+
+// Moves the selected blocks and art to the layer
+func (t *SelectEditor) moveToLayer(layer string) error {
+	members := []Selectable{t.s.s}
+	if g, ok := t.s.s.(*GroupSelector); ok {
+		members = g.members
+	}
+	moved := 0
+	for _, m := range members {
+		switch m := m.(type) {
+		case *BlockSelector:
+			m.b.Layer = layer
+			moved++
+		case *ArtSelector:
+			m.a.Layer = layer
+			moved++
+		}
+	}
+	if moved == 0 {
+		return fmt.Errorf("select blocks or art to move them to a layer")
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Identifies a level's contents, ignoring any hash it was saved with. Saved in level files to detect changes made
+// outside the editor. Prefabs are identified by path, their contents are left out.
+func levelHash(l *Level) (string, error) {
+	kopy := *l
+	kopy.Hash = ""
+	b, err := json.Marshal(kopy)
+	if err != nil {
+		return "", fmt.Errorf("encode level: %w", err)
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:8]), nil
+}
+
+// Identifies a level as it's played: its hash, and the contents of the loaded prefabs placed in it. Editing the level or
+// a prefab it uses resets its best times. Levels without prefabs are identified by their hash alone.
+func playedHash(l *Level) (string, error) {
+	hash, err := levelHash(l)
+	if err != nil || len(l.Prefabs) == 0 {
+		return hash, err
+	}
+	h := sha256.New()
+	_, _ = h.Write([]byte(hash))
+	for _, p := range l.Prefabs {
+		b, err := json.Marshal(p.clip)
+		if err != nil {
+			return "", fmt.Errorf("encode prefab %v: %w", p.Path, err)
+		}
+		_, _ = h.Write(b)
+	}
+	return hex.EncodeToString(h.Sum(nil)[:8]), nil
+}
+
+// Loads the best times for the level stored at the given path, starting a new board if they were set before the
+// level was last edited.
+func loadBoard(path string, l *Level) (BestTimes, error) {
+	hash, err := playedHash(l)
+	if err != nil {
+		return BestTimes{}, err
+	}
+	var b BestTimes
+	err = b.load(bestTimesPath(path))
+	if err != nil || b.Level != hash {
+		return BestTimes{Level: hash}, err
+	}
+	return b, nil
+}
+
+// Writes out the best times, marking the given place. Place is -1 if the run didn't make the board.
+func writeLeaderboard(s *strings.Builder, b BestTimes, place int, run time.Duration) {
+	s.WriteString("Best times\n\n")
+	for i, ms := range b.Times {
+		marker := "  "
+		if i == place {
+			marker = "> "
+		}
+		_, _ = fmt.Fprintf(s, "%v%2d. %v\n", marker, i+1, formatRunTime(time.Duration(ms)*time.Millisecond))
+	}
+	if place < 0 {
+		_, _ = fmt.Fprintf(s, "\nYour time %v\n", formatRunTime(run))
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBoardResetsWhenLevelChanges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "level.json")
+	l := floorLevel()
+	b, err := loadBoard(path, &l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.add(time.Second)
+	err = b.save(bestTimesPath(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err = loadBoard(path, &l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b.Times) != 1 {
+		t.Errorf("got %v times for the unchanged level, want 1", len(b.Times))
+	}
+
+	l.Spawn.X++
+	b, err = loadBoard(path, &l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b.Times) != 0 {
+		t.Errorf("got %v times after editing the level, want none", len(b.Times))
+	}
+}
+
+func TestPlayedHashCoversPrefabs(t *testing.T) {
+	l := floorLevel()
+	plain, err := playedHash(&l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hash, _ := levelHash(&l); hash != plain {
+		t.Errorf("level without prefabs played as %v, want its hash %v", plain, hash)
+	}
+	p := &Prefab{Path: "prefabs/bridge.json", clip: Clip{Blocks: []*Block{{T: Mx{}}}}}
+	l.Prefabs = []*Prefab{p}
+	before, err := playedHash(&l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.clip.Blocks[0].Name = "edited"
+	after, err := playedHash(&l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if before == after {
+		t.Errorf("editing a prefab didn't change the played hash")
+	}
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"github.com/ByteArena/box2d"
+	"image/color"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files from the current output")
+
+// A level saved by the current format, which must keep loading as the format changes
+const goldenLevel = "testdata/level.golden.json"
+
+// Builds a transform from its elements, in the order they are saved
+func mx(t *testing.T, elements ...float64) Mx {
+	var m Mx
+	err := m.fromArray(elements)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+// A level using every saved field
+func everyField(t *testing.T) Level {
+	volume := 0.5
+	l := NewLevel()
+	l.Spawn = box2d.B2Vec2{X: 1, Y: 3}
+	l.Spawns = map[string]box2d.B2Vec2{"door": {X: 15, Y: 2}}
+	goal := mx(t, 2, 0, 20, 0, 4, 1)
+	l.Goal = &goal
+	bounds := mx(t, 120, 0, 0, 0, 40, 10)
+	l.CameraBounds = &bounds
+	l.Blocks = []*Block{
+		{T: mx(t, 100, 0, 0, 0, 0.5, 0), Name: "floor", Material: materialIce, Layer: "collision"},
+		{T: mx(t, 2, 0, 3, 0, 0.5, -1), Crumble: &Crumble{Delay: 0.5, Respawn: 3}, Restitution: 1,
+			Shader: &CustomShader{Path: "shaders/lava_shader.go",
+				Uniforms: map[string]UniformValue{"Color": {1, 0.5, 0}, "Speed": {2}}}},
+	}
+	parent := 1
+	l.Art = []*Art{{T: mx(t, 4, 0, 0, 0, 2, 1), Path: "resources/grass.png",
+		Variants: []string{"resources/flowers.png"}, Parent: &parent, Z: -1,
+		Slice: &NineSlice{Left: 4, Top: 4, Right: 4, Bottom: 2}, Normal: "resources/grass_normal.png",
+		Shader: &CustomShader{Path: "shaders/sway_shader.go"}, Layer: "decoration"}}
+	l.BGAudio = &Audio{Path: "resources/song.mp3", Volume: &volume}
+	l.BGArt = &Art{Path: "resources/bg.png"}
+	l.Characters = map[string]*Character{
+		"runner": {Width: 0.8, Height: 1.2, Speed: 7, Jump: 5.5, WallKick: 3, Shoots: true,
+			Art: &Art{Path: "resources/runner.png"}},
+	}
+	l.Character = "runner"
+	dusk, freeze := 0.75, true
+	l.Triggers["jump"] = Trigger{Audio: &Audio{Path: "resources/jump.mp3"},
+		Dialogue: &Dialogue{Speaker: "Guide", Pages: []string{"Hello there.", "Mind the gap."}},
+		Time:     &TimeChange{At: &dusk, Freeze: &freeze}, Spawn: "door"}
+	l.Triggers["shoot"] = Trigger{LookAt: &LookAt{X: 10, Y: 2, Hold: 1.5},
+		Shake: &Shake{Amplitude: 0.2, Frequency: 10, Decay: 0.5},
+		Cutscene: &Cutscene{Camera: []CameraKey{{Time: 2, X: 30, Y: 5, Zoom: 2}},
+			Moves:  []ArtMove{{Art: 0, Keys: []ArtKey{{Time: 1, X: 0, Y: 3, Angle: 0.5}}}},
+			Sounds: []CutsceneSound{{Time: 0.5, Audio: Audio{Path: "resources/door.mp3"}}}}}
+	l.Ropes = []*Rope{{A: box2d.B2Vec2{X: 0, Y: 5}, B: box2d.B2Vec2{X: 4, Y: 5}, Length: 6}}
+	l.Attractors = []*Attractor{{X: -5, Y: 2, Radius: 3, Strength: 20, Falloff: falloffLinear, PlayerOnly: true, Well: true}}
+	l.Portals = []*Portal{{A: mx(t, 1, 0, -8, 0, 2, 0), B: mx(t, 0, -1, 8, 1, 0, 4)}}
+	l.Contraptions = []*Contraption{{Kind: contraptionSeesaw, T: mx(t, 4, 0, 12, 0, 0.25, 1), Limit: 0.5}}
+	l.Switches = []*Switch{{T: mx(t, 1, 0, 6, 0, 1, 1), Trigger: "shoot", Targets: []int{1}}}
+	l.Ambients = []*Ambient{{X: 30, Y: 0, Radius: 12, Audio: Audio{Path: "resources/waterfall.wav", Volume: &volume}}}
+	l.MusicZones = []*MusicZone{{T: mx(t, 20, 0, 40, 0, 10, -5), Audio: Audio{Path: "resources/cave.mp3"}}}
+	l.CameraZones = []*CameraZone{{T: mx(t, 16, 0, 60, 0, 8, 4), Zoom: 1.5, Lock: true, Trigger: "jump"}}
+	l.Lights = []*Light{{X: -3, Y: 6, Radius: 8, Color: &color.RGBA{R: 0xff, G: 0xa0, B: 0x40, A: 0xff}}}
+	l.Shadows = true
+	l.Bloom = 0.6
+	l.Grade = &Grade{Tint: &color.RGBA{R: 0xc0, G: 0xe0, B: 0xff, A: 0xff}, Hue: 0.2, Saturation: -0.5, Brightness: 0.1}
+	l.DayNight = &DayNight{Length: 120, Start: 0.3}
+	l.Stations = []*Station{{T: mx(t, 2, 0, -12, 0, 2, 1), Character: "heavy"}}
+	l.Enemies = []*Enemy{{
+		T:        mx(t, 1, 0, 16, 0, 1, 3),
+		Health:   5,
+		Interval: 2,
+		Range:    12,
+		Trigger:  "shoot",
+		Phases:   []Phase{{Health: 4, Pattern: patternRing, Interval: 0.5, Count: 8, Trigger: "jump"}},
+	}}
+	l.Tilemaps = []*Tilemap{{
+		Tileset:  "resources/tiles.png",
+		TileSize: 16,
+		X:        -4,
+		Y:        -2,
+		Width:    3,
+		Height:   2,
+		Tiles:    []int{1, 2, 1, 0, 3, 0},
+		Solid:    true,
+	}}
+	l.Prefabs = []*Prefab{{Path: "prefabs/bridge.json", T: mx(t, 1, 0, 30, 0, 1, -2)}}
+	l.Labels = []*Label{{T: mx(t, 1, 0, 2, 0, 1, 4), Text: "Press W\nto jump", Size: 0.5,
+		Color: &color.RGBA{R: 0xff, G: 0xd0, B: 0x40, A: 0xff}}}
+	l.Author = "hherman1"
+	l.Deterministic = true
+	l.Planetary = true
+	l.PixelsPerUnit = 40
+	l.PlayerScale = 1.5
+	l.Seed = 7
+	return l
+}
+
+// Saves the level and returns the file's contents
+func saved(t *testing.T, l Level) []byte {
+	path := filepath.Join(t.TempDir(), "level.json")
+	err := l.save(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestLevelMatchesGolden(t *testing.T) {
+	out := saved(t, everyField(t))
+	if *update {
+		err := os.WriteFile(goldenLevel, out, 0666)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	golden, err := os.ReadFile(goldenLevel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, golden) {
+		t.Errorf("saved level differs from %v, run with -update if the format change is intended and old levels "+
+			"still load.\ngot:\n%s", goldenLevel, out)
+	}
+}
+
+func TestLevelRoundTrips(t *testing.T) {
+	var l Level
+	err := l.decode(goldenLevel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden, err := os.ReadFile(goldenLevel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out := saved(t, l); !bytes.Equal(out, golden) {
+		t.Errorf("level changed after loading and saving.\ngot:\n%s\nwant:\n%s", out, golden)
+	}
+}
+
+func TestCompressedLevelRoundTrips(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "level.lvl.gz")
+	err := everyField(t).save(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(out, gzipMagic) {
+		t.Fatalf("level saved to %v isn't gzipped", path)
+	}
+	var l Level
+	err = l.decode(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden, err := os.ReadFile(goldenLevel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out := saved(t, l); !bytes.Equal(out, golden) {
+		t.Errorf("level changed after compressing and decompressing.\ngot:\n%s\nwant:\n%s", out, golden)
+	}
+}
+
+func TestLevelsDirectoryLoads(t *testing.T) {
+	paths, err := filepath.Glob("levels/*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range paths {
+		var l Level
+		if err := l.decode(p); err != nil {
+			t.Errorf("%v: %v", p, err)
+		}
+	}
+}
+
+func TestMxRoundTrips(t *testing.T) {
+	var m Mx
+	m.Scale(2, 3)
+	m.Rotate(0.3)
+	m.Translate(-1, 5)
+	bs, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Mx
+	err = json.Unmarshal(bs, &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		for j := 0; j < 3; j++ {
+			if a, b := got.Element(i, j), m.Element(i, j); math.Abs(a-b) > 1e-12 {
+				t.Errorf("element %v,%v is %v after a round trip, want %v", i, j, a, b)
+			}
+		}
+	}
+}
+
+func TestMxRejectsShortArrays(t *testing.T) {
+	var m Mx
+	if err := json.Unmarshal([]byte("[1, 0, 0, 1]"), &m); err == nil {
+		t.Errorf("expected an error decoding 4 elements")
+	}
+}
+
+func TestLevelDetectsOutsideChanges(t *testing.T) {
+	out := saved(t, floorLevel())
+	var l Level
+	err := l.read(bytes.NewReader(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.modified {
+		t.Errorf("freshly saved level reported as modified")
+	}
+	edited := bytes.Replace(out, []byte(`"Y": 3`), []byte(`"Y": 4`), 1)
+	err = l.read(bytes.NewReader(edited))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !l.modified {
+		t.Errorf("level edited by hand wasn't reported as modified")
+	}
+}
+
+func TestLevelWithoutSeedLoadsTheSame(t *testing.T) {
+	l := floorLevel()
+	l.Seed = 0
+	out := saved(t, l)
+	if bytes.Contains(out, []byte(`"Seed"`)) {
+		t.Fatalf("level without a seed saved one")
+	}
+	for i := 0; i < 2; i++ {
+		var got Level
+		if err := got.read(bytes.NewReader(out)); err != nil {
+			t.Fatal(err)
+		}
+		if got.Seed != 0 || got.modified {
+			t.Errorf("load %v: seed %v, modified %v, want 0 and unmodified", i, got.Seed, got.modified)
+		}
+	}
+}
+
+func TestLevelRejectsEmptyPlayers(t *testing.T) {
+	for _, level := range []string{
+		`{"PlayerScale": -1}`,
+		`{"Characters": {"flat": {"Width": 1, "Height": 0}}}`,
+		`{"Characters": {"inside out": {"Width": -1, "Height": 1}}}`,
+	} {
+		var l Level
+		if err := l.read(bytes.NewReader([]byte(level))); err == nil {
+			t.Errorf("%v: expected an error", level)
+		}
+	}
+}
+
+func TestLevelRejectsFlatContraptions(t *testing.T) {
+	level := `{"Contraptions": [{"Kind": "seesaw", "T": [0, 0, 3, 0, 0, 2]}]}`
+	var l Level
+	if err := l.read(bytes.NewReader([]byte(level))); err == nil {
+		t.Errorf("expected an error for a contraption with a zero size plank")
+	}
+}
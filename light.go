@@ -0,0 +1,355 @@
+package main
+
+import (
+	"fmt"
+	"github.com/hajimehoshi/ebiten/v2"
+	"image/color"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Lights the shaders light the screen with at once, see the Lights uniform. The lights nearest the camera are used.
+const maxLights = 8
+
+// Brightness of a lit level away from its lights, between 0 and 1, unless it has a day night cycle
+const ambientLight = 0.25
+
+// Bodies the shaders cast shadows from at once, see the Blocks uniform. The bodies nearest the camera are used.
+const maxShadowBlocks = 32
+
+var lightColor = color.RGBA{R: 0xff, G: 0xe0, B: 0x80, A: 0xff}
+
+// A point light placed in the level. Levels with lights are dark away from them, with art lit using its normal map if
+// it has one.
+type Light struct {
+	// Center in world units
+	X, Y float64
+	// World units the light reaches
+	Radius float64
+	// White if unset
+	Color *color.RGBA `json:",omitempty"`
+}
+
+// Draws the light and how far it reaches
+func (lt *Light) draw(screen *ebiten.Image, toScreen Mx) {
+	c := lightColor
+	if lt.Color != nil {
+		c = *lt.Color
+	}
+	drawcircle(screen, lt.X, lt.Y, lt.Radius, 1, toScreen, c)
+	drawpoint(screen, lt.X, lt.Y, 10, toScreen, c)
+}
+
+// The lights lighting a frame in screen pixels, passed to the shaders as uniforms
+type lighting struct {
+	// x, y and radius of each light, then its color
+	lights []float32
+	colors []float32
+	// Color of the light away from the lights
+	ambient []float32
+	// Set if bodies cast shadows. The center of each body, and the vectors from its center to the middles of its
+	// right and top edges.
+	shadows bool
+	blocks  []float32
+	axes    []float32
+}
+
+// The lighting of the frame seen through the given transform from the world to the screen, or nil if the level has no
+// lights and should be drawn unlit
+func (g *Game) lighting(toScreen Mx) *lighting {
+	if len(g.lights) == 0 || lowQuality {
+		return nil
+	}
+	nearest := append([]*Light(nil), g.lights...)
+	sort.SliceStable(nearest, func(i, j int) bool {
+		return math.Hypot(nearest[i].X-g.c.x, nearest[i].Y-g.c.y) < math.Hypot(nearest[j].X-g.c.x, nearest[j].Y-g.c.y)
+	})
+	if len(nearest) > maxLights {
+		nearest = nearest[:maxLights]
+	}
+	l := &lighting{
+		lights:  make([]float32, 3*maxLights),
+		colors:  make([]float32, 3*maxLights),
+		ambient: []float32{ambientLight, ambientLight, ambientLight},
+	}
+	if g.day != nil {
+		c := daylightAt(g.day.at)
+		l.ambient = []float32{float32(c[0]), float32(c[1]), float32(c[2])}
+	}
+	// pixels per world unit
+	scale := float64(g.c.sw) / (2 * g.c.hw)
+	for i, lt := range nearest {
+		sx, sy := toScreen.Apply(lt.X, lt.Y)
+		l.lights[3*i], l.lights[3*i+1], l.lights[3*i+2] = float32(sx), float32(sy), float32(lt.Radius*scale)
+		c := color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+		if lt.Color != nil {
+			c = *lt.Color
+		}
+		l.colors[3*i], l.colors[3*i+1], l.colors[3*i+2] = float32(c.R)/0xff, float32(c.G)/0xff, float32(c.B)/0xff
+	}
+	if g.shadows {
+		l.castShadows(g, toScreen)
+	}
+	return l
+}
+
+// Sets the bodies nearest the camera to cast shadows. Bullets are too small to bother with.
+func (l *lighting) castShadows(g *Game, toScreen Mx) {
+	var casters []*Entity
+	for _, e := range g.entities {
+		if e.b.IsActive() && !e.bullet && !e.hostile {
+			casters = append(casters, e)
+		}
+	}
+	distance := func(e *Entity) float64 {
+		pos := e.b.GetPosition()
+		return math.Hypot(pos.X-g.c.x, pos.Y-g.c.y)
+	}
+	sort.SliceStable(casters, func(i, j int) bool {
+		return distance(casters[i]) < distance(casters[j])
+	})
+	if len(casters) > maxShadowBlocks {
+		casters = casters[:maxShadowBlocks]
+	}
+	l.shadows = true
+	l.blocks = make([]float32, 2*maxShadowBlocks)
+	l.axes = make([]float32, 4*maxShadowBlocks)
+	for i, e := range casters {
+		position, angle := g.drawTransform(e.b)
+		m := pose(position.X, position.Y, angle)
+		m.Concat(toScreen.GeoM)
+		cx, cy := m.Apply(0, 0)
+		rx, ry := m.Apply(e.w/2, 0)
+		ux, uy := m.Apply(0, e.h/2)
+		l.blocks[2*i], l.blocks[2*i+1] = float32(cx), float32(cy)
+		l.axes[4*i], l.axes[4*i+1] = float32(rx-cx), float32(ry-cy)
+		l.axes[4*i+2], l.axes[4*i+3] = float32(ux-cx), float32(uy-cy)
+	}
+}
+
+// The uniforms lighting a shader with the lights. Unlit if the lighting is nil.
+func (l *lighting) uniforms() map[string]interface{} {
+	if l == nil {
+		return map[string]interface{}{}
+	}
+	u := map[string]interface{}{
+		"Lit":         float32(1),
+		"Ambient":     l.ambient,
+		"Lights":      l.lights,
+		"LightColors": l.colors,
+	}
+	if l.shadows {
+		u["Shadows"] = float32(1)
+		u["Blocks"] = l.blocks
+		u["BlockAxes"] = l.axes
+	}
+	return u
+}
+
+// Makes lights selectable, scaling changes their radius
+type LightSelector struct {
+	l  *Level
+	lt *Light
+}
+
+func (s *LightSelector) Paste() Selectable {
+	kopy := *s.lt
+	s.l.Lights = append(s.l.Lights, &kopy)
+	return &LightSelector{l: s.l, lt: &kopy}
+}
+
+func (s *LightSelector) Delete() {
+	for i, o := range s.l.Lights {
+		if o == s.lt {
+			s.l.Lights = append(s.l.Lights[:i], s.l.Lights[i+1:]...)
+			return
+		}
+	}
+}
+
+func (s *LightSelector) Transform() Mx {
+	var m Mx
+	m.Scale(2*s.lt.Radius, 2*s.lt.Radius)
+	m.Translate(s.lt.X, s.lt.Y)
+	return m
+}
+
+func (s *LightSelector) SetTransform(m Mx) {
+	s.lt.X, s.lt.Y = m.Apply(0, 0)
+	// use the larger side as the diameter, the light is always a circle
+	rx, ry := m.Apply(0.5, 0)
+	ux, uy := m.Apply(0, 0.5)
+	s.lt.Radius = math.Max(math.Hypot(rx-s.lt.X, ry-s.lt.Y), math.Hypot(ux-s.lt.X, uy-s.lt.Y))
+}
+
+// Editor for placing lights. Click to place one using the current settings, which can be changed by typing
+// "radius <units>" or "color <r> <g> <b>". "shadows" turns the level's shadows on or off, and "bloom <strength>" sets
+// its bloom. "grade <hue degrees> <saturation> <brightness>" and "tint <r> <g> <b>" set its color grade, and
+// "grade off" removes it. "day <seconds> [start]" gives it a day night cycle, and "day off" removes it.
+type LightEditor struct {
+	e *Editor
+	t *Typer
+	// The light new lights copy
+	next Light
+}
+
+func ActivateLightEditor(r *Root, e *Editor) {
+	l := &LightEditor{e: e, t: &Typer{C: &e.c}, next: Light{Radius: 8}}
+	l.t.Placeholder = l.describe()
+	r.a = l
+}
+
+func (l *LightEditor) Layout(outsideWidth, outsideHeight int) (screenWidth, screenHeight int) {
+	return l.e.Layout(outsideWidth, outsideHeight)
+}
+
+func (l *LightEditor) String() string {
+	return "Lights"
+}
+
+// Summarizes the next light
+func (l *LightEditor) describe() string {
+	c := color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+	if l.next.Color != nil {
+		c = *l.next.Color
+	}
+	shadows := "off"
+	if l.e.l.Shadows {
+		shadows = "on"
+	}
+	grade := "off"
+	if gr := l.e.l.Grade; gr != nil {
+		grade = fmt.Sprintf("%v %v %v", gr.Hue*180/math.Pi, gr.Saturation, gr.Brightness)
+		if gr.Tint != nil {
+			grade += fmt.Sprintf(" tinted %v %v %v", gr.Tint.R, gr.Tint.G, gr.Tint.B)
+		}
+	}
+	day := "off"
+	if d := l.e.l.DayNight; d != nil {
+		day = fmt.Sprintf("%vs from %v", d.Length, d.Start)
+	}
+	return fmt.Sprintf("Light Editor: radius %v, color %v %v %v, shadows %v, bloom %v, grade %v, day %v. Enter "+
+		"'radius <units>', 'color <r> <g> <b>', 'shadows', 'bloom <strength>', 'grade <hue> <saturation> "+
+		"<brightness>', 'tint <r> <g> <b>', 'grade off', 'day <seconds> [start]' or 'day off'.", l.next.Radius, c.R,
+		c.G, c.B, shadows, l.e.l.Bloom, grade, day)
+}
+
+// Parses a color from its red, green and blue channels
+func parseRGB(channels []string) (*color.RGBA, error) {
+	var rgb [3]uint8
+	for i, p := range channels {
+		n, err := strconv.ParseUint(p, 10, 8)
+		if err != nil {
+			return nil, fmt.Errorf("color %q: expected a number from 0 to 255", p)
+		}
+		rgb[i] = uint8(n)
+	}
+	return &color.RGBA{R: rgb[0], G: rgb[1], B: rgb[2], A: 0xff}, nil
+}
+
+// Applies a command of the form "radius <units>", "color <r> <g> <b>", "shadows", "bloom <strength>",
+// "grade <hue> <saturation> <brightness>", "tint <r> <g> <b>", "grade off", "day <seconds> [start]" or "day off"
+func (l *LightEditor) apply(cmd string) error {
+	parts := strings.Fields(cmd)
+	switch {
+	case len(parts) == 2 && parts[0] == "day" && parts[1] == "off":
+		l.e.l.DayNight = nil
+	case (len(parts) == 2 || len(parts) == 3) && parts[0] == "day":
+		length, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil || length <= 0 {
+			return fmt.Errorf("day %q: expected a positive number of seconds", parts[1])
+		}
+		start := 0.0
+		if len(parts) == 3 {
+			start, err = strconv.ParseFloat(parts[2], 64)
+			if err != nil || start < 0 || start >= 1 {
+				return fmt.Errorf("start %q: expected a time of day from 0 at midnight up to 1", parts[2])
+			}
+		}
+		l.e.l.DayNight = &DayNight{Length: length, Start: start}
+	case len(parts) == 2 && parts[0] == "grade" && parts[1] == "off":
+		l.e.l.Grade = nil
+	case len(parts) == 4 && parts[0] == "grade":
+		var values [3]float64
+		for i, p := range parts[1:] {
+			v, err := strconv.ParseFloat(p, 64)
+			if err != nil {
+				return fmt.Errorf("grade %q: expected a number", p)
+			}
+			values[i] = v
+		}
+		if values[1] < -1 || values[2] < -1 {
+			return fmt.Errorf("grade: expected a saturation and brightness of -1 or more")
+		}
+		gr := l.grade()
+		gr.Hue, gr.Saturation, gr.Brightness = values[0]*math.Pi/180, values[1], values[2]
+	case len(parts) == 4 && parts[0] == "tint":
+		tint, err := parseRGB(parts[1:])
+		if err != nil {
+			return err
+		}
+		l.grade().Tint = tint
+	case len(parts) == 1 && parts[0] == "shadows":
+		l.e.l.Shadows = !l.e.l.Shadows
+	case len(parts) == 2 && parts[0] == "bloom":
+		bloom, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil || bloom < 0 {
+			return fmt.Errorf("bloom %q: expected a strength of 0 or more", parts[1])
+		}
+		l.e.l.Bloom = bloom
+	case len(parts) == 2 && parts[0] == "radius":
+		radius, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil || radius <= 0 {
+			return fmt.Errorf("radius %q: expected a positive number of world units", parts[1])
+		}
+		l.next.Radius = radius
+	case len(parts) == 4 && parts[0] == "color":
+		c, err := parseRGB(parts[1:])
+		if err != nil {
+			return err
+		}
+		l.next.Color = c
+	default:
+		return fmt.Errorf("expected 'radius <units>', 'color <r> <g> <b>', 'shadows', 'bloom <strength>', " +
+			"'grade <hue> <saturation> <brightness>', 'tint <r> <g> <b>', 'grade off', 'day <seconds> [start]' or " +
+			"'day off'")
+	}
+	return nil
+}
+
+// The level's color grade, added if it has none
+func (l *LightEditor) grade() *Grade {
+	if l.e.l.Grade == nil {
+		l.e.l.Grade = &Grade{}
+	}
+	return l.e.l.Grade
+}
+
+func (l *LightEditor) Update(r *Root) error {
+	cmd, typ := l.t.Update()
+	if typ {
+		return nil
+	}
+	if cmd != "" {
+		err := l.apply(cmd)
+		if err != nil {
+			l.t.Placeholder = fmt.Sprintf("Failed to apply '%v': %v", cmd, err)
+		} else {
+			l.t.Placeholder = l.describe()
+		}
+	}
+	if MouseClicked(ebiten.MouseButtonLeft) {
+		lt := l.next
+		lt.X, lt.Y = l.e.c.Cursor()
+		l.e.l.Lights = append(l.e.l.Lights, &lt)
+	}
+	return l.e.Update(r)
+}
+
+func (l *LightEditor) Draw(screen *ebiten.Image) {
+	l.e.Draw(screen)
+	l.t.Draw(screen)
+	printAt(screen, "Click to place a light", 10, l.e.c.sh-35)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestLightingUsesNearestLights(t *testing.T) {
+	g := NewGame()
+	g.c.sw, g.c.sh = 240, 160
+	if g.lighting(g.c.ToScreen()) != nil {
+		t.Errorf("lit a level without lights")
+	}
+	// one more light than the shaders take, the furthest from the camera
+	for i := 0; i <= maxLights; i++ {
+		g.lights = append(g.lights, &Light{X: float64(maxLights - i), Y: 0, Radius: 2})
+	}
+	lit := g.lighting(g.c.ToScreen())
+	if lit == nil {
+		t.Fatalf("level with lights drawn unlit")
+	}
+	// the camera's view is 24 units across 240 pixels, so 10 pixels per unit
+	for i := 0; i < maxLights; i++ {
+		x, y, radius := lit.lights[3*i], lit.lights[3*i+1], lit.lights[3*i+2]
+		if want := float32(120 + 10*i); math.Abs(float64(x-want)) > 1e-3 || math.Abs(float64(y-80)) > 1e-3 {
+			t.Errorf("light %v at (%v, %v) on screen, want (%v, 80)", i, x, y, want)
+		}
+		if math.Abs(float64(radius-20)) > 1e-3 {
+			t.Errorf("light %v has a radius of %v pixels, want 20", i, radius)
+		}
+		if c := lit.colors[3*i : 3*i+3]; c[0] != 1 || c[1] != 1 || c[2] != 1 {
+			t.Errorf("light %v colored %v, want white", i, c)
+		}
+	}
+}
+
+func TestBlocksCastShadows(t *testing.T) {
+	l := floorLevel()
+	l.Lights = []*Light{{X: 0, Y: 5, Radius: 10}}
+	g := NewHeadlessGame(l, &Script{})
+	g.c.sw, g.c.sh = 240, 160
+	if lit := g.lighting(g.c.ToScreen()); lit.shadows {
+		t.Errorf("cast shadows in a level without them")
+	}
+	g.shadows = true
+	lit := g.lighting(g.c.ToScreen())
+	if !lit.shadows {
+		t.Fatalf("didn't cast shadows in a level with them")
+	}
+	// the floor is 100x0.5 units at the origin, seen at 10 pixels per unit with y flipped
+	want := []float32{120, 80, 500, 0, 0, -2.5}
+	got := append(lit.blocks[:2:2], lit.axes[:4]...)
+	for i := range want {
+		if math.Abs(float64(got[i]-want[i])) > 1e-3 {
+			t.Fatalf("floor's center and axes on screen %v, want %v", got, want)
+		}
+	}
+	for i, v := range lit.axes[4:] {
+		if v != 0 {
+			t.Fatalf("unused block %v has a size, want only the floor to cast shadows", i/4+1)
+		}
+	}
+}
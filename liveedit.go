@@ -0,0 +1,94 @@
+package main
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Runs the selector against a running game, so objects can be moved mid-simulation. Edits are written back to the
+// level of the editor the game was started from.
+type LiveEditor struct {
+	s Selector
+	a *Admin
+}
+
+// Activates the live editor for the game being played. The game must have been started from an editor.
+func ActivateLiveEditor(r *Root, a *Admin) {
+	l := &a.e.l
+	var ss []Selectable
+	ss = append(ss, &SpawnSelector{
+		C: &a.g.c,
+		L: l,
+	})
+	for n := range l.Spawns {
+		ss = append(ss, &NamedSpawnSelector{C: &a.g.c, L: l, Name: n})
+	}
+	for _, art := range a.g.art {
+		ss = append(ss, &ArtSelector{l: l, a: art})
+	}
+	for _, e := range a.g.entities {
+		if e.block != nil {
+			ss = append(ss, &LiveBlockSelector{l: l, g: a.g, e: e})
+		}
+	}
+	r.a = &LiveEditor{
+		s: Selector{
+			C:           &a.g.c,
+			Selectables: ss,
+		},
+		a: a,
+	}
+}
+
+func (l *LiveEditor) Layout(outsideWidth, outsideHeight int) (screenWidth, screenHeight int) {
+	return l.a.Layout(outsideWidth, outsideHeight)
+}
+
+func (l *LiveEditor) Update(r *Root) error {
+	if Clicked(ebiten.KeyT) {
+		r.a = l.a
+		return nil
+	}
+	l.s.Update()
+	return l.a.g.Update()
+}
+
+func (l *LiveEditor) Draw(screen *ebiten.Image) {
+	l.a.g.Draw(screen)
+	l.s.Draw(screen)
+	printAt(screen, "Live Editor\n(T) Back to game", 10, 10)
+}
+
+// Transforms a block in both the level and the running game
+type LiveBlockSelector struct {
+	l *Level
+	g *Game
+	e *Entity
+}
+
+func (b *LiveBlockSelector) Transform() Mx {
+	return b.e.block.T
+}
+
+func (b *LiveBlockSelector) SetTransform(m Mx) {
+	b.e.block.T = m
+	center, angle, hw, hh := b.e.block.box()
+	b.e.b.SetTransform(center, angle)
+	b.e.b.SetAwake(true)
+	if hw*2 != b.e.w || hh*2 != b.e.h {
+		// resized, rebuild the fixture
+		b.e.b.DestroyFixture(b.e.b.GetFixtureList())
+		b.e.b.CreateFixtureFromDef(b.e.block.fixture(hw, hh))
+		b.e.w, b.e.h = hw*2, hh*2
+	}
+}
+
+func (b *LiveBlockSelector) Delete() {
+	(&BlockSelector{l: b.l, b: b.e.block}).Delete()
+	for i, e := range b.g.entities {
+		if e == b.e {
+			b.g.entities = append(b.g.entities[:i], b.g.entities[i+1:]...)
+			break
+		}
+	}
+	b.g.world.DestroyBody(b.e.b)
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"image/color"
+	"sync/atomic"
+)
+
+// Displays a progress bar while some slow loading work runs in the background.
+type Loading struct {
+	// Describes what is being loaded
+	message string
+	// Progress reported by the background work, updated atomically
+	done, total int64
+	// Receives the result of the background work once it finishes
+	result chan error
+	// Called on the main loop with the result of the work once it finishes. Expected to replace the root app.
+	then func(r *Root, err error) error
+
+	// Screen dimensions for drawing
+	sw, sh int
+}
+
+// Runs the given work in the background and shows a loading screen until it completes, at which point then is called.
+// The work reports its progress by calling the supplied progress function.
+func ActivateLoading(r *Root, message string, work func(progress func(done, total int)) error, then func(r *Root, err error) error) {
+	l := &Loading{
+		message: message,
+		result:  make(chan error, 1),
+		then:    then,
+	}
+	go func() {
+		l.result <- work(func(done, total int) {
+			atomic.StoreInt64(&l.total, int64(total))
+			atomic.StoreInt64(&l.done, int64(done))
+		})
+	}()
+	r.a = l
+}
+
+// Decodes the level at the given path and loads its assets in the background behind a loading screen. then is called
+// with the loaded level, or the error if it couldn't be loaded.
+func ActivateLevelLoading(r *Root, path string, then func(r *Root, l Level, err error) error) {
+	var l Level
+	ActivateLoading(r, fmt.Sprintf("Loading %v", path), func(progress func(done, total int)) error {
+		err := l.decode(path)
+		if err != nil {
+			return err
+		}
+		return l.loadAssets(progress)
+	}, func(r *Root, err error) error {
+		return then(r, l, err)
+	})
+}
+
+func (l *Loading) Layout(outsideWidth, outsideHeight int) (screenWidth, screenHeight int) {
+	l.sw, l.sh = outsideWidth, outsideHeight
+	return outsideWidth, outsideHeight
+}
+
+func (l *Loading) Update(r *Root) error {
+	select {
+	case err := <-l.result:
+		return l.then(r, err)
+	default:
+	}
+	return nil
+}
+
+func (l *Loading) Draw(screen *ebiten.Image) {
+	done, total := atomic.LoadInt64(&l.done), atomic.LoadInt64(&l.total)
+	progress := 0.0
+	if total > 0 {
+		progress = float64(done) / float64(total)
+	}
+	w, h := float64(l.sw)/2, 20.0
+	x, y := (float64(l.sw)-w)/2, (float64(l.sh)-h)/2
+	ebitenutil.DrawRect(screen, x, y, w, h, color.RGBA{R: 0x40, G: 0x40, B: 0x40, A: 0xff})
+	ebitenutil.DrawRect(screen, x, y, w*progress, h, color.White)
+	printAt(screen, l.message, int(x), int(y)-20)
+}
@@ -0,0 +1,62 @@
+package main
+
+// Points the camera somewhere other than the player for a while, e.g to show a door opening across the level. Player
+// input is ignored until the camera returns.
+type LookAt struct {
+	// World position to look at
+	X, Y float64
+	// Seconds before the camera returns to the player, including the time taken to pan over
+	Hold float64
+}
+
+// Runs the trigger for the given event, if any, as if it happened at the given world position.
+func (g *Game) fire(event string, x, y float64) {
+	t, ok := g.Triggers[event]
+	if !ok {
+		return
+	}
+	t.ActivateAt(&g.c, x, y, occlusion(&g.world, g.c.x, g.c.y, x, y))
+	if t.LookAt != nil {
+		look := *t.LookAt
+		g.look = &look
+		g.schedule.After(secondsToTicks(look.Hold), func() {
+			// unless another look at has taken over since
+			if g.look == &look {
+				g.look = nil
+			}
+		})
+	}
+	if t.Shake != nil {
+		g.c.Shake(*t.Shake)
+	}
+	if t.Dialogue != nil {
+		g.say(t.Dialogue)
+	}
+	if t.Cutscene != nil {
+		g.play(t.Cutscene)
+	}
+	if t.Time != nil {
+		g.changeTime(t.Time)
+	}
+	if t.Spawn != "" {
+		g.respawn(t.Spawn)
+	}
+}
+
+// The point the camera should approach, and whether the player is in control rather than watching a look at
+func (g *Game) cameraTarget() (float64, float64, bool) {
+	if g.cutscene != nil {
+		if x, y, _, ok := g.cutscene.camera(float64(g.cutscene.ticks) / ticksPerSecond); ok {
+			return x, y, false
+		}
+	}
+	if g.look != nil {
+		return g.look.X, g.look.Y, false
+	}
+	if g.cameraZone != nil && g.cameraZone.Lock {
+		x, y := g.cameraZone.T.Apply(0, 0)
+		return x, y, true
+	}
+	position := g.p.b.GetPosition()
+	return position.X, position.Y, true
+}
@@ -1,18 +1,26 @@
 package main
 
 import (
-	_ "embed"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"github.com/hajimehoshi/ebiten/v2"
-	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
 	"github.com/hherman1/gobananas/resources"
 	"image/color"
 	"log"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
 )
 
 var mainShader *ebiten.Shader
 var outlineShader *ebiten.Shader
+var photoShader *ebiten.Shader
+var artShader *ebiten.Shader
+var brightShader *ebiten.Shader
+var blurShader *ebiten.Shader
+var crtShader *ebiten.Shader
 
 
 // Serializable wrapper around ebiten's matrix transform type.
@@ -72,8 +80,94 @@ func main() {
 	}
 }
 
+const usage = `Usage: gobananas [flags] [command]
+
+Commands:
+  edit [level]        Open the editor on the level, or the autosave if none is given (default)
+  play <level> [spawn]
+                      Play the level, starting from the named spawn point if given
+  validate <level>    Check the level for problems
+  export <in> <out>   Convert a level, e.g a Tiled map, to the level format
+  simulate <level> <ticks> [script]
+                      Run the level without a window, with input from a JSON script, and print where the player ends up
+  demo [demo...]      Play demos of levels in a loop until a key is pressed, the bundled ones if none are given
+  replay <demo>       Step through a demo with a timeline, to see exactly what happened during it
+  index <dir> [out]   Write a thumbnail of each level in the directory beside it, and an index of the levels to the out
+                      path, or index.json in the directory
+
+Flags:
+`
+
 func run() error {
-	var err error
+	assets := flag.String("assets", "", "Comma separated directories to load resources from before the built in ones")
+	character := flag.String("character", "", "Character to play as, instead of the one the level starts with")
+	pprof := flag.String("pprof", "", "Address to serve pprof profiles and frame phase timings on, e.g "+
+		"localhost:6060. Hitching frames are printed.")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if *assets != "" {
+		resources.SearchPath = strings.Split(*assets, ",")
+	}
+	if *pprof != "" {
+		startProfiling(*pprof)
+	}
+
+	cmd, args := "edit", flag.Args()
+	if len(args) > 0 {
+		cmd, args = args[0], args[1:]
+	}
+	// commands which don't open a window
+	switch cmd {
+	case "validate":
+		if len(args) != 1 {
+			flag.Usage()
+			return fmt.Errorf("validate takes a level")
+		}
+		return validateLevel(args[0])
+	case "export":
+		if len(args) != 2 {
+			flag.Usage()
+			return fmt.Errorf("export takes an input and output level")
+		}
+		return exportLevel(args[0], args[1])
+	case "simulate":
+		if len(args) != 2 && len(args) != 3 {
+			flag.Usage()
+			return fmt.Errorf("simulate takes a level, a number of ticks and optionally a script")
+		}
+		ticks, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("parse ticks: %w", err)
+		}
+		script := ""
+		if len(args) == 3 {
+			script = args[2]
+		}
+		return simulateLevel(args[0], ticks, script)
+	case "index":
+		if len(args) != 1 && len(args) != 2 {
+			flag.Usage()
+			return fmt.Errorf("index takes a directory of levels and optionally an output path")
+		}
+		out := ""
+		if len(args) == 2 {
+			out = args[1]
+		}
+		return indexLevels(args[0], out)
+	}
+
+	err := settings.load(settingsPath)
+	if err != nil {
+		// defaults are fine
+		fmt.Println("Failed to load settings:", err)
+	}
+	for _, w := range bindingWarnings(settings.Bindings, runtime.GOOS) {
+		fmt.Println("Key bindings:", w)
+	}
+	applyRateSettings()
 	mainShader, err = resources.Shader("shaders/main_shader.go")
 	if err != nil {
 		return fmt.Errorf("loading main shader: %w", err)
@@ -82,34 +176,147 @@ func run() error {
 	if err != nil {
 		return fmt.Errorf("loading outline shader: %w", err)
 	}
+	photoShader, err = resources.Shader("shaders/photo_shader.go")
+	if err != nil {
+		return fmt.Errorf("loading photo shader: %w", err)
+	}
+	artShader, err = resources.Shader("shaders/art_shader.go")
+	if err != nil {
+		return fmt.Errorf("loading art shader: %w", err)
+	}
+	brightShader, err = resources.Shader("shaders/bright_shader.go")
+	if err != nil {
+		return fmt.Errorf("loading bright shader: %w", err)
+	}
+	blurShader, err = resources.Shader("shaders/blur_shader.go")
+	if err != nil {
+		return fmt.Errorf("loading blur shader: %w", err)
+	}
+	crtShader, err = resources.Shader("shaders/crt_shader.go")
+	if err != nil {
+		return fmt.Errorf("loading crt shader: %w", err)
+	}
+
+	var r Root
+	switch {
+	case cmd == "edit" && len(args) == 0:
+		ActivateNewEditor(&r, func(r *Root, e *Editor) error {
+			r.a = e
+			return nil
+		})
+	case cmd == "edit" && len(args) == 1:
+		ActivateEditLevel(&r, args[0], func(r *Root, e *Editor) error {
+			r.a = e
+			return nil
+		})
+	case cmd == "demo":
+		err := ActivateAttractMode(&r, args)
+		if err != nil {
+			return err
+		}
+	case cmd == "replay" && len(args) == 1:
+		err := ActivateReplayViewer(&r, args[0])
+		if err != nil {
+			return err
+		}
+	case cmd == "play" && (len(args) == 1 || len(args) == 2):
+		ActivateEditLevel(&r, args[0], func(r *Root, e *Editor) error {
+			if len(args) == 2 {
+				if _, ok := e.l.spawnPoint(args[1]); !ok {
+					return fmt.Errorf("%v has no spawn point %q", args[0], args[1])
+				}
+				e.start = args[1]
+			}
+			if *character != "" {
+				if _, ok := e.l.character(*character); !ok {
+					return fmt.Errorf("%v has no character %q, expected one of %v", args[0], *character,
+						strings.Join(e.l.characterNames(), ", "))
+				}
+				e.character = *character
+			}
+			g := NewGame()
+			e.l.apply(g)
+			g.become(e.character)
+			g.respawn(e.start)
+			r.a = &Admin{g: g, e: e}
+			return nil
+		})
+	default:
+		flag.Usage()
+		return fmt.Errorf("unknown command %v %v", cmd, strings.Join(args, " "))
+	}
 
 	ebiten.SetWindowSize(720, 480)
 	ebiten.SetWindowResizable(true)
-	r := Root{NewEditor()}
+	ebiten.SetFullscreen(settings.Fullscreen)
 	return fmt.Errorf("run game: %w", ebiten.RunGame(&r))
 }
 
+// Converts the level at the in path to the level format and saves it to the out path.
+func exportLevel(in, out string) error {
+	var l Level
+	err := l.decode(in)
+	if err != nil {
+		return err
+	}
+	err = l.save(out)
+	if err != nil {
+		return err
+	}
+	fmt.Println("Exported", in, "to", out)
+	return nil
+}
+
 // The root is a wrapper that implements the game interface and allows games to rewrap themselves to promote a new
 // leader game.
 type Root struct {
 	a App
+	// The last size the window was laid out at
+	w, h int
 }
 
 func (r *Root) Update() error {
 	// Universal updates
 	InputsUpdate()
+	updateQuality()
 	// Circumvent keyboard disabling
-	if ebiten.IsKeyPressed(ebiten.KeyEscape) {
+	if driver.IsKeyPressed(ebiten.KeyEscape) {
 		return fmt.Errorf("escape pressed")
 	}
+	if shortcut(actionFullscreen) {
+		toggleFullscreen()
+	}
+	defer phase("update")()
 	return r.a.Update(r)
 }
 
+// Switches between fullscreen and a window, remembering the choice in the settings
+func toggleFullscreen() {
+	settings.Fullscreen = !settings.Fullscreen
+	ebiten.SetFullscreen(settings.Fullscreen)
+	err := settings.save(settingsPath)
+	if err != nil {
+		fmt.Println("Failed to save settings:", err)
+	}
+}
+
 func (r *Root) Draw(screen *ebiten.Image) {
+	end := phase("draw")
 	r.a.Draw(screen)
+	end()
+	endFrame()
 }
 
 func (r *Root) Layout(outsideWidth, outsideHeight int) (screenWidth, screenHeight int) {
+	// the window can briefly have no size while switching in or out of fullscreen, which would leave the cameras
+	// without an aspect ratio, so the last size is kept until it has one again
+	if outsideWidth <= 0 || outsideHeight <= 0 {
+		if r.w == 0 || r.h == 0 {
+			r.w, r.h = 1, 1
+		}
+		outsideWidth, outsideHeight = r.w, r.h
+	}
+	r.w, r.h = outsideWidth, outsideHeight
 	return r.a.Layout(outsideWidth, outsideHeight)
 }
 
@@ -128,12 +335,26 @@ type Camera struct {
 	hw, hh float64
 	// center of the camera in world units
 	x, y float64
+	// Pixels per world unit to zoom to on the next layout, once the screen size is known. 0 keeps the current zoom.
+	zoom float64
+	// Pixels between the left of the window and the left of the camera's view, when it shares the window
+	left int
+	// The camera's current shake, and seconds since it started
+	shake  Shake
+	shaken float64
+}
+
+// The number of world units spanning the given number of pixels on screen, for things drawn at a fixed size on
+// screen whatever the zoom, e.g selection handles
+func (c *Camera) pixels(n float64) float64 {
+	return n * 2 * c.hw / float64(c.sw)
 }
 
 // Returns a transformation that converts points in world coordinates to screen coordinates for the camera
 func (c *Camera) ToScreen() Mx {
 	geo := Mx{}
-	geo.Translate(-c.x+c.hw, -c.y+c.hh)
+	sx, sy := c.shakeOffset()
+	geo.Translate(-c.x-sx+c.hw, -c.y-sy+c.hh)
 	geo.Scale(1/(2*c.hw), 1/(2*c.hh))
 	//geo.Scale(1, -1)
 	geo.Scale(1, -1)
@@ -151,6 +372,10 @@ func (c *Camera) Layout(outsideWidth, outsideHeight int) (screenWidth, screenHei
 	}
 	c.sw = outsideWidth
 	c.sh = outsideHeight
+	if c.zoom != 0 {
+		c.hh = float64(c.sh) / (2 * c.zoom)
+		c.zoom = 0
+	}
 	c.hw = c.hh * float64(c.sw)/float64(c.sh)
 	return outsideWidth, outsideHeight
 }
@@ -159,8 +384,8 @@ func (c *Camera) Layout(outsideWidth, outsideHeight int) (screenWidth, screenHei
 func (c *Camera) Cursor() (wx, wy float64) {
 	toWorld := c.ToScreen()
 	toWorld.Invert()
-	sx, sy := ebiten.CursorPosition()
-	wx, wy = toWorld.Apply(float64(sx), float64(sy))
+	sx, sy := driver.CursorPosition()
+	wx, wy = toWorld.Apply(float64(sx-c.left), float64(sy))
 	return
 }
 
@@ -168,6 +393,24 @@ func (c *Camera) Cursor() (wx, wy float64) {
 type Admin struct {
 	// Current game instance
 	g *Game
+	// The editor the game was started from, if any. Its level is updated by live edits.
+	e *Editor
+	// Developer overlay for inspecting the game's bodies
+	i Inspector
+	// Developer overlay for frame rate and memory
+	perf PerfOverlay
+
+	// The level's best times, loaded on the first update if the game came from an editor
+	times       BestTimes
+	timesLoaded bool
+	// Where the game's finished run placed in the best times, -1 if it was too slow
+	place int
+	// The fastest run finished this session, raced as a ghost after restarting
+	fastest *Ghost
+	// The level's best time before the game's finished run, 0 if there wasn't one
+	previous time.Duration
+	// The game the best time widget was added to the HUD of
+	hudGame *Game
 }
 
 func (a *Admin) Layout(outsideWidth, outsideHeight int) (screenWidth, screenHeight int) {
@@ -175,20 +418,160 @@ func (a *Admin) Layout(outsideWidth, outsideHeight int) (screenWidth, screenHeig
 }
 
 func (a *Admin) Update(r *Root) error {
+	if a.i.Update(a.g) {
+		// the inspector is taking keyboard input, pause the game
+		return nil
+	}
+	a.perf.Update()
 	if Clicked(ebiten.KeyE) {
-		r.a = NewEditor()
-		return r.a.Update(r)
+		return a.edit(r)
+	}
+	if Clicked(ebiten.KeyR) && a.e != nil {
+		a.restart()
+		return nil
+	}
+	if Clicked(ebiten.KeyT) && a.e != nil {
+		ActivateLiveEditor(r, a)
+		return nil
+	}
+	if Clicked(ebiten.KeyO) {
+		ActivatePhotoMode(r, a)
+		return nil
+	}
+	if Clicked(ebiten.KeyY) && a.e != nil {
+		ActivateSplitView(r, a)
+		return nil
+	}
+	{
+		// volume and display controls
+		changed := true
+		switch {
+		case Clicked(ebiten.KeyMinus):
+			nudgeVolume(&settings.MusicVolume, -0.1)
+		case Clicked(ebiten.KeyEqual):
+			nudgeVolume(&settings.MusicVolume, 0.1)
+		case Clicked(ebiten.KeyLeftBracket):
+			nudgeVolume(&settings.SFXVolume, -0.1)
+		case Clicked(ebiten.KeyRightBracket):
+			nudgeVolume(&settings.SFXVolume, 0.1)
+		case Clicked(ebiten.KeyC):
+			settings.CRT = !settings.CRT
+		default:
+			changed = false
+		}
+		if changed {
+			err := settings.save(settingsPath)
+			if err != nil {
+				fmt.Println("Failed to save settings:", err)
+			}
+		}
 	}
 	err := a.g.Update()
 	if err != nil {
 		return fmt.Errorf("playing: %w", err)
 	}
+	a.finish()
+	switch {
+	case a.g.timer.end != 0:
+		ActivateResults(r, a, true)
+	case a.g.fellOut():
+		ActivateResults(r, a, false)
+	}
 	return nil
 }
 
+// Stops the game and returns to the editor it was started from, or a new one.
+func (a *Admin) edit(r *Root) error {
+	a.g.Stop()
+	if a.e == nil {
+		ActivateNewEditor(r, func(r *Root, e *Editor) error {
+			r.transition(e, transitionFade)
+			return nil
+		})
+		return nil
+	}
+	r.transition(a.e, transitionFade)
+	return r.a.Update(r)
+}
+
+// Adds the game's run to the level's best times once it reaches the goal.
+func (a *Admin) finish() {
+	if a.e == nil {
+		return
+	}
+	if !a.timesLoaded {
+		a.timesLoaded = true
+		var err error
+		a.times, err = loadBoard(a.e.path, &a.e.l)
+		if err != nil {
+			fmt.Println("Failed to load best times:", err)
+		}
+	}
+	if a.g.timer.end == 0 || a.g.timer.recorded {
+		return
+	}
+	a.g.timer.recorded = true
+	// the level may have been live edited since the times were loaded
+	if hash, err := playedHash(&a.e.l); err == nil && hash != a.times.Level {
+		a.times = BestTimes{Level: hash}
+	}
+	a.previous, _ = a.times.best()
+	a.place = a.times.add(a.g.timer.elapsed(a.g.time))
+	if a.place == 0 || a.fastest == nil {
+		run := a.g.run
+		a.fastest = &run
+	}
+	err := a.times.save(bestTimesPath(a.e.path))
+	if err != nil {
+		fmt.Println("Failed to save best times:", err)
+	}
+}
+
+// Replaces the game with a fresh one from the editor's level, keeping the camera's zoom. The fastest finished run is
+// raced as a ghost in the new game, or the abandoned run if none have finished.
+func (a *Admin) restart() {
+	a.g.Stop()
+	g := NewGame()
+	a.e.l.apply(g)
+	g.become(a.e.character)
+	g.respawn(a.e.start)
+	g.c.hw, g.c.hh = a.g.unframed()
+	g.c.zoom = 0
+	run := a.g.run
+	g.ghost = &run
+	if a.fastest != nil {
+		g.ghost = a.fastest
+	}
+	a.g = g
+	a.i = Inspector{}
+}
+
 func (a *Admin) Draw(screen *ebiten.Image) {
+	if a.hudGame != a.g {
+		// the game is replaced on restart, so the widget is added to each new one
+		a.hudGame = a.g
+		a.g.hud.Add(anchorTopRight, TextWidget(func(g *Game) string {
+			if best, ok := a.times.best(); ok && g.goal != nil {
+				return "Best " + formatRunTime(best)
+			}
+			return ""
+		}))
+	}
 	a.g.Draw(screen)
-	ebitenutil.DebugPrintAt(screen, "(E) Edit Mode", 10, 10)
+	a.i.Draw(screen, a.g)
+	var l *Level
+	if a.e != nil {
+		l = &a.e.l
+	}
+	a.perf.Draw(screen, l, a.g)
+	printAt(screen, "(E) Edit Mode\n(R) Restart\n(T) Live Edit\n(O) Photo Mode\n(Y) Split View\n(I) Inspector\n"+
+		"(F) Performance", 10, 10)
+	crt := "off"
+	if settings.CRT {
+		crt = "on"
+	}
+	printAt(screen, fmt.Sprintf("(-/=) Music %.0f%%\n([/]) SFX %.0f%%\n(C) CRT %v",
+		settings.MusicVolume*100, settings.SFXVolume*100, crt), 10, 120)
 }
 
 
@@ -0,0 +1,18 @@
+package main
+
+import "testing"
+
+func TestRootLayoutKeepsSizeWhileSwitching(t *testing.T) {
+	r := &Root{a: &countingApp{}}
+	if w, h := r.Layout(0, 0); w != 1 || h != 1 {
+		t.Errorf("laid out at %vx%v before the window had a size, want 1x1", w, h)
+	}
+	r.Layout(720, 480)
+	// switching in or out of fullscreen
+	if w, h := r.Layout(0, 0); w != 720 || h != 480 {
+		t.Errorf("laid out at %vx%v while the window had no size, want the last size 720x480", w, h)
+	}
+	if w, h := r.Layout(1920, 1080); w != 1920 || h != 1080 {
+		t.Errorf("laid out at %vx%v in fullscreen, want 1920x1080", w, h)
+	}
+}
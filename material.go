@@ -0,0 +1,100 @@
+package main
+
+import (
+	"github.com/ByteArena/box2d"
+	"image/color"
+	"math"
+)
+
+// Surface materials a block can be made of
+const (
+	// Slippery, the player slides along it
+	materialIce = "ice"
+	// Slows the player down while they are on it
+	materialMud = "mud"
+	// The player can walk up walls and along ceilings made of it
+	materialSticky = "sticky"
+	// Hurts the player when they touch it, knocking them away, see Game.hurt
+	materialHazard = "hazard"
+)
+
+// Materials in the order the editor cycles through them, "" is a normal block
+var materials = []string{"", materialIce, materialMud, materialSticky, materialHazard}
+
+// Colors blocks of each material are outlined with in the editor
+var materialColors = map[string]color.Color{
+	materialIce:    color.RGBA{R: 0xa0, G: 0xe0, B: 0xff, A: 0xff},
+	materialMud:    color.RGBA{R: 0x80, G: 0x50, B: 0x20, A: 0xff},
+	materialSticky: color.RGBA{R: 0xe0, G: 0x40, B: 0xc0, A: 0xff},
+	materialHazard: color.RGBA{R: 0xff, G: 0x30, B: 0x20, A: 0xff},
+}
+
+const (
+	// Fastest the default character walks on their own
+	walkSpeed = 5
+	// Fastest the default character walks through mud, other characters are slowed in proportion
+	mudSpeed = 2
+	// Contact friction of ice, and of sticky blocks so the player doesn't slide off them
+	iceFriction    = 0.02
+	stickyFriction = 5
+	// Force pressing the player into sticky blocks, per unit of mass
+	stickiness = 5
+)
+
+// How hard the player can push off each material when walking, as a fraction of normal
+var materialGrip = map[string]float64{
+	materialIce: 0.3,
+	materialMud: 0.6,
+}
+
+// The fraction of their normal walking force the player gets from what they're standing on
+func (g *Game) grip() float64 {
+	grip := 1.0
+	for m, f := range materialGrip {
+		if _, ok := g.touching(m); ok {
+			grip = math.Min(grip, f)
+		}
+	}
+	return grip
+}
+
+// The material of the body's block, if any
+func material(b *box2d.B2Body) string {
+	e, ok := b.GetUserData().(*Entity)
+	if !ok || e.block == nil {
+		return ""
+	}
+	return e.block.Material
+}
+
+// Overrides the friction of contacts with slippery or sticky blocks before they are solved
+func (g *Game) PreSolve(contact box2d.B2ContactInterface, oldManifold box2d.B2Manifold) {
+	for _, b := range []*box2d.B2Body{contact.GetFixtureA().GetBody(), contact.GetFixtureB().GetBody()} {
+		switch material(b) {
+		case materialIce:
+			contact.SetFriction(iceFriction)
+		case materialSticky:
+			contact.SetFriction(stickyFriction)
+		}
+	}
+}
+
+// Whether the player is touching a block of the given material, and if so the surface's normal pointing out of the
+// block towards the player.
+func (g *Game) touching(m string) (box2d.B2Vec2, bool) {
+	for next := g.p.b.GetContactList(); next != nil; next = next.Next {
+		if !next.Contact.IsTouching() || material(next.Other) != m {
+			continue
+		}
+		return contactNormal(next.Contact, g.p.b), true
+	}
+	return box2d.B2Vec2{}, false
+}
+
+// Holds the player against a sticky surface with the given normal, cancelling out gravity so they can walk along it.
+func (g *Game) stick(normal box2d.B2Vec2) {
+	mass := g.p.b.GetMass()
+	force := box2d.B2Vec2MulScalar(-mass, g.world.GetGravity())
+	force.OperatorPlusInplace(box2d.B2Vec2MulScalar(-stickiness*mass, normal))
+	g.p.b.ApplyForceToCenter(force, true)
+}
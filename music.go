@@ -0,0 +1,198 @@
+package main
+
+import (
+	"fmt"
+	"github.com/hajimehoshi/ebiten/v2"
+	"image/color"
+	"math"
+	"strings"
+	"time"
+)
+
+var musicColor = color.RGBA{R: 0xc0, G: 0x80, B: 0xff, A: 0xff}
+
+// How long it takes to fade from one track to another when the camera crosses into a music zone
+const musicCrossfade = 2 * time.Second
+
+// A region of the level with its own music, e.g a cave. While the camera is inside it the zone's track plays instead
+// of the level's background audio.
+type MusicZone struct {
+	// Transform of a unit square centered at the origin to the zone
+	T Mx
+	// The track to play in the zone
+	Audio Audio
+}
+
+// Draws the outline of the zone with the name of its track
+func (z *MusicZone) draw(screen *ebiten.Image, toScreen Mx) {
+	drawoutline(screen, z.T, 2, toScreen, musicColor)
+	x, y := z.T.Apply(-0.5, 0.5)
+	sx, sy := toScreen.Apply(x, y)
+	printAt(screen, z.Audio.Path, int(sx)+4, int(sy)+4)
+}
+
+// The track which should be playing, from the first music zone containing the camera or the background audio
+// otherwise. Nil if there shouldn't be any music.
+func (g *Game) track() *Audio {
+	for _, z := range g.musicZones {
+		if contains(z.T, g.c.x, g.c.y) {
+			return &z.Audio
+		}
+	}
+	return g.bgAudio
+}
+
+// Keeps the level's music looping, fading in the track for where the camera is and fading out the rest.
+func (g *Game) mixMusic() {
+	tracks := make([]*Audio, 0, len(g.musicZones)+1)
+	if g.bgAudio != nil {
+		tracks = append(tracks, g.bgAudio)
+	}
+	for _, z := range g.musicZones {
+		tracks = append(tracks, &z.Audio)
+	}
+	current := g.track()
+	if g.musicLevels == nil {
+		// the first track starts at full volume
+		g.musicLevels = map[*Audio]float64{current: 1}
+	}
+	step := 1 / (musicCrossfade.Seconds() * ticksPerSecond)
+	for _, a := range tracks {
+		level := g.musicLevels[a]
+		if a == current {
+			level = math.Min(1, level+step)
+		} else {
+			level = math.Max(0, level-step)
+		}
+		g.musicLevels[a] = level
+		if !a.player.IsPlaying() {
+			_ = a.player.Seek(0)
+			a.player.Play()
+		}
+		// also picks up any changes to the volume settings
+		a.Fade(level)
+	}
+}
+
+// Makes music zones selectable
+type MusicZoneSelector struct {
+	l *Level
+	z *MusicZone
+}
+
+func (m *MusicZoneSelector) Delete() {
+	for i, o := range m.l.MusicZones {
+		if o == m.z {
+			m.l.MusicZones = append(m.l.MusicZones[:i], m.l.MusicZones[i+1:]...)
+			return
+		}
+	}
+}
+
+func (m *MusicZoneSelector) Transform() Mx {
+	return m.z.T
+}
+
+func (m *MusicZoneSelector) SetTransform(t Mx) {
+	m.z.T = t
+}
+
+// Editor for music zones. Type "track <path>" to choose the music, then drag to draw a zone which plays it.
+type MusicEditor struct {
+	e *Editor
+	t *Typer
+	// The track for new zones
+	path string
+	// The zone being dragged out, and the point the drag started at
+	creating *Mx
+	startx   float64
+	starty   float64
+}
+
+func ActivateMusicEditor(r *Root, e *Editor) {
+	m := &MusicEditor{e: e, t: &Typer{C: &e.c}}
+	m.t.Placeholder = m.describe()
+	r.a = m
+}
+
+func (m *MusicEditor) Layout(outsideWidth, outsideHeight int) (screenWidth, screenHeight int) {
+	return m.e.Layout(outsideWidth, outsideHeight)
+}
+
+func (m *MusicEditor) String() string {
+	return "Music"
+}
+
+// Summarizes the track for the next zone
+func (m *MusicEditor) describe() string {
+	path := m.path
+	if path == "" {
+		path = "none"
+	}
+	return fmt.Sprintf("Music Editor: track %v. Enter 'track <path>' to change.", path)
+}
+
+// Applies a command of the form "track <path>"
+func (m *MusicEditor) apply(cmd string) error {
+	parts := strings.Fields(cmd)
+	if len(parts) != 2 || parts[0] != "track" {
+		return fmt.Errorf("expected 'track <path>'")
+	}
+	m.path = parts[1]
+	return nil
+}
+
+// Adds a zone playing the current track
+func (m *MusicEditor) place(t Mx) error {
+	if m.path == "" {
+		return fmt.Errorf("no track chosen")
+	}
+	z := &MusicZone{T: t, Audio: Audio{Path: m.path, music: true}}
+	err := z.Audio.Load()
+	if err != nil {
+		return err
+	}
+	m.e.l.MusicZones = append(m.e.l.MusicZones, z)
+	return nil
+}
+
+func (m *MusicEditor) Update(r *Root) error {
+	cmd, typ := m.t.Update()
+	if typ {
+		return nil
+	}
+	if cmd != "" {
+		err := m.apply(cmd)
+		if err != nil {
+			m.t.Placeholder = fmt.Sprintf("Failed to apply '%v': %v", cmd, err)
+		} else {
+			m.t.Placeholder = m.describe()
+		}
+	}
+	if driver.IsMouseButtonPressed(ebiten.MouseButtonLeft) {
+		wx, wy := m.e.c.Cursor()
+		if m.creating == nil {
+			m.creating = &Mx{}
+			m.startx, m.starty = wx, wy
+		}
+		*m.creating = boxBetween(m.startx, m.starty, wx, wy)
+	} else if m.creating != nil {
+		if _, _, hw, hh := decompose(*m.creating); hw*2 >= minBlockSize && hh*2 >= minBlockSize {
+			err := m.place(*m.creating)
+			if err != nil {
+				m.t.Placeholder = fmt.Sprintf("Failed to place zone: %v", describe(err))
+			}
+		}
+		m.creating = nil
+	}
+	return m.e.Update(r)
+}
+
+func (m *MusicEditor) Draw(screen *ebiten.Image) {
+	m.e.Draw(screen)
+	m.t.Draw(screen)
+	if m.creating != nil {
+		drawoutline(screen, *m.creating, 2, m.e.c.ToScreen(), musicColor)
+	}
+	printAt(screen, "Drag to draw a zone playing the track", 10, m.e.c.sh-35)
+}
@@ -0,0 +1,17 @@
+package main
+
+import "testing"
+
+func TestTrackFollowsCamera(t *testing.T) {
+	g := NewGame()
+	g.bgAudio = &Audio{Path: "resources/song.mp3"}
+	cave := &MusicZone{T: boxBetween(10, -5, 30, 5), Audio: Audio{Path: "resources/cave.mp3"}}
+	g.musicZones = []*MusicZone{cave}
+	if a := g.track(); a != g.bgAudio {
+		t.Errorf("playing %v outside the cave, want the background audio", a.Path)
+	}
+	g.c.x = 20
+	if a := g.track(); a != &cave.Audio {
+		t.Errorf("playing %v inside the cave, want the cave's track", a.Path)
+	}
+}
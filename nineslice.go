@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"github.com/hajimehoshi/ebiten/v2"
+	"image"
+	"math"
+	"strconv"
+)
+
+// Pixels in from each edge of an art's image which keep their proportions when the art is stretched, so that only
+// the middle of the image stretches, e.g for panels and long borders. The corners are scaled evenly to fit the art's
+// shorter side, and the edges only stretch along their length.
+type NineSlice struct {
+	Left, Top, Right, Bottom int
+}
+
+// Parses the insets of a nine slice from the arguments of a command, in the order left, top, right and bottom
+func parseNineSlice(args []string) (NineSlice, error) {
+	if len(args) != 4 {
+		return NineSlice{}, fmt.Errorf("expected 4 insets, left, top, right and bottom, got %v", len(args))
+	}
+	var insets [4]int
+	for i, a := range args {
+		n, err := strconv.Atoi(a)
+		if err != nil || n < 0 {
+			return NineSlice{}, fmt.Errorf("inset %q: expected a whole number of pixels", a)
+		}
+		insets[i] = n
+	}
+	return NineSlice{Left: insets[0], Top: insets[1], Right: insets[2], Bottom: insets[3]}, nil
+}
+
+// Whether the insets fit inside an image of the given size, leaving some of the middle to stretch
+func (s NineSlice) fits(w, h int) bool {
+	return s.Left+s.Right < w && s.Top+s.Bottom < h
+}
+
+// Draws the art's image stretched over a unit square centered at the origin, transformed by t and then toScreen, and
+// lit by the lighting if it's set. Nine sliced art stretches only its middle. Time is passed to custom shaders, in
+// seconds.
+func drawArt(screen *ebiten.Image, a *Art, t Mx, toScreen Mx, lit *lighting, time float64) {
+	w, h := a.img.Size()
+	if a.Slice == nil || !a.Slice.fits(w, h) {
+		// unflip the images
+		var geo Mx
+		geo.Scale(1/float64(w), 1/float64(h))
+		geo.Translate(-0.5, -0.5)
+		geo.Scale(1, -1)
+		geo.Concat(t.GeoM)
+		geo.Concat(toScreen.GeoM)
+		drawArtPiece(screen, a, a.img.Bounds(), geo, lit, time)
+		return
+	}
+	s := *a.Slice
+	// world units across the unit square, and per pixel of the corners
+	_, _, hw, hh := decompose(t)
+	if hw == 0 || hh == 0 {
+		return
+	}
+	pixel := math.Min(2*hw/float64(w), 2*hh/float64(h))
+	// the slices' edges in the image's pixels, and in the unit square, with y up
+	xs := [4]int{0, s.Left, w - s.Right, w}
+	ys := [4]int{0, s.Top, h - s.Bottom, h}
+	lx := [4]float64{-0.5, -0.5 + float64(s.Left)*pixel/(2*hw), 0.5 - float64(s.Right)*pixel/(2*hw), 0.5}
+	ly := [4]float64{0.5, 0.5 - float64(s.Top)*pixel/(2*hh), -0.5 + float64(s.Bottom)*pixel/(2*hh), -0.5}
+	min := a.img.Bounds().Min
+	for row := 0; row < 3; row++ {
+		for col := 0; col < 3; col++ {
+			pw, ph := xs[col+1]-xs[col], ys[row+1]-ys[row]
+			if pw == 0 || ph == 0 {
+				continue
+			}
+			r := image.Rect(xs[col], ys[row], xs[col+1], ys[row+1]).Add(min)
+			var geo Mx
+			geo.Scale((lx[col+1]-lx[col])/float64(pw), (ly[row+1]-ly[row])/float64(ph))
+			geo.Translate(lx[col], ly[row])
+			geo.Concat(t.GeoM)
+			geo.Concat(toScreen.GeoM)
+			drawArtPiece(screen, a, r, geo, lit, time)
+		}
+	}
+}
+
+// Draws the part of the art's image inside r with the given transform from its pixels to the screen, lit by the
+// lighting and the art's normal map if it has one and the lighting is set, or drawn by its custom shader if it has one
+func drawArtPiece(screen *ebiten.Image, a *Art, r image.Rectangle, geo Mx, lit *lighting, time float64) {
+	img := a.img.SubImage(r).(*ebiten.Image)
+	if lit == nil && (a.Shader == nil || a.Shader.shader == nil) {
+		screen.DrawImage(img, &ebiten.DrawImageOptions{GeoM: geo.GeoM})
+		return
+	}
+	uniforms := lit.uniforms()
+	images := [4]*ebiten.Image{img}
+	if a.normal != nil && a.normal.Bounds().Size() == a.img.Bounds().Size() {
+		offset := a.normal.Bounds().Min.Sub(a.img.Bounds().Min)
+		images[1] = a.normal.SubImage(r.Add(offset)).(*ebiten.Image)
+		uniforms["Normals"] = float32(1)
+	}
+	sw, sh := screen.Size()
+	cx, cy := driver.CursorPosition()
+	uniforms["Time"] = float32(time)
+	uniforms["Cursor"] = []float32{float32(cx), float32(cy)}
+	uniforms["ScreenPixels"] = []float32{float32(sw), float32(sh)}
+	shader, uniforms := a.Shader.use(artShader, uniforms)
+	screen.DrawRectShader(r.Dx(), r.Dy(), shader, &ebiten.DrawRectShaderOptions{
+		GeoM:     geo.GeoM,
+		Uniforms: uniforms,
+		Images:   images,
+	})
+}
@@ -0,0 +1,21 @@
+package main
+
+import "testing"
+
+func TestParseNineSlice(t *testing.T) {
+	s, err := parseNineSlice([]string{"1", "2", "3", "4"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (NineSlice{Left: 1, Top: 2, Right: 3, Bottom: 4}); s != want {
+		t.Errorf("parsed %+v, want %+v", s, want)
+	}
+	if !s.fits(5, 7) || s.fits(4, 7) || s.fits(5, 6) {
+		t.Errorf("%+v should fit a 5x7 image but not 4x7 or 5x6", s)
+	}
+	for _, args := range [][]string{{"1", "2", "3"}, {"1", "2", "3", "-4"}, {"1", "2", "3", "x"}} {
+		if _, err := parseNineSlice(args); err == nil {
+			t.Errorf("parsed %v, want an error", args)
+		}
+	}
+}
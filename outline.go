@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"image/color"
+	"sort"
+	"strings"
+)
+
+// Width in pixels of the outline panel
+const outlineWidth = 260
+
+var (
+	outlineBackground = color.RGBA{A: 0xc0}
+	outlineHighlight  = color.RGBA{R: 0x80, G: 0x20, B: 0x20, A: 0xc0}
+	outlineDisabled   = color.RGBA{R: 0x90, G: 0x90, B: 0x90, A: 0xff}
+)
+
+// A panel along the right of the select editor listing every object in the level by name. Clicking an entry selects
+// it and centers the camera on it, so objects hidden under others can still be picked.
+type Outline struct {
+	open bool
+	// Index of the first entry shown, when there are more than fit on the screen
+	first int
+}
+
+// A line of the outline
+type outlineEntry struct {
+	label string
+	// Selected when the entry is clicked. Nil for entries with nothing to select, e.g triggers no block fires.
+	s Selectable
+}
+
+// Lists the selectables, followed by the level's triggers linked to the blocks which fire them
+func outlineEntries(l *Level, ss []Selectable) []outlineEntry {
+	var entries []outlineEntry
+	blocks := make(map[*Block]int)
+	for i, b := range l.Blocks {
+		blocks[b] = i
+	}
+	named := make(map[string]Selectable)
+	for _, s := range ss {
+		entries = append(entries, outlineEntry{label: outlineLabel(s, blocks), s: s})
+		if b, ok := s.(*BlockSelector); ok && b.b.Name != "" {
+			if _, ok := named[b.b.Name]; !ok {
+				named[b.b.Name] = s
+			}
+		}
+	}
+	var events []string
+	for n := range l.Triggers {
+		events = append(events, n)
+	}
+	sort.Strings(events)
+	for _, n := range events {
+		entry := outlineEntry{label: fmt.Sprintf("Trigger '%v'", n)}
+		if name := strings.TrimPrefix(n, touchEvent("")); name != n {
+			entry.s = named[name]
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// Names the selectable in the outline
+func outlineLabel(s Selectable, blocks map[*Block]int) string {
+	switch s := s.(type) {
+	case *SpawnSelector:
+		return "Spawn"
+	case *NamedSpawnSelector:
+		return fmt.Sprintf("Spawn '%v'", s.Name)
+	case *ArtSelector:
+		return fmt.Sprintf("Art %v", s.a.Path)
+	case *BlockSelector:
+		if s.b.Name != "" {
+			return fmt.Sprintf("Block '%v'", s.b.Name)
+		}
+		return fmt.Sprintf("Block %v", blocks[s.b])
+	case *PrefabSelector:
+		return fmt.Sprintf("Prefab %v", s.p.Path)
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(fmt.Sprintf("%T", s), "*main."), "Selector")
+}
+
+// Entries which fit on a screen of the given height
+func outlineRows(sh int) int {
+	rows := (sh - 60) / lineHeight(TextStyle{})
+	if rows < 1 {
+		return 1
+	}
+	return rows
+}
+
+// Toggles and scrolls the outline, and selects the clicked entry. Reports whether the click was on the outline, in
+// which case it shouldn't also select things in the level.
+func (o *Outline) Update(s *Selector, l *Level) bool {
+	if Clicked(ebiten.KeyTab) {
+		o.open = !o.open
+	}
+	if !o.open {
+		return false
+	}
+	entries := outlineEntries(l, s.Selectables)
+	rows := outlineRows(s.C.sh)
+	if Clicked(ebiten.KeyHome) {
+		o.first -= rows
+	}
+	if Clicked(ebiten.KeyEnd) {
+		o.first += rows
+	}
+	if o.first > len(entries)-rows {
+		o.first = len(entries) - rows
+	}
+	if o.first < 0 {
+		o.first = 0
+	}
+	// the view may start partway across the window, e.g in the split view
+	x, y := driver.CursorPosition()
+	x -= s.C.left
+	if x < s.C.sw-outlineWidth || x >= s.C.sw {
+		return false
+	}
+	if !MouseClicked(ebiten.MouseButtonLeft) {
+		return driver.IsMouseButtonPressed(ebiten.MouseButtonLeft)
+	}
+	i := o.first + (y-10)/lineHeight(TextStyle{})
+	if y < 10 || i >= len(entries) || entries[i].s == nil {
+		return true
+	}
+	s.s = entries[i].s
+	s.state = selidle
+	t := s.s.Transform()
+	s.C.x, s.C.y = t.Apply(0, 0)
+	return true
+}
+
+func (o *Outline) Draw(screen *ebiten.Image, s *Selector, l *Level) {
+	if !o.open {
+		return
+	}
+	x := s.C.sw - outlineWidth
+	ebitenutil.DrawRect(screen, float64(x), 0, outlineWidth, float64(s.C.sh), outlineBackground)
+	entries := outlineEntries(l, s.Selectables)
+	line := lineHeight(TextStyle{})
+	rows := outlineRows(s.C.sh)
+	for i := o.first; i < len(entries) && i < o.first+rows; i++ {
+		e := entries[i]
+		y := 10 + (i-o.first)*line
+		if e.s != nil && e.s == s.s {
+			ebitenutil.DrawRect(screen, float64(x), float64(y), outlineWidth, float64(line), outlineHighlight)
+		}
+		style := TextStyle{}
+		if e.s == nil {
+			style.Color = outlineDisabled
+		}
+		drawText(screen, e.label, x+10, y, style)
+	}
+	if len(entries) > rows {
+		printAt(screen, fmt.Sprintf("(Home/End) Scroll, %v-%v of %v", o.first+1, o.first+rows, len(entries)),
+			x+10, s.C.sh-50)
+	}
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"fmt"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hherman1/gobananas/resources"
+	"image/color"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Levels whose assets use more memory than this are flagged by the performance overlay
+const memoryBudget = 256 << 20
+
+// The most assets the performance overlay lists
+const perfListed = 8
+
+// Frames shown in the performance overlay's frame time graph, and its height in pixels at the frame time of a tick.
+// Frames taking over one and a half ticks, so jitter around a tick isn't flagged, are drawn in red.
+const (
+	perfFrames      = 120
+	perfGraphHeight = 40
+)
+
+var (
+	perfFrameColor = color.RGBA{R: 0x60, G: 0xe0, B: 0x60, A: 0xc0}
+	perfSlowColor  = color.RGBA{R: 0xff, G: 0x40, B: 0x30, A: 0xc0}
+)
+
+// Developer overlay showing the frame rate, how busy the game is, a graph of recent frame times and the memory used
+// by the level's assets. Toggled with F.
+type PerfOverlay struct {
+	open bool
+	// When the last frame was drawn, and the times between recent frames, oldest first
+	drawn  time.Time
+	frames []time.Duration
+}
+
+// Records the time since the last frame. Called every frame, whether or not the overlay is open.
+func (p *PerfOverlay) frame() {
+	now := time.Now()
+	if !p.drawn.IsZero() {
+		p.frames = append(p.frames, now.Sub(p.drawn))
+		if len(p.frames) > perfFrames {
+			p.frames = p.frames[len(p.frames)-perfFrames:]
+		}
+	}
+	p.drawn = now
+}
+
+func (p *PerfOverlay) Update() {
+	if Clicked(ebiten.KeyF) {
+		p.open = !p.open
+	}
+}
+
+// Resource paths of every image and audio file the level uses, without duplicates.
+func (l *Level) assetPaths() []string {
+	seen := make(map[string]bool)
+	var paths []string
+	add := func(p string) {
+		if !seen[p] {
+			seen[p] = true
+			paths = append(paths, p)
+		}
+	}
+	for _, a := range l.Art {
+		add(a.Path)
+		for _, v := range a.Variants {
+			add(v)
+		}
+	}
+	for _, a := range []*Art{l.BGArt, l.PlayerArt} {
+		if a != nil {
+			add(a.Path)
+		}
+	}
+	for _, c := range l.Characters {
+		if c.Art != nil {
+			add(c.Art.Path)
+		}
+	}
+	for _, t := range l.Tilemaps {
+		add(t.Tileset)
+	}
+	if l.BGAudio != nil {
+		add(l.BGAudio.Path)
+	}
+	for _, t := range l.Triggers {
+		if t.Audio != nil {
+			add(t.Audio.Path)
+		}
+	}
+	for _, a := range l.Ambients {
+		add(a.Audio.Path)
+	}
+	for _, z := range l.MusicZones {
+		add(z.Audio.Path)
+	}
+	return paths
+}
+
+// Formats a number of bytes for humans
+func formatBytes(b int) string {
+	switch {
+	case b >= 1<<20:
+		return fmt.Sprintf("%.1fMB", float64(b)/(1<<20))
+	case b >= 1<<10:
+		return fmt.Sprintf("%.1fKB", float64(b)/(1<<10))
+	}
+	return fmt.Sprintf("%dB", b)
+}
+
+// Draws the overlay for the level, and the game being played if any. The level may be nil when playing one which
+// isn't being edited.
+func (p *PerfOverlay) Draw(screen *ebiten.Image, l *Level, g *Game) {
+	p.frame()
+	if !p.open {
+		return
+	}
+	var s strings.Builder
+	quality := "high"
+	if lowQuality {
+		quality = "low"
+	}
+	_, _ = fmt.Fprintf(&s, "FPS %.0f  TPS %.0f  Quality %v\n", ebiten.CurrentFPS(), ebiten.CurrentTPS(), quality)
+	if g != nil {
+		_, _ = fmt.Fprintf(&s, "Bodies %v  Entities %v  Draws %v\n", g.world.GetBodyCount(), len(g.entities), g.draws)
+	}
+	w, h := screen.Size()
+	p.drawGraph(screen, w/2, h/2-10)
+	if l == nil {
+		printAt(screen, s.String(), w/2, h/2)
+		return
+	}
+
+	// atlas regions share their atlas's memory, so count each atlas once
+	counted := make(map[string]bool)
+	var usage []resources.Usage
+	total := 0
+	for _, path := range l.assetPaths() {
+		base := path
+		if i := strings.Index(path, "#"); i >= 0 {
+			base = path[:i]
+		}
+		if counted[base] {
+			continue
+		}
+		counted[base] = true
+		u := resources.Usage{Path: base, Bytes: resources.MemoryUsage(base)}
+		usage = append(usage, u)
+		total += u.Bytes
+	}
+	sort.Slice(usage, func(i, j int) bool {
+		return usage[i].Bytes > usage[j].Bytes
+	})
+	_, _ = fmt.Fprintf(&s, "Level assets %v of %v budget", formatBytes(total), formatBytes(memoryBudget))
+	if total > memoryBudget {
+		s.WriteString("  OVER BUDGET")
+	}
+	s.WriteString("\n")
+	for i, u := range usage {
+		if i == perfListed {
+			_, _ = fmt.Fprintf(&s, "  ... %v more\n", len(usage)-perfListed)
+			break
+		}
+		_, _ = fmt.Fprintf(&s, "  %8v %v\n", formatBytes(u.Bytes), u.Path)
+	}
+	printAt(screen, s.String(), w/2, h/2)
+}
+
+// Draws the recent frame times as a bar per frame, with the bottom left of the graph at x, y
+func (p *PerfOverlay) drawGraph(screen *ebiten.Image, x, y int) {
+	for i, d := range p.frames {
+		c := perfFrameColor
+		if d > tickDuration*3/2 {
+			c = perfSlowColor
+		}
+		bar := math.Min(3, float64(d)/float64(tickDuration)) * perfGraphHeight
+		ebitenutil.DrawRect(screen, float64(x+2*i), float64(y)-bar, 2, bar, c)
+	}
+	ebitenutil.DrawRect(screen, float64(x), float64(y-perfGraphHeight), 2*perfFrames, 1, color.White)
+	printAt(screen, fmt.Sprintf("%.1fms", float64(tickDuration)/float64(time.Millisecond)), x+2*perfFrames+4,
+		y-perfGraphHeight-8)
+}
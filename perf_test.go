@@ -0,0 +1,17 @@
+package main
+
+import "testing"
+
+func TestPerfOverlayKeepsRecentFrames(t *testing.T) {
+	var p PerfOverlay
+	p.frame()
+	if len(p.frames) != 0 {
+		t.Errorf("recorded %v frame times from the first frame, want none", len(p.frames))
+	}
+	for i := 0; i < perfFrames+10; i++ {
+		p.frame()
+	}
+	if len(p.frames) != perfFrames {
+		t.Errorf("kept %v frame times, want the last %v", len(p.frames), perfFrames)
+	}
+}
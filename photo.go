@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"github.com/ByteArena/box2d"
+	"github.com/hajimehoshi/ebiten/v2"
+	"image/png"
+	"math"
+	"os"
+	"time"
+)
+
+// Screenshots are exported at this multiple of the window resolution
+const photoScale = 4
+
+// Photo mode pauses the game and frees the camera so the player can frame and export screenshots of the level.
+type PhotoMode struct {
+	// The paused game
+	a *Admin
+	// The game's camera before entering photo mode, restored on exit.
+	saved Camera
+
+	// Strength of the vignette effect, between 0 and 1
+	vignette float64
+	// Blur radius in pixels of the depth of field effect
+	blur float64
+	// If true the controls are hidden
+	hideHelp bool
+	// Result of the last export, if any
+	status string
+
+	// The game is rendered here before effects are applied
+	frame *ebiten.Image
+}
+
+// Activates photo mode for the game currently being played.
+func ActivatePhotoMode(r *Root, a *Admin) {
+	r.a = &PhotoMode{
+		a:        a,
+		saved:    a.g.c,
+		vignette: 0.3,
+	}
+}
+
+func (p *PhotoMode) Layout(outsideWidth, outsideHeight int) (screenWidth, screenHeight int) {
+	return p.a.Layout(outsideWidth, outsideHeight)
+}
+
+func (p *PhotoMode) Update(r *Root) error {
+	c := &p.a.g.c
+	if Clicked(ebiten.KeyO) {
+		*c = p.saved
+		r.a = p.a
+		return nil
+	}
+	{
+		// free camera
+		speed := c.hh / 50
+		if driver.IsKeyPressed(ebiten.KeyRight) {
+			c.x += speed
+		}
+		if driver.IsKeyPressed(ebiten.KeyLeft) {
+			c.x -= speed
+		}
+		if driver.IsKeyPressed(ebiten.KeyUp) {
+			c.y += speed
+		}
+		if driver.IsKeyPressed(ebiten.KeyDown) {
+			c.y -= speed
+		}
+		_, yoff := driver.Wheel()
+		if yoff != 0 {
+			c.hh *= math.Pow(0.98, yoff)
+			c.hw *= math.Pow(0.98, yoff)
+		}
+		d := MouseDrag(ebiten.MouseButtonRight)
+		if d != (box2d.B2Vec2{}) {
+			c.x -= 2 * c.hw * d.X / float64(c.sw)
+			c.y += 2 * c.hh * d.Y / float64(c.sh)
+		}
+	}
+	{
+		// effect sliders
+		if driver.IsKeyPressed(ebiten.KeyDigit1) {
+			p.vignette = math.Max(0, p.vignette-0.01)
+		}
+		if driver.IsKeyPressed(ebiten.KeyDigit2) {
+			p.vignette = math.Min(1, p.vignette+0.01)
+		}
+		if driver.IsKeyPressed(ebiten.KeyDigit3) {
+			p.blur = math.Max(0, p.blur-0.1)
+		}
+		if driver.IsKeyPressed(ebiten.KeyDigit4) {
+			p.blur = math.Min(20, p.blur+0.1)
+		}
+	}
+	if Clicked(ebiten.KeyH) {
+		p.hideHelp = !p.hideHelp
+	}
+	if Clicked(ebiten.KeyEnter) {
+		path := fmt.Sprintf("photo-%v.png", time.Now().Unix())
+		err := p.export(path)
+		if err != nil {
+			p.status = fmt.Sprintf("Failed to export: %v", err)
+		} else {
+			p.status = fmt.Sprintf("Saved %v", path)
+		}
+	}
+	return nil
+}
+
+// Renders the game with the current effects to the given image, which is expected to match the game camera's size.
+func (p *PhotoMode) render(dst *ebiten.Image) {
+	c := p.a.g.c
+	if p.frame == nil || p.frame.Bounds().Dx() != c.sw || p.frame.Bounds().Dy() != c.sh {
+		if p.frame != nil {
+			p.frame.Dispose()
+		}
+		p.frame = ebiten.NewImage(c.sw, c.sh)
+	}
+	p.frame.Clear()
+	p.a.g.Draw(p.frame)
+	// the blur is specified in window pixels, so it needs to grow with the resolution of exports
+	scale := float64(c.sw) / float64(p.saved.sw)
+	dst.DrawRectShader(c.sw, c.sh, photoShader, &ebiten.DrawRectShaderOptions{
+		Uniforms: map[string]interface{}{
+			"Vignette":     float32(p.vignette),
+			"Blur":         float32(p.blur * scale),
+			"ScreenPixels": []float32{float32(c.sw), float32(c.sh)},
+		},
+		Images: [4]*ebiten.Image{p.frame},
+	})
+}
+
+// Renders the current view at a multiple of the window resolution and writes it as a png to the given path.
+func (p *PhotoMode) export(path string) error {
+	c := &p.a.g.c
+	window := *c
+	defer func() {
+		*c = window
+		p.frame.Dispose()
+		p.frame = nil
+	}()
+	c.Layout(window.sw*photoScale, window.sh*photoScale)
+	img := ebiten.NewImage(c.sw, c.sh)
+	defer img.Dispose()
+	p.render(img)
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0666)
+	if err != nil {
+		return fmt.Errorf("open file to save photo: %w", err)
+	}
+	defer f.Close()
+	err = png.Encode(f, img)
+	if err != nil {
+		return fmt.Errorf("encode png: %w", err)
+	}
+	return nil
+}
+
+func (p *PhotoMode) Draw(screen *ebiten.Image) {
+	p.render(screen)
+	if p.hideHelp {
+		return
+	}
+	printAt(screen, fmt.Sprintf(`Photo Mode
+(O) Exit  (H) Hide controls
+(Arrows/Right drag/Wheel) Move camera
+(1/2) Vignette %.2f
+(3/4) Depth of field %.1f
+(Enter) Export %vx screenshot
+%v`, p.vignette, p.blur, photoScale, p.status), 10, 10)
+}
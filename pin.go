@@ -0,0 +1,96 @@
+package main
+
+// A transform which rotates then moves to the given pose, without scaling. Art pinned to a block is positioned
+// relative to the block's pose so it isn't stretched when the block is resized.
+func pose(x, y, angle float64) Mx {
+	var m Mx
+	m.Rotate(angle)
+	m.Translate(x, y)
+	return m
+}
+
+// The pose of a block in the level
+func (b *Block) pose() Mx {
+	center, angle, _, _ := b.box()
+	return pose(center.X, center.Y, angle)
+}
+
+// The block the art is pinned to, if any
+func (l *Level) parent(a *Art) *Block {
+	if a.Parent == nil || *a.Parent < 0 || *a.Parent >= len(l.Blocks) {
+		return nil
+	}
+	return l.Blocks[*a.Parent]
+}
+
+// The art's transform in world coordinates, following the block it is pinned to if any.
+func (l *Level) artTransform(a *Art) Mx {
+	t := a.T
+	if b := l.parent(a); b != nil {
+		t.Concat(b.pose().GeoM)
+	}
+	return t
+}
+
+// Moves the art to the given world transform, keeping it pinned to its block if any.
+func (l *Level) setArtTransform(a *Art, m Mx) {
+	if b := l.parent(a); b != nil {
+		inv := b.pose()
+		inv.Invert()
+		m.Concat(inv.GeoM)
+	}
+	a.T = m
+}
+
+// Pins the art to the block with the given index, or unpins it if the index is -1. The art stays where it is.
+func (l *Level) pin(a *Art, block int) {
+	world := l.artTransform(a)
+	a.Parent = nil
+	if block >= 0 {
+		a.Parent = &block
+	}
+	l.setArtTransform(a, world)
+}
+
+// The transform to draw the art with in world coordinates, following the entity it is pinned to as it moves, and moved
+// by any cutscene. Art pinned to a block which has crumbled away isn't drawn.
+func (g *Game) artTransform(a *Art) (Mx, bool) {
+	t := a.T
+	if e, ok := g.pinned[a]; ok && a.Parent != nil {
+		if !e.b.IsActive() {
+			return Mx{}, false
+		}
+		position, angle := g.drawTransform(e.b)
+		if e.crumble != nil {
+			position.OperatorPlusInplace(e.crumble.shake(g.time))
+		}
+		t.Concat(pose(position.X, position.Y, angle).GeoM)
+	}
+	if k, ok := g.moved[a]; ok {
+		t = k.apply(t)
+	}
+	return t, true
+}
+
+// Pins the selected art to the block under the cursor, or unpins it if there is no block there.
+func (t *SelectEditor) pinSelection() {
+	as, ok := t.s.s.(*ArtSelector)
+	if !ok {
+		return
+	}
+	cx, cy := t.s.C.Cursor()
+	block := -1
+	for _, s := range t.s.Selectables {
+		bs, ok := s.(*BlockSelector)
+		if !ok || !t.s.hit(cx, cy, bs.Transform()) {
+			continue
+		}
+		for i, b := range t.e.l.Blocks {
+			if b == bs.b {
+				block = i
+			}
+		}
+		break
+	}
+	t.e.l.pin(as.a, block)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+// A level with two blocks and art pinned to the second
+func pinnedLevel(t *testing.T) (Level, *Art) {
+	l := NewLevel()
+	var a, b Mx
+	a.Scale(2, 1)
+	b.Scale(2, 1)
+	b.Translate(10, 0)
+	l.Blocks = []*Block{{T: a}, {T: b}}
+	var art Mx
+	art.Translate(10, 2)
+	l.Art = []*Art{{T: art}}
+	l.pin(l.Art[0], 1)
+	return l, l.Art[0]
+}
+
+func assertAt(t *testing.T, m Mx, x, y float64) {
+	t.Helper()
+	gx, gy := m.Apply(0, 0)
+	if math.Abs(gx-x) > 1e-9 || math.Abs(gy-y) > 1e-9 {
+		t.Errorf("at %v, %v, want %v, %v", gx, gy, x, y)
+	}
+}
+
+func TestPinnedArtFollowsBlock(t *testing.T) {
+	l, a := pinnedLevel(t)
+	assertAt(t, l.artTransform(a), 10, 2)
+	l.Blocks[1].T.Translate(5, 1)
+	assertAt(t, l.artTransform(a), 15, 3)
+	// rotating the block about its center swings the art around it
+	center, _, _, _ := l.Blocks[1].box()
+	l.Blocks[1].T.Translate(-center.X, -center.Y)
+	l.Blocks[1].T.Rotate(math.Pi / 2)
+	l.Blocks[1].T.Translate(center.X, center.Y)
+	assertAt(t, l.artTransform(a), 13, 1)
+}
+
+func TestRemovingBlockUnpinsArt(t *testing.T) {
+	l, a := pinnedLevel(t)
+	l.Blocks[1].T.Translate(5, 0)
+	l.removeBlock(1)
+	if a.Parent != nil {
+		t.Fatalf("art still pinned to block %v", *a.Parent)
+	}
+	assertAt(t, a.T, 15, 2)
+}
+
+func TestRemovingEarlierBlockKeepsPin(t *testing.T) {
+	l, a := pinnedLevel(t)
+	pinned := l.Blocks[1]
+	l.removeBlock(0)
+	if l.parent(a) != pinned {
+		t.Errorf("art lost its block after an earlier block was removed")
+	}
+	assertAt(t, l.artTransform(a), 10, 2)
+}
+
+func TestRemovingBlockRewiresSwitches(t *testing.T) {
+	l := NewLevel()
+	l.Blocks = []*Block{{}, {}, {}}
+	s := &Switch{Targets: []int{0, 1, 2}}
+	l.Switches = []*Switch{s}
+	l.removeBlock(1)
+	if len(s.Targets) != 2 || s.Targets[0] != 0 || s.Targets[1] != 1 {
+		t.Errorf("targets %v after removing block 1, want [0 1]", s.Targets)
+	}
+}
+
+func TestRemovingArtRewiresCutscenes(t *testing.T) {
+	l := NewLevel()
+	l.Art = []*Art{{}, {}, {}}
+	c := &Cutscene{Moves: []ArtMove{{Art: 0}, {Art: 1}, {Art: 2}}}
+	l.Triggers["intro"] = Trigger{Cutscene: c}
+	l.removeArt(1)
+	if len(c.Moves) != 2 || c.Moves[0].Art != 0 || c.Moves[1].Art != 1 {
+		t.Errorf("moves %+v after removing art 1, want art 0 and 1", c.Moves)
+	}
+	if len(l.Art) != 2 {
+		t.Errorf("got %v art, want 2", len(l.Art))
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"github.com/ByteArena/box2d"
+	"math"
+)
+
+// Straight down, the player's down outside of planetary levels
+var worldDown = box2d.B2Vec2{X: 0, Y: -1}
+
+// The unit direction the player treats as down. In planetary levels this points at the nearest gravity well, so the
+// player can walk around small planets, otherwise it is straight down.
+func (g *Game) down() box2d.B2Vec2 {
+	if !g.planetary {
+		return worldDown
+	}
+	pos := g.p.b.GetPosition()
+	nearest := math.Inf(1)
+	down := worldDown
+	for _, a := range g.attractors {
+		if !a.Well {
+			continue
+		}
+		dir := box2d.B2Vec2{X: a.X - pos.X, Y: a.Y - pos.Y}
+		d := dir.Normalize()
+		if d < nearest && d > 0 {
+			nearest = d
+			down = dir
+		}
+	}
+	return down
+}
+
+// Turns the player so their feet point down
+func (g *Game) orient(down box2d.B2Vec2) {
+	if !g.planetary {
+		return
+	}
+	angle := math.Atan2(down.Y, down.X) + math.Pi/2
+	g.p.b.SetTransform(g.p.b.GetPosition(), angle)
+}
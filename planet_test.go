@@ -0,0 +1,51 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+// A planetary level with a 4x4 planet at the origin pulling the player, who spawns off its right side.
+func planetLevel() Level {
+	l := NewLevel()
+	var planet Mx
+	planet.Scale(4, 4)
+	l.Blocks = []*Block{{T: planet}}
+	l.Attractors = []*Attractor{{Radius: 50, Strength: 10, PlayerOnly: true, Well: true}}
+	l.Planetary = true
+	l.Spawn.X = 4
+	return l
+}
+
+func TestPlayerLandsOnSideOfPlanet(t *testing.T) {
+	s := &Script{}
+	g := NewHeadlessGame(planetLevel(), s)
+	err := Simulate(g, 3*ticksPerSecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pos := g.p.b.GetPosition()
+	// resting with their feet on the planet's right side
+	if pos.X < 2.4 || pos.X > 2.6 {
+		t.Errorf("player at x %v, want about 2.5", pos.X)
+	}
+	// feet pointing left
+	if a := math.Remainder(g.p.b.GetAngle(), 2*math.Pi); math.Abs(a+math.Pi/2) > 0.01 {
+		t.Errorf("player at angle %v, want %v", a, -math.Pi/2)
+	}
+}
+
+func TestPlayerWalksAroundPlanet(t *testing.T) {
+	s := &Script{Steps: []ScriptStep{
+		{Tick: 2 * ticksPerSecond, Press: []string{"D"}},
+	}}
+	g := NewHeadlessGame(planetLevel(), s)
+	err := Simulate(g, 3*ticksPerSecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// right is clockwise around the planet, so down the right side
+	if y := g.p.b.GetPosition().Y; y > -0.5 {
+		t.Errorf("player at y %v after walking, want below -0.5", y)
+	}
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"github.com/ByteArena/box2d"
+	"github.com/hajimehoshi/ebiten/v2"
+	"image/color"
+	"math"
+)
+
+// Portals are created this thick in world units, so fast bodies can't skip over them in one tick.
+const portalThickness = 0.5
+
+var portalColors = [2]color.RGBA{{R: 0x30, G: 0x90, B: 0xff, A: 0xff}, {R: 0xff, G: 0x90, B: 0x20, A: 0xff}}
+
+// A pair of linked portals. Bodies moving into the front of either end leave from the front of the other, with their
+// velocity rotated into the exit's frame so momentum is preserved.
+type Portal struct {
+	// Transforms of a unit square centered at the origin to each end. The front of each end faces along its
+	// transformed +y axis.
+	A, B Mx
+}
+
+// A transform from world coordinates to the unscaled local coordinates of the given end, where the end's center is
+// at the origin and its front faces +y.
+func portalFrame(end Mx) Mx {
+	center, angle, _, _ := decompose(end)
+	var m Mx
+	m.Rotate(angle)
+	m.Translate(center.X, center.Y)
+	m.Invert()
+	return m
+}
+
+// Maps world coordinates entering the from end to the corresponding world coordinates leaving the to end, and returns
+// the rotation applied.
+func portalMapping(from, to Mx) (Mx, float64) {
+	m := portalFrame(from)
+	m.Rotate(math.Pi)
+	exit := portalFrame(to)
+	exit.Invert()
+	m.Concat(exit.GeoM)
+	_, fromAngle, _, _ := decompose(from)
+	_, toAngle, _, _ := decompose(to)
+	return m, toAngle - fromAngle + math.Pi
+}
+
+// Teleports any dynamic bodies moving into either end to the other.
+func (p *Portal) teleport(g *Game) {
+	for b := g.world.GetBodyList(); b != nil; b = b.GetNext() {
+		if b.GetType() != box2d.B2BodyType.B2_dynamicBody {
+			continue
+		}
+		if !p.enter(b, p.A, p.B) {
+			p.enter(b, p.B, p.A)
+		}
+	}
+}
+
+// Moves the body from one end to the other if it is moving into the from end. Returns true if it was moved.
+func (p *Portal) enter(b *box2d.B2Body, from, to Mx) bool {
+	pos := b.GetPosition()
+	inv := from
+	inv.Invert()
+	lx, ly := inv.Apply(pos.X, pos.Y)
+	if lx < -0.5 || lx > 0.5 || ly < -0.5 || ly > 0.5 {
+		return false
+	}
+	// moving against the front's normal?
+	_, angle, _, _ := decompose(from)
+	normal := box2d.B2Vec2{X: -math.Sin(angle), Y: math.Cos(angle)}
+	v := b.GetLinearVelocity()
+	if box2d.B2Vec2Dot(v, normal) >= 0 {
+		return false
+	}
+
+	m, rot := portalMapping(from, to)
+	x, y := m.Apply(pos.X, pos.Y)
+	b.SetTransform(box2d.B2Vec2{X: x, Y: y}, b.GetAngle()+rot)
+	b.SetLinearVelocity(box2d.B2Vec2{
+		X: v.X*math.Cos(rot) - v.Y*math.Sin(rot),
+		Y: v.X*math.Sin(rot) + v.Y*math.Cos(rot),
+	})
+	return true
+}
+
+// If the player is part way through either end of the portal, draws the part of the player emerging from the other.
+func (g *Game) drawPlayerThrough(screen *ebiten.Image, p *Portal, screenTransform Mx) {
+	pos := g.p.b.GetPosition()
+	// distance from the center of the player to its corners
+	reach := math.Hypot(g.p.w, g.p.h) / 2
+	for _, ends := range [][2]Mx{{p.A, p.B}, {p.B, p.A}} {
+		from, to := ends[0], ends[1]
+		center, angle, hw, _ := decompose(from)
+		// position of the player relative to the end, along and in front of its surface
+		dx, dy := pos.X-center.X, pos.Y-center.Y
+		along := dx*math.Cos(angle) + dy*math.Sin(angle)
+		front := -dx*math.Sin(angle) + dy*math.Cos(angle)
+		if math.Abs(along) > hw || front > reach || front < -reach {
+			continue
+		}
+		m, _ := portalMapping(from, to)
+		var geo Mx
+		geo.Translate(-g.p.w/2, -g.p.h/2)
+		geo.Rotate(g.p.b.GetAngle())
+		geo.Translate(pos.X, pos.Y)
+		geo.Concat(m.GeoM)
+		geo.Concat(screenTransform.GeoM)
+		g.drawPlayer(screen, geo, g.p.b.GetLinearVelocity())
+	}
+}
+
+// Draws both ends of the portal
+func (p *Portal) draw(screen *ebiten.Image, toScreen Mx) {
+	drawPortalEnd(screen, p.A, portalColors[0], toScreen)
+	drawPortalEnd(screen, p.B, portalColors[1], toScreen)
+}
+
+// Draws one end of a portal, with a line marking its front
+func drawPortalEnd(screen *ebiten.Image, end Mx, clr color.Color, toScreen Mx) {
+	l0x, l0y := end.Apply(-0.5, 0)
+	l1x, l1y := end.Apply(0.5, 0)
+	drawline(screen, l0x, l0y, l1x, l1y, 4, toScreen, clr)
+	cx, cy := end.Apply(0, 0)
+	fx, fy := end.Apply(0, 0.5)
+	drawline(screen, cx, cy, fx, fy, 2, toScreen, clr)
+}
+
+// Editor for placing portals. Drag along a surface to place the first end of a pair, then drag again to place the
+// second. The front of each end is to the left of the drag direction.
+type PortalEditor struct {
+	// The pair being placed, if the first end is done
+	pending *Portal
+	// The end being dragged out, if any, and where the drag started
+	creating *Mx
+	startx   float64
+	starty   float64
+
+	e *Editor
+}
+
+func (p *PortalEditor) Layout(outsideWidth, outsideHeight int) (screenWidth, screenHeight int) {
+	return p.e.Layout(outsideWidth, outsideHeight)
+}
+
+func (p *PortalEditor) String() string {
+	return "Portals"
+}
+
+func (p *PortalEditor) Update(r *Root) error {
+	if driver.IsMouseButtonPressed(ebiten.MouseButtonLeft) {
+		wx, wy := p.e.c.Cursor()
+		if p.creating == nil {
+			p.creating = &Mx{}
+			p.startx, p.starty = wx, wy
+		}
+		var m Mx
+		m.Scale(math.Hypot(wx-p.startx, wy-p.starty), portalThickness)
+		m.Rotate(math.Atan2(wy-p.starty, wx-p.startx))
+		m.Translate((wx+p.startx)/2, (wy+p.starty)/2)
+		*p.creating = m
+	}
+	if !driver.IsMouseButtonPressed(ebiten.MouseButtonLeft) && p.creating != nil {
+		if p.pending == nil {
+			p.pending = &Portal{A: *p.creating}
+		} else {
+			p.pending.B = *p.creating
+			p.e.l.Portals = append(p.e.l.Portals, p.pending)
+			p.pending = nil
+		}
+		p.creating = nil
+	}
+	return p.e.Update(r)
+}
+
+func (p *PortalEditor) Draw(screen *ebiten.Image) {
+	p.e.Draw(screen)
+	toScreen := p.e.c.ToScreen()
+	if p.pending != nil {
+		drawPortalEnd(screen, p.pending.A, portalColors[0], toScreen)
+		if p.creating != nil {
+			drawPortalEnd(screen, *p.creating, portalColors[1], toScreen)
+		}
+	} else if p.creating != nil {
+		drawPortalEnd(screen, *p.creating, portalColors[0], toScreen)
+	}
+	msg := "Portal Editor: drag to place the first end"
+	if p.pending != nil {
+		msg = "Portal Editor: drag to place the second end"
+	}
+	printAt(screen, msg, 10, p.e.c.sh-20)
+}
+
+// Makes each end of a portal selectable. Deleting either end removes the pair.
+type PortalSelector struct {
+	l   *Level
+	p   *Portal
+	end *Mx
+}
+
+func (p *PortalSelector) Delete() {
+	for i, o := range p.l.Portals {
+		if o == p.p {
+			p.l.Portals = append(p.l.Portals[:i], p.l.Portals[i+1:]...)
+			return
+		}
+	}
+}
+
+func (p *PortalSelector) Transform() Mx {
+	return *p.end
+}
+
+func (p *PortalSelector) SetTransform(m Mx) {
+	*p.end = m
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hherman1/gobananas/resources"
+	"image/color"
+	"os"
+	"path/filepath"
+)
+
+// Directory the editor saves prefabs to, one file per prefab named after it
+const prefabDir = "prefabs"
+
+// Color instances of prefabs are outlined with in the editor
+var prefabColor = color.RGBA{R: 0x60, G: 0xc0, B: 0xff, A: 0xff}
+
+// A prefab placed in a level. Prefabs are groups of blocks and art saved to their own file, see Clip, which can be
+// placed any number of times in any level. The file is read whenever the level is loaded, so changes to a prefab show
+// up in every instance of it. Since they're edited separately, the level's saved hash leaves out their contents, but
+// best times account for them, see playedHash.
+type Prefab struct {
+	// Path of the prefab's file, e.g "prefabs/bridge.json"
+	Path string
+	// Moves the prefab's blocks and art from where they were saved to where they are in the level
+	T Mx
+
+	// The loaded prefab
+	clip Clip
+	// Transform of a unit square around the loaded prefab's blocks and art, before T
+	box Mx
+}
+
+// Reads the prefab's file, without loading its images or shaders. The path is resolved like other assets, see
+// resources.ReadLocal.
+func (p *Prefab) decode() (Clip, error) {
+	var c Clip
+	b, err := resources.ReadLocal(p.Path)
+	if err != nil {
+		return c, fmt.Errorf("read prefab: %w", err)
+	}
+	err = c.read(bytes.NewReader(b))
+	return c, err
+}
+
+// Loads the prefab's blocks and art from its file
+func (p *Prefab) Load() error {
+	c, err := p.decode()
+	if err != nil {
+		return err
+	}
+	err = c.loadAssets()
+	if err != nil {
+		return err
+	}
+	var ts []Mx
+	for _, b := range c.Blocks {
+		ts = append(ts, b.T)
+	}
+	l := Level{Blocks: c.Blocks}
+	for _, a := range c.Art {
+		ts = append(ts, l.artTransform(a))
+	}
+	p.clip = c
+	p.box = Mx{}
+	if len(ts) > 0 {
+		p.box = enclosing(ts)
+	}
+	return nil
+}
+
+// Copies of the prefab's blocks and art where the instance places them. Art pinned to one of the prefab's blocks has
+// its Parent set to the block's index in the returned blocks.
+func (p *Prefab) placed() ([]*Block, []*Art) {
+	var blocks []*Block
+	for _, b := range p.clip.Blocks {
+		kopy := *b
+		kopy.T.Concat(p.T.GeoM)
+		blocks = append(blocks, &kopy)
+	}
+	var art []*Art
+	for _, a := range p.clip.Art {
+		kopy := *a
+		if a.Parent == nil {
+			kopy.T.Concat(p.T.GeoM)
+		}
+		art = append(art, &kopy)
+	}
+	return blocks, art
+}
+
+// The level's blocks and art followed by those of each prefab placed in it, as they're played
+func (l *Level) expanded() ([]*Block, []*Art) {
+	if len(l.Prefabs) == 0 {
+		return l.Blocks, l.Art
+	}
+	blocks := append([]*Block(nil), l.Blocks...)
+	art := append([]*Art(nil), l.Art...)
+	for _, p := range l.Prefabs {
+		pb, pa := p.placed()
+		for _, a := range pa {
+			if a.Parent != nil {
+				parent := len(blocks) + *a.Parent
+				a.Parent = &parent
+			}
+		}
+		blocks = append(blocks, pb...)
+		art = append(art, pa...)
+	}
+	return blocks, art
+}
+
+// Draws the prefab's blocks and art in the editor, outlined to set them apart from the level's own
+func (p *Prefab) draw(e *Editor, screen *ebiten.Image) {
+	blocks, art := p.placed()
+	for _, b := range blocks {
+		e.drawBlock(screen, b)
+	}
+	toScreen := e.c.ToScreen()
+	l := Level{Blocks: blocks}
+	for _, a := range layered(art) {
+		drawArt(screen, a, l.artTransform(a), toScreen, nil, 0)
+	}
+	box := p.box
+	box.Concat(p.T.GeoM)
+	drawoutline(screen, box, 2, toScreen, prefabColor)
+}
+
+// Makes a prefab instance selectable
+type PrefabSelector struct {
+	l *Level
+	p *Prefab
+}
+
+func (s *PrefabSelector) Paste() Selectable {
+	kopy := *s.p
+	s.l.Prefabs = append(s.l.Prefabs, &kopy)
+	return &PrefabSelector{l: s.l, p: &kopy}
+}
+
+func (s *PrefabSelector) Delete() {
+	for i, o := range s.l.Prefabs {
+		if o == s.p {
+			s.l.Prefabs = append(s.l.Prefabs[:i], s.l.Prefabs[i+1:]...)
+			return
+		}
+	}
+}
+
+func (s *PrefabSelector) Transform() Mx {
+	t := s.p.box
+	t.Concat(s.p.T.GeoM)
+	return t
+}
+
+func (s *PrefabSelector) SetTransform(m Mx) {
+	t := s.p.box
+	t.Invert()
+	t.Concat(m.GeoM)
+	s.p.T = t
+}
+
+// Saves the selected blocks and art as the named prefab and replaces them with an instance of it. Other instances of
+// the prefab in the level are reloaded, so re-saving an unpacked prefab updates them all.
+func (t *SelectEditor) makePrefab(name string) error {
+	if t.s.s == nil {
+		return fmt.Errorf("nothing selected")
+	}
+	c := clipOf(t.s.s, &t.e.l)
+	if c.empty() {
+		return fmt.Errorf("select blocks or art to make a prefab from")
+	}
+	// the prefab is saved around the origin, and placed back where the selection was
+	center := t.s.s.Transform()
+	cx, cy := center.Apply(0, 0)
+	for _, b := range c.Blocks {
+		b.T.Translate(-cx, -cy)
+	}
+	for _, a := range c.Art {
+		if a.Parent == nil {
+			a.T.Translate(-cx, -cy)
+		}
+	}
+	err := os.MkdirAll(t.prefabs, 0777)
+	if err != nil {
+		return fmt.Errorf("make prefab directory: %w", err)
+	}
+	path := filepath.Join(t.prefabs, name+".json")
+	err = c.save(path)
+	if err != nil {
+		return err
+	}
+	// replace what was made into the prefab
+	members := []Selectable{t.s.s}
+	if g, ok := t.s.s.(*GroupSelector); ok {
+		members = g.members
+	}
+	for _, m := range members {
+		switch m := m.(type) {
+		case *ArtSelector, *BlockSelector:
+			m.(Deletable).Delete()
+			t.s.remove(m)
+		}
+	}
+	for _, o := range t.e.l.Prefabs {
+		if o.Path == path {
+			err = o.Load()
+			if err != nil {
+				return fmt.Errorf("reload instance: %w", err)
+			}
+		}
+	}
+	p := &Prefab{Path: path}
+	p.T.Translate(cx, cy)
+	return t.addPrefab(p)
+}
+
+// Places an instance of the named prefab in the middle of the view
+func (t *SelectEditor) placePrefab(name string) error {
+	p := &Prefab{Path: filepath.Join(t.prefabs, name+".json")}
+	p.T.Translate(t.e.c.x, t.e.c.y)
+	return t.addPrefab(p)
+}
+
+// Loads the instance and adds it to the level, selected
+func (t *SelectEditor) addPrefab(p *Prefab) error {
+	err := p.Load()
+	if err != nil {
+		return err
+	}
+	t.e.l.Prefabs = append(t.e.l.Prefabs, p)
+	t.s.s = &PrefabSelector{l: &t.e.l, p: p}
+	t.s.add(t.s.s)
+	return nil
+}
+
+// Replaces the selected instance with copies of its blocks and art, so they can be edited and saved over the prefab
+func (t *SelectEditor) unpack() error {
+	s, ok := t.s.s.(*PrefabSelector)
+	if !ok {
+		return fmt.Errorf("select a prefab to unpack")
+	}
+	blocks, art := s.p.placed()
+	pasted, err := Clip{Blocks: blocks, Art: art}.paste(&t.e.l)
+	if err != nil {
+		return err
+	}
+	s.Delete()
+	t.s.remove(s)
+	t.s.Selectables = append(t.s.Selectables, pasted...)
+	t.s.s = group(pasted)
+	return nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPrefabExpandsAfterLevel(t *testing.T) {
+	var left, right, offset Mx
+	left.Translate(-1, 0)
+	right.Translate(1, 0)
+	offset.Translate(0, 1)
+	zero := 0
+	l := NewLevel()
+	own := &Block{T: left}
+	l.Blocks = []*Block{own}
+	p := &Prefab{clip: Clip{Blocks: []*Block{{T: right}}, Art: []*Art{{T: offset, Parent: &zero}, {T: offset}}}}
+	p.T.Translate(10, 0)
+	l.Prefabs = []*Prefab{p}
+
+	blocks, art := l.expanded()
+	if len(blocks) != 2 || blocks[0] != own || len(art) != 2 {
+		t.Fatalf("expanded to %v blocks and %v art, want the level's block then the prefab's", len(blocks), len(art))
+	}
+	c := &Camera{sw: 1000, hw: 1}
+	assertCorner(t, c, blocks[1].T, 0, 0, 11, 0)
+	// pinned art follows the prefab's block, now after the level's
+	if a := art[0]; a.Parent == nil || *a.Parent != 1 {
+		t.Errorf("pinned art has parent %v, want 1", a.Parent)
+	}
+	x := Level{Blocks: blocks}
+	assertCorner(t, c, x.artTransform(art[0]), 0, 0, 11, 1)
+	assertCorner(t, c, art[1].T, 0, 0, 10, 1)
+	if *p.clip.Art[0].Parent != 0 || p.clip.Blocks[0].T != right {
+		t.Errorf("expanding changed the prefab")
+	}
+}
+
+func TestPrefabLoadsFromFile(t *testing.T) {
+	var m Mx
+	m.Scale(4, 2)
+	m.Translate(1, 0)
+	path := filepath.Join(t.TempDir(), "step.json")
+	if err := (Clip{Blocks: []*Block{{T: m, Material: materialIce}}}).save(path); err != nil {
+		t.Fatal(err)
+	}
+	p := &Prefab{Path: path}
+	p.T.Translate(0, 5)
+	if err := p.Load(); err != nil {
+		t.Fatal(err)
+	}
+	// the selection box covers the block where the instance puts it
+	s := &PrefabSelector{p: p}
+	c := &Camera{sw: 1000, hw: 1}
+	assertCorner(t, c, s.Transform(), -0.5, -0.5, -1, 4)
+	assertCorner(t, c, s.Transform(), 0.5, 0.5, 3, 6)
+	if err := (&Prefab{Path: filepath.Join(t.TempDir(), "missing.json")}).Load(); err == nil {
+		t.Errorf("loaded a missing prefab, want an error")
+	}
+}
+
+func TestPrefabRejectsBrokenFiles(t *testing.T) {
+	for _, data := range []string{`{"Blocks": [null]}`, `{"Art": [null]}`, `{"Art": [{"Parent": 0}]}`} {
+		path := filepath.Join(t.TempDir(), "broken.json")
+		if err := os.WriteFile(path, []byte(data), 0666); err != nil {
+			t.Fatal(err)
+		}
+		if err := (&Prefab{Path: path}).Load(); err == nil {
+			t.Errorf("loaded prefab %v, want an error", data)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	_ "net/http/pprof"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Frames taking longer than this are reported as hitches while profiling, with the time spent in each phase
+const hitchDuration = 2 * tickDuration
+
+// Times the phases of each frame, e.g physics and drawing the world, so hitches can be tracked down. Nil unless
+// profiling is turned on with the pprof flag.
+var phases *phaseTimes
+
+// Time spent in each phase of the frame being run and the worst so far
+type phaseTimes struct {
+	mu sync.Mutex
+	// Phases in the order they were first timed
+	names []string
+	// Time spent in each phase this frame
+	current map[string]time.Duration
+	// When this frame started
+	started time.Time
+	// The slowest frame so far, and its phases
+	worst       time.Duration
+	worstPhases map[string]time.Duration
+	frames      int
+}
+
+// Starts an HTTP server on the address serving pprof profiles under /debug/pprof/, and the phase timings of the
+// slowest frame under /debug/phases. Frames which hitch are printed as they happen.
+func startProfiling(addr string) {
+	phases = &phaseTimes{current: make(map[string]time.Duration)}
+	http.HandleFunc("/debug/phases", func(w http.ResponseWriter, r *http.Request) {
+		phases.report(w)
+	})
+	go func() {
+		err := http.ListenAndServe(addr, nil)
+		if err != nil {
+			fmt.Println("Failed to serve profiles:", err)
+		}
+	}()
+	fmt.Printf("Serving profiles at http://%v/debug/pprof/ and phase timings at http://%v/debug/phases\n", addr, addr)
+}
+
+// Starts timing a phase of the frame, returning a function which ends it. Phases may be timed several times a
+// frame, e.g once per tick, and are summed. Does nothing unless profiling.
+func phase(name string) func() {
+	if phases == nil {
+		return func() {}
+	}
+	start := time.Now()
+	return func() {
+		phases.add(name, time.Since(start))
+	}
+}
+
+func (p *phaseTimes) add(name string, d time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.current[name]; !ok {
+		known := false
+		for _, n := range p.names {
+			known = known || n == name
+		}
+		if !known {
+			p.names = append(p.names, name)
+		}
+	}
+	p.current[name] += d
+}
+
+// Ends the frame, printing its phases if it hitched. Does nothing unless profiling.
+func endFrame() {
+	if phases == nil {
+		return
+	}
+	phases.end(time.Now())
+}
+
+func (p *phaseTimes) end(now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.started.IsZero() {
+		took := now.Sub(p.started)
+		if took > hitchDuration {
+			fmt.Printf("Frame %v hitched, taking %v: %v\n", p.frames, took.Round(time.Microsecond),
+				p.describe(p.current))
+		}
+		if took > p.worst {
+			p.worst, p.worstPhases = took, p.current
+		}
+	}
+	p.started = now
+	p.current = make(map[string]time.Duration)
+	p.frames++
+}
+
+// Lists the time spent in each phase, in the order they were first timed
+func (p *phaseTimes) describe(times map[string]time.Duration) string {
+	var parts []string
+	for _, n := range p.names {
+		if d, ok := times[n]; ok {
+			parts = append(parts, fmt.Sprintf("%v %v", n, d.Round(time.Microsecond)))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Writes the phases of the slowest frame so far
+func (p *phaseTimes) report(w io.Writer) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, _ = fmt.Fprintf(w, "%v frames, the slowest taking %v: %v\n", p.frames, p.worst.Round(time.Microsecond),
+		p.describe(p.worstPhases))
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPhaseTimesKeepsSlowestFrame(t *testing.T) {
+	p := &phaseTimes{current: make(map[string]time.Duration)}
+	start := time.Unix(0, 0)
+	p.end(start)
+	p.add("update", 2*time.Millisecond)
+	p.add("draw", 3*time.Millisecond)
+	p.add("update", time.Millisecond)
+	p.end(start.Add(10 * time.Millisecond))
+	p.add("draw", time.Millisecond)
+	p.end(start.Add(15 * time.Millisecond))
+
+	var report strings.Builder
+	p.report(&report)
+	want := "3 frames, the slowest taking 10ms: update 3ms, draw 3ms\n"
+	if report.String() != want {
+		t.Errorf("reported %q, want %q", report.String(), want)
+	}
+}
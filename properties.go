@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Where a unit square is moved to by a transform, as the position of its center, its size and the degrees it's turned
+// counterclockwise. A flipped square has a negative height.
+type placement struct {
+	x, y, w, h, rotation float64
+}
+
+// Splits the transform of a unit square into where it places the square. Transforms made by the editor only scale,
+// turn and move the square, so nothing is lost.
+func place(m Mx) placement {
+	a, b := m.Element(0, 0), m.Element(0, 1)
+	c, d := m.Element(1, 0), m.Element(1, 1)
+	w := math.Hypot(a, c)
+	p := placement{x: m.Element(0, 2), y: m.Element(1, 2), w: w, rotation: math.Atan2(c, a) * 180 / math.Pi}
+	if w != 0 {
+		p.h = (a*d - b*c) / w
+	}
+	return p
+}
+
+// The transform of a unit square placed as described
+func (p placement) transform() Mx {
+	var m Mx
+	m.Scale(p.w, p.h)
+	m.Rotate(p.rotation * math.Pi / 180)
+	m.Translate(p.x, p.y)
+	return m
+}
+
+// Fields of the placement which can be set in the properties panel, several at once for commands like "at 2 3"
+var placementFields = map[string]func(p *placement) []*float64{
+	"x":        func(p *placement) []*float64 { return []*float64{&p.x} },
+	"y":        func(p *placement) []*float64 { return []*float64{&p.y} },
+	"w":        func(p *placement) []*float64 { return []*float64{&p.w} },
+	"h":        func(p *placement) []*float64 { return []*float64{&p.h} },
+	"rotation": func(p *placement) []*float64 { return []*float64{&p.rotation} },
+	"angle":    func(p *placement) []*float64 { return []*float64{&p.rotation} },
+	"at":       func(p *placement) []*float64 { return []*float64{&p.x, &p.y} },
+	"size":     func(p *placement) []*float64 { return []*float64{&p.w, &p.h} },
+}
+
+// Sets a property of the selection from a command of the form "<field> <value>...", e.g "rotation 45", "at 2 3",
+// "path resources/grass.png" for art or "material ice" for blocks
+func setProperty(s Selectable, cmd string) error {
+	if s == nil {
+		return fmt.Errorf("nothing selected")
+	}
+	parts := strings.Fields(cmd)
+	if len(parts) == 0 {
+		return fmt.Errorf("expected '<field> <value>'")
+	}
+	if fields, ok := placementFields[parts[0]]; ok {
+		p := place(s.Transform())
+		values := fields(&p)
+		if len(parts) != len(values)+1 {
+			return fmt.Errorf("%v expects %v values", parts[0], len(values))
+		}
+		for i, v := range values {
+			var err error
+			*v, err = strconv.ParseFloat(parts[i+1], 64)
+			if err != nil {
+				return fmt.Errorf("parse %v: %w", parts[0], err)
+			}
+			// ParseFloat accepts "nan" and "inf", which would put the selection nowhere
+			if math.IsNaN(*v) || math.IsInf(*v, 0) {
+				return fmt.Errorf("%v expects finite values, got %v", parts[0], parts[i+1])
+			}
+		}
+		if p.w == 0 || p.h == 0 {
+			return fmt.Errorf("%v would flatten the selection", parts[0])
+		}
+		s.SetTransform(p.transform())
+		return nil
+	}
+	if len(parts) != 2 {
+		return fmt.Errorf("expected '<field> <value>'")
+	}
+	switch s := s.(type) {
+	case *ArtSelector:
+		if parts[0] == "path" {
+			old := s.a.Path
+			s.a.Path = parts[1]
+			if err := s.a.Load(); err != nil {
+				s.a.Path = old
+				return err
+			}
+			return nil
+		}
+	case *BlockSelector:
+		if parts[0] == "material" {
+			material := parts[1]
+			if material == "normal" {
+				material = ""
+			}
+			for _, m := range materials {
+				if m == material {
+					s.b.Material = m
+					return nil
+				}
+			}
+			return fmt.Errorf("unknown material %v, expected normal, %v", parts[1], strings.Join(materials[1:], ", "))
+		}
+	}
+	return fmt.Errorf("unknown field %v", parts[0])
+}
+
+// Describes the selection's properties, and the commands which set them
+func describeProperties(s Selectable) string {
+	p := place(s.Transform())
+	var b strings.Builder
+	_, _ = fmt.Fprintf(&b, "x, y: %.3f, %.3f\nw, h: %.3f, %.3f\nrotation: %.2f\n", p.x, p.y, p.w, p.h, p.rotation)
+	switch s := s.(type) {
+	case *ArtSelector:
+		_, _ = fmt.Fprintf(&b, "path: %v\nlayer: %v\n", s.a.Path, layerName(s.a.Layer))
+	case *BlockSelector:
+		material := s.b.Material
+		if material == "" {
+			material = "normal"
+		}
+		_, _ = fmt.Fprintf(&b, "material: %v\nlayer: %v\n", material, layerName(s.b.Layer))
+	}
+	b.WriteString("(Enter) Set, e.g 'at 2 3', 'size 4 1' or 'angle 45'")
+	return b.String()
+}
+
+// Draws the selection's properties in a box above the bottom left of the screen
+func drawProperties(screen *ebiten.Image, s Selectable, c *Camera) {
+	desc := describeProperties(s)
+	w, h := measureText(desc, TextStyle{})
+	x, y := 10, c.sh-50-h
+	ebitenutil.DrawRect(screen, float64(x-5), float64(y-5), float64(w+10), float64(h+10), outlineBackground)
+	printAt(screen, desc, x, y)
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPlaceRoundTrips(t *testing.T) {
+	for _, p := range []placement{
+		{x: 1, y: 2, w: 3, h: 4},
+		{x: -5, y: 0.5, w: 0.25, h: 2, rotation: 30},
+		{w: 2, h: -1, rotation: -120},
+	} {
+		got := place(p.transform())
+		if math.Abs(got.x-p.x) > 1e-9 || math.Abs(got.y-p.y) > 1e-9 || math.Abs(got.w-p.w) > 1e-9 ||
+			math.Abs(got.h-p.h) > 1e-9 || math.Abs(got.rotation-p.rotation) > 1e-9 {
+			t.Errorf("placed %+v as %+v", p, got)
+		}
+	}
+}
+
+func TestSetPropertyRejectsBadValues(t *testing.T) {
+	l := NewLevel()
+	b := &Block{T: placement{w: 1, h: 1}.transform()}
+	l.Blocks = []*Block{b}
+	s := &BlockSelector{l: &l, b: b}
+	for _, cmd := range []string{"w 0", "x far", "x NaN", "at inf 0", "size 1 -Inf", "material lava",
+		"path resources/grass.png", "depth 3"} {
+		if err := setProperty(s, cmd); err == nil {
+			t.Errorf("set '%v' on a block, want an error", cmd)
+		}
+	}
+	if p := place(b.T); p != (placement{w: 1, h: 1}) || b.Material != "" {
+		t.Errorf("block is placed %+v with material %q after failed sets, want it unchanged", p, b.Material)
+	}
+	a := &Art{Path: "resources/grass.png"}
+	if err := setProperty(&ArtSelector{l: &l, a: a}, "path resources/missing.png"); err == nil ||
+		a.Path != "resources/grass.png" {
+		t.Errorf("set a missing art path with error %v, leaving %v, want an error and the old path", err, a.Path)
+	}
+}
+
+func TestSetPropertyPlacesExactly(t *testing.T) {
+	l := NewLevel()
+	b := &Block{T: placement{w: 1, h: 1}.transform()}
+	l.Blocks = []*Block{b}
+	s := &BlockSelector{l: &l, b: b}
+	for _, cmd := range []string{"at 12.25 -3.5", "size 6 0.5", "angle 0"} {
+		if err := setProperty(s, cmd); err != nil {
+			t.Fatalf("set '%v': %v", cmd, err)
+		}
+	}
+	if p := place(b.T); p != (placement{x: 12.25, y: -3.5, w: 6, h: 0.5}) {
+		t.Errorf("block is placed %+v, want a 6x0.5 platform at 12.25, -3.5", p)
+	}
+	if err := setProperty(s, "at 1"); err == nil {
+		t.Errorf("set 'at 1' without a y, want an error")
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"github.com/ByteArena/box2d"
+	"github.com/hajimehoshi/ebiten/v2"
+	"math"
+	"time"
+)
+
+// Game ticks per second. Gameplay timing is counted in ticks, while physics may step several times per tick.
+const ticksPerSecond = 60
+
+const tickDuration = time.Second / ticksPerSecond
+
+// The most ticks run in one update to catch up on lost time
+const maxCatchUp = 5
+
+// Values for the quality setting
+const (
+	// Start at high quality, dropping to low if the device can't keep up
+	qualityAuto = "auto"
+	qualityHigh = "high"
+	// Skip expensive effects like the full screen background shader
+	qualityLow = "low"
+)
+
+// How many seconds in a row the frame rate must be too low before auto quality drops to low
+const slowSeconds = 3
+
+// Whether expensive effects should be skipped, decided by the quality setting and how the device is coping.
+var lowQuality bool
+
+// Tracks the frame rate to detect slow devices
+var quality struct {
+	// When the frame rate was last checked
+	checked time.Time
+	// Consecutive checks which found the frame rate too low
+	slow int
+}
+
+// Applies the rate settings to ebiten. Must be called after settings are loaded.
+func applyRateSettings() {
+	ebiten.SetVsyncEnabled(settings.Vsync && !settings.UncappedRender)
+	switch {
+	case settings.UncappedRender:
+		// update every frame, games run ticks as time passes
+		ebiten.SetMaxTPS(ebiten.UncappedTPS)
+	case settings.TPS > 0:
+		ebiten.SetMaxTPS(settings.TPS)
+	}
+	lowQuality = settings.Quality == qualityLow
+}
+
+// Whether games are updated at a different rate to their ticks, so bodies should be drawn interpolated between ticks
+// rather than jumping from one to the next
+func interpolating() bool {
+	return settings.UncappedRender || (settings.TPS > 0 && settings.TPS != ticksPerSecond)
+}
+
+// Checks the frame rate once a second, and switches to low quality if it has been too low for a while and the
+// quality setting is auto. Called every tick.
+func updateQuality() {
+	if settings.Quality != qualityAuto || lowQuality {
+		return
+	}
+	if time.Since(quality.checked) < time.Second {
+		return
+	}
+	quality.checked = time.Now()
+	if ebiten.CurrentFPS() < 0.75*ticksPerSecond {
+		quality.slow++
+	} else {
+		quality.slow = 0
+	}
+	if quality.slow >= slowSeconds {
+		fmt.Printf("Running at %.0f FPS, switching to low quality\n", ebiten.CurrentFPS())
+		lowQuality = true
+	}
+}
+
+// The number of physics steps to run per game tick for the physics rate setting
+func physicsSteps() int {
+	return int(math.Max(1, math.Round(float64(settings.PhysicsRate)/ticksPerSecond)))
+}
+
+// Where a body was at the start of the last tick
+type snapshot struct {
+	position box2d.B2Vec2
+	angle    float64
+}
+
+// Remembers where the camera and every body were before the tick, so drawing can interpolate between ticks.
+func (g *Game) snapshot() {
+	if !interpolating() {
+		return
+	}
+	// rebuilt rather than updated, so bodies destroyed since, like bullets, are forgotten
+	g.prev = make(map[*box2d.B2Body]snapshot, g.world.GetBodyCount())
+	for b := g.world.GetBodyList(); b != nil; b = b.GetNext() {
+		g.prev[b] = snapshot{position: b.GetPosition(), angle: b.GetAngle()}
+	}
+	g.prevCamera = g.c
+}
+
+// How far drawing is between the previous tick and the last, from 0 to 1. Always 1 unless interpolating.
+func (g *Game) alpha() float64 {
+	if !interpolating() || g.prev == nil {
+		return 1
+	}
+	return math.Min(1, float64(g.accumulator)/float64(tickDuration))
+}
+
+// The position and angle to draw a body at
+func (g *Game) drawTransform(b *box2d.B2Body) (box2d.B2Vec2, float64) {
+	position, angle := b.GetPosition(), b.GetAngle()
+	prev, ok := g.prev[b]
+	if !ok {
+		return position, angle
+	}
+	a := g.alpha()
+	position.X = prev.position.X + (position.X-prev.position.X)*a
+	position.Y = prev.position.Y + (position.Y-prev.position.Y)*a
+	return position, prev.angle + (angle-prev.angle)*a
+}
+
+// The camera to draw with
+func (g *Game) drawCamera() Camera {
+	if g.prev == nil {
+		return g.c
+	}
+	a := g.alpha()
+	c := g.c
+	c.x = g.prevCamera.x + (c.x-g.prevCamera.x)*a
+	c.y = g.prevCamera.y + (c.y-g.prevCamera.y)*a
+	return c
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"github.com/ByteArena/box2d"
+	"testing"
+)
+
+func TestInterpolatesAtOtherUpdateRates(t *testing.T) {
+	defer func(s Settings) { settings = s }(settings)
+	for _, c := range []struct {
+		tps   int
+		alpha float64
+	}{{ticksPerSecond, 1}, {144, 0.5}} {
+		settings.TPS = c.tps
+		g := NewHeadlessGame(floorLevel(), &Script{})
+		if err := Simulate(g, 2); err != nil {
+			t.Fatal(err)
+		}
+		g.accumulator = tickDuration / 2
+		if a := g.alpha(); a != c.alpha {
+			t.Errorf("drawn %v of the way between ticks updating %v times a second, want %v", a, c.tps, c.alpha)
+		}
+	}
+}
+
+func TestSnapshotForgetsDestroyedBodies(t *testing.T) {
+	defer func(s Settings) { settings = s }(settings)
+	settings.TPS = 144
+	g := NewHeadlessGame(floorLevel(), &Script{})
+	bullet := g.world.CreateBody(box2d.NewB2BodyDef())
+	g.snapshot()
+	g.world.DestroyBody(bullet)
+	g.snapshot()
+	if _, ok := g.prev[bullet]; ok || len(g.prev) != g.world.GetBodyCount() {
+		t.Errorf("remembering %v bodies of %v after one was destroyed, want only those in the world", len(g.prev),
+			g.world.GetBodyCount())
+	}
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"fmt"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"image/color"
+	"math"
+	"time"
+)
+
+// Playback speeds the replay viewer steps through, as multiples of real time
+var replaySpeeds = []float64{0.125, 0.25, 0.5, 1, 2, 4}
+
+// Ticks between the replay viewer's keyframes, and the most ticks it simulates each update to build missing ones
+const (
+	replayKeyframeInterval = 2 * ticksPerSecond
+	replayBuildBudget      = 10 * ticksPerSecond
+)
+
+// Height in pixels of the replay viewer's timeline, and its distance from the bottom of the screen
+const (
+	timelineHeight = 8
+	timelineMargin = 30
+)
+
+var (
+	timelineColor  = color.RGBA{R: 0x60, G: 0x60, B: 0x60, A: 0xff}
+	timelineMarker = color.RGBA{R: 0xff, G: 0xd0, B: 0x40, A: 0xff}
+)
+
+// Plays back a demo for inspection, with a timeline to pause, step and seek through it at different speeds. Replays
+// are simulated without audio. Seeking backwards re-simulates the run from the nearest keyframe before the target,
+// which plays out the same way every time since the input is scripted.
+type ReplayViewer struct {
+	d Demo
+	// The demo's level, decoded and loaded once
+	l Level
+	// The run, simulated up to the tick being shown
+	g      *Game
+	length int
+	// Runs paused at every replayKeyframeInterval ticks, by how many intervals in they are, nil until built. A physics
+	// world can't be copied, so a keyframe is used up when a seek continues from it, and built again in the background.
+	keyframes []*Game
+	// The run being simulated up to the earliest missing keyframe, if any
+	building *Game
+
+	paused bool
+	// Index in replaySpeeds of the playback speed
+	speed int
+	// Fractions of a tick left over from previous updates at the playback speed
+	pending float64
+}
+
+// Activates the replay viewer for the demo at the given path, paused at the start once its level has loaded
+func ActivateReplayViewer(r *Root, path string) error {
+	d, err := loadDemo(path)
+	if err != nil {
+		return err
+	}
+	ActivateLevelLoading(r, d.Level, func(r *Root, l Level, err error) error {
+		if err != nil {
+			return fmt.Errorf("load %v: %w", d.Level, err)
+		}
+		v := &ReplayViewer{d: d, l: l, length: d.length(), paused: true}
+		for i, s := range replaySpeeds {
+			if s == 1 {
+				v.speed = i
+			}
+		}
+		v.restart()
+		r.a = v
+		return nil
+	})
+	return nil
+}
+
+// A new run from the start of the demo
+func (v *ReplayViewer) fresh() *Game {
+	// a fresh copy, scripts keep track of what's held
+	s := Script{Steps: v.d.Script.Steps}
+	g := NewHeadlessGame(v.l, &s)
+	g.respawn("")
+	return g
+}
+
+// Replaces the game with the given run, keeping the camera's zoom
+func (v *ReplayViewer) resume(g *Game) {
+	var c Camera
+	if v.g != nil {
+		c = v.g.c
+		c.hw, c.hh = v.g.unframed()
+	}
+	v.g = g
+	if c.hw != 0 {
+		v.g.c.hw, v.g.c.hh, v.g.c.zoom = c.hw, c.hh, 0
+	}
+}
+
+// Replaces the game with a fresh run from the start of the demo
+func (v *ReplayViewer) restart() {
+	v.resume(v.fresh())
+}
+
+// Takes the latest keyframe at or before the given tick, or a fresh run if none are built
+func (v *ReplayViewer) keyframe(tick int) *Game {
+	for i := tick / replayKeyframeInterval; i > 0; i-- {
+		if i < len(v.keyframes) && v.keyframes[i] != nil {
+			g := v.keyframes[i]
+			v.keyframes[i] = nil
+			return g
+		}
+	}
+	return v.fresh()
+}
+
+// Simulates up to replayBuildBudget ticks towards the earliest missing keyframes
+func (v *ReplayViewer) build() error {
+	if v.keyframes == nil {
+		v.keyframes = make([]*Game, v.length/replayKeyframeInterval+1)
+	}
+	budget := replayBuildBudget
+	// the start is always a fresh run, so it's never kept
+	for i := 1; i < len(v.keyframes) && budget > 0; i++ {
+		if v.keyframes[i] != nil {
+			continue
+		}
+		if v.building == nil {
+			v.building = v.fresh()
+		}
+		n := i*replayKeyframeInterval - v.building.time
+		if n > budget {
+			n = budget
+		}
+		err := Simulate(v.building, n)
+		if err != nil {
+			return fmt.Errorf("replay keyframe: %w", err)
+		}
+		budget -= n
+		if v.building.time < i*replayKeyframeInterval {
+			return nil
+		}
+		v.keyframes[i] = v.building
+		v.building = nil
+	}
+	return nil
+}
+
+// Simulates the run up to the given tick, clamped to the length of the demo
+func (v *ReplayViewer) seek(tick int) error {
+	if tick < 0 {
+		tick = 0
+	}
+	if tick > v.length {
+		tick = v.length
+	}
+	if tick < v.g.time {
+		v.resume(v.keyframe(tick))
+	}
+	err := Simulate(v.g, tick-v.g.time)
+	if err != nil {
+		return fmt.Errorf("replay: %w", err)
+	}
+	return nil
+}
+
+func (v *ReplayViewer) Layout(outsideWidth, outsideHeight int) (screenWidth, screenHeight int) {
+	return v.g.Layout(outsideWidth, outsideHeight)
+}
+
+// The timeline's left edge, width, and top in pixels
+func (v *ReplayViewer) timeline() (float64, float64, float64) {
+	return 10, float64(v.g.c.sw - 20), float64(v.g.c.sh - timelineMargin)
+}
+
+func (v *ReplayViewer) Update(r *Root) error {
+	err := v.build()
+	if err != nil {
+		return err
+	}
+	if Clicked(ebiten.KeyE) {
+		ActivateEditLevel(r, v.d.Level, func(r *Root, e *Editor) error {
+			r.a = e
+			return nil
+		})
+		return nil
+	}
+	if Clicked(ebiten.KeySpace) {
+		v.paused = !v.paused
+		if v.g.time >= v.length {
+			// play again from the start
+			v.restart()
+		}
+	}
+	if Clicked(ebiten.KeyUp) && v.speed < len(replaySpeeds)-1 {
+		v.speed++
+	}
+	if Clicked(ebiten.KeyDown) && v.speed > 0 {
+		v.speed--
+	}
+	// step a tick, or a second with shift
+	step := 1
+	if driver.IsKeyPressed(ebiten.KeyShift) {
+		step = ticksPerSecond
+	}
+	target := v.g.time
+	if Clicked(ebiten.KeyRight) {
+		target += step
+	}
+	if Clicked(ebiten.KeyLeft) {
+		target -= step
+	}
+	if driver.IsMouseButtonPressed(ebiten.MouseButtonLeft) {
+		x, y := driver.CursorPosition()
+		left, width, top := v.timeline()
+		if float64(y) >= top-timelineHeight && float64(y) <= top+2*timelineHeight {
+			target = int(math.Round(float64(v.length) * (float64(x) - left) / width))
+		}
+	}
+	if !v.paused && target == v.g.time {
+		v.pending += replaySpeeds[v.speed]
+		target += int(v.pending)
+		v.pending -= math.Floor(v.pending)
+	}
+	if target != v.g.time {
+		err := v.seek(target)
+		if err != nil {
+			return err
+		}
+	}
+	if v.g.time >= v.length {
+		v.paused = true
+	}
+	return nil
+}
+
+func (v *ReplayViewer) Draw(screen *ebiten.Image) {
+	v.g.Draw(screen)
+	left, width, top := v.timeline()
+	ebitenutil.DrawRect(screen, left, top, width, timelineHeight, timelineColor)
+	x := left + width*float64(v.g.time)/float64(v.length)
+	ebitenutil.DrawRect(screen, x-2, top-timelineHeight/2, 4, 2*timelineHeight, timelineMarker)
+	state := "Playing"
+	if v.paused {
+		state = "Paused"
+	}
+	printAt(screen, fmt.Sprintf(
+		"%v  tick %v/%v  %v  speed x%v\n(Space) Play/Pause (Left/Right) Step, a second with Shift (Up/Down) Speed "+
+			"(E) Edit level",
+		state, v.g.time, v.length, formatRunTime(time.Duration(v.g.time)*tickDuration), replaySpeeds[v.speed]),
+		10, v.g.c.sh-timelineMargin-40)
+}
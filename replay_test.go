@@ -0,0 +1,73 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestReplaySeeksBackToTheSameState(t *testing.T) {
+	d, err := loadDemo("demos/funnel.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := &ReplayViewer{d: d, length: d.length()}
+	err = v.l.decode(d.Level)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v.restart()
+	mid := v.length / 2
+	if err := v.seek(mid); err != nil {
+		t.Fatal(err)
+	}
+	want := v.g.p.b.GetPosition()
+	if err := v.seek(v.length); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.seek(mid); err != nil {
+		t.Fatal(err)
+	}
+	if v.g.time != mid {
+		t.Fatalf("seeked to tick %v, want %v", v.g.time, mid)
+	}
+	if got := v.g.p.b.GetPosition(); got != want {
+		t.Errorf("player at %v after seeking back, want %v like the first time", got, want)
+	}
+}
+
+func TestReplaySeeksBackFromKeyframes(t *testing.T) {
+	d, err := loadDemo("demos/funnel.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := &ReplayViewer{d: d, length: d.length()}
+	err = v.l.decode(d.Level)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v.restart()
+	target := replayKeyframeInterval + 10
+	if err := v.seek(target); err != nil {
+		t.Fatal(err)
+	}
+	want := v.g.p.b.GetPosition()
+	for i := 0; i < 10; i++ {
+		if err := v.build(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if v.keyframes[1] == nil || v.keyframes[1].time != replayKeyframeInterval {
+		t.Fatalf("keyframe 1 wasn't built at tick %v", replayKeyframeInterval)
+	}
+	if err := v.seek(v.length); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.seek(target); err != nil {
+		t.Fatal(err)
+	}
+	if v.keyframes[1] != nil {
+		t.Errorf("seeking back didn't continue from keyframe 1")
+	}
+	if got := v.g.p.b.GetPosition(); v.g.time != target || got != want {
+		t.Errorf("player at %v on tick %v after seeking back, want %v on tick %v", got, v.g.time, want, target)
+	}
+}
@@ -0,0 +1,126 @@
+package resources
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Returned when a resource doesn't exist, embedded or in the search path.
+type NotFoundError struct {
+	Path string
+	// Existing resources with similar paths, closest first. Empty if none are close.
+	Suggestions []string
+}
+
+func (e *NotFoundError) Error() string {
+	if len(e.Suggestions) == 0 {
+		return fmt.Sprintf("%v not found", e.Path)
+	}
+	return fmt.Sprintf("%v not found, did you mean %v?", e.Path, strings.Join(e.Suggestions, " or "))
+}
+
+// Allows errors.Is(err, fs.ErrNotExist)
+func (e *NotFoundError) Unwrap() error {
+	return fs.ErrNotExist
+}
+
+// Returned when a resource exists but is in a format its loader can't decode.
+type UnsupportedFormatError struct {
+	Path string
+	// Extensions the loader supports, e.g ".png"
+	Supported []string
+}
+
+func (e *UnsupportedFormatError) Error() string {
+	return fmt.Sprintf("%v is not a supported format, use %v", e.Path, strings.Join(e.Supported, " or "))
+}
+
+// Supported extensions for each kind of resource
+var (
+	imageFormats  = []string{".png"}
+	audioFormats  = []string{".wav"}
+	shaderFormats = []string{".go"}
+	fontFormats   = []string{".ttf", ".otf"}
+)
+
+// Returns an UnsupportedFormatError if the path doesn't end in one of the given extensions
+func checkFormat(p string, supported []string) error {
+	for _, ext := range supported {
+		if strings.HasSuffix(p, ext) {
+			return nil
+		}
+	}
+	return &UnsupportedFormatError{Path: p, Supported: supported}
+}
+
+// The most suggestions given for a missing resource
+const maxSuggestions = 3
+
+// Builds the error for a missing resource, suggesting existing resources in the same top level directory whose paths
+// are a few edits away.
+func notFound(p string) error {
+	prefix := p
+	if i := strings.Index(p, "/"); i >= 0 {
+		prefix = p[:i+1]
+	}
+	existing, err := List(prefix)
+	if err != nil {
+		return &NotFoundError{Path: p}
+	}
+	type candidate struct {
+		path     string
+		distance int
+	}
+	// close enough to be a typo, or the same file name in another directory
+	limit := len(p)/4 + 1
+	var close []candidate
+	for _, e := range existing {
+		d := editDistance(p, e)
+		if d <= limit || path.Base(e) == path.Base(p) {
+			close = append(close, candidate{e, d})
+		}
+	}
+	sort.SliceStable(close, func(i, j int) bool {
+		return close[i].distance < close[j].distance
+	})
+	nf := &NotFoundError{Path: p}
+	for i := 0; i < len(close) && i < maxSuggestions; i++ {
+		nf.Suggestions = append(nf.Suggestions, close[i].path)
+	}
+	return nf
+}
+
+// The Levenshtein distance between two strings, the fewest single byte insertions, deletions and substitutions that
+// turn one into the other.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
@@ -0,0 +1,60 @@
+package resources
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+)
+
+func TestMissingImageSuggestsClosest(t *testing.T) {
+	_, err := Image("resources/gras.png")
+	var nf *NotFoundError
+	if !errors.As(err, &nf) {
+		t.Fatalf("got %v, want a NotFoundError", err)
+	}
+	if len(nf.Suggestions) == 0 || nf.Suggestions[0] != "resources/grass.png" {
+		t.Errorf("got suggestions %v, want resources/grass.png first", nf.Suggestions)
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("a NotFoundError should be fs.ErrNotExist")
+	}
+}
+
+func TestMissingResourceWithNothingClose(t *testing.T) {
+	_, err := Audio("resources/nothing-like-any-of-these.wav")
+	var nf *NotFoundError
+	if !errors.As(err, &nf) {
+		t.Fatalf("got %v, want a NotFoundError", err)
+	}
+	if len(nf.Suggestions) != 0 {
+		t.Errorf("got suggestions %v, want none", nf.Suggestions)
+	}
+}
+
+func TestUnsupportedImageFormat(t *testing.T) {
+	_, err := Image("resources/jump.wav")
+	var uf *UnsupportedFormatError
+	if !errors.As(err, &uf) {
+		t.Fatalf("got %v, want an UnsupportedFormatError", err)
+	}
+	if len(uf.Supported) == 0 || uf.Supported[0] != ".png" {
+		t.Errorf("got supported formats %v, want .png", uf.Supported)
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	for _, c := range []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"grass", "grass", 0},
+		{"gras", "grass", 1},
+		{"kitten", "sitting", 3},
+		{"", "abc", 3},
+	} {
+		if got := editDistance(c.a, c.b); got != c.want {
+			t.Errorf("editDistance(%q, %q) = %v, want %v", c.a, c.b, got, c.want)
+		}
+	}
+}
@@ -3,13 +3,23 @@ package resources
 import (
 	"bytes"
 	"embed"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/audio/wav"
+	"golang.org/x/image/font/opentype"
+	"image"
 	"image/png"
 	"io"
+	"io/fs"
+	"os"
+	pathpkg "path"
+	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 )
 
 // Sample rate for all our audio
@@ -17,21 +27,198 @@ const SampleRate = 44100
 
 //go:embed shaders
 var shadersFS embed.FS
-var shaders = map[string]*ebiten.Shader{}
 
 //go:embed resources
 var resources embed.FS
 
-// Caches images loaded from disk. Not thread safe.
+// Directories searched, in order, for resources before falling back to the embedded files. Paths given to the loaders
+// are resolved relative to each directory, e.g with "assets" in the search path "resources/grass.png" is first looked
+// for at "assets/resources/grass.png". Must be set before any resources are loaded.
+var SearchPath []string
+
+// Opens the resource at the given path from the first directory in the search path containing it, or from the
+// embedded files if none do. Returns a NotFoundError if it is in neither.
+func open(embedded fs.FS, path string) (fs.File, error) {
+	for _, dir := range SearchPath {
+		f, err := os.DirFS(dir).Open(path)
+		if err == nil {
+			return f, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("open from %v: %w", dir, err)
+		}
+	}
+	f, err := embedded.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, notFound(path)
+	}
+	return f, err
+}
+
+// Reads the resource at the given path, see open.
+func readFile(embedded fs.FS, path string) ([]byte, error) {
+	f, err := open(embedded, path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+// Reads a file which isn't embedded, e.g a prefab, from the first directory in the search path containing it, or
+// relative to the working directory if none do. Absolute paths are read as they are.
+func ReadLocal(path string) ([]byte, error) {
+	if filepath.IsAbs(path) {
+		return os.ReadFile(path)
+	}
+	for _, dir := range SearchPath {
+		b, err := os.ReadFile(filepath.Join(dir, path))
+		if err == nil {
+			return b, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("read from %v: %w", dir, err)
+		}
+	}
+	return os.ReadFile(path)
+}
+
+// Lists the paths of all resources, embedded or in the search path, which start with the given prefix. e.g
+// List("resources/") lists every image and audio file.
+func List(prefix string) ([]string, error) {
+	found := make(map[string]bool)
+	collect := func(fsys fs.FS) error {
+		return fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && strings.HasPrefix(path, prefix) {
+				found[path] = true
+			}
+			return nil
+		})
+	}
+	for _, embedded := range []fs.FS{resources, shadersFS} {
+		err := collect(embedded)
+		if err != nil {
+			return nil, fmt.Errorf("list embedded: %w", err)
+		}
+	}
+	for _, dir := range SearchPath {
+		err := collect(os.DirFS(dir))
+		if err != nil {
+			return nil, fmt.Errorf("list %v: %w", dir, err)
+		}
+	}
+	var paths []string
+	for p := range found {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// Lists the paths of all resources, embedded or in the search path, which match the given pattern. Patterns use the
+// syntax of path.Match, e.g Glob("resources/*.png") lists every image.
+func Glob(pattern string) ([]string, error) {
+	// only walk resources under the part of the pattern before any wildcards
+	prefix := pattern
+	if i := strings.IndexAny(pattern, `*?[\`); i >= 0 {
+		prefix = pattern[:i]
+	}
+	paths, err := List(prefix)
+	if err != nil {
+		return nil, err
+	}
+	var matched []string
+	for _, p := range paths {
+		ok, err := pathpkg.Match(pattern, p)
+		if err != nil {
+			return nil, fmt.Errorf("match %v: %w", pattern, err)
+		}
+		if ok {
+			matched = append(matched, p)
+		}
+	}
+	return matched, nil
+}
+
+// Finds the modification time of the resource at the given path in the search path. Returns false if the resource
+// is not in the search path, e.g if it is embedded.
+func modtime(path string) (time.Time, bool) {
+	for _, dir := range SearchPath {
+		info, err := fs.Stat(os.DirFS(dir), path)
+		if err == nil {
+			return info.ModTime(), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// Guards the caches below, which may be warmed from background goroutines.
+var mu sync.Mutex
+
+// Caches images loaded from disk.
 var imgs = map[string]*ebiten.Image{}
 
-// Loads an image from the given resource path (resource/*), reusing it if previously loaded. Not thread safe.
+// Modification times of cached images which were loaded from the search path.
+var imgTimes = map[string]time.Time{}
+
+// Caches compiled shaders.
+var shaders = map[string]*ebiten.Shader{}
+
+// Sizes in bytes of the most recently decoded audio for each path.
+var audioSizes = map[string]int{}
+
+// Caches parsed fonts.
+var fonts = map[string]*opentype.Font{}
+
+// Evicts cached images whose files in the search path have changed since they were loaded, and returns their paths
+// so that callers can reload them.
+func StaleImages() []string {
+	mu.Lock()
+	defer mu.Unlock()
+	var stale []string
+	for path, loaded := range imgTimes {
+		t, ok := modtime(path)
+		if ok && t.Equal(loaded) {
+			continue
+		}
+		delete(imgs, path)
+		delete(imgTimes, path)
+		stale = append(stale, path)
+		// regions of a changed atlas are stale too
+		for p := range imgs {
+			if strings.HasPrefix(p, path+"#") {
+				delete(imgs, p)
+				stale = append(stale, p)
+			}
+		}
+	}
+	return stale
+}
+
+// Bounds in pixels of a named region of an atlas image.
+type AtlasRegion struct {
+	X, Y, W, H int
+}
+
+// Loads an image from the given resource path (resource/*), reusing it if previously loaded. Regions of an atlas can
+// be loaded with a path like "resources/tiles.png#grass", which will find the bounds of the region named grass in
+// "resources/tiles.json" and return that part of tiles.png. The json file maps region names to AtlasRegions. Bounds
+// can also be given directly in pixels as "resources/tiles.png#x,y,w,h". Regions of the same atlas share one texture.
 func Image(path string) (*ebiten.Image, error) {
-	if img, ok := imgs[path]; ok {
+	mu.Lock()
+	img, ok := imgs[path]
+	mu.Unlock()
+	if ok {
 		return img, nil
 	}
+	if i := strings.Index(path, "#"); i >= 0 {
+		return atlasImage(path[:i], path[i+1:])
+	}
 
-	b, err := resources.ReadFile(path)
+	b, err := readFile(resources, path)
 	if err != nil {
 		return nil, fmt.Errorf("read file: %w", err)
 	}
@@ -42,36 +229,155 @@ func Image(path string) (*ebiten.Image, error) {
 			return nil, fmt.Errorf("decode png: %w", err)
 		}
 		eimg := ebiten.NewImageFromImage(img)
+
+		mu.Lock()
+		defer mu.Unlock()
+		if cached, ok := imgs[path]; ok {
+			// loaded concurrently, keep the first so everyone shares one image
+			eimg.Dispose()
+			return cached, nil
+		}
 		imgs[path] = eimg
+		if t, ok := modtime(path); ok {
+			imgTimes[path] = t
+		}
 		return eimg, nil
 	}
-	return nil, errors.New("unrecognized format")
+	return nil, &UnsupportedFormatError{Path: path, Supported: imageFormats}
 }
 
-// Loads an image from the given shader path (shaders/*), reusing it if previously loaded. Not thread safe.
+// Loads the named region of the atlas at the given path, see Image.
+func atlasImage(path, region string) (*ebiten.Image, error) {
+	atlas, err := Image(path)
+	if err != nil {
+		return nil, fmt.Errorf("load atlas: %w", err)
+	}
+	var r AtlasRegion
+	if _, err := fmt.Sscanf(region, "%d,%d,%d,%d", &r.X, &r.Y, &r.W, &r.H); err != nil {
+		// not literal bounds, look up the named region
+		meta := strings.TrimSuffix(path, ".png") + ".json"
+		b, err := readFile(resources, meta)
+		if err != nil {
+			return nil, fmt.Errorf("read atlas regions: %w", err)
+		}
+		var regions map[string]AtlasRegion
+		err = json.Unmarshal(b, &regions)
+		if err != nil {
+			return nil, fmt.Errorf("decode atlas regions %v: %w", meta, err)
+		}
+		var ok bool
+		r, ok = regions[region]
+		if !ok {
+			return nil, fmt.Errorf("no region %v in %v", region, meta)
+		}
+	}
+	img := atlas.SubImage(image.Rect(r.X, r.Y, r.X+r.W, r.Y+r.H)).(*ebiten.Image)
+
+	mu.Lock()
+	defer mu.Unlock()
+	imgs[path+"#"+region] = img
+	return img, nil
+}
+
+// Loads an image from the given shader path (shaders/*), reusing it if previously loaded.
 func Shader(path string) (*ebiten.Shader, error) {
-	if s, ok := shaders[path]; ok {
+	mu.Lock()
+	s, ok := shaders[path]
+	mu.Unlock()
+	if ok {
 		return s, nil
 	}
-	b, err := shadersFS.ReadFile(path)
+	b, err := readFile(shadersFS, path)
 	if err != nil {
 		return nil, fmt.Errorf("read file: %w", err)
 	}
+	err = checkFormat(path, shaderFormats)
+	if err != nil {
+		return nil, err
+	}
 	shader, err := ebiten.NewShader(b)
 	if err != nil {
 		return nil, fmt.Errorf("loading shader: %w", err)
 	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if cached, ok := shaders[path]; ok {
+		shader.Dispose()
+		return cached, nil
+	}
 	shaders[path] = shader
 	return shader, nil
 }
 
+// Reads the source of the shader at the given path (shaders/*), e.g to find the uniforms it declares
+func ShaderSource(path string) ([]byte, error) {
+	b, err := readFile(shadersFS, path)
+	if err != nil {
+		return nil, fmt.Errorf("read file: %w", err)
+	}
+	return b, nil
+}
+
+// Loads the given images and shaders into the caches, so later loads are instant. Safe to call from a background
+// goroutine while the game is running. Returns the first error encountered, after attempting every path.
+func Preload(paths ...string) error {
+	var first error
+	for _, p := range paths {
+		var err error
+		if strings.HasPrefix(p, "shaders/") {
+			_, err = Shader(p)
+		} else {
+			_, err = Image(p)
+		}
+		if err != nil && first == nil {
+			first = fmt.Errorf("preload %v: %w", p, err)
+		}
+	}
+	return first
+}
+
+// Loads a TrueType or OpenType font from the resources directory, reusing it if previously loaded.
+func Font(path string) (*opentype.Font, error) {
+	mu.Lock()
+	f, ok := fonts[path]
+	mu.Unlock()
+	if ok {
+		return f, nil
+	}
+	b, err := readFile(resources, path)
+	if err != nil {
+		return nil, fmt.Errorf("read file: %w", err)
+	}
+	err = checkFormat(path, fontFormats)
+	if err != nil {
+		return nil, err
+	}
+	f, err = opentype.Parse(b)
+	if err != nil {
+		return nil, fmt.Errorf("parse font: %w", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if cached, ok := fonts[path]; ok {
+		return cached, nil
+	}
+	fonts[path] = f
+	return f, nil
+}
+
 // Loads and decodes audio file from the resources directory
 func Audio(path string) ([]byte, error) {
-	f, err := resources.Open(path)
+	f, err := open(resources, path)
 	if err != nil {
 		return nil, fmt.Errorf("open: %w", err)
 	}
 	defer f.Close()
+	err = checkFormat(path, audioFormats)
+	if err != nil {
+		return nil, err
+	}
 	stream, err := wav.DecodeWithSampleRate(SampleRate, f.(io.ReadSeeker))
 	if err != nil {
 		return nil, fmt.Errorf("decode: %w", err)
@@ -80,5 +386,30 @@ func Audio(path string) ([]byte, error) {
 	if err != nil {
 		return nil, fmt.Errorf("read stream: %w", err)
 	}
+	mu.Lock()
+	defer mu.Unlock()
+	audioSizes[path] = len(a)
 	return a, nil
 }
+
+// Approximate memory held by a loaded asset
+type Usage struct {
+	Path string
+	// Texture memory for images, at 4 bytes per pixel, or heap memory for decoded audio.
+	Bytes int
+}
+
+// Approximates the memory held by the loaded asset at the given path, or 0 if it isn't loaded. Atlas regions share
+// their atlas's texture, so report the size of the whole atlas.
+func MemoryUsage(path string) int {
+	if i := strings.Index(path, "#"); i >= 0 {
+		path = path[:i]
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if img, ok := imgs[path]; ok {
+		w, h := img.Size()
+		return w * h * 4
+	}
+	return audioSizes[path]
+}
@@ -0,0 +1,56 @@
+package resources
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestGlobMatchesEmbeddedImages(t *testing.T) {
+	got, err := Glob("resources/*.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"resources/grass.png", "resources/sky.png"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestGlobMatchesShaders(t *testing.T) {
+	got, err := Glob("shaders/photo_*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"shaders/photo_shader.go"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestGlobRejectsBadPatterns(t *testing.T) {
+	if _, err := Glob("resources/[.png"); err == nil {
+		t.Errorf("expected an error for an unclosed [")
+	}
+}
+
+func TestReadLocalSearchesPath(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "prefabs"), 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "prefabs", "bridge.json"), []byte("{}"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	SearchPath = []string{dir}
+	defer func() {
+		SearchPath = nil
+	}()
+	got, err := ReadLocal("prefabs/bridge.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "{}" {
+		t.Errorf("read %q, want the file in the search path", got)
+	}
+}
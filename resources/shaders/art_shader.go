@@ -0,0 +1,104 @@
+//go:build ignore
+// +build ignore
+
+package shaders
+
+// 1 if the level has lights, in which case it's dark away from them
+var Lit float
+// Color of the light away from any light, each channel between 0 and 1
+var Ambient vec3
+// The lights nearest the camera, x, y and radius in screen pixels, and their colors. Unused lights have no radius.
+var Lights [8]vec3
+var LightColors [8]vec3
+// 1 if blocks cast shadows from the lights
+var Shadows float
+// The blocks nearest the camera, their centers in screen pixels and the vectors from their centers to the middles of
+// two adjacent edges. Unused blocks have no size.
+var Blocks [32]vec2
+var BlockAxes [32]vec4
+// 1 if the second image is the art's normal map, otherwise the art is lit as if it faces the screen
+var Normals float
+
+func Fragment(position vec4, texCoord vec2, color vec4) vec4 {
+	clr := imageSrc0At(texCoord)
+	normal := vec3(0, 0, 1)
+	if Normals != 0 {
+		// normal maps point y up, but the screen's y points down
+		n := imageSrc1At(texCoord).xyz*2 - 1
+		normal = normalize(vec3(n.x, -n.y, n.z))
+	}
+	return vec4(clr.rgb*light(position.xy, normal), clr.a)
+}
+
+// The light falling on a surface facing the given normal at a point on the screen
+func light(position vec2, normal vec3) vec3 {
+	if Lit == 0 {
+		return vec3(1)
+	}
+	sum := Ambient
+	for i := 0; i < 8; i++ {
+		radius := Lights[i].z
+		if radius > 0 {
+			// lights hang a little in front of the screen, so flat surfaces under them are lit too
+			to := vec3(Lights[i].xy-position, radius/4)
+			falloff := clamp(1-length(to.xy)/radius, 0, 1)
+			if falloff > 0 {
+				falloff *= 1 - shadow(position, Lights[i].xy, radius)
+			}
+			sum += LightColors[i] * falloff * max(0, dot(normal, normalize(to)))
+		}
+	}
+	return sum
+}
+
+// How much of a light the blocks hide from a point on the screen, between 0 and 1. The light is treated as a small
+// disc, so shadows have soft edges.
+func shadow(position vec2, light vec2, radius float) float {
+	if Shadows == 0 {
+		return 0
+	}
+	across := normalize(vec2(position.y-light.y, light.x-position.x)) * radius / 20
+	return (blocked(position, light) + blocked(position, light+across) + blocked(position, light-across)) / 3
+}
+
+// 1 if a block stands between the two points on the screen, otherwise 0. Blocks either point is inside don't count,
+// so blocks are lit on the side facing the light and lights inside blocks still shine.
+func blocked(from vec2, to vec2) float {
+	for j := 0; j < 32; j++ {
+		u := BlockAxes[j].xy
+		v := BlockAxes[j].zw
+		det := u.x*v.y - u.y*v.x
+		if det != 0 {
+			// in the block's own coordinates, where it spans -1 to 1 along both axes
+			inv := mat2(v.y, -u.y, -v.x, u.x) / det
+			a := inv * (from - Blocks[j])
+			b := inv * (to - Blocks[j])
+			if max(abs(a.x), abs(a.y)) > 1 && max(abs(b.x), abs(b.y)) > 1 {
+				// clip the segment between the block's edges along each axis
+				d := b - a
+				near := 0.0
+				far := 1.0
+				if abs(d.x) > 0.0001 {
+					t1 := (-1 - a.x) / d.x
+					t2 := (1 - a.x) / d.x
+					near = max(near, min(t1, t2))
+					far = min(far, max(t1, t2))
+				} else if abs(a.x) > 1 {
+					far = -1.0
+				}
+				if abs(d.y) > 0.0001 {
+					t1 := (-1 - a.y) / d.y
+					t2 := (1 - a.y) / d.y
+					near = max(near, min(t1, t2))
+					far = min(far, max(t1, t2))
+				} else if abs(a.y) > 1 {
+					far = -1.0
+				}
+				if near <= far {
+					return 1
+				}
+			}
+		}
+	}
+	return 0
+}
@@ -0,0 +1,18 @@
+//go:build ignore
+// +build ignore
+
+package shaders
+
+// Pixels between the samples blurred together, e.g 1, 0 to blur horizontally
+var Direction vec2
+
+func Fragment(position vec4, texCoord vec2, color vec4) vec4 {
+	// a gaussian blur, one axis at a time
+	step := Direction / imageSrcTextureSize()
+	sum := imageSrc0At(texCoord) * 0.227027
+	sum += (imageSrc0At(texCoord+step) + imageSrc0At(texCoord-step)) * 0.1945946
+	sum += (imageSrc0At(texCoord+2*step) + imageSrc0At(texCoord-2*step)) * 0.1216216
+	sum += (imageSrc0At(texCoord+3*step) + imageSrc0At(texCoord-3*step)) * 0.054054
+	sum += (imageSrc0At(texCoord+4*step) + imageSrc0At(texCoord-4*step)) * 0.016216
+	return sum
+}
@@ -0,0 +1,13 @@
+//go:build ignore
+// +build ignore
+
+package shaders
+
+// Brightness between 0 and 1 above which pixels are kept, fading in up to full brightness
+var Threshold float
+
+func Fragment(position vec4, texCoord vec2, color vec4) vec4 {
+	clr := imageSrc0At(texCoord)
+	luma := dot(clr.rgb, vec3(0.2126, 0.7152, 0.0722))
+	return clr * clamp((luma-Threshold)/(1-Threshold), 0, 1)
+}
@@ -0,0 +1,27 @@
+//go:build ignore
+// +build ignore
+
+package shaders
+
+var ScreenPixels vec2
+
+func Fragment(position vec4, texCoord vec2, color vec4) vec4 {
+	// -1 to 1 across the screen, bulged out towards the corners like the curved glass of a CRT
+	c := position.xy/ScreenPixels*2 - 1
+	bulge := c.yx / 6
+	c += c * bulge * bulge
+	if abs(c.x) > 1 || abs(c.y) > 1 {
+		return vec4(0, 0, 0, 1)
+	}
+	origin, size := imageSrcRegionOnTexture()
+	at := origin + (c*0.5+0.5)*size
+
+	// red and blue drift apart slightly, like a misaligned electron beam
+	split := vec2(1.5, 0) / imageSrcTextureSize()
+	clr := vec3(imageSrc0At(at+split).r, imageSrc0At(at).g, imageSrc0At(at-split).b)
+
+	// every other row of pixels is darker, and the edges fade out
+	scan := 0.8 + 0.2*sin(position.y*3.14159)
+	edge := clamp((1-abs(c.x))*20, 0, 1) * clamp((1-abs(c.y))*20, 0, 1)
+	return vec4(clr*scan*edge, 1)
+}
@@ -0,0 +1,20 @@
+//go:build ignore
+// +build ignore
+
+package shaders
+
+// An example custom shader for blocks, a slowly churning pool of lava. See CustomShader.
+
+// Seconds since the game started, set by the game
+var Time float
+// Set by the level, the color of the lava's hottest parts and how fast it churns
+var Color vec3
+var Speed float
+
+func Fragment(position vec4, texCoord vec2, color vec4) vec4 {
+	p := position.xy / 24
+	t := Time * Speed
+	heat := sin(p.x+t) + sin(p.y*1.3-t*0.7) + sin((p.x+p.y)*0.7+t*1.3)
+	heat = heat/6 + 0.5
+	return vec4(Color*(0.3+0.7*heat*heat), 1)
+}
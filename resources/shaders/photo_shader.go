@@ -0,0 +1,29 @@
+//go:build ignore
+// +build ignore
+
+package shaders
+
+// Darkening towards the corners of the screen, between 0 and 1
+var Vignette float
+// Blur radius in pixels at the top and bottom edges of the screen, fading to nothing in the middle band
+var Blur float
+var ScreenPixels vec2
+
+func Fragment(position vec4, texCoord vec2, color vec4) vec4 {
+	// -0.5 to 0.5 across the screen
+	uv := position.xy/ScreenPixels - 0.5
+
+	// tilt shift depth of field, the middle of the screen is in focus
+	radius := Blur * clamp(abs(uv.y)*2, 0, 1)
+	sum := vec4(0)
+	for i := -2; i <= 2; i++ {
+		for j := -2; j <= 2; j++ {
+			offset := vec2(float(i), float(j)) * radius / 2
+			sum += imageSrc0At(texCoord + offset/imageSrcTextureSize())
+		}
+	}
+	clr := sum / 25
+
+	vig := 1 - Vignette*clamp(length(uv)*1.4, 0, 1)
+	return vec4(clr.rgb*vig, clr.a)
+}
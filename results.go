@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"image/color"
+	"math"
+	"strings"
+	"time"
+)
+
+// How far in world units the player can fall below the lowest thing in the level before they have fallen out of it
+const fallDistance = 30
+
+// Counts of what the player did during a run, for the results screen
+type runStats struct {
+	// Times the player ran out of health. Triggers sending them back to a spawn point aren't counted.
+	deaths int
+	jumps  int
+	shots  int
+}
+
+// Sets how low the player can fall before they've fallen out of the level, below the lowest body in the world.
+// Levels with nothing to fall past can't be fallen out of.
+func (g *Game) setBottom() {
+	lowest := math.Inf(1)
+	for b := g.world.GetBodyList(); b != nil; b = b.GetNext() {
+		if b == g.p.b {
+			continue
+		}
+		for f := b.GetFixtureList(); f != nil; f = f.GetNext() {
+			lowest = math.Min(lowest, f.GetAABB(0).LowerBound.Y)
+		}
+	}
+	g.bottom = lowest - fallDistance
+	if math.IsInf(lowest, 1) {
+		g.bottom = math.Inf(-1)
+	}
+}
+
+// Whether the player has fallen out of the bottom of the level, ending the run
+func (g *Game) fellOut() bool {
+	return g.p.b.GetPosition().Y < g.bottom
+}
+
+// Shown over the paused game when a run ends, either by reaching the goal or by falling out of the level.
+type ResultScreen struct {
+	a *Admin
+	// If true the player reached the goal
+	won bool
+}
+
+// Activates the results screen for the game being played, which stops updating while it is shown.
+func ActivateResults(r *Root, a *Admin, won bool) {
+	a.g.Stop()
+	r.a = &ResultScreen{a: a, won: won}
+}
+
+func (s *ResultScreen) Layout(outsideWidth, outsideHeight int) (screenWidth, screenHeight int) {
+	return s.a.Layout(outsideWidth, outsideHeight)
+}
+
+func (s *ResultScreen) Update(r *Root) error {
+	if Clicked(ebiten.KeyR) && s.a.e != nil {
+		s.a.restart()
+		r.transition(s.a, transitionWipe)
+		return nil
+	}
+	if Clicked(ebiten.KeyE) {
+		return s.a.edit(r)
+	}
+	return nil
+}
+
+// Describes the run, including how it compares to the level's best times if it reached the goal
+func (s *ResultScreen) describe() string {
+	a := s.a
+	var b strings.Builder
+	if s.won {
+		run := a.g.timer.elapsed(a.g.time)
+		b.WriteString("Level complete!\n\n")
+		_, _ = fmt.Fprintf(&b, "Time    %v\n", formatRunTime(run))
+		if a.previous != 0 {
+			diff := run - a.previous
+			sign := "+"
+			if diff < 0 {
+				sign, diff = "-", -diff
+			}
+			_, _ = fmt.Fprintf(&b, "Best    %v (%v%v)\n", formatRunTime(a.previous), sign, formatRunTime(diff))
+		}
+	} else {
+		b.WriteString("You fell out of the level\n\n")
+		_, _ = fmt.Fprintf(&b, "Time    %v\n", formatRunTime(time.Duration(a.g.time)*tickDuration))
+	}
+	_, _ = fmt.Fprintf(&b, "Deaths  %v\nJumps   %v\nShots   %v\n", a.g.stats.deaths, a.g.stats.jumps, a.g.stats.shots)
+	if s.won && a.g.timer.recorded {
+		b.WriteString("\n")
+		writeLeaderboard(&b, a.times, a.place, a.g.timer.elapsed(a.g.time))
+	}
+	if a.e != nil {
+		b.WriteString("\n(R) Restart")
+	}
+	b.WriteString("\n(E) Edit")
+	return b.String()
+}
+
+func (s *ResultScreen) Draw(screen *ebiten.Image) {
+	s.a.g.Draw(screen)
+	c := &s.a.g.c
+	const w, h = 220, 340
+	x, y := c.sw/2-w/2, c.sh/2-h/2
+	ebitenutil.DrawRect(screen, float64(x), float64(y), w, h, color.RGBA{A: 0xc0})
+	printAt(screen, s.describe(), x+15, y+10)
+}
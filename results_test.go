@@ -0,0 +1,42 @@
+package main
+
+import "testing"
+
+func TestPlayerFallsOutOfLevel(t *testing.T) {
+	l := floorLevel()
+	s := &Script{}
+	g := NewHeadlessGame(l, s)
+	err := Simulate(g, ticksPerSecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.fellOut() {
+		t.Fatalf("player standing on the floor counted as falling out")
+	}
+	// off the end of the floor
+	l.Spawn.X = 60
+	s = &Script{}
+	g = NewHeadlessGame(l, s)
+	err = Simulate(g, 5*ticksPerSecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !g.fellOut() {
+		t.Errorf("player at y %v hasn't fallen out of the level", g.p.b.GetPosition().Y)
+	}
+}
+
+func TestStatsCountJumps(t *testing.T) {
+	s := &Script{Steps: []ScriptStep{
+		{Tick: ticksPerSecond, Press: []string{"W"}},
+		{Tick: ticksPerSecond + 1, Release: []string{"W"}},
+	}}
+	g := NewHeadlessGame(floorLevel(), s)
+	err := Simulate(g, 2*ticksPerSecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.stats.jumps != 1 {
+		t.Errorf("counted %v jumps, want 1", g.stats.jumps)
+	}
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"github.com/ByteArena/box2d"
+	"github.com/hajimehoshi/ebiten/v2"
+	"image/color"
+	"math"
+)
+
+const (
+	// Approximate length in world units of each simulated rope segment
+	ropeSegment = 0.25
+	// Upper bound on the number of segments in a single rope
+	ropeMaxSegments = 64
+	// Number of constraint passes per tick. More is stiffer.
+	ropeIterations = 10
+	// Thickness of ropes in pixels
+	ropeThickness = 3
+)
+
+var ropeColor = color.RGBA{R: 0x8b, G: 0x5a, B: 0x2b, A: 0xff}
+
+// A purely visual rope, chain, or bridge hanging between two points. The rope is simulated cheaply with verlet
+// integration and does not affect the physics world, but its ends follow any bodies they are pinned to.
+type Rope struct {
+	// End points of the rope in world units
+	A, B box2d.B2Vec2
+	// Length of the rope in world units. Longer than the distance between the ends for a sagging rope.
+	Length float64
+
+	// Current and previous positions of the simulated points
+	pts, prev []box2d.B2Vec2
+	// Bodies the ends are pinned to, if any, and the ends' positions relative to those bodies.
+	ba, bb *box2d.B2Body
+	la, lb box2d.B2Vec2
+}
+
+// Prepares the rope for simulation in the given world, pinning each end to the body it lies in, if any.
+func (r *Rope) attach(world *box2d.B2World) {
+	r.ba, r.la = bodyAt(world, r.A)
+	r.bb, r.lb = bodyAt(world, r.B)
+
+	n := int(math.Ceil(r.Length / ropeSegment))
+	if n < 1 {
+		n = 1
+	}
+	if n > ropeMaxSegments {
+		n = ropeMaxSegments
+	}
+	r.pts = make([]box2d.B2Vec2, n+1)
+	for i := range r.pts {
+		t := float64(i) / float64(n)
+		r.pts[i] = box2d.B2Vec2{X: r.A.X + t*(r.B.X-r.A.X), Y: r.A.Y + t*(r.B.Y-r.A.Y)}
+	}
+	r.prev = append([]box2d.B2Vec2(nil), r.pts...)
+}
+
+// Finds a body containing the given world point, and the point relative to that body.
+func bodyAt(world *box2d.B2World, p box2d.B2Vec2) (*box2d.B2Body, box2d.B2Vec2) {
+	var hit *box2d.B2Body
+	world.QueryAABB(func(f *box2d.B2Fixture) bool {
+		if f.TestPoint(p) {
+			hit = f.GetBody()
+			return false
+		}
+		return true
+	}, box2d.B2AABB{LowerBound: p, UpperBound: p})
+	if hit == nil {
+		return nil, p
+	}
+	return hit, hit.GetLocalPoint(p)
+}
+
+// Advances the rope simulation by dt seconds under the given gravity.
+func (r *Rope) step(dt float64, gravity box2d.B2Vec2) {
+	if r.ba != nil {
+		r.A = r.ba.GetWorldPoint(r.la)
+	}
+	if r.bb != nil {
+		r.B = r.bb.GetWorldPoint(r.lb)
+	}
+	for i := range r.pts {
+		p := r.pts[i]
+		v := box2d.B2Vec2{X: p.X - r.prev[i].X, Y: p.Y - r.prev[i].Y}
+		r.prev[i] = p
+		r.pts[i] = box2d.B2Vec2{X: p.X + v.X + gravity.X*dt*dt, Y: p.Y + v.Y + gravity.Y*dt*dt}
+	}
+	last := len(r.pts) - 1
+	rest := r.Length / float64(last)
+	for it := 0; it < ropeIterations; it++ {
+		r.pts[0] = r.A
+		r.pts[last] = r.B
+		for i := 0; i < last; i++ {
+			a, b := &r.pts[i], &r.pts[i+1]
+			dx, dy := b.X-a.X, b.Y-a.Y
+			d := math.Hypot(dx, dy)
+			if d == 0 {
+				continue
+			}
+			diff := (d - rest) / d
+			// split the correction between both points, unless one of them is a pinned end
+			wa, wb := 0.5, 0.5
+			switch {
+			case i == 0 && i+1 == last:
+				continue
+			case i == 0:
+				wa, wb = 0, 1
+			case i+1 == last:
+				wa, wb = 1, 0
+			}
+			a.X += dx * diff * wa
+			a.Y += dy * diff * wa
+			b.X -= dx * diff * wb
+			b.Y -= dy * diff * wb
+		}
+	}
+	r.pts[0] = r.A
+	r.pts[last] = r.B
+}
+
+// The points along the rope. Before simulation this is just the two ends.
+func (r *Rope) points() []box2d.B2Vec2 {
+	if r.pts == nil {
+		return []box2d.B2Vec2{r.A, r.B}
+	}
+	return r.pts
+}
+
+// Draws all the given ropes as polylines in a single batch.
+func drawRopes(screen *ebiten.Image, ropes []*Rope, toScreen Mx) {
+	cr, cg, cb, ca := colorToScale(ropeColor)
+	var vertices []ebiten.Vertex
+	var is []uint16
+	for _, r := range ropes {
+		pts := r.points()
+		for i := 0; i+1 < len(pts); i++ {
+			if len(vertices)+4 > math.MaxUint16 {
+				break
+			}
+			x1, y1 := toScreen.Apply(pts[i].X, pts[i].Y)
+			x2, y2 := toScreen.Apply(pts[i+1].X, pts[i+1].Y)
+			// offset the segment's sides by half the thickness along its normal
+			nx, ny := y1-y2, x2-x1
+			l := math.Hypot(nx, ny)
+			if l == 0 {
+				continue
+			}
+			nx, ny = nx/l*ropeThickness/2, ny/l*ropeThickness/2
+			base := uint16(len(vertices))
+			for _, p := range [][2]float64{{x1 + nx, y1 + ny}, {x1 - nx, y1 - ny}, {x2 + nx, y2 + ny}, {x2 - nx, y2 - ny}} {
+				vertices = append(vertices, ebiten.Vertex{
+					DstX:   float32(p[0]),
+					DstY:   float32(p[1]),
+					SrcX:   1,
+					SrcY:   1,
+					ColorR: float32(cr),
+					ColorG: float32(cg),
+					ColorB: float32(cb),
+					ColorA: float32(ca),
+				})
+			}
+			is = append(is, base, base+1, base+2, base+1, base+2, base+3)
+		}
+	}
+	if len(vertices) == 0 {
+		return
+	}
+	screen.DrawTriangles(vertices, is, emptySubImage, &ebiten.DrawTrianglesOptions{})
+}
+
+// Editor for hanging ropes between two points. Drag from one end to the other to create a rope.
+type RopeEditor struct {
+	// The rope being dragged out, if any
+	creating *Rope
+
+	e *Editor
+}
+
+// Ropes are created this much longer than the distance between their ends so that they sag
+const ropeSlack = 1.2
+
+func (p *RopeEditor) Layout(outsideWidth, outsideHeight int) (screenWidth, screenHeight int) {
+	return p.e.Layout(outsideWidth, outsideHeight)
+}
+
+func (p *RopeEditor) String() string {
+	return "Ropes"
+}
+
+func (p *RopeEditor) Update(r *Root) error {
+	if driver.IsMouseButtonPressed(ebiten.MouseButtonLeft) {
+		wx, wy := p.e.c.Cursor()
+		if p.creating == nil {
+			p.creating = &Rope{A: box2d.B2Vec2{X: wx, Y: wy}}
+		}
+		p.creating.B = box2d.B2Vec2{X: wx, Y: wy}
+		p.creating.Length = ropeSlack * math.Hypot(p.creating.B.X-p.creating.A.X, p.creating.B.Y-p.creating.A.Y)
+	}
+	if !driver.IsMouseButtonPressed(ebiten.MouseButtonLeft) && p.creating != nil {
+		if p.creating.Length > 0 {
+			p.e.l.Ropes = append(p.e.l.Ropes, p.creating)
+		}
+		p.creating = nil
+	}
+	return p.e.Update(r)
+}
+
+func (p *RopeEditor) Draw(screen *ebiten.Image) {
+	p.e.Draw(screen)
+	if p.creating != nil {
+		drawRopes(screen, []*Rope{p.creating}, p.e.c.ToScreen())
+	}
+	printAt(screen, "Rope Editor", 10, p.e.c.sh-20)
+}
+
+// Makes ropes selectable as a thin box spanning their ends
+type RopeSelector struct {
+	l *Level
+	r *Rope
+}
+
+func (r *RopeSelector) Delete() {
+	for i, o := range r.l.Ropes {
+		if o == r.r {
+			r.l.Ropes = append(r.l.Ropes[:i], r.l.Ropes[i+1:]...)
+			return
+		}
+	}
+}
+
+func (r *RopeSelector) Transform() Mx {
+	a, b := r.r.A, r.r.B
+	var m Mx
+	m.Scale(math.Max(math.Hypot(b.X-a.X, b.Y-a.Y), 0.1), 0.25)
+	m.Rotate(math.Atan2(b.Y-a.Y, b.X-a.X))
+	m.Translate((a.X+b.X)/2, (a.Y+b.Y)/2)
+	return m
+}
+
+func (r *RopeSelector) SetTransform(m Mx) {
+	old := math.Hypot(r.r.B.X-r.r.A.X, r.r.B.Y-r.r.A.Y)
+	r.r.A.X, r.r.A.Y = m.Apply(-0.5, 0)
+	r.r.B.X, r.r.B.Y = m.Apply(0.5, 0)
+	// keep the same amount of sag when stretched
+	if old > 0 {
+		r.r.Length *= math.Hypot(r.r.B.X-r.r.A.X, r.r.B.Y-r.r.A.Y) / old
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"github.com/hajimehoshi/ebiten/v2"
+	"image/color"
+	"math"
+)
+
+// Color the cursor's position is marked with on the rulers
+var rulerCursorColor = color.RGBA{R: 0xff, G: 0xd0, B: 0x40, A: 0xff}
+
+// The width of the ruler along the left of the screen and the height of the one along the top, in pixels. The left
+// ruler fits labels like -1000.
+func rulerSize() (w, h int) {
+	w, _ = measureText("-0000", TextStyle{})
+	return w + 6, lineHeight(TextStyle{}) + 4
+}
+
+// Where the editor's help starts, moved out from under the rulers if they're shown
+func (e *Editor) helpOrigin() (x, y int) {
+	x, y = 10, 5
+	if settings.Rulers {
+		w, h := rulerSize()
+		x, y = x+w, y+h
+	}
+	return x, y
+}
+
+// Draws rulers along the top and left of the screen if they're turned on in the settings. They're ticked where the
+// grid's lines would be, labelled at its heavier lines, with the cursor's position highlighted. They have no
+// background, so text drawn under them by the subeditors stays readable.
+func (e *Editor) drawRulers(screen *ebiten.Image) {
+	if !settings.Rulers || e.c.sw == 0 || e.c.hw == 0 {
+		return
+	}
+	w, h := rulerSize()
+	minor, heavy := gridSteps(float64(e.c.sw)/(2*e.c.hw), settings.GridMajor)
+	toScreen := e.c.ToScreen()
+	// screen space lines
+	var flat Mx
+	for x := math.Ceil((e.c.x-e.c.hw)/minor) * minor; x <= e.c.x+e.c.hw; x += minor {
+		sx, _ := toScreen.Apply(x, 0)
+		if sx < float64(w) {
+			continue
+		}
+		length := float64(h) / 4
+		if math.Mod(x, heavy) == 0 {
+			length = float64(h)
+			printAt(screen, fmt.Sprint(x), int(sx)+3, 2)
+		}
+		drawline(screen, sx, 0, sx, length, 1, flat, color.White)
+	}
+	for y := math.Ceil((e.c.y-e.c.hh)/minor) * minor; y <= e.c.y+e.c.hh; y += minor {
+		_, sy := toScreen.Apply(0, y)
+		if sy < float64(h) {
+			continue
+		}
+		length := float64(w) / 4
+		if math.Mod(y, heavy) == 0 {
+			length = float64(w)
+			printAt(screen, fmt.Sprint(y), 3, int(sy)+2)
+		}
+		drawline(screen, 0, sy, length, sy, 1, flat, color.White)
+	}
+	cx, cy := driver.CursorPosition()
+	cx -= e.c.left
+	drawline(screen, float64(cx), 0, float64(cx), float64(h), 2, flat, rulerCursorColor)
+	drawline(screen, 0, float64(cy), float64(w), float64(cy), 2, flat, rulerCursorColor)
+}
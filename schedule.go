@@ -0,0 +1,112 @@
+package main
+
+import "math"
+
+// Runs gameplay code at later ticks of a game, so features can wait without keeping their own tick counters. Tasks due
+// on the same tick run in the order they were scheduled, so games play out the same way every time.
+type Scheduler struct {
+	// The current tick
+	now   int
+	tasks []*Task
+	// Counts tasks as they are scheduled, to order tasks due on the same tick
+	scheduled int
+}
+
+// Code scheduled to run later, which can be cancelled
+type Task struct {
+	// Tick the task is next due, and its place in the order of tasks due then
+	at, order int
+	// Runs the task, returning whether to run it again and in how many ticks
+	step      func() (int, bool)
+	cancelled bool
+}
+
+// One step of a sequence, see Scheduler.Sequence
+type Step struct {
+	// Ticks to wait after the previous step, or after the sequence starts for the first step
+	Wait int
+	Do   func()
+}
+
+// Stops the task from running again. Safe to call from inside the task.
+func (t *Task) Cancel() {
+	t.cancelled = true
+}
+
+// Queues the task to run the given number of ticks from now
+func (s *Scheduler) add(t *Task, ticks int) *Task {
+	t.at = s.now + ticks
+	t.order = s.scheduled
+	s.scheduled++
+	s.tasks = append(s.tasks, t)
+	return t
+}
+
+// Runs fn once, the given number of ticks from now. Tasks scheduled for 0 ticks from inside another task run on the
+// same tick, otherwise on the next one.
+func (s *Scheduler) After(ticks int, fn func()) *Task {
+	return s.add(&Task{step: func() (int, bool) {
+		fn()
+		return 0, false
+	}}, ticks)
+}
+
+// Runs fn every given number of ticks, at least 1, starting that many ticks from now, until cancelled.
+func (s *Scheduler) Every(ticks int, fn func()) *Task {
+	if ticks < 1 {
+		ticks = 1
+	}
+	return s.add(&Task{step: func() (int, bool) {
+		fn()
+		return ticks, true
+	}}, ticks)
+}
+
+// Runs the steps one after another, each waiting for its own number of ticks after the last. Cancelling the returned
+// task skips the rest of the steps.
+func (s *Scheduler) Sequence(steps ...Step) *Task {
+	if len(steps) == 0 {
+		return &Task{cancelled: true}
+	}
+	i := 0
+	return s.add(&Task{step: func() (int, bool) {
+		steps[i].Do()
+		i++
+		if i == len(steps) {
+			return 0, false
+		}
+		return steps[i].Wait, true
+	}}, steps[0].Wait)
+}
+
+// Moves the scheduler to the given tick, running every task due by then.
+func (s *Scheduler) advance(time int) {
+	s.now = time
+	for {
+		next := -1
+		for i, t := range s.tasks {
+			if t.at > s.now {
+				continue
+			}
+			if next == -1 || t.at < s.tasks[next].at || (t.at == s.tasks[next].at && t.order < s.tasks[next].order) {
+				next = i
+			}
+		}
+		if next == -1 {
+			return
+		}
+		t := s.tasks[next]
+		s.tasks = append(s.tasks[:next], s.tasks[next+1:]...)
+		if t.cancelled {
+			continue
+		}
+		if ticks, again := t.step(); again && !t.cancelled {
+			s.add(t, ticks)
+		}
+	}
+}
+
+// Converts a duration in seconds to a whole number of ticks, rounding up so waits are never cut short
+func secondsToTicks(seconds float64) int {
+	return int(math.Ceil(seconds * ticksPerSecond))
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSchedulerRunsTasksInOrder(t *testing.T) {
+	var s Scheduler
+	var ran []string
+	log := func(name string) func() {
+		return func() { ran = append(ran, name) }
+	}
+	s.After(2, log("after"))
+	every := s.Every(1, log("every"))
+	s.Sequence(Step{Wait: 1, Do: log("first")}, Step{Wait: 0, Do: log("second")}, Step{Wait: 2, Do: log("third")})
+	cancelled := s.After(1, log("cancelled"))
+	cancelled.Cancel()
+	for tick := 1; tick <= 3; tick++ {
+		s.advance(tick)
+		if tick == 2 {
+			every.Cancel()
+		}
+	}
+	want := []string{"every", "first", "second", "after", "every", "third"}
+	if !reflect.DeepEqual(ran, want) {
+		t.Errorf("ran %v, want %v", ran, want)
+	}
+}
@@ -1,11 +1,12 @@
 package main
 
 import (
+	"fmt"
 	"github.com/ByteArena/box2d"
 	"github.com/hajimehoshi/ebiten/v2"
-	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
 	"image/color"
 	"math"
+	"strings"
 )
 
 // the selection helper provides a UI for selecting arbitrary objects and applying transformations, such as moves,
@@ -25,7 +26,8 @@ type Deletable interface {
 	Delete()
 }
 
-// If a selectable implements copyable, it will allow the user to, via Cmd+C and Cmd+V, to copy and paste the object.
+// If a selectable implements copyable, it will allow the user to, via the copy and paste shortcuts (Cmd+C and Cmd+V
+// by default), to copy and paste the object.
 type Copyable interface {
 	// Adds a copy of this object to the scene and returns it.
 	Paste() Selectable
@@ -39,8 +41,13 @@ const (
 	selmoving
 	selrotating
 	selscaling
+	// dragging out a marquee from empty space
+	selmarquee
 )
 
+// World units right and down a duplicate is placed from the original
+const duplicateOffset = 1
+
 type Selector struct {
 	// Current selection
 	s Selectable
@@ -49,13 +56,17 @@ type Selector struct {
 	// All possible selectables.
 	Selectables []Selectable
 
-	// If the user presses Cmd+C while selecting something, it will be stored to the clipboard for later pasting.
+	// If the user presses the copy shortcut while selecting something, it will be stored to the clipboard for later
+	// pasting.
 	clipboard Copyable
 
 	state selstate
 
 	// Which scalar was clicked on.
 	scalar int
+
+	// Where the marquee was started, in world units
+	from box2d.B2Vec2
 }
 
 // determines if the given coordinates intersect with a 1x1 square transformed by the inverse of the given matrix.
@@ -73,8 +84,8 @@ func (s *Selector) rotator() Mx {
 	x, y := t.Apply(0, 0.5)
 	up := box2d.B2Vec2{x - cx, y - cy}
 	up.Normalize()
-	pxToWorld := 2 * s.C.hw / float64(s.C.sw)
-	up.OperatorScalarMulInplace(25 * 2 * s.C.hw / float64(s.C.sw))
+	pxToWorld := s.C.pixels(1)
+	up.OperatorScalarMulInplace(s.C.pixels(25))
 	up.OperatorPlusInplace(box2d.B2Vec2{x, y})
 	var m Mx
 	m.Scale(pxToWorld * 10, pxToWorld * 10)
@@ -82,20 +93,22 @@ func (s *Selector) rotator() Mx {
 	return m
 }
 
+// Positions of the scalar handles on the unit square
+var scalarPositions = [4]struct{X, Y float64}{
+	{0.5, 0.5},
+	{-0.5, 0.5},
+	{0.5, -0.5},
+	{-0.5, -0.5},
+}
+
 // Computes the selection matrices for the scalar handles.
 func (s *Selector) scalars() [4]Mx {
 	var out [4]Mx
-	positions := []struct{X, Y float64}{
-		{0.5, 0.5},
-		{-0.5, 0.5},
-		{0.5, -0.5},
-		{-0.5, -0.5},
-	}
-	for i, p := range positions {
+	for i, p := range scalarPositions {
 		// draw scale handle at position
 		t := s.s.Transform()
 		cx, cy := t.Apply(p.X, p.Y)
-		pxToWorld := 2 * s.C.hw / float64(s.C.sw)
+		pxToWorld := s.C.pixels(1)
 		var m Mx
 		m.Scale(pxToWorld * 10, pxToWorld * 10)
 		m.Translate(cx, cy)
@@ -104,6 +117,25 @@ func (s *Selector) scalars() [4]Mx {
 	return out
 }
 
+// Makes a newly pasted selectable, or the members of a pasted group, selectable
+func (s *Selector) add(se Selectable) {
+	if g, ok := se.(*GroupSelector); ok {
+		s.Selectables = append(s.Selectables, g.members...)
+	} else if se != nil {
+		s.Selectables = append(s.Selectables, se)
+	}
+}
+
+// Stops the selectable being selectable, e.g once it's been removed from the level
+func (s *Selector) remove(se Selectable) {
+	for i, o := range s.Selectables {
+		if o == se {
+			s.Selectables = append(s.Selectables[:i], s.Selectables[i+1:]...)
+			return
+		}
+	}
+}
+
 func (s *Selector) Update() {
 	if s.s != nil && Clicked(ebiten.KeyBackspace) {
 		if del, ok := s.s.(Deletable); ok {
@@ -111,18 +143,37 @@ func (s *Selector) Update() {
 			del.Delete()
 		}
 	}
-	if s.s != nil && Clicked(ebiten.KeyC) && ebiten.IsKeyPressed(ebiten.KeyMeta) {
+	if s.s != nil && shortcut(actionCopy) {
 		// Copy triggered
 		if kopy, ok := s.s.(Copyable); ok {
 			s.clipboard = kopy
 		}
 	}
-	if s.clipboard != nil && Clicked(ebiten.KeyV) && ebiten.IsKeyPressed(ebiten.KeyMeta) {
+	if s.clipboard != nil && shortcut(actionPaste) {
 		// Paste triggered
 		s.s = s.clipboard.Paste()
-		s.Selectables = append(s.Selectables, s.s)
+		s.add(s.s)
 	}
-	if !ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft) {
+	if s.s != nil && shortcut(actionDuplicate) {
+		if kopy, ok := s.s.(Copyable); ok {
+			s.s = kopy.Paste()
+			s.add(s.s)
+			if s.s != nil {
+				// offset the copy so it can be told apart from the original
+				t := s.s.Transform()
+				t.Translate(duplicateOffset, -duplicateOffset)
+				s.s.SetTransform(t)
+			}
+		}
+	}
+	if !driver.IsMouseButtonPressed(ebiten.MouseButtonLeft) {
+		if s.state == selmarquee {
+			// select everything in the marquee, unless it was just a click
+			cx, cy := s.C.Cursor()
+			if math.Abs(cx-s.from.X) > s.C.pixels(3) || math.Abs(cy-s.from.Y) > s.C.pixels(3) {
+				s.s = group(s.inside(s.from.X, s.from.Y, cx, cy))
+			}
+		}
 		s.state = selidle
 		return
 	}
@@ -131,7 +182,7 @@ func (s *Selector) Update() {
 		if s.s != nil {
 			// still hitting?
 			hit := s.hit(cx, cy, s.s.Transform())
-			hit = hit && s.hit(cx, cy, s.rotator())
+			hit = hit || s.hit(cx, cy, s.rotator())
 			for _, m := range s.scalars() {
 				if s.hit(cx, cy, m) {
 					hit = true
@@ -160,7 +211,9 @@ func (s *Selector) Update() {
 			}
 		}
 		if s.s == nil {
-			// Nothing left to do
+			// clicked on empty space, drag out a marquee
+			s.state = selmarquee
+			s.from = box2d.B2Vec2{X: cx, Y: cy}
 			return
 		}
 		// what type of dragging should we do?
@@ -208,20 +261,30 @@ func (s *Selector) Update() {
 		t.Translate(wdx, wdy)
 		s.s.SetTransform(t)
 	case selscaling:
-
-		scalar := s.scalars()[s.scalar]
-		sx, sy := scalar.Apply(0, 0)
+		// move the dragged corner to the cursor, keeping the opposite corner in place, or with Alt the center
+		p := scalarPositions[s.scalar]
 		mx, my := s.C.Cursor()
 
 		t := s.s.Transform()
 		t.Invert()
-		usx, usy := t.Apply(sx, sy)
 		umx, umy := t.Apply(mx, my)
 
-		scalex := umx/usx
-		scaley := umy/usy
+		// the fixed point, in unit square coordinates
+		fx, fy := -p.X, -p.Y
+		if driver.IsKeyPressed(ebiten.KeyAlt) {
+			fx, fy = 0, 0
+		}
+		scalex := (umx - fx) / (p.X - fx)
+		scaley := (umy - fy) / (p.Y - fy)
+		if driver.IsKeyPressed(ebiten.KeyShift) {
+			// keep the aspect ratio, taking the larger scale so the corner reaches out to the cursor
+			scale := math.Max(math.Abs(scalex), math.Abs(scaley))
+			scalex, scaley = math.Copysign(scale, scalex), math.Copysign(scale, scaley)
+		}
 		var m Mx
+		m.Translate(-fx, -fy)
 		m.Scale(scalex, scaley)
+		m.Translate(fx, fy)
 		m.Concat(s.s.Transform().GeoM)
 		s.s.SetTransform(m)
 	}
@@ -240,6 +303,14 @@ func (s *Selector) Draw(screen *ebiten.Image) {
 		drawline(screen, trx, try, tlx, tly, 3, geom, color.RGBA{R: 255, A: 255})
 		drawline(screen, tlx, tly, blx, bly, 3, geom, color.RGBA{R: 255, A: 255})
 	}
+	if s.state == selmarquee {
+		s.drawMarquee(screen)
+	}
+	if g, ok := s.s.(*GroupSelector); ok {
+		for _, m := range g.members {
+			outline(-0.5, -0.5, 0.5, 0.5, m.Transform())
+		}
+	}
 	if s.s != nil {
 		t := s.s.Transform()
 		outline(-0.5, -0.5, 0.5, 0.5, t)
@@ -262,7 +333,7 @@ type SpawnSelector struct {
 func (s *SpawnSelector) Transform() Mx {
 	// The spawn needs to scale with zoom, so we compute its scale transform based on the current camera dimensions
 	// The spawn is 20px * 20px when rendered.
-	side := 2 * s.C.hw * 20 / float64(s.C.sw)
+	side := s.C.pixels(20)
 	geo := Mx{}
 	geo.Scale(side, side)
 	geo.Translate(s.L.Spawn.X, s.L.Spawn.Y)
@@ -294,15 +365,15 @@ func (a *ArtSelector) Delete() {
 			break
 		}
 	}
-	a.l.Art = append(a.l.Art[:found], a.l.Art[found+1:]...)
+	a.l.removeArt(found)
 }
 
 func (a *ArtSelector) Transform() Mx {
-	return a.a.T
+	return a.l.artTransform(a.a)
 }
 
 func (a *ArtSelector) SetTransform(m Mx) {
-	a.a.T = m
+	a.l.setArtTransform(a.a, m)
 }
 
 // Adds delete functionality to blocks
@@ -325,7 +396,7 @@ func (b *BlockSelector) Delete() {
 			break
 		}
 	}
-	b.l.Blocks = append(b.l.Blocks[:found], b.l.Blocks[found+1:]...)
+	b.l.removeBlock(found)
 }
 
 func (b *BlockSelector) Transform() Mx {
@@ -340,6 +411,13 @@ func (b *BlockSelector) SetTransform(m Mx) {
 type SelectEditor struct {
 	s Selector
 	e *Editor
+	o Outline
+	// For setting properties of the selection
+	t *Typer
+	// Where copied blocks and art are saved for pasting into other levels, see Clip
+	clip string
+	// Directory prefabs are saved to and placed from
+	prefabs string
 }
 
 func ActivateSelectEditor(r *Root, e *Editor) {
@@ -348,11 +426,57 @@ func ActivateSelectEditor(r *Root, e *Editor) {
 		C: &e.c,
 		L: &e.l,
 	})
+	for n := range e.l.Spawns {
+		ss = append(ss, &NamedSpawnSelector{C: &e.c, L: &e.l, Name: n})
+	}
 	for _, a := range e.l.Art {
-		ss = append(ss, &ArtSelector{l: &e.l, a:a})
+		if !e.locked(a.Layer) {
+			ss = append(ss, &ArtSelector{l: &e.l, a:a})
+		}
 	}
 	for _, b := range e.l.Blocks {
-		ss = append(ss, &BlockSelector{b: b, l: &e.l})
+		if !e.locked(b.Layer) {
+			ss = append(ss, &BlockSelector{b: b, l: &e.l})
+		}
+	}
+	for _, c := range e.l.Contraptions {
+		ss = append(ss, &ContraptionSelector{c: c, l: &e.l})
+	}
+	for _, rope := range e.l.Ropes {
+		ss = append(ss, &RopeSelector{r: rope, l: &e.l})
+	}
+	for _, a := range e.l.Attractors {
+		ss = append(ss, &AttractorSelector{a: a, l: &e.l})
+	}
+	for _, p := range e.l.Portals {
+		ss = append(ss, &PortalSelector{l: &e.l, p: p, end: &p.A}, &PortalSelector{l: &e.l, p: p, end: &p.B})
+	}
+	for _, s := range e.l.Switches {
+		ss = append(ss, &SwitchSelector{l: &e.l, s: s})
+	}
+	for _, a := range e.l.Ambients {
+		ss = append(ss, &AmbientSelector{l: &e.l, a: a})
+	}
+	for _, z := range e.l.MusicZones {
+		ss = append(ss, &MusicZoneSelector{l: &e.l, z: z})
+	}
+	for _, z := range e.l.CameraZones {
+		ss = append(ss, &CameraZoneSelector{l: &e.l, z: z})
+	}
+	for _, lt := range e.l.Lights {
+		ss = append(ss, &LightSelector{l: &e.l, lt: lt})
+	}
+	for _, s := range e.l.Stations {
+		ss = append(ss, &StationSelector{l: &e.l, s: s})
+	}
+	for _, en := range e.l.Enemies {
+		ss = append(ss, &EnemySelector{l: &e.l, en: en})
+	}
+	for _, lb := range e.l.Labels {
+		ss = append(ss, &LabelSelector{l: &e.l, lb: lb})
+	}
+	for _, p := range e.l.Prefabs {
+		ss = append(ss, &PrefabSelector{l: &e.l, p: p})
 	}
 	r.a = &SelectEditor{
 		s: Selector{
@@ -360,6 +484,10 @@ func ActivateSelectEditor(r *Root, e *Editor) {
 			Selectables: ss,
 		},
 		e: e,
+		t: &Typer{C: &e.c, Placeholder: "Enter 'prefab <name>' to save the selection as a prefab, 'place <name>' " +
+			"to place one or 'unpack' to edit one"},
+		clip:    clipboardPath,
+		prefabs: prefabDir,
 	}
 }
 
@@ -368,12 +496,113 @@ func (t *SelectEditor) Layout(outsideWidth, outsideHeight int) (screenWidth, scr
 }
 
 func (t *SelectEditor) Update(r *Root) error {
-	t.s.Update()
+	cmd, typ := t.t.Update()
+	if typ {
+		return nil
+	}
+	if cmd != "" {
+		err := t.apply(cmd)
+		if err != nil {
+			t.t.Placeholder = fmt.Sprintf("Failed to apply '%v': %v", cmd, err)
+		} else {
+			t.t.Placeholder = fmt.Sprintf("Applied '%v'", cmd)
+		}
+	}
+	if t.s.s != nil && shortcut(actionCopy) {
+		if c := clipOf(t.s.s, &t.e.l); !c.empty() {
+			err := c.save(t.clip)
+			if err != nil {
+				fmt.Println("Failed to save clipboard:", err)
+			}
+		}
+	}
+	if t.s.clipboard == nil && shortcut(actionPaste) {
+		// nothing copied since the editor opened, paste what was copied before, possibly from another level
+		err := t.pasteClip()
+		if err != nil {
+			t.t.Placeholder = fmt.Sprintf("Failed to paste: %v", describe(err))
+		}
+	}
+	if t.s.s != nil && Clicked(ebiten.KeyJ) {
+		t.pinSelection()
+	}
+	if a, ok := t.s.s.(*ArtSelector); ok {
+		if Clicked(ebiten.KeyPageUp) {
+			a.a.Z++
+		}
+		if Clicked(ebiten.KeyPageDown) {
+			a.a.Z--
+		}
+		if Clicked(ebiten.KeyLeft) || Clicked(ebiten.KeyRight) {
+			flip(a, -1, 1)
+		}
+		if Clicked(ebiten.KeyUp) || Clicked(ebiten.KeyDown) {
+			flip(a, 1, -1)
+		}
+	}
+	if !t.o.Update(&t.s, &t.e.l) {
+		t.s.Update()
+	}
 	return t.e.Update(r)
 }
 
+// Applies a typed command, either setting a property of the selection, see setProperty, or one of the prefab commands
+func (t *SelectEditor) apply(cmd string) error {
+	parts := strings.Fields(cmd)
+	switch {
+	case len(parts) == 2 && parts[0] == "prefab":
+		return t.makePrefab(parts[1])
+	case len(parts) == 2 && parts[0] == "place":
+		return t.placePrefab(parts[1])
+	case len(parts) == 1 && parts[0] == "unpack":
+		return t.unpack()
+	case len(parts) == 2 && parts[0] == "layer":
+		return t.moveToLayer(layerNamed(parts[1]))
+	}
+	return setProperty(t.s.s, cmd)
+}
+
+// Pastes the blocks and art saved to the clipboard file into the level, selecting them
+func (t *SelectEditor) pasteClip() error {
+	var c Clip
+	err := c.load(t.clip)
+	if err != nil {
+		return err
+	}
+	pasted, err := c.paste(&t.e.l)
+	if err != nil {
+		return err
+	}
+	t.s.Selectables = append(t.s.Selectables, pasted...)
+	if s := group(pasted); s != nil {
+		t.s.s = s
+	}
+	return nil
+}
+
 func (t *SelectEditor) Draw(screen *ebiten.Image) {
 	t.e.Draw(screen)
 	t.s.Draw(screen)
-	ebitenutil.DebugPrintAt(screen, "Transform Editor", 10, t.e.c.sh-20)
+	t.o.Draw(screen, &t.s, &t.e.l)
+	if t.s.s != nil {
+		drawProperties(screen, t.s.s, &t.e.c)
+	}
+	t.t.Draw(screen)
+	msg := fmt.Sprintf("Transform Editor: (J) Pin art to the block under the cursor, (%v) Duplicate, (Tab) "+
+		"Show/hide the outline, hold (Shift) to keep the shape or (Alt) to scale about the center while dragging a "+
+		"corner, drag over empty space to select several", shortcutName(actionDuplicate))
+	if a, ok := t.s.s.(*ArtSelector); ok {
+		msg += fmt.Sprintf(", (PageUp/PageDown) Bring forward/Send back from layer %v", a.a.Z) +
+			", (Left/Right) Flip horizontally, (Up/Down) Flip vertically"
+	}
+	printAt(screen, msg, 10, t.e.c.sh-35)
+}
+
+// Mirrors the selection in place by scaling it by x and y, -1 to flip an axis, before its transform
+func flip(s Selectable, x, y float64) {
+	var m Mx
+	m.Scale(x, y)
+	t := s.Transform()
+	m.Concat(t.GeoM)
+	s.SetTransform(m)
 }
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Gaps between editor updates at least this long aren't counted as time spent editing
+const sessionGap = time.Second
+
+// Statistics about an editing session, shown when the level is saved to see which tools get the most use and which
+// are awkward. The editor has no undo, so deletions are the closest measure of mistakes.
+type EditorSession struct {
+	// Time spent in each subeditor by name, "" for the editor itself. Measured between editor updates, so time spent
+	// playtesting isn't included.
+	spent map[string]time.Duration
+	// When the editor last updated
+	last time.Time
+	// Objects added to and removed from the level
+	placed, deleted int
+
+	// Objects in the level at the last update, and whether they've been counted yet
+	objects int
+	counted bool
+}
+
+// The number of things placed in the level, for noticing objects being added and removed
+func (l *Level) objectCount() int {
+	n := len(l.Blocks) + len(l.Art) + len(l.Spawns) + len(l.Ropes) + len(l.Attractors) + len(l.Portals) +
+		len(l.Contraptions) + len(l.Switches) + len(l.Ambients) + len(l.MusicZones) + len(l.CameraZones) +
+		len(l.Lights) + len(l.Stations) + len(l.Enemies) + len(l.Tilemaps) + len(l.Labels) +
+		len(l.Prefabs)
+	if l.Goal != nil {
+		n++
+	}
+	return n
+}
+
+// Records an update of the editor with the named subeditor active, and any objects placed or deleted since the last
+func (s *EditorSession) update(mode string, l *Level) {
+	if s.spent == nil {
+		s.spent = make(map[string]time.Duration)
+	}
+	now := time.Now()
+	// longer gaps are time away from the editor, e.g playtesting
+	if gap := now.Sub(s.last); !s.last.IsZero() && gap < sessionGap {
+		s.spent[mode] += gap
+	}
+	s.last = now
+	n := l.objectCount()
+	if !s.counted {
+		s.recount(l)
+		return
+	}
+	if n > s.objects {
+		s.placed += n - s.objects
+	} else {
+		s.deleted += s.objects - n
+	}
+	s.objects = n
+}
+
+// Starts counting objects afresh, for when the level is replaced rather than edited
+func (s *EditorSession) recount(l *Level) {
+	s.objects = l.objectCount()
+	s.counted = true
+}
+
+// Describes the session, with the subeditors used most first
+func (s *EditorSession) summary() string {
+	var total time.Duration
+	modes := make([]string, 0, len(s.spent))
+	for m, d := range s.spent {
+		total += d
+		modes = append(modes, m)
+	}
+	sort.Slice(modes, func(i, j int) bool {
+		if s.spent[modes[i]] != s.spent[modes[j]] {
+			return s.spent[modes[i]] > s.spent[modes[j]]
+		}
+		return modes[i] < modes[j]
+	})
+	var b strings.Builder
+	_, _ = fmt.Fprintf(&b, "Session %v: %v placed, %v deleted\n", total.Round(time.Second), s.placed, s.deleted)
+	for _, m := range modes {
+		name := m
+		if name == "" {
+			name = "Editor"
+		}
+		_, _ = fmt.Fprintf(&b, "  %v %v\n", name, s.spent[m].Round(time.Second))
+	}
+	return b.String()
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+)
+
+// User preferences are stored here between sessions
+const settingsPath = "settings.json"
+
+// The active user preferences. Loaded on startup.
+var settings = DefaultSettings()
+
+// User preferences which persist between sessions.
+type Settings struct {
+	// Multiplier between 0 and 1 applied to the level's background music
+	MusicVolume float64
+	// Multiplier between 0 and 1 applied to sound effects, e.g triggers
+	SFXVolume float64
+	// Physics steps per second, rounded to a multiple of the 60 game ticks per second. Higher is more accurate.
+	PhysicsRate int
+	// If true, frames are drawn as fast as possible rather than synced to the display, with bodies interpolated
+	// between ticks.
+	UncappedRender bool
+	// Updates per second, e.g 144 to match a 144Hz display. The game still ticks 60 times a second, with bodies
+	// interpolated between ticks if the rates differ. Ignored if rendering is uncapped.
+	TPS int
+	// If true, frames are synced to the display's refresh to avoid tearing. Ignored if rendering is uncapped.
+	Vsync bool
+	// One of "auto", "high" or "low". Low skips expensive effects, and auto switches to low on slow devices.
+	Quality string
+	// If true, games are drawn as if on an old CRT television, see CRT
+	CRT bool
+	// If true, the game fills the screen rather than a window. Toggled with the fullscreen shortcut.
+	Fullscreen bool
+	// If true, the editor draws a grid of world units behind the level. Toggled with the grid shortcut.
+	Grid bool
+	// World units between the grid's heavier lines
+	GridMajor int
+	// If true, the editor draws rulers along the top and left of the screen. Toggled with the rulers shortcut.
+	Rulers bool
+	// Editor shortcuts by action, see Binding. Actions missing from the settings file keep their default bindings.
+	Bindings map[string]Binding
+	// Credited as the author of levels saved in the editor which don't have one yet
+	Author string `json:",omitempty"`
+}
+
+func DefaultSettings() Settings {
+	return Settings{
+		MusicVolume: 1,
+		SFXVolume:   1,
+		PhysicsRate: ticksPerSecond,
+		TPS:         ticksPerSecond,
+		Vsync:       true,
+		Quality:     qualityAuto,
+		GridMajor:   defaultGridMajor,
+		Bindings:    defaultBindings(),
+	}
+}
+
+// Replaces the settings with those stored at the given path. A missing file leaves the defaults in place.
+func (s *Settings) load(path string) error {
+	*s = DefaultSettings()
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("open settings: %w", err)
+	}
+	defer f.Close()
+	err = json.NewDecoder(f).Decode(s)
+	if err != nil {
+		return fmt.Errorf("decode settings: %w", err)
+	}
+	return nil
+}
+
+// Saves the settings to the given path
+func (s Settings) save(path string) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0777)
+	if err != nil {
+		return fmt.Errorf("open file to save settings: %w", err)
+	}
+	defer f.Close()
+	encoder := json.NewEncoder(f)
+	encoder.SetIndent("", "    ")
+	err = encoder.Encode(s)
+	if err != nil {
+		return fmt.Errorf("save settings: %w", err)
+	}
+	return nil
+}
+
+// Adjusts a volume setting by the given amount, keeping it between 0 and 1.
+func nudgeVolume(v *float64, by float64) {
+	*v = math.Max(0, math.Min(1, *v+by))
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hherman1/gobananas/resources"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strconv"
+)
+
+// A shader from the shaders directory which draws a block or art in place of the default, e.g for lava, water or
+// pulsing hazards. Besides its own uniforms the game sets Time, Cursor and ScreenPixels like the main shader's, and
+// the lighting uniforms if the level is lit. Art's image is the shader's first image, and its normal map the second.
+type CustomShader struct {
+	// Path to the shader, e.g "shaders/lava_shader.go"
+	Path string
+	// Values of the shader's own uniforms by name
+	Uniforms map[string]UniformValue `json:",omitempty"`
+	// The loaded shader. Nil until the level is loaded, in which case the default is used.
+	shader *ebiten.Shader
+	// The number of floats in each uniform the loaded shader declares, see uniformSizes
+	sizes map[string]int
+}
+
+// Floats in a uniform of each type. Uniforms of other types, e.g int, can't be set by levels or the game.
+var uniformTypeSizes = map[string]int{"float": 1, "vec2": 2, "vec3": 3, "vec4": 4, "mat2": 4, "mat3": 9, "mat4": 16}
+
+// The number of floats in each uniform declared by the shader source, 0 for types which can't be set. Shaders are Go
+// syntax, so they're parsed as Go.
+func uniformSizes(src []byte) (map[string]int, error) {
+	f, err := parser.ParseFile(token.NewFileSet(), "", src, 0)
+	if err != nil {
+		return nil, fmt.Errorf("parse shader: %w", err)
+	}
+	var size func(t ast.Expr) int
+	size = func(t ast.Expr) int {
+		switch t := t.(type) {
+		case *ast.Ident:
+			return uniformTypeSizes[t.Name]
+		case *ast.ArrayType:
+			lit, ok := t.Len.(*ast.BasicLit)
+			if !ok || lit.Kind != token.INT {
+				return 0
+			}
+			n, err := strconv.Atoi(lit.Value)
+			if err != nil {
+				return 0
+			}
+			return n * size(t.Elt)
+		}
+		return 0
+	}
+	sizes := make(map[string]int)
+	for _, d := range f.Decls {
+		g, ok := d.(*ast.GenDecl)
+		if !ok || g.Tok != token.VAR {
+			continue
+		}
+		for _, spec := range g.Specs {
+			v := spec.(*ast.ValueSpec)
+			for _, n := range v.Names {
+				sizes[n.Name] = size(v.Type)
+			}
+		}
+	}
+	return sizes, nil
+}
+
+// The number of floats in a uniform value the game sets
+func uniformSize(v interface{}) int {
+	switch v := v.(type) {
+	case float32:
+		return 1
+	case []float32:
+		return len(v)
+	}
+	return 0
+}
+
+// The value of a shader uniform. Written as a number for floats, or a list of numbers for vectors and arrays.
+type UniformValue []float32
+
+func (u *UniformValue) UnmarshalJSON(bytes []byte) error {
+	var f float32
+	if json.Unmarshal(bytes, &f) == nil {
+		*u = UniformValue{f}
+		return nil
+	}
+	var fs []float32
+	err := json.Unmarshal(bytes, &fs)
+	if err != nil {
+		return fmt.Errorf("expected a number or a list of numbers: %w", err)
+	}
+	*u = fs
+	return nil
+}
+
+func (u UniformValue) MarshalJSON() ([]byte, error) {
+	if len(u) == 1 {
+		return json.Marshal(u[0])
+	}
+	return json.Marshal([]float32(u))
+}
+
+// Loads the shader, and checks the level's uniforms match what it declares. Ebiten fails the whole frame on a
+// mismatch, e.g 1 value for a vec3, so they're caught here.
+func (c *CustomShader) Load() error {
+	s, err := resources.Shader(c.Path)
+	if err != nil {
+		return fmt.Errorf("load shader: %w", err)
+	}
+	src, err := resources.ShaderSource(c.Path)
+	if err != nil {
+		return fmt.Errorf("load shader: %w", err)
+	}
+	sizes, err := uniformSizes(src)
+	if err != nil {
+		return err
+	}
+	for name, v := range c.Uniforms {
+		size, ok := sizes[name]
+		if !ok {
+			return fmt.Errorf("shader %v has no uniform %v", c.Path, name)
+		}
+		if size != len(v) {
+			return fmt.Errorf("uniform %v has %v values, shader %v expects %v", name, len(v), c.Path, size)
+		}
+	}
+	c.shader = s
+	c.sizes = sizes
+	return nil
+}
+
+// The shader to draw with, the given default if the custom shader is unset or not loaded, and the uniforms to draw
+// with, the given ones overridden by the custom shader's own. Given uniforms the custom shader declares differently are
+// left out, e.g Ambient in shaders written when it was a float, so they're zeroed rather than failing the frame.
+func (c *CustomShader) use(def *ebiten.Shader, uniforms map[string]interface{}) (*ebiten.Shader, map[string]interface{}) {
+	if c == nil || c.shader == nil {
+		return def, uniforms
+	}
+	for name, v := range uniforms {
+		if size, ok := c.sizes[name]; ok && size != uniformSize(v) {
+			delete(uniforms, name)
+		}
+	}
+	for name, v := range c.Uniforms {
+		if len(v) == 1 {
+			uniforms[name] = v[0]
+		} else {
+			uniforms[name] = []float32(v)
+		}
+	}
+	return c.shader, uniforms
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestUniformValueJSON(t *testing.T) {
+	var u map[string]UniformValue
+	err := json.Unmarshal([]byte(`{"Speed": 2, "Color": [1, 0.5, 0]}`), &u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]UniformValue{"Speed": {2}, "Color": {1, 0.5, 0}}
+	if !reflect.DeepEqual(u, want) {
+		t.Errorf("decoded uniforms %v, want %v", u, want)
+	}
+	out, err := json.Marshal(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != `{"Color":[1,0.5,0],"Speed":2}` {
+		t.Errorf("encoded uniforms as %s, want floats as numbers and vectors as lists", out)
+	}
+	if json.Unmarshal([]byte(`{"Speed": "fast"}`), &u) == nil {
+		t.Errorf("decoded a uniform which isn't a number")
+	}
+}
+
+func TestUniformSizes(t *testing.T) {
+	src := `package main
+
+var Speed float
+var Color vec3
+var (
+	Lights [8]vec3
+	Count  int
+)
+
+func Fragment(position vec4, texCoord vec2, color vec4) vec4 {
+	return vec4(Color, 1)
+}
+`
+	got, err := uniformSizes([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]int{"Speed": 1, "Color": 3, "Lights": 24, "Count": 0}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got sizes %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import "math"
+
+// Shakes the camera for a moment, e.g for an explosion or a hard landing. The shake is added to where the camera
+// points when drawing, so it doesn't affect the camera's movement or gameplay.
+type Shake struct {
+	// Greatest distance in world units the camera is moved from where it points
+	Amplitude float64
+	// Times per second the camera moves back and forth
+	Frequency float64
+	// Seconds for the shake to die down to about a third of its amplitude
+	Decay float64
+}
+
+// Shakes weaker than this fraction of their amplitude are over
+const shakeCutoff = 0.01
+
+const (
+	// Speed in world units per second the player must be falling at for landing to shake the camera
+	hardLanding = 12
+	// World units of shake for each unit of speed the player lands at beyond hardLanding, and the most it can be
+	landingShake    = 0.02
+	maxLandingShake = 0.3
+)
+
+// The shake when the player is hurt
+var hurtShake = Shake{Amplitude: 0.15, Frequency: 15, Decay: 0.2}
+
+// Starts shaking the camera, unless it's already shaking harder
+func (c *Camera) Shake(s Shake) {
+	if s.Amplitude <= 0 || s.Decay <= 0 {
+		return
+	}
+	if c.shaking().Amplitude > s.Amplitude {
+		return
+	}
+	c.shake = s
+	c.shaken = 0
+}
+
+// The shake the camera is in the middle of, with its amplitude decayed so far. Zero when it isn't shaking.
+func (c *Camera) shaking() Shake {
+	s := c.shake
+	if s.Amplitude <= 0 || s.Decay <= 0 {
+		return Shake{}
+	}
+	s.Amplitude *= math.Exp(-c.shaken / s.Decay)
+	return s
+}
+
+// Advances the camera's shake by a tick, ending it once it has died down
+func (c *Camera) advanceShake() {
+	if c.shake.Amplitude == 0 {
+		return
+	}
+	c.shaken += 1.0 / ticksPerSecond
+	if c.shaking().Amplitude < shakeCutoff*c.shake.Amplitude {
+		c.shake, c.shaken = Shake{}, 0
+	}
+}
+
+// How far in world units the shake moves the camera from where it points. The two axes move at slightly different
+// rates so the camera wobbles rather than sliding along a line, and the same shake always moves the same way, so
+// replays and headless runs draw identically.
+func (c *Camera) shakeOffset() (float64, float64) {
+	s := c.shaking()
+	if s.Amplitude == 0 {
+		return 0, 0
+	}
+	phase := 2 * math.Pi * s.Frequency * c.shaken
+	return s.Amplitude * math.Sin(phase), s.Amplitude * math.Sin(1.3*phase+1)
+}
+
+// Shakes the camera if the player just landed hard, going by how fast they were falling before the last physics step
+func (g *Game) land(wasGrounded bool) {
+	if !g.p.grounded || wasGrounded || g.p.fall < hardLanding {
+		return
+	}
+	amplitude := math.Min(maxLandingShake, landingShake*(g.p.fall-hardLanding))
+	g.c.Shake(Shake{Amplitude: amplitude, Frequency: 12, Decay: 0.15})
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"github.com/ByteArena/box2d"
+	"github.com/hajimehoshi/ebiten/v2"
+	"image/color"
+	"sort"
+	"strings"
+)
+
+var startColor = color.RGBA{R: 0xff, G: 0xe0, B: 0x40, A: 0xff}
+
+// Finds the spawn point with the given name, where "" is the level's default spawn.
+func (l *Level) spawnPoint(name string) (box2d.B2Vec2, bool) {
+	if name == "" {
+		return l.Spawn, true
+	}
+	p, ok := l.Spawns[name]
+	return p, ok
+}
+
+// Names of the level's spawn points in order, starting with "" for the default spawn
+func (l *Level) spawnNames() []string {
+	var names []string
+	for n := range l.Spawns {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return append([]string{""}, names...)
+}
+
+// Moves the player to the named spawn point, stops them and restores their health. Unknown names are ignored.
+func (g *Game) respawn(name string) {
+	p, ok := g.spawns[name]
+	if !ok {
+		return
+	}
+	g.p.b.SetTransform(p, 0)
+	g.p.b.SetLinearVelocity(box2d.B2Vec2{})
+	g.p.b.SetAngularVelocity(0)
+	g.p.spawn = name
+	g.p.health = playerHealth
+}
+
+// Makes a named spawn point of a level selectable
+type NamedSpawnSelector struct {
+	C    *Camera
+	L    *Level
+	Name string
+}
+
+func (s *NamedSpawnSelector) Transform() Mx {
+	// drawn at a fixed size like the default spawn
+	side := s.C.pixels(20)
+	p := s.L.Spawns[s.Name]
+	geo := Mx{}
+	geo.Scale(side, side)
+	geo.Translate(p.X, p.Y)
+	return geo
+}
+
+func (s *NamedSpawnSelector) SetTransform(m Mx) {
+	x, y := m.Apply(0, 0)
+	s.L.Spawns[s.Name] = box2d.B2Vec2{X: x, Y: y}
+}
+
+func (s *NamedSpawnSelector) Delete() {
+	delete(s.L.Spawns, s.Name)
+}
+
+// Draws each spawn point with its name, highlighting the one playtests start from
+func drawSpawns(screen *ebiten.Image, l *Level, start string, toScreen Mx) {
+	for _, n := range l.spawnNames() {
+		p, _ := l.spawnPoint(n)
+		c := color.Color(color.White)
+		if n == start {
+			c = startColor
+		}
+		drawpoint(screen, p.X, p.Y, 20, toScreen, c)
+		if n != "" {
+			sx, sy := toScreen.Apply(p.X, p.Y)
+			printAt(screen, n, int(sx)+12, int(sy)-8)
+		}
+	}
+}
+
+// Editor for placing named spawn points. The chosen spawn is also where playtests start.
+type SpawnEditor struct {
+	e *Editor
+	t *Typer
+}
+
+func ActivateSpawnEditor(r *Root, e *Editor) {
+	r.a = &SpawnEditor{e: e, t: &Typer{
+		Placeholder: "Spawn Editor: (Enter) Name a spawn (Tab) Next spawn (Click) Place (Backspace) Delete",
+		C:           &e.c,
+	}}
+}
+
+func (s *SpawnEditor) Layout(outsideWidth, outsideHeight int) (screenWidth, screenHeight int) {
+	return s.e.Layout(outsideWidth, outsideHeight)
+}
+
+func (s *SpawnEditor) String() string {
+	return "Spawns"
+}
+
+func (s *SpawnEditor) Update(r *Root) error {
+	name, typ := s.t.Update()
+	if typ {
+		return nil
+	}
+	l := &s.e.l
+	if name = strings.TrimSpace(name); name != "" {
+		s.e.start = name
+	}
+	if Clicked(ebiten.KeyTab) {
+		names := l.spawnNames()
+		for i, n := range names {
+			if n == s.e.start {
+				s.e.start = names[(i+1)%len(names)]
+				break
+			}
+		}
+	}
+	if Clicked(ebiten.KeyBackspace) && s.e.start != "" {
+		delete(l.Spawns, s.e.start)
+		s.e.start = ""
+	}
+	if MouseClicked(ebiten.MouseButtonLeft) {
+		wx, wy := s.e.c.Cursor()
+		if s.e.start == "" {
+			l.Spawn = box2d.B2Vec2{X: wx, Y: wy}
+		} else {
+			if l.Spawns == nil {
+				l.Spawns = make(map[string]box2d.B2Vec2)
+			}
+			l.Spawns[s.e.start] = box2d.B2Vec2{X: wx, Y: wy}
+		}
+	}
+	return s.e.Update(r)
+}
+
+func (s *SpawnEditor) Draw(screen *ebiten.Image) {
+	s.e.Draw(screen)
+	s.t.Draw(screen)
+	current := "default"
+	if s.e.start != "" {
+		current = s.e.start
+		if _, ok := s.e.l.Spawns[current]; !ok {
+			current += " (click to place)"
+		}
+	}
+	printAt(screen, fmt.Sprintf("Spawn: %v", current), 10, s.e.c.sh-40)
+}
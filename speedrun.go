@@ -0,0 +1,196 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/hajimehoshi/ebiten/v2"
+	"image/color"
+	"io/fs"
+	"os"
+	"sort"
+	"time"
+)
+
+var goalColor = color.RGBA{R: 0x40, G: 0xff, B: 0x60, A: 0xff}
+
+// How many of a level's fastest runs are kept in its best times file
+const bestTimesKept = 10
+
+// Times a run from the player's first input until they reach the level's goal. Counted in ticks, so a run takes the
+// same time however fast it was drawn.
+type speedrun struct {
+	// Tick of the first input, 0 until then
+	start int
+	// Tick the goal was reached, 0 until then
+	end int
+	// Set once the finished run has been written to the best times
+	recorded bool
+}
+
+// How long the run has taken by the given tick, or took if it is finished.
+func (s speedrun) elapsed(now int) time.Duration {
+	if s.start == 0 {
+		return 0
+	}
+	if s.end != 0 {
+		now = s.end
+	}
+	return time.Duration(now-s.start) * tickDuration
+}
+
+// Starts the timer on the player's first input, and stops it when the player reaches the goal. Called at the end of
+// every tick.
+func (g *Game) timeRun(input bool) {
+	if g.timer.start == 0 && input {
+		g.timer.start = g.time
+	}
+	if g.timer.start == 0 || g.timer.end != 0 || g.goal == nil {
+		return
+	}
+	pos := g.p.b.GetPosition()
+	if contains(*g.goal, pos.X, pos.Y) {
+		g.timer.end = g.time
+	}
+}
+
+// Whether the world position is inside the unit square centered at the origin under the given transform
+func contains(m Mx, x, y float64) bool {
+	inv := m
+	inv.Invert()
+	lx, ly := inv.Apply(x, y)
+	return lx >= -0.5 && lx <= 0.5 && ly >= -0.5 && ly <= 0.5
+}
+
+// Formats a run time as minutes, seconds and milliseconds, e.g 1:02.345
+func formatRunTime(d time.Duration) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%d:%02d.%03d", ms/60000, ms/1000%60, ms%1000)
+}
+
+// Draws the outline of a goal zone
+func drawGoal(screen *ebiten.Image, goal Mx, toScreen Mx) {
+	drawoutline(screen, goal, 3, toScreen, goalColor)
+}
+
+// The fastest runs of a level, stored in a file next to it
+type BestTimes struct {
+	// Hash of the level the times were set on, see playedHash. Times set before the level was edited don't count.
+	Level string
+	// Run times in milliseconds, fastest first
+	Times []int64
+}
+
+// Where the best times for the level at the given path are kept
+func bestTimesPath(level string) string {
+	return level + ".times"
+}
+
+// Replaces the best times with those stored at the given path. A missing file means there are no times yet.
+func (b *BestTimes) load(path string) error {
+	*b = BestTimes{}
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("open best times: %w", err)
+	}
+	defer f.Close()
+	err = json.NewDecoder(f).Decode(b)
+	if err != nil {
+		return fmt.Errorf("decode best times: %w", err)
+	}
+	return nil
+}
+
+// Saves the best times to the given path
+func (b BestTimes) save(path string) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0777)
+	if err != nil {
+		return fmt.Errorf("open file to save best times: %w", err)
+	}
+	defer f.Close()
+	encoder := json.NewEncoder(f)
+	encoder.SetIndent("", "    ")
+	err = encoder.Encode(b)
+	if err != nil {
+		return fmt.Errorf("save best times: %w", err)
+	}
+	return nil
+}
+
+// Adds a run, dropping the slowest if there are too many. Returns the run's place in the times, 0 for the fastest,
+// or -1 if it was too slow to keep.
+func (b *BestTimes) add(d time.Duration) int {
+	ms := d.Milliseconds()
+	// after any equal times, which were set first
+	rank := sort.Search(len(b.Times), func(i int) bool {
+		return b.Times[i] > ms
+	})
+	if rank >= bestTimesKept {
+		return -1
+	}
+	b.Times = append(b.Times, 0)
+	copy(b.Times[rank+1:], b.Times[rank:])
+	b.Times[rank] = ms
+	if len(b.Times) > bestTimesKept {
+		b.Times = b.Times[:bestTimesKept]
+	}
+	return rank
+}
+
+// The fastest run, if any
+func (b BestTimes) best() (time.Duration, bool) {
+	if len(b.Times) == 0 {
+		return 0, false
+	}
+	return time.Duration(b.Times[0]) * time.Millisecond, true
+}
+
+// Editor for placing the level's goal. Dragging draws a new goal zone.
+type GoalEditor struct {
+	e *Editor
+	// The zone being dragged out, and the point the drag started at
+	creating *Mx
+	startx   float64
+	starty   float64
+}
+
+func ActivateGoalEditor(r *Root, e *Editor) {
+	r.a = &GoalEditor{e: e}
+}
+
+func (g *GoalEditor) Layout(outsideWidth, outsideHeight int) (screenWidth, screenHeight int) {
+	return g.e.Layout(outsideWidth, outsideHeight)
+}
+
+func (g *GoalEditor) String() string {
+	return "Goal"
+}
+
+func (g *GoalEditor) Update(r *Root) error {
+	if Clicked(ebiten.KeyBackspace) {
+		g.e.l.Goal = nil
+	}
+	if driver.IsMouseButtonPressed(ebiten.MouseButtonLeft) {
+		wx, wy := g.e.c.Cursor()
+		if g.creating == nil {
+			g.creating = &Mx{}
+			g.startx, g.starty = wx, wy
+		}
+		*g.creating = boxBetween(g.startx, g.starty, wx, wy)
+	} else if g.creating != nil {
+		g.e.l.Goal = g.creating
+		g.creating = nil
+	}
+	return g.e.Update(r)
+}
+
+func (g *GoalEditor) Draw(screen *ebiten.Image) {
+	if g.creating != nil {
+		drawGoal(screen, *g.creating, g.e.c.ToScreen())
+	}
+	printAt(screen, "Goal Editor: Drag to place the goal, (Backspace) Remove", 10, g.e.c.sh-20)
+	g.e.Draw(screen)
+}
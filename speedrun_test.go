@@ -0,0 +1,60 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRunTimedToGoal(t *testing.T) {
+	l := floorLevel()
+	var goal Mx
+	goal.Scale(2, 4)
+	goal.Translate(1.5, 1)
+	l.Goal = &goal
+	s := &Script{Steps: []ScriptStep{
+		{Tick: ticksPerSecond, Press: []string{"D"}},
+		{Tick: 3 * ticksPerSecond, Release: []string{"D"}},
+	}}
+	g := NewHeadlessGame(l, s)
+	err := Simulate(g, 4*ticksPerSecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.timer.start != ticksPerSecond+1 {
+		t.Errorf("timer started at tick %v, want the first tick D was held", g.timer.start)
+	}
+	if g.timer.end == 0 {
+		t.Fatalf("player at x %v never reached the goal", g.p.b.GetPosition().X)
+	}
+	if d := g.timer.elapsed(g.time); d <= 0 || d > 2*time.Second {
+		t.Errorf("run took %v, want under the 2s D was held", d)
+	}
+}
+
+func TestBestTimesKeepsFastest(t *testing.T) {
+	var b BestTimes
+	if rank := b.add(3 * time.Second); rank != 0 {
+		t.Errorf("first time placed %v, want 0", rank)
+	}
+	if rank := b.add(4 * time.Second); rank != 1 {
+		t.Errorf("slower time placed %v, want 1", rank)
+	}
+	for i := 0; i < bestTimesKept; i++ {
+		b.add(time.Second + time.Duration(i)*time.Millisecond)
+	}
+	if len(b.Times) != bestTimesKept {
+		t.Errorf("kept %v times, want %v", len(b.Times), bestTimesKept)
+	}
+	if best, _ := b.best(); best != time.Second {
+		t.Errorf("best is %v, want 1s", best)
+	}
+	if rank := b.add(5 * time.Second); rank != -1 {
+		t.Errorf("time slower than the whole board placed %v, want -1", rank)
+	}
+}
+
+func TestFormatRunTime(t *testing.T) {
+	if got := formatRunTime(62*time.Second + 345*time.Millisecond); got != "1:02.345" {
+		t.Errorf("got %v, want 1:02.345", got)
+	}
+}
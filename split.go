@@ -0,0 +1,148 @@
+package main
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"image/color"
+)
+
+var splitDividerColor = color.RGBA{R: 0x10, G: 0x10, B: 0x10, A: 0xff}
+
+// Plays the game in the left half of the window beside the editor it was started from in the right half, for tuning
+// the level while watching it play. The half under the cursor takes the keyboard and mouse, the game carries on
+// without player input while the editor has them. Restarting the game picks up the edits made so far.
+type SplitView struct {
+	a *Admin
+	// Runs the editor and whichever of its subeditors is active in the right half
+	right Root
+	// Whether the editor camera is kept centered on the player
+	follow bool
+
+	// Offscreen images each half is drawn to, reallocated when the window changes size
+	left, edit *ebiten.Image
+}
+
+// Activates the split view for the game being played. The game must have been started from an editor.
+func ActivateSplitView(r *Root, a *Admin) {
+	s := &SplitView{a: a}
+	s.right.a = a.e
+	r.a = s
+}
+
+// Input with nothing held, for a game that keeps running while the player is busy elsewhere
+type idleInput struct{}
+
+func (idleInput) IsKeyPressed(ebiten.Key) bool {
+	return false
+}
+
+func (idleInput) IsMouseButtonPressed(ebiten.MouseButton) bool {
+	return false
+}
+
+func (idleInput) Wheel() (float64, float64) {
+	return 0, 0
+}
+
+func (idleInput) Cursor(c *Camera) (float64, float64) {
+	return c.x, c.y
+}
+
+func (s *SplitView) Layout(outsideWidth, outsideHeight int) (screenWidth, screenHeight int) {
+	half := outsideWidth / 2
+	s.a.g.Layout(half, outsideHeight)
+	s.right.Layout(outsideWidth-half, outsideHeight)
+	s.a.e.c.left = half
+	return outsideWidth, outsideHeight
+}
+
+// Whether the cursor is over the game's half of the window
+func (s *SplitView) gameFocused() bool {
+	x, _ := driver.CursorPosition()
+	return x < s.a.e.c.left
+}
+
+// Returns to playing the game full screen
+func (s *SplitView) exit(r *Root) {
+	s.a.e.c.left = 0
+	s.a.g.in = liveInput{}
+	r.a = s.a
+}
+
+func (s *SplitView) Update(r *Root) error {
+	focused := s.gameFocused()
+	if focused {
+		switch {
+		case Clicked(ebiten.KeyY):
+			s.exit(r)
+			return nil
+		case Clicked(ebiten.KeyR):
+			s.a.restart()
+		case Clicked(ebiten.KeyC):
+			s.follow = !s.follow
+		}
+		s.a.g.in = liveInput{}
+	} else {
+		s.a.g.in = idleInput{}
+		err := s.right.a.Update(&s.right)
+		if err != nil {
+			return err
+		}
+		// the editor started a new game, which takes over the window, along with the transition to it if any
+		started := false
+		switch a := s.right.a.(type) {
+		case *Admin:
+			started = true
+		case *Transition:
+			_, started = a.to.(*Admin)
+		}
+		if started {
+			s.a.e.c.left = 0
+			r.a = s.right.a
+			return nil
+		}
+	}
+	err := s.a.g.Update()
+	if err != nil {
+		return err
+	}
+	if s.follow {
+		p := s.a.g.p.b.GetPosition()
+		s.a.e.c.x, s.a.e.c.y = p.X, p.Y
+	}
+	return nil
+}
+
+// Returns an image of the given size to draw a half into, reusing the old one if it's the right size
+func splitImage(old *ebiten.Image, w, h int) *ebiten.Image {
+	if old != nil {
+		if ow, oh := old.Size(); ow == w && oh == h {
+			old.Clear()
+			return old
+		}
+		old.Dispose()
+	}
+	return ebiten.NewImage(w, h)
+}
+
+func (s *SplitView) Draw(screen *ebiten.Image) {
+	half := s.a.e.c.left
+	w, h := screen.Size()
+	s.left = splitImage(s.left, half, h)
+	s.edit = splitImage(s.edit, w-half, h)
+
+	s.a.g.Draw(s.left)
+	follow := "Off"
+	if s.follow {
+		follow = "On"
+	}
+	printAt(s.left, "Split View\n(Y) Back to game\n(R) Restart with edits\n(C) Editor follows player: "+
+		follow, 10, 10)
+	s.right.Draw(s.edit)
+
+	screen.DrawImage(s.left, nil)
+	var op ebiten.DrawImageOptions
+	op.GeoM.Translate(float64(half), 0)
+	screen.DrawImage(s.edit, &op)
+	ebitenutil.DrawRect(screen, float64(half)-1, 0, 2, float64(h), splitDividerColor)
+}
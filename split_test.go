@@ -0,0 +1,37 @@
+package main
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+	"testing"
+)
+
+func TestSplitViewEditsBesideRunningGame(t *testing.T) {
+	r, e, f := testEditor(t)
+	g := NewHeadlessGame(e.l, &Script{})
+	g.respawn("")
+	ActivateSplitView(r, &Admin{g: g, e: e})
+	r.Layout(1440, 480)
+	if e.c.left != 720 {
+		t.Fatalf("editor starts %v pixels from the left, want 720", e.c.left)
+	}
+	// keys go to the editor while the cursor is over its half
+	f.moveTo(&e.c, 0, 0)
+	press(t, r, f, ebiten.KeyL)
+	s, ok := r.a.(*SplitView)
+	if !ok {
+		t.Fatalf("L left the split view for %v", r.a)
+	}
+	if _, ok := s.right.a.(*PlatformEditor); !ok {
+		t.Fatalf("L opened %v in the editor half, want the platform editor", s.right.a)
+	}
+	dragMouse(t, r, f, &e.c, 3, 2, 1, -1)
+	if len(e.l.Blocks) != 1 {
+		t.Fatalf("got %v blocks, want 1", len(e.l.Blocks))
+	}
+	b := e.l.Blocks[0].T
+	assertCorner(t, &e.c, b, -0.5, -0.5, 1, -1)
+	assertCorner(t, &e.c, b, 0.5, 0.5, 3, 2)
+	if g.time == 0 {
+		t.Error("the game didn't run while editing")
+	}
+}
@@ -0,0 +1,293 @@
+package main
+
+import (
+	"fmt"
+	"github.com/ByteArena/box2d"
+	"github.com/hajimehoshi/ebiten/v2"
+	"image/color"
+	"strings"
+)
+
+var (
+	switchColor        = color.RGBA{R: 0xff, G: 0x80, B: 0x20, A: 0xff}
+	switchPressedColor = color.RGBA{R: 0xff, G: 0xd0, B: 0xa0, A: 0xff}
+	wireColor          = color.RGBA{R: 0xff, G: 0x80, B: 0x20, A: 0x80}
+)
+
+// A zone which is pressed when the player touches it or shoots it, firing a trigger and turning blocks on or off.
+type Switch struct {
+	// Transform of a unit square centered at the origin to the switch's zone
+	T Mx
+	// Name of the trigger to fire when pressed, if any
+	Trigger string `json:",omitempty"`
+	// Indices in the level's blocks of the blocks turned on or off when pressed
+	Targets []int `json:",omitempty"`
+}
+
+// Draws the switch, with wires to each of its targets
+func (s *Switch) draw(screen *ebiten.Image, blocks []*Block, toScreen Mx) {
+	drawoutline(screen, s.T, 2, toScreen, switchColor)
+	x, y := s.T.Apply(0, 0)
+	for _, i := range s.Targets {
+		if i < 0 || i >= len(blocks) {
+			continue
+		}
+		tx, ty := blocks[i].T.Apply(0, 0)
+		drawline(screen, x, y, tx, ty, 1, toScreen, wireColor)
+	}
+}
+
+// Toggles whether the block with the given index is one of the switch's targets
+func (s *Switch) toggleTarget(block int) {
+	for i, t := range s.Targets {
+		if t == block {
+			s.Targets = append(s.Targets[:i], s.Targets[i+1:]...)
+			return
+		}
+	}
+	s.Targets = append(s.Targets, block)
+}
+
+// A switch in a running game
+type pressable struct {
+	s *Switch
+	// The switch's sensor body
+	e *Entity
+	// Entities of the switch's target blocks
+	targets []*Entity
+	// Whether something was touching the switch last tick, so it is only pressed once per touch
+	pressed bool
+}
+
+// Adds the switch to the game as a sensor, which bodies pass through
+func (s *Switch) build(g *Game, blocks []*Block) {
+	center, angle, hw, hh := decompose(s.T)
+	body := box2d.NewB2BodyDef()
+	body.Position = center
+	body.Angle = angle
+	// not in the game's entities, switches are drawn separately
+	e := &Entity{
+		w: hw * 2,
+		h: hh * 2,
+		b: g.world.CreateBody(body),
+	}
+	e.b.SetUserData(e)
+	shape := box2d.MakeB2PolygonShape()
+	shape.SetAsBox(hw, hh)
+	def := box2d.MakeB2FixtureDef()
+	def.Shape = &shape
+	def.IsSensor = true
+	e.b.CreateFixtureFromDef(&def)
+
+	p := &pressable{s: s, e: e}
+	for _, i := range s.Targets {
+		if i < 0 || i >= len(blocks) {
+			continue
+		}
+		for _, t := range g.entities {
+			if t.block == blocks[i] {
+				p.targets = append(p.targets, t)
+			}
+		}
+	}
+	g.switches = append(g.switches, p)
+}
+
+// Presses any switches the player or a bullet has started touching since the last tick
+func (g *Game) pressSwitches() {
+	for _, p := range g.switches {
+		touching := false
+		for next := p.e.b.GetContactList(); next != nil; next = next.Next {
+			if !next.Contact.IsTouching() {
+				continue
+			}
+			switch u := next.Other.GetUserData().(type) {
+			case *Player:
+				touching = true
+			case *Entity:
+				touching = touching || u.bullet
+			}
+		}
+		if touching && !p.pressed {
+			g.press(p)
+		}
+		p.pressed = touching
+	}
+}
+
+// Fires the switch's trigger and turns its targets on or off
+func (g *Game) press(p *pressable) {
+	for _, t := range p.targets {
+		t.b.SetActive(!t.b.IsActive())
+	}
+	if p.s.Trigger != "" {
+		pos := p.e.b.GetPosition()
+		g.fire(p.s.Trigger, pos.X, pos.Y)
+	}
+}
+
+// Draws the game's switches, lit while something is touching them
+func (g *Game) drawSwitches(screen *ebiten.Image, toScreen Mx) {
+	for _, p := range g.switches {
+		c := switchColor
+		if p.pressed {
+			c = switchPressedColor
+		}
+		drawoutline(screen, p.s.T, 2, toScreen, c)
+	}
+}
+
+// Makes switches selectable
+type SwitchSelector struct {
+	l *Level
+	s *Switch
+}
+
+func (s *SwitchSelector) Paste() Selectable {
+	kopy := *s.s
+	kopy.Targets = append([]int(nil), s.s.Targets...)
+	s.l.Switches = append(s.l.Switches, &kopy)
+	return &SwitchSelector{l: s.l, s: &kopy}
+}
+
+func (s *SwitchSelector) Delete() {
+	for i, o := range s.l.Switches {
+		if o == s.s {
+			s.l.Switches = append(s.l.Switches[:i], s.l.Switches[i+1:]...)
+			return
+		}
+	}
+}
+
+func (s *SwitchSelector) Transform() Mx {
+	return s.s.T
+}
+
+func (s *SwitchSelector) SetTransform(m Mx) {
+	s.s.T = m
+}
+
+// Editor for placing switches and wiring them to the blocks they turn on or off
+type SwitchEditor struct {
+	e *Editor
+	t *Typer
+	// The switch being wired, if any
+	selected *Switch
+	// The switch being dragged out, and the point the drag started at
+	creating *Mx
+	startx   float64
+	starty   float64
+	// Set while the mouse is held after a click on a switch or block, which doesn't start a new switch
+	clicked bool
+}
+
+func ActivateSwitchEditor(r *Root, e *Editor) {
+	r.a = &SwitchEditor{e: e, t: &Typer{
+		Placeholder: "Switch Editor: (Enter) 'trigger <name>' to fire a trigger from the selected switch",
+		C:           &e.c,
+	}}
+}
+
+func (s *SwitchEditor) Layout(outsideWidth, outsideHeight int) (screenWidth, screenHeight int) {
+	return s.e.Layout(outsideWidth, outsideHeight)
+}
+
+func (s *SwitchEditor) String() string {
+	return "Switches"
+}
+
+// Applies a command of the form "trigger [name]" to the selected switch. Without a name the trigger is removed.
+func (s *SwitchEditor) apply(cmd string) error {
+	parts := strings.Fields(cmd)
+	if len(parts) == 0 || len(parts) > 2 || parts[0] != "trigger" {
+		return fmt.Errorf("expected 'trigger [name]'")
+	}
+	if s.selected == nil {
+		return fmt.Errorf("no switch selected")
+	}
+	s.selected.Trigger = ""
+	if len(parts) == 2 {
+		s.selected.Trigger = parts[1]
+	}
+	return nil
+}
+
+// Handles a click, selecting the switch under the cursor, or wiring the selected switch to the block under the
+// cursor. Returns false if there was nothing under the cursor.
+func (s *SwitchEditor) click(x, y float64) bool {
+	l := &s.e.l
+	for _, sw := range l.Switches {
+		if contains(sw.T, x, y) {
+			s.selected = sw
+			return true
+		}
+	}
+	if s.selected == nil {
+		return false
+	}
+	for i, b := range l.Blocks {
+		if contains(b.T, x, y) {
+			s.selected.toggleTarget(i)
+			return true
+		}
+	}
+	return false
+}
+
+func (s *SwitchEditor) Update(r *Root) error {
+	cmd, typ := s.t.Update()
+	if typ {
+		return nil
+	}
+	if cmd != "" {
+		err := s.apply(cmd)
+		if err != nil {
+			s.t.Placeholder = fmt.Sprintf("Failed to apply '%v': %v", cmd, err)
+		}
+	}
+	l := &s.e.l
+	if Clicked(ebiten.KeyBackspace) && s.selected != nil {
+		(&SwitchSelector{l: l, s: s.selected}).Delete()
+		s.selected = nil
+	}
+	wx, wy := s.e.c.Cursor()
+	if MouseClicked(ebiten.MouseButtonLeft) {
+		s.clicked = s.click(wx, wy)
+	}
+	if driver.IsMouseButtonPressed(ebiten.MouseButtonLeft) && !s.clicked {
+		if s.creating == nil {
+			s.creating = &Mx{}
+			s.startx, s.starty = wx, wy
+		}
+		*s.creating = boxBetween(s.startx, s.starty, wx, wy)
+	} else if !driver.IsMouseButtonPressed(ebiten.MouseButtonLeft) {
+		s.clicked = false
+		if s.creating != nil {
+			if _, _, hw, hh := decompose(*s.creating); hw*2 >= minBlockSize && hh*2 >= minBlockSize {
+				s.selected = &Switch{T: *s.creating}
+				l.Switches = append(l.Switches, s.selected)
+			}
+			s.creating = nil
+		}
+	}
+	return s.e.Update(r)
+}
+
+func (s *SwitchEditor) Draw(screen *ebiten.Image) {
+	s.e.Draw(screen)
+	s.t.Draw(screen)
+	toScreen := s.e.c.ToScreen()
+	if s.creating != nil {
+		drawoutline(screen, *s.creating, 2, toScreen, switchColor)
+	}
+	msg := "Drag to place a switch, click a switch to select it"
+	if s.selected != nil {
+		drawoutline(screen, s.selected.T, 3, toScreen, startColor)
+		trigger := "none"
+		if s.selected.Trigger != "" {
+			trigger = s.selected.Trigger
+		}
+		msg = fmt.Sprintf("Click blocks to wire them to the switch, (Backspace) Delete. Trigger: %v", trigger)
+	}
+	printAt(screen, msg, 10, s.e.c.sh-35)
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/text"
+	"github.com/hherman1/gobananas/resources"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/font/opentype"
+	"image/color"
+	"strings"
+)
+
+// The font text is drawn in
+const uiFont = "resources/fonts/mplus-1p-regular.ttf"
+
+// Size in points of text drawn by printAt, chosen so lines are about as tall as the debug font's
+const defaultTextSize = 12
+
+// How lines of text are lined up with the x position they're drawn at
+type Align int
+
+const (
+	// Lines start at x
+	alignLeft Align = iota
+	// Lines are centered on x
+	alignCenter
+	// Lines end at x
+	alignRight
+)
+
+// How to draw some text
+type TextStyle struct {
+	// Size in points, defaultTextSize if 0
+	Size  float64
+	Align Align
+	// Width in pixels lines are wrapped to between words, or 0 to only break lines at newlines
+	Width int
+	// White if nil
+	Color color.Color
+}
+
+// Font faces by size, created as they're first used
+var faces = make(map[float64]font.Face)
+
+// The face to draw text of the given size with. Falls back to a fixed size bitmap font if the font can't be loaded,
+// so text is still readable.
+func face(size float64) font.Face {
+	if size == 0 {
+		size = defaultTextSize
+	}
+	if f, ok := faces[size]; ok {
+		return f
+	}
+	var f font.Face = basicfont.Face7x13
+	parsed, err := resources.Font(uiFont)
+	if err == nil {
+		f, err = opentype.NewFace(parsed, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+	}
+	if err != nil {
+		fmt.Println("Failed to load font, falling back to the bitmap font:", err)
+		f = basicfont.Face7x13
+	}
+	faces[size] = f
+	return f
+}
+
+// Splits the text into the lines it's drawn as, breaking at newlines, and between words where a line would be wider
+// than width if it's set. Words wider than width on their own get a line to themselves.
+func wrapText(f font.Face, s string, width int) []string {
+	var lines []string
+	for _, paragraph := range strings.Split(s, "\n") {
+		if width <= 0 {
+			lines = append(lines, paragraph)
+			continue
+		}
+		line := ""
+		for _, word := range strings.Fields(paragraph) {
+			next := word
+			if line != "" {
+				next = line + " " + word
+			}
+			if line != "" && font.MeasureString(f, next).Ceil() > width {
+				lines = append(lines, line)
+				next = word
+			}
+			line = next
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// The height in pixels of a line of text in the style
+func lineHeight(style TextStyle) int {
+	return face(style.Size).Metrics().Height.Ceil()
+}
+
+// The size in pixels of the box the text fills when drawn in the style
+func measureText(s string, style TextStyle) (w, h int) {
+	f := face(style.Size)
+	lines := wrapText(f, s, style.Width)
+	for _, line := range lines {
+		if lw := font.MeasureString(f, line).Ceil(); lw > w {
+			w = lw
+		}
+	}
+	return w, len(lines) * lineHeight(style)
+}
+
+// Draws the text in the style with the top of its first line at y, lined up with x by the style's alignment
+func drawText(screen *ebiten.Image, s string, x, y int, style TextStyle) {
+	f := face(style.Size)
+	c := style.Color
+	if c == nil {
+		c = color.White
+	}
+	height := lineHeight(style)
+	ascent := f.Metrics().Ascent.Ceil()
+	for i, line := range wrapText(f, s, style.Width) {
+		lx := x
+		switch style.Align {
+		case alignCenter:
+			lx -= font.MeasureString(f, line).Ceil() / 2
+		case alignRight:
+			lx -= font.MeasureString(f, line).Ceil()
+		}
+		text.Draw(screen, line, f, lx, y+i*height+ascent, c)
+	}
+}
+
+// Draws the text in the default style with its top left corner at x, y
+func printAt(screen *ebiten.Image, s string, x, y int) {
+	drawText(screen, s, x, y, TextStyle{})
+}
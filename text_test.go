@@ -0,0 +1,33 @@
+package main
+
+import (
+	"golang.org/x/image/font"
+	"reflect"
+	"testing"
+)
+
+func TestWrapTextBreaksBetweenWords(t *testing.T) {
+	f := face(defaultTextSize)
+	width := font.MeasureString(f, "jump over").Ceil()
+	got := wrapText(f, "jump over the gap\nthen go", width)
+	want := []string{"jump over", "the gap", "then go"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wrapped to %q, want %q", got, want)
+	}
+	// too long words get their own line rather than being split
+	got = wrapText(f, "a supercalifragilistic b", 1)
+	want = []string{"a", "supercalifragilistic", "b"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wrapped to %q, want %q", got, want)
+	}
+}
+
+func TestMeasureTextCountsLines(t *testing.T) {
+	_, h := measureText("one\ntwo\nthree", TextStyle{})
+	if want := 3 * lineHeight(TextStyle{}); h != want {
+		t.Errorf("three lines measured %v pixels tall, want %v", h, want)
+	}
+	if face(defaultTextSize) == face(2*defaultTextSize) {
+		t.Errorf("text sizes share a face")
+	}
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Tiled stores flip flags in the top bits of tile ids
+const tiledFlipMask = 0x1fffffff
+
+// The parts of a map in Tiled's JSON format (.tmj) that we can import. See https://doc.mapeditor.org/en/stable/reference/json-map-format/
+type tiledMap struct {
+	// Size in tiles
+	Width, Height int
+	// Size of a tile in pixels
+	TileWidth, TileHeight int
+	Layers                []tiledLayer
+	Tilesets              []tiledTileset
+}
+
+type tiledLayer struct {
+	Name string
+	// "tilelayer" or "objectgroup"
+	Type string
+	// Size in tiles and tile ids by row, for tile layers
+	Width, Height int
+	Data          []uint32
+	// For object layers
+	Objects    []tiledObject
+	Properties []tiledProperty
+}
+
+type tiledTileset struct {
+	// Tile id of the first tile in this tileset
+	FirstGID uint32
+	// Path of the tileset image, relative to the map
+	Image string
+	// Set when the tileset is stored in its own file, which we don't support
+	Source                string
+	TileWidth, TileHeight int
+	Columns               int
+	Margin, Spacing       int
+}
+
+type tiledObject struct {
+	Name string
+	// The object's kind, called type in older versions of Tiled and class in newer ones
+	Type, Class string
+	// Top left corner and size in pixels
+	X, Y, Width, Height float64
+	Properties          []tiledProperty
+}
+
+type tiledProperty struct {
+	Name  string
+	Value interface{}
+}
+
+// Finds the property with the given name, if set.
+func tiledProp(props []tiledProperty, name string) (interface{}, bool) {
+	for _, p := range props {
+		if p.Name == name {
+			return p.Value, true
+		}
+	}
+	return nil, false
+}
+
+// Replaces a level with one imported from a map made with the Tiled editor, saved in its JSON format. Like decode,
+// assets are not loaded. Each tile is one world unit square.
+//
+// Tile layers become art, except layers named "collision" or with a true "collision" property which become blocks.
+// Tileset images are expected to be in the resources directory under the same file name.
+//
+// Objects become level features according to their type: "spawn" sets the spawn point, "block" adds a block, and
+// "trigger" adds a trigger for the game event of the object's name which plays the audio in its "audio" property.
+// Object rotation is ignored.
+func (l *Level) importTiled(p string) error {
+	*l = NewLevel()
+	f, err := os.Open(p)
+	if err != nil {
+		return fmt.Errorf("open tiled map: %w", err)
+	}
+	defer f.Close()
+	var m tiledMap
+	err = json.NewDecoder(f).Decode(&m)
+	if err != nil {
+		return fmt.Errorf("decode tiled map: %w", err)
+	}
+	if m.TileWidth == 0 || m.TileHeight == 0 {
+		return fmt.Errorf("map has no tile size")
+	}
+	for _, layer := range m.Layers {
+		switch layer.Type {
+		case "tilelayer":
+			err := l.importTileLayer(m, layer)
+			if err != nil {
+				return fmt.Errorf("layer %v: %w", layer.Name, err)
+			}
+		case "objectgroup":
+			for _, o := range layer.Objects {
+				err := l.importTiledObject(m, o)
+				if err != nil {
+					return fmt.Errorf("layer %v object %v: %w", layer.Name, o.Name, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Converts a position in the map's pixels, with y pointing down from the top, to world units.
+func (m tiledMap) toWorld(px, py float64) (float64, float64) {
+	return px / float64(m.TileWidth), float64(m.Height) - py/float64(m.TileHeight)
+}
+
+func (l *Level) importTileLayer(m tiledMap, layer tiledLayer) error {
+	collision := strings.EqualFold(layer.Name, "collision")
+	if v, ok := tiledProp(layer.Properties, "collision"); ok {
+		collision = v == true
+	}
+	if len(layer.Data) > 0 && layer.Width <= 0 {
+		return fmt.Errorf("layer %q: width %v is not positive", layer.Name, layer.Width)
+	}
+	for i, gid := range layer.Data {
+		gid &= tiledFlipMask
+		if gid == 0 {
+			continue
+		}
+		col, row := i%layer.Width, i/layer.Width
+		// center of the tile in world units
+		x, y := m.toWorld((float64(col)+0.5)*float64(m.TileWidth), (float64(row)+0.5)*float64(m.TileHeight))
+		var t Mx
+		t.Translate(x, y)
+		if collision {
+			l.Blocks = append(l.Blocks, &Block{T: t})
+			continue
+		}
+		region, err := m.tileRegion(gid)
+		if err != nil {
+			return err
+		}
+		l.Art = append(l.Art, &Art{T: t, Path: region})
+	}
+	return nil
+}
+
+// Finds the resource path of the atlas region containing the given tile.
+func (m tiledMap) tileRegion(gid uint32) (string, error) {
+	var ts *tiledTileset
+	for i := range m.Tilesets {
+		if m.Tilesets[i].FirstGID <= gid && (ts == nil || m.Tilesets[i].FirstGID > ts.FirstGID) {
+			ts = &m.Tilesets[i]
+		}
+	}
+	if ts == nil {
+		return "", fmt.Errorf("no tileset for tile %v", gid)
+	}
+	if ts.Source != "" {
+		return "", fmt.Errorf("external tileset %v is not supported, embed it in the map", ts.Source)
+	}
+	if ts.Columns == 0 {
+		return "", fmt.Errorf("tileset %v has no columns", ts.Image)
+	}
+	i := int(gid - ts.FirstGID)
+	x := ts.Margin + (i%ts.Columns)*(ts.TileWidth+ts.Spacing)
+	y := ts.Margin + (i/ts.Columns)*(ts.TileHeight+ts.Spacing)
+	return fmt.Sprintf("%v#%d,%d,%d,%d", path.Join("resources", filepath.Base(ts.Image)), x, y, ts.TileWidth, ts.TileHeight), nil
+}
+
+func (l *Level) importTiledObject(m tiledMap, o tiledObject) error {
+	kind := o.Type
+	if kind == "" {
+		kind = o.Class
+	}
+	cx, cy := m.toWorld(o.X+o.Width/2, o.Y+o.Height/2)
+	switch kind {
+	case "spawn":
+		l.Spawn.X, l.Spawn.Y = cx, cy
+	case "block":
+		var t Mx
+		t.Scale(o.Width/float64(m.TileWidth), o.Height/float64(m.TileHeight))
+		t.Translate(cx, cy)
+		l.Blocks = append(l.Blocks, &Block{T: t})
+	case "trigger":
+		v, ok := tiledProp(o.Properties, "audio")
+		p, isString := v.(string)
+		if !ok || !isString {
+			return fmt.Errorf("trigger needs an audio property")
+		}
+		l.Triggers[o.Name] = Trigger{Audio: &Audio{Path: p}}
+	}
+	return nil
+}
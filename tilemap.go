@@ -0,0 +1,312 @@
+package main
+
+import (
+	"fmt"
+	"github.com/ByteArena/box2d"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hherman1/gobananas/resources"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// A grid of tiles drawn from a tileset image, painted in the editor.
+type Tilemap struct {
+	// Path of the tileset image, a grid of equally sized square tiles. e.g "resources/grass.png"
+	Tileset string
+	// Side length of each tile in the tileset in pixels
+	TileSize int
+	// World position of the bottom left corner of the grid
+	X, Y float64
+	// Size of the grid in tiles
+	Width, Height int
+	// Tile per cell, row by row from the bottom. 0 is empty, otherwise the tileset's tiles are numbered from 1
+	// left to right, top to bottom.
+	Tiles []int
+	// If true, painted tiles are solid platforms in the game
+	Solid bool `json:",omitempty"`
+
+	// The loaded tileset
+	img *ebiten.Image
+}
+
+// Load the tileset from resources
+func (t *Tilemap) Load() error {
+	img, err := resources.Image(t.Tileset)
+	if err != nil {
+		return fmt.Errorf("load tileset: %w", err)
+	}
+	t.img = img
+	if t.count() == 0 {
+		return fmt.Errorf("tileset %v is smaller than a %v pixel tile", t.Tileset, t.TileSize)
+	}
+	return nil
+}
+
+// Number of tiles in the tileset
+func (t *Tilemap) count() int {
+	w, h := t.img.Size()
+	return (w / t.TileSize) * (h / t.TileSize)
+}
+
+// Finds the cell containing the given world position. The cell may be outside the grid.
+func (t *Tilemap) cell(wx, wy float64) (int, int) {
+	return int(math.Floor(wx - t.X)), int(math.Floor(wy - t.Y))
+}
+
+// Returns the tile in the given cell, 0 if empty or outside the grid.
+func (t *Tilemap) get(cx, cy int) int {
+	if cx < 0 || cy < 0 || cx >= t.Width || cy >= t.Height {
+		return 0
+	}
+	return t.Tiles[cy*t.Width+cx]
+}
+
+// Sets the tile in the given cell, growing the grid if the cell is outside it.
+func (t *Tilemap) set(cx, cy, tile int) {
+	if cx < 0 || cy < 0 || cx >= t.Width || cy >= t.Height {
+		if tile == 0 {
+			return
+		}
+		// grow to fit, shifting the origin for cells to the left or below
+		minx, miny := minInt(cx, 0), minInt(cy, 0)
+		maxx, maxy := maxInt(cx+1, t.Width), maxInt(cy+1, t.Height)
+		w, h := maxx-minx, maxy-miny
+		tiles := make([]int, w*h)
+		for y := 0; y < t.Height; y++ {
+			copy(tiles[(y-miny)*w-minx:], t.Tiles[y*t.Width:(y+1)*t.Width])
+		}
+		t.X += float64(minx)
+		t.Y += float64(miny)
+		t.Tiles, t.Width, t.Height = tiles, w, h
+		cx, cy = cx-minx, cy-miny
+	}
+	t.Tiles[cy*t.Width+cx] = tile
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// Adds blocks for each horizontal run of painted tiles to the game, if the tilemap is solid. The blocks aren't added
+// to the game's entities since the tiles themselves are drawn instead.
+func (t *Tilemap) build(g *Game) {
+	if !t.Solid {
+		return
+	}
+	for y := 0; y < t.Height; y++ {
+		for x := 0; x < t.Width; {
+			if t.get(x, y) == 0 {
+				x++
+				continue
+			}
+			start := x
+			for x < t.Width && t.get(x, y) != 0 {
+				x++
+			}
+			var m Mx
+			m.Translate(0.5, 0.5)
+			m.Scale(float64(x-start), 1)
+			m.Translate(t.X+float64(start), t.Y+float64(y))
+			b := &Block{T: m}
+
+			body := box2d.NewB2BodyDef()
+			center, angle, hw, hh := b.box()
+			body.Position = center
+			body.Angle = angle
+			entity := &Entity{
+				w:            hw * 2,
+				h:            hh * 2,
+				b:            g.world.CreateBody(body),
+				restoresJump: true,
+			}
+			entity.b.SetUserData(entity)
+			entity.b.CreateFixtureFromDef(b.fixture(hw, hh))
+		}
+	}
+}
+
+// Draws every tile of the map in a single batch
+func (t *Tilemap) draw(screen *ebiten.Image, toScreen Mx) {
+	if t.img == nil {
+		return
+	}
+	w, _ := t.img.Size()
+	cols := w / t.TileSize
+	if cols == 0 {
+		return
+	}
+	var vertices []ebiten.Vertex
+	var is []uint16
+	for y := 0; y < t.Height; y++ {
+		for x := 0; x < t.Width; x++ {
+			tile := t.get(x, y)
+			if tile == 0 {
+				continue
+			}
+			if len(vertices)+4 > math.MaxUint16 {
+				// flush, indices must fit in 16 bits
+				screen.DrawTriangles(vertices, is, t.img, &ebiten.DrawTrianglesOptions{})
+				vertices, is = vertices[:0], is[:0]
+			}
+			sx := float32(((tile - 1) % cols) * t.TileSize)
+			sy := float32(((tile - 1) / cols) * t.TileSize)
+			size := float32(t.TileSize)
+			wx, wy := t.X+float64(x), t.Y+float64(y)
+			base := uint16(len(vertices))
+			// world y points up and image y points down, so the top of the cell takes the top of the tile
+			corners := []struct {
+				wx, wy, sx, sy float64
+			}{
+				{wx, wy + 1, float64(sx), float64(sy)},
+				{wx + 1, wy + 1, float64(sx + size), float64(sy)},
+				{wx, wy, float64(sx), float64(sy + size)},
+				{wx + 1, wy, float64(sx + size), float64(sy + size)},
+			}
+			for _, c := range corners {
+				dx, dy := toScreen.Apply(c.wx, c.wy)
+				vertices = append(vertices, ebiten.Vertex{
+					DstX:   float32(dx),
+					DstY:   float32(dy),
+					SrcX:   float32(c.sx),
+					SrcY:   float32(c.sy),
+					ColorR: 1,
+					ColorG: 1,
+					ColorB: 1,
+					ColorA: 1,
+				})
+			}
+			is = append(is, base, base+1, base+2, base+1, base+2, base+3)
+		}
+	}
+	if len(vertices) > 0 {
+		screen.DrawTriangles(vertices, is, t.img, &ebiten.DrawTrianglesOptions{})
+	}
+}
+
+// Editor for painting tilemaps. Type "<tileset path> <tile size>" to start painting with a tileset, then left click
+// to paint and shift click to erase.
+type TileEditor struct {
+	t *Typer
+	// The tilemap being painted, if any
+	m *Tilemap
+	// The tile to paint
+	tile int
+
+	e *Editor
+}
+
+func ActivateTileEditor(r *Root, e *Editor) {
+	te := &TileEditor{
+		t: &Typer{
+			Placeholder: "Tile Editor: Press enter to pick a tileset, e.g 'resources/grass.png 16'",
+			C:           &e.c,
+		},
+		tile: 1,
+		e:    e,
+	}
+	if len(e.l.Tilemaps) > 0 {
+		te.m = e.l.Tilemaps[len(e.l.Tilemaps)-1]
+	}
+	r.a = te
+}
+
+func (t *TileEditor) Layout(outsideWidth, outsideHeight int) (screenWidth, screenHeight int) {
+	return t.e.Layout(outsideWidth, outsideHeight)
+}
+
+func (t *TileEditor) String() string {
+	return "Tiles"
+}
+
+// Switches to painting the tilemap using the tileset described by cmd, "<path> <tile size>", creating it if needed.
+func (t *TileEditor) pick(cmd string) error {
+	parts := strings.Fields(cmd)
+	if len(parts) != 2 {
+		return fmt.Errorf("expected '<tileset path> <tile size>'")
+	}
+	size, err := strconv.Atoi(parts[1])
+	if err != nil || size <= 0 {
+		return fmt.Errorf("invalid tile size %v", parts[1])
+	}
+	for _, m := range t.e.l.Tilemaps {
+		if m.Tileset == parts[0] && m.TileSize == size {
+			t.m = m
+			return nil
+		}
+	}
+	m := &Tilemap{Tileset: parts[0], TileSize: size}
+	err = m.Load()
+	if err != nil {
+		return err
+	}
+	m.X, m.Y = t.e.c.Cursor()
+	m.X, m.Y = math.Floor(m.X), math.Floor(m.Y)
+	t.e.l.Tilemaps = append(t.e.l.Tilemaps, m)
+	t.m = m
+	t.tile = 1
+	return nil
+}
+
+func (t *TileEditor) Update(r *Root) error {
+	cmd, typ := t.t.Update()
+	if typ {
+		return nil
+	}
+	if cmd != "" {
+		err := t.pick(cmd)
+		if err != nil {
+			t.t.Placeholder = fmt.Sprintf("Failed to use %v: %v", cmd, err)
+		} else {
+			t.t.Placeholder = fmt.Sprintf("Painting with %v", t.m.Tileset)
+		}
+	}
+	if t.m != nil {
+		// empty tilesets are rejected on load, this keeps one which slipped through from crashing the editor
+		if n := t.m.count(); n > 0 {
+			if Clicked(ebiten.KeyRightBracket) {
+				t.tile = t.tile%n + 1
+			}
+			if Clicked(ebiten.KeyLeftBracket) {
+				t.tile = (t.tile+n-2)%n + 1
+			}
+		}
+		if Clicked(ebiten.KeyTab) {
+			t.m.Solid = !t.m.Solid
+		}
+		if driver.IsMouseButtonPressed(ebiten.MouseButtonLeft) {
+			cx, cy := t.m.cell(t.e.c.Cursor())
+			if driver.IsKeyPressed(ebiten.KeyShift) {
+				t.m.set(cx, cy, 0)
+			} else {
+				t.m.set(cx, cy, t.tile)
+			}
+		}
+	}
+	return t.e.Update(r)
+}
+
+func (t *TileEditor) Draw(screen *ebiten.Image) {
+	t.e.Draw(screen)
+	t.t.Draw(screen)
+	if t.m == nil {
+		return
+	}
+	// preview the tile under the cursor
+	cx, cy := t.m.cell(t.e.c.Cursor())
+	preview := Tilemap{Tileset: t.m.Tileset, TileSize: t.m.TileSize, X: t.m.X + float64(cx), Y: t.m.Y + float64(cy),
+		Width: 1, Height: 1, Tiles: []int{t.tile}, img: t.m.img}
+	preview.draw(screen, t.e.c.ToScreen())
+	printAt(screen, fmt.Sprintf("([/]) Tile %v/%v  (Tab) Solid %v  (Shift+Click) Erase",
+		t.tile, t.m.count(), t.m.Solid), 10, t.e.c.sh-35)
+}
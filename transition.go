@@ -0,0 +1,88 @@
+package main
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+	"image"
+)
+
+// Ticks a transition between apps takes
+const transitionTicks = ticksPerSecond / 3
+
+// How a transition reveals the app being switched to
+type TransitionStyle int
+
+const (
+	// The new app fades in over the old
+	transitionFade TransitionStyle = iota
+	// The new app is uncovered from left to right
+	transitionWipe
+)
+
+// Shows the switch from one app to another over a few ticks rather than cutting straight to it. The new app is
+// updated as normal while both are drawn, the old frozen as it was.
+type Transition struct {
+	from, to App
+	style    TransitionStyle
+	ticks    int
+	// The new app's frame, drawn over the old
+	frame *ebiten.Image
+}
+
+// Switches to the given app with a transition from the current one. If a transition is already underway it's cut
+// short, transitioning from the app it was switching to.
+func (r *Root) transition(to App, style TransitionStyle) {
+	from := r.a
+	if t, ok := from.(*Transition); ok {
+		from = t.to
+	}
+	r.a = &Transition{from: from, to: to, style: style}
+}
+
+func (t *Transition) Layout(outsideWidth, outsideHeight int) (screenWidth, screenHeight int) {
+	t.from.Layout(outsideWidth, outsideHeight)
+	return t.to.Layout(outsideWidth, outsideHeight)
+}
+
+// Updates the new app, making way for it once the transition is over. If the new app switches to another, that
+// switch takes over.
+func (t *Transition) Update(r *Root) error {
+	t.ticks++
+	r.a = t.to
+	err := t.to.Update(r)
+	if r.a == t.to && t.ticks < transitionTicks {
+		r.a = t
+	}
+	return err
+}
+
+// Fraction of the way through the transition, from 0 to 1
+func (t *Transition) progress() float64 {
+	f := float64(t.ticks) / transitionTicks
+	if f > 1 {
+		return 1
+	}
+	return f
+}
+
+func (t *Transition) Draw(screen *ebiten.Image) {
+	w, h := screen.Size()
+	if t.frame == nil || t.frame.Bounds().Dx() != w || t.frame.Bounds().Dy() != h {
+		if t.frame != nil {
+			t.frame.Dispose()
+		}
+		t.frame = ebiten.NewImage(w, h)
+	}
+	t.frame.Clear()
+	t.from.Draw(screen)
+	t.to.Draw(t.frame)
+	f := t.progress()
+	switch t.style {
+	case transitionWipe:
+		edge := int(f * float64(w))
+		screen.DrawImage(t.frame.SubImage(image.Rect(0, 0, edge, h)).(*ebiten.Image), nil)
+	default:
+		op := &ebiten.DrawImageOptions{}
+		op.ColorM.Scale(1, 1, 1, f)
+		screen.DrawImage(t.frame, op)
+	}
+}
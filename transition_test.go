@@ -0,0 +1,65 @@
+package main
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+	"testing"
+)
+
+// An app which counts its updates, switching to next on the given update if set
+type countingApp struct {
+	updates  int
+	switchAt int
+	next     App
+}
+
+func (c *countingApp) Layout(outsideWidth, outsideHeight int) (int, int) {
+	return outsideWidth, outsideHeight
+}
+
+func (c *countingApp) Update(r *Root) error {
+	c.updates++
+	if c.next != nil && c.updates == c.switchAt {
+		r.a = c.next
+	}
+	return nil
+}
+
+func (c *countingApp) Draw(screen *ebiten.Image) {}
+
+func TestTransitionUpdatesNewApp(t *testing.T) {
+	r, _, _ := testEditor(t)
+	from, to := &countingApp{}, &countingApp{}
+	r.a = from
+	r.transition(to, transitionFade)
+	for i := 1; i < transitionTicks; i++ {
+		frame(t, r)
+		if _, ok := r.a.(*Transition); !ok {
+			t.Fatalf("transition ended after %v ticks, want %v", i, transitionTicks)
+		}
+	}
+	frame(t, r)
+	if r.a != to {
+		t.Fatalf("switched to %T at the end of the transition, want the new app", r.a)
+	}
+	if from.updates != 0 || to.updates != transitionTicks {
+		t.Errorf("updated the old app %v times and the new %v, want 0 and %v", from.updates, to.updates,
+			transitionTicks)
+	}
+}
+
+func TestTransitionGivesWayToSwitches(t *testing.T) {
+	r, _, _ := testEditor(t)
+	next := &countingApp{}
+	to := &countingApp{switchAt: 2, next: next}
+	r.transition(to, transitionWipe)
+	frame(t, r)
+	frame(t, r)
+	if r.a != next {
+		t.Fatalf("kept transitioning to %T after it switched apps, want its switch", r.a)
+	}
+	r.transition(&countingApp{}, transitionFade)
+	r.transition(&countingApp{}, transitionFade)
+	if tr := r.a.(*Transition); tr.from != next {
+		t.Errorf("transitioned from %T, want the app the cut short transition was switching to", tr.from)
+	}
+}
@@ -40,6 +40,35 @@ func drawpoint(img *ebiten.Image, x, y float64, px int, toScreen Mx, c color.Col
 	drawline(img, sx - float64(px)/2, sy + float64(px)/2, sx + float64(px)/2, sy - float64(px)/2, 3, Mx{}, c)
 }
 
+// Utility for drawing a circle outline in world coordinates
+func drawcircle(img *ebiten.Image, x, y, radius float64, thickness float64, toScreen Mx, c color.Color) {
+	const segments = 32
+	for i := 0; i < segments; i++ {
+		a0 := 2 * math.Pi * float64(i) / segments
+		a1 := 2 * math.Pi * float64(i+1) / segments
+		drawline(img, x+radius*math.Cos(a0), y+radius*math.Sin(a0), x+radius*math.Cos(a1), y+radius*math.Sin(a1), thickness, toScreen, c)
+	}
+}
+
+// Utility for drawing the outline of a unit square centered at the origin under the given transform
+func drawoutline(img *ebiten.Image, m Mx, thickness float64, toScreen Mx, c color.Color) {
+	corners := [][2]float64{{-0.5, -0.5}, {0.5, -0.5}, {0.5, 0.5}, {-0.5, 0.5}}
+	for i, corner := range corners {
+		next := corners[(i+1)%len(corners)]
+		x0, y0 := m.Apply(corner[0], corner[1])
+		x1, y1 := m.Apply(next[0], next[1])
+		drawline(img, x0, y0, x1, y1, thickness, toScreen, c)
+	}
+}
+
+// The transform of a unit square centered at the origin to the axis aligned box with the given opposite corners
+func boxBetween(x0, y0, x1, y1 float64) Mx {
+	var m Mx
+	m.Scale(math.Abs(x1-x0), math.Abs(y1-y0))
+	m.Translate((x0+x1)/2, (y0+y1)/2)
+	return m
+}
+
 func colorToScale(clr color.Color) (float64, float64, float64, float64) {
 	cr, cg, cb, ca := clr.RGBA()
 	if ca == 0 {
@@ -0,0 +1,495 @@
+package main
+
+import (
+	"fmt"
+	"github.com/hherman1/gobananas/resources"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Game events which fire triggers of the same name
+var gameEvents = map[string]bool{
+	"jump":  true,
+	"shoot": true,
+	"hurt":  true,
+	"died":  true,
+}
+
+// Blocks thinner than this in world units are reported as degenerate
+const minBlockSize = 1e-3
+
+// The range of sizes in world units Box2D simulates moving bodies well at, since it treats world units as meters
+const (
+	minBodySize = 0.1
+	maxBodySize = 10
+)
+
+// Checks a decoded level for mistakes that would break or confuse the game, returning a description of each problem
+// found. Assets are loaded to check that they exist but the level itself is left unchanged.
+func (l *Level) validate() []string {
+	var problems []string
+	report := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+	if l.modified {
+		report("level: doesn't match the hash it was saved with, it was changed outside the editor")
+	}
+
+	// assets
+	checkImage := func(what, path string) {
+		if _, err := resources.Image(path); err != nil {
+			report("%v: %v", what, err)
+		}
+	}
+	checkShader := func(what string, s *CustomShader) {
+		if s == nil {
+			return
+		}
+		kopy := *s
+		if err := kopy.Load(); err != nil {
+			report("%v: %v", what, err)
+		}
+	}
+	for i, b := range l.Blocks {
+		checkShader(fmt.Sprintf("block %v shader", i), b.Shader)
+	}
+	for i, a := range l.Art {
+		checkImage(fmt.Sprintf("art %v", i), a.Path)
+		checkShader(fmt.Sprintf("art %v shader", i), a.Shader)
+		for _, v := range a.Variants {
+			checkImage(fmt.Sprintf("art %v variant", i), v)
+		}
+		if a.Parent != nil && l.parent(a) == nil {
+			report("art %v: pinned to block %v, which doesn't exist", i, *a.Parent)
+		}
+		if a.Normal != "" {
+			checkImage(fmt.Sprintf("art %v normal map", i), a.Normal)
+			img, err := resources.Image(a.Path)
+			normal, nerr := resources.Image(a.Normal)
+			if err == nil && nerr == nil && normal.Bounds().Size() != img.Bounds().Size() {
+				report("art %v: normal map is %v but its image is %v, it's lit without it", i, normal.Bounds().Size(),
+					img.Bounds().Size())
+			}
+		}
+		if a.Slice != nil {
+			if img, err := resources.Image(a.Path); err == nil && !a.Slice.fits(img.Size()) {
+				w, h := img.Size()
+				report("art %v: nine slice insets don't fit its %vx%v image", i, w, h)
+			}
+		}
+	}
+	if l.BGArt != nil {
+		checkImage("background art", l.BGArt.Path)
+	}
+	if l.PlayerArt != nil {
+		checkImage("player art", l.PlayerArt.Path)
+	}
+	if l.PixelsPerUnit < 0 {
+		report("level: negative pixels per unit %v", l.PixelsPerUnit)
+	}
+	for _, n := range l.characterNames() {
+		c, _ := l.character(n)
+		if s := scaleCharacter(c, l.playerScale()); s.Width > 0 && s.Height > 0 &&
+			(math.Min(s.Width, s.Height) < minBodySize || math.Max(s.Width, s.Height) > maxBodySize) {
+			report("character %q: %.2fx%.2f at player scale %v, outside the %v to %v units physics handles well",
+				n, s.Width, s.Height, l.playerScale(), minBodySize, maxBodySize)
+		}
+		if c.Art != nil {
+			checkImage(fmt.Sprintf("character %q art", n), c.Art.Path)
+		}
+		if c.Width < minBlockSize || c.Height < minBlockSize {
+			report("character %q: zero size (%.3fx%.3f)", n, c.Width, c.Height)
+		}
+		if c.Speed <= 0 {
+			report("character %q: speed %v, so they can't walk", n, c.Speed)
+		}
+	}
+	if _, ok := l.character(l.Character); !ok {
+		report("level: starts as unknown character %q", l.Character)
+	}
+	for i, s := range l.Stations {
+		if _, ok := l.character(s.Character); !ok || s.Character == "" {
+			report("station %v: unknown character %q", i, s.Character)
+		}
+		if _, _, hw, hh := decompose(s.T); hw*2 < minBlockSize || hh*2 < minBlockSize {
+			report("station %v: zero size, so it can't be entered", i)
+		}
+	}
+	for i, en := range l.Enemies {
+		if _, _, hw, hh := decompose(en.T); hw*2 < minBlockSize || hh*2 < minBlockSize {
+			report("enemy %v: zero size", i)
+		}
+		if en.Health < 0 || en.Interval < 0 || en.Range < 0 {
+			report("enemy %v: negative health %v, interval %v or range %v", i, en.Health, en.Interval, en.Range)
+		}
+		if _, ok := l.Triggers[en.Trigger]; en.Trigger != "" && !ok {
+			report("enemy %v: no trigger named %q", i, en.Trigger)
+		}
+		for j, p := range en.Phases {
+			if p.Health <= 0 {
+				report("enemy %v phase %v: health %v, so it ends straight away", i, j, p.Health)
+			}
+			if !patterns[p.Pattern] {
+				report("enemy %v phase %v: unknown pattern %q", i, j, p.Pattern)
+			}
+			if p.Interval < 0 || p.Count < 0 {
+				report("enemy %v phase %v: negative interval %v or count %v", i, j, p.Interval, p.Count)
+			}
+			if _, ok := l.Triggers[p.Trigger]; p.Trigger != "" && !ok {
+				report("enemy %v phase %v: no trigger named %q", i, j, p.Trigger)
+			}
+		}
+	}
+	for i, z := range l.CameraZones {
+		if _, _, hw, hh := decompose(z.T); hw*2 < minBlockSize || hh*2 < minBlockSize {
+			report("camera zone %v: zero size, so the player can't enter it", i)
+		}
+		if z.Zoom < 0 {
+			report("camera zone %v: negative zoom %v", i, z.Zoom)
+		}
+		if _, ok := l.Triggers[z.Trigger]; z.Trigger != "" && !ok {
+			report("camera zone %v: no trigger named %q", i, z.Trigger)
+		}
+	}
+	for i, lt := range l.Lights {
+		if lt.Radius <= 0 {
+			report("light %v: no radius, it lights nothing", i)
+		}
+	}
+	if l.Shadows && len(l.Lights) == 0 {
+		report("level: has shadows but no lights to cast them")
+	}
+	if l.Bloom < 0 {
+		report("level: negative bloom %v", l.Bloom)
+	}
+	if gr := l.Grade; gr != nil && (gr.Saturation < -1 || gr.Brightness < -1) {
+		report("level: grade removes more than all saturation or brightness")
+	}
+	if d := l.DayNight; d != nil && (d.Length <= 0 || d.Start < 0 || d.Start >= 1) {
+		report("level: day night cycle of %vs from %v, want a positive length starting from 0 up to 1", d.Length,
+			d.Start)
+	}
+	for i, t := range l.Tilemaps {
+		checkImage(fmt.Sprintf("tilemap %v", i), t.Tileset)
+	}
+	for i, p := range l.Prefabs {
+		if _, err := p.decode(); err != nil {
+			report("prefab %v: %v", i, err)
+		}
+	}
+	for i, lb := range l.Labels {
+		if strings.TrimSpace(lb.Text) == "" {
+			report("label %v: no text", i)
+		}
+		if lb.Size <= 0 {
+			report("label %v: size %v is too small to read", i, lb.Size)
+		}
+	}
+	if l.BGAudio != nil {
+		if _, err := resources.Audio(l.BGAudio.Path); err != nil {
+			report("background audio: %v", err)
+		}
+	}
+	for i, a := range l.Ambients {
+		if _, err := resources.Audio(a.Audio.Path); err != nil {
+			report("ambient sound %v: %v", i, err)
+		}
+		if a.Radius <= 0 {
+			report("ambient sound %v: radius %v is too small to be heard", i, a.Radius)
+		}
+	}
+	for i, z := range l.MusicZones {
+		if _, err := resources.Audio(z.Audio.Path); err != nil {
+			report("music zone %v: %v", i, err)
+		}
+		if _, _, hw, hh := decompose(z.T); hw*2 < minBlockSize || hh*2 < minBlockSize {
+			report("music zone %v: zero size, so its music never plays", i)
+		}
+	}
+
+	// triggers, in a stable order
+	var names []string
+	for n := range l.Triggers {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	fired := make(map[string]bool)
+	for _, s := range l.Switches {
+		fired[s.Trigger] = true
+	}
+	for _, b := range l.Blocks {
+		if b.Name != "" {
+			fired[touchEvent(b.Name)] = true
+		}
+	}
+	for _, z := range l.CameraZones {
+		fired[z.Trigger] = true
+	}
+	for _, en := range l.Enemies {
+		fired[en.Trigger] = true
+		for _, p := range en.Phases {
+			fired[p.Trigger] = true
+		}
+	}
+	for _, n := range names {
+		if !gameEvents[n] && !fired[n] {
+			report("trigger %q: no game event has this name, so it never fires", n)
+		}
+		t := l.Triggers[n]
+		if _, ok := l.spawnPoint(t.Spawn); !ok {
+			report("trigger %q: no spawn point named %q", n, t.Spawn)
+		}
+		if t.Audio != nil {
+			if _, err := resources.Audio(t.Audio.Path); err != nil {
+				report("trigger %q audio: %v", n, err)
+			}
+		}
+		if t.Shake != nil && (t.Shake.Amplitude <= 0 || t.Shake.Frequency <= 0 || t.Shake.Decay <= 0) {
+			report("trigger %q: shake needs a positive amplitude, frequency and decay, it never shakes", n)
+		}
+		if t.Dialogue != nil && len(t.Dialogue.Pages) == 0 {
+			report("trigger %q: dialogue has no pages, it's never shown", n)
+		}
+		if c := t.Cutscene; c != nil {
+			// keys must be in order, counting from the start of the cutscene
+			last := 0.0
+			for i, k := range c.Camera {
+				if k.Time < last {
+					report("trigger %q: cutscene camera key %v at %vs is before the key before it", n, i, k.Time)
+				}
+				last = k.Time
+			}
+			for i, m := range c.Moves {
+				if m.Art < 0 || m.Art >= len(l.Art) {
+					report("trigger %q: cutscene move %v is of art %v, which doesn't exist", n, i, m.Art)
+				}
+				last = 0
+				for j, k := range m.Keys {
+					if k.Time < last {
+						report("trigger %q: cutscene move %v key %v at %vs is before the key before it", n, i, j, k.Time)
+					}
+					last = k.Time
+				}
+			}
+			for i, s := range c.Sounds {
+				if _, err := resources.Audio(s.Audio.Path); err != nil {
+					report("trigger %q cutscene sound %v: %v", n, i, err)
+				}
+			}
+		}
+		if t.Time != nil && l.DayNight == nil {
+			report("trigger %q: changes the time of day, but the level has no day night cycle", n)
+		}
+	}
+
+	// blocks
+	for i, b := range l.Blocks {
+		if _, ok := materialColors[b.Material]; b.Material != "" && !ok {
+			report("block %v: unknown material %q", i, b.Material)
+		}
+		if b.Restitution < 0 {
+			report("block %v: negative restitution %v", i, b.Restitution)
+		}
+		_, _, hw, hh := b.box()
+		if hw*2 < minBlockSize || hh*2 < minBlockSize {
+			report("block %v: zero size (%.3fx%.3f)", i, hw*2, hh*2)
+			continue
+		}
+		for _, n := range l.spawnNames() {
+			p, _ := l.spawnPoint(n)
+			if !spawnOverlaps(p.X, p.Y, b.T) {
+				continue
+			}
+			if n == "" {
+				report("block %v: overlaps the spawn point", i)
+			} else {
+				report("block %v: overlaps spawn point %q", i, n)
+			}
+		}
+	}
+	if l.Goal != nil {
+		if _, _, hw, hh := decompose(*l.Goal); hw*2 < minBlockSize || hh*2 < minBlockSize {
+			report("goal: zero size, so it can't be reached")
+		}
+	}
+	if l.CameraBounds != nil {
+		if _, _, hw, hh := decompose(*l.CameraBounds); hw*2 < minBlockSize || hh*2 < minBlockSize {
+			report("camera bounds: zero size")
+		}
+	}
+	for i, c := range l.Contraptions {
+		_, _, hw, hh := decompose(c.T)
+		if hw*2 < minBlockSize || hh*2 < minBlockSize {
+			report("contraption %v: zero size plank", i)
+		}
+	}
+	for i, p := range l.Portals {
+		for _, end := range []Mx{p.A, p.B} {
+			if _, _, hw, _ := decompose(end); hw*2 < minBlockSize {
+				report("portal %v: zero width end", i)
+			}
+		}
+	}
+	for i, s := range l.Switches {
+		if _, _, hw, hh := decompose(s.T); hw*2 < minBlockSize || hh*2 < minBlockSize {
+			report("switch %v: zero size, so it can't be pressed", i)
+		}
+		if _, ok := l.Triggers[s.Trigger]; s.Trigger != "" && !ok {
+			report("switch %v: no trigger named %q", i, s.Trigger)
+		}
+		for _, t := range s.Targets {
+			if t < 0 || t >= len(l.Blocks) {
+				report("switch %v: targets block %v, which doesn't exist", i, t)
+			}
+		}
+	}
+	return problems
+}
+
+// Checks a decoded level for structural problems which would crash the game, like null list entries or tilemaps
+// with the wrong number of tiles. Unlike validate, any problem found means the level can't be used.
+func (l *Level) check() error {
+	for i, b := range l.Blocks {
+		if b == nil {
+			return fmt.Errorf("block %v is null", i)
+		}
+	}
+	for i, a := range l.Art {
+		if a == nil {
+			return fmt.Errorf("art %v is null", i)
+		}
+	}
+	for i, r := range l.Ropes {
+		if r == nil {
+			return fmt.Errorf("rope %v is null", i)
+		}
+	}
+	for i, a := range l.Attractors {
+		if a == nil {
+			return fmt.Errorf("attractor %v is null", i)
+		}
+	}
+	for i, p := range l.Portals {
+		if p == nil {
+			return fmt.Errorf("portal %v is null", i)
+		}
+	}
+	for i, c := range l.Contraptions {
+		if c == nil {
+			return fmt.Errorf("contraption %v is null", i)
+		}
+		// the plank is a dynamic body, which box2d can't give mass if it has no area
+		if _, _, hw, hh := decompose(c.T); hw*2 < minBlockSize || hh*2 < minBlockSize {
+			return fmt.Errorf("contraption %v: plank %.4gx%.4g is too small", i, hw*2, hh*2)
+		}
+	}
+	for i, s := range l.Switches {
+		if s == nil {
+			return fmt.Errorf("switch %v is null", i)
+		}
+	}
+	for i, a := range l.Ambients {
+		if a == nil {
+			return fmt.Errorf("ambient sound %v is null", i)
+		}
+	}
+	for i, z := range l.MusicZones {
+		if z == nil {
+			return fmt.Errorf("music zone %v is null", i)
+		}
+	}
+	for i, z := range l.CameraZones {
+		if z == nil {
+			return fmt.Errorf("camera zone %v is null", i)
+		}
+	}
+	for i, lt := range l.Lights {
+		if lt == nil {
+			return fmt.Errorf("light %v is null", i)
+		}
+	}
+	// the player's box can't be empty or inside out
+	if l.PlayerScale < 0 {
+		return fmt.Errorf("negative player scale %v", l.PlayerScale)
+	}
+	for n, c := range l.Characters {
+		if c == nil {
+			return fmt.Errorf("character %q is null", n)
+		}
+		if c.Width <= 0 || c.Height <= 0 {
+			return fmt.Errorf("character %q: size %vx%v is not positive", n, c.Width, c.Height)
+		}
+	}
+	for i, s := range l.Stations {
+		if s == nil {
+			return fmt.Errorf("station %v is null", i)
+		}
+	}
+	for i, en := range l.Enemies {
+		if en == nil {
+			return fmt.Errorf("enemy %v is null", i)
+		}
+	}
+	for i, lb := range l.Labels {
+		if lb == nil {
+			return fmt.Errorf("label %v is null", i)
+		}
+	}
+	for i, p := range l.Prefabs {
+		if p == nil {
+			return fmt.Errorf("prefab %v is null", i)
+		}
+	}
+	for i, t := range l.Tilemaps {
+		if t == nil {
+			return fmt.Errorf("tilemap %v is null", i)
+		}
+		if t.TileSize <= 0 {
+			return fmt.Errorf("tilemap %v: tile size %v is not positive", i, t.TileSize)
+		}
+		// sizes are checked by division too, so huge ones can't overflow to match
+		if t.Width < 0 || t.Height < 0 || (t.Width == 0) != (t.Height == 0) || len(t.Tiles) != t.Width*t.Height ||
+			(t.Width > 0 && len(t.Tiles)/t.Width != t.Height) {
+			return fmt.Errorf("tilemap %v: %v tiles don't fill a %vx%v grid", i, len(t.Tiles), t.Width, t.Height)
+		}
+	}
+	return nil
+}
+
+// Whether a player spawned at the given point would start inside the block with the given transform. The player is a
+// unit square.
+func spawnOverlaps(x, y float64, block Mx) bool {
+	inv := block
+	inv.Invert()
+	inside := func(wx, wy float64) bool {
+		lx, ly := inv.Apply(wx, wy)
+		return lx > -0.5 && lx < 0.5 && ly > -0.5 && ly < 0.5
+	}
+	// any corner of the player inside the block, or the block's center inside the player
+	for _, c := range [][2]float64{{-0.5, -0.5}, {0.5, -0.5}, {-0.5, 0.5}, {0.5, 0.5}} {
+		if inside(x+c[0], y+c[1]) {
+			return true
+		}
+	}
+	cx, cy := block.Apply(0, 0)
+	return cx > x-0.5 && cx < x+0.5 && cy > y-0.5 && cy < y+0.5
+}
+
+// Loads the level at the given path and prints any problems with it. Returns an error if there were any.
+func validateLevel(path string) error {
+	var l Level
+	err := l.decode(path)
+	if err != nil {
+		return err
+	}
+	problems := l.validate()
+	for _, p := range problems {
+		fmt.Println(p)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%v has %v problems", path, len(problems))
+	}
+	fmt.Println(path, "is valid")
+	return nil
+}